@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/shikaan/keydex/pkg/config"
+	"github.com/shikaan/keydex/pkg/credentials"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/info"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/shikaan/keydex/tui"
+	"github.com/spf13/cobra"
+)
+
+var Add = &cobra.Command{
+	Use:   "add [file] [reference]",
+	Short: "Add an entry from a template.",
+	Long: `Add an entry from a template.
+
+Creates an entry at 'reference' in the database at 'file' with the fields of a template, and opens the editor
+on it. The entry is added to the database once saved.
+
+The 'file' is the path to the *.kdbx database. It can be passed either as an argument or via the ` + ENV_DATABASE + ` environment variable.
+The 'reference' is the path of the group followed by the title of the entry. A reference ending with "/" keeps
+the title of the template. Without reference, the entry is added to the root group.
+
+'--template' chooses the template, ignoring case. Built-in templates are ` + builtinTemplateNames() + `.
+Templates are also read from the 'templates' section of the configuration, and from the entries of the
+"` + kdbx.TEMPLATES_GROUP + `" group of the database, replacing those with the same name. The default template is set
+by the 'template' setting. See the 'config' command.
+
+See "Examples" for more details.`,
+	Example: `  # Add a "github" login to the "coding" group of the "test" database at test.kdbx
+  ` + info.NAME + ` add test.kdbx /test/coding/github
+
+  # Add a Wi-Fi network to the root group
+  ` + info.NAME + ` add --template wi-fi test.kdbx /test/home`,
+	Args:    cobra.MaximumNArgs(2),
+	PreRunE: DatabaseMustBeDefined(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, reference, key := ReadDatabaseArguments(cmd, args)
+		template, _ := cmd.Flags().GetString("template")
+		if template == "" {
+			template = kdbx.DefaultTemplate
+		}
+
+		if err := ConfigureTUI(cmd); err != nil {
+			return err
+		}
+
+		log.Infof(
+			"Using: database: %s, reference: %s, key: %s, template: %s",
+			database,
+			orDefault(reference),
+			orDefault(key),
+			template)
+
+		return add(readPassphraseSource(cmd, "", database), database, key, reference, template)
+	},
+	DisableAutoGenTag: true,
+}
+
+func add(source credentials.PassphraseSource, databasePath, keyPath, reference, templateName string) error {
+	database, err := openDatabase(source, databasePath, keyPath)
+	if err != nil {
+		return err
+	}
+	database.Backups = config.Current().Backups
+
+	template, err := database.GetTemplate(templateName)
+	if err != nil {
+		return err
+	}
+
+	entry, err := database.NewEntryFromTemplate(template)
+	if err != nil {
+		return err
+	}
+
+	group := database.GetRootGroup()
+	if reference != "" {
+		groupPath, title := splitReference(reference)
+		if group = database.GetFirstGroupByPath(groupPath); group == nil {
+			return errors.MakeTypedError(errors.ErrNotFound, "Missing group at "+groupPath+".", "add")
+		}
+
+		if title != "" {
+			entry.SetValue(kdbx.TITLE_KEY, title)
+		}
+	}
+
+	if group == nil {
+		return errors.MakeError("Cannot add entries to a database without groups.", "add")
+	}
+
+	reference, err = database.MakeEntryEntityPath(entry, group)
+	if err != nil {
+		return err
+	}
+
+	return tui.Run(tui.State{
+		Entry:     entry,
+		Group:     group,
+		Database:  database,
+		Reference: reference,
+	}, false)
+}
+
+// Splits reference into the path of its group and the title of the entry
+func splitReference(reference string) (kdbx.EntityPath, string) {
+	index := strings.LastIndex(reference, kdbx.PATH_SEPARATOR)
+	return reference[:index+1], reference[index+1:]
+}
+
+func builtinTemplateNames() string {
+	names := []string{}
+	for _, t := range kdbx.BUILTIN_TEMPLATES {
+		names = append(names, t.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/shikaan/keydex/pkg/agent"
+	"github.com/shikaan/keydex/pkg/config"
+	"github.com/shikaan/keydex/pkg/credentials"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/info"
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/shikaan/keydex/pkg/secure"
+	"github.com/spf13/cobra"
+)
+
+// Time the agent is given to start listening, when started in the background
+const AGENT_START_TIMEOUT = 5 * time.Second
+
+var Agent = &cobra.Command{
+	Use:   "agent",
+	Short: "Keeps databases unlocked for a while, like ssh-agent.",
+	Long: `Keeps databases unlocked for a while, like ssh-agent.
+
+The agent is a background process that holds unlocked databases in memory. When it
+is running, the 'copy', 'list', and 'show' commands ask the agent for entries, and
+prompt for the passphrase only when the agent has not unlocked the database yet.
+
+Databases are forgotten after the time set by '--ttl', when they change on disk, and
+when the agent is locked with 'agent lock'.
+
+The agent listens on a socket that only the current user can access, at
+$XDG_RUNTIME_DIR/` + info.NAME + `/` + agent.SOCKET_FILE + ` or in the temporary directory by default. Its
+path can be changed with the ` + agent.ENV_AGENT_SOCKET + ` environment variable. Where the
+operating system allows it, connections from other users are refused, agents of other users
+are not sent passphrases, and the agent does not start when the default directory of its socket
+belongs to another user or can be read by others.`,
+	Example: `  # Start the agent, keeping databases unlocked for an hour
+  ` + info.NAME + ` agent start --ttl 1h
+
+  # The passphrase is asked only once
+  ` + info.NAME + ` copy vault.kdbx /vault/coding/github
+  ` + info.NAME + ` copy -f UserName vault.kdbx /vault/coding/github
+
+  # Forget all the unlocked databases
+  ` + info.NAME + ` agent lock`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+	DisableAutoGenTag: true,
+}
+
+var AgentStart = &cobra.Command{
+	Use:   "start",
+	Short: "Starts the agent in the background.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := readAgentSocket()
+		ttl, err := readAgentTTL(cmd)
+		if err != nil {
+			return err
+		}
+
+		if foreground, _ := cmd.Flags().GetBool("foreground"); foreground {
+			return serveAgent(path, ttl)
+		}
+
+		if agent.NewClient(path).IsRunning() {
+			return errors.MakeError("An agent is already listening on "+path+".", "agent")
+		}
+
+		executable, err := os.Executable()
+		if err != nil {
+			return errors.MakeError("Cannot start agent: "+err.Error(), "agent")
+		}
+
+		daemon := exec.Command(executable, "agent", "start", "--foreground", "--ttl", ttl.String())
+		daemon.Env = append(os.Environ(), agent.ENV_AGENT_SOCKET+"="+path)
+		daemon.SysProcAttr = detachedProcessAttributes()
+		if err := daemon.Start(); err != nil {
+			return errors.MakeError("Cannot start agent: "+err.Error(), "agent")
+		}
+		daemon.Process.Release()
+
+		client := agent.NewClient(path)
+		for deadline := time.Now().Add(AGENT_START_TIMEOUT); !client.IsRunning(); {
+			if time.Now().After(deadline) {
+				return errors.MakeError("Agent did not start. Check logs for details.", "agent")
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+
+		fmt.Println("Agent listening on " + path)
+		return nil
+	},
+	DisableAutoGenTag: true,
+}
+
+var AgentStop = &cobra.Command{
+	Use:   "stop",
+	Short: "Locks and stops the agent.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return agent.NewClient(readAgentSocket()).Stop()
+	},
+	DisableAutoGenTag: true,
+}
+
+var AgentLock = &cobra.Command{
+	Use:   "lock",
+	Short: "Makes the agent forget all the unlocked databases.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return agent.NewClient(readAgentSocket()).Lock()
+	},
+	DisableAutoGenTag: true,
+}
+
+var AgentStatus = &cobra.Command{
+	Use:   "status",
+	Short: "Lists the databases unlocked in the agent.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vaults, err := agent.NewClient(readAgentSocket()).Status()
+		if err != nil {
+			return err
+		}
+
+		for _, vault := range vaults {
+			expires := "until locked"
+			if !vault.Expires.IsZero() {
+				expires = "expires in " + time.Until(vault.Expires).Round(time.Second).String()
+			}
+			fmt.Printf("%s (%s)\n", vault.Database, expires)
+		}
+
+		return nil
+	},
+	DisableAutoGenTag: true,
+}
+
+// Runs the agent until it is stopped or the process is interrupted
+func serveAgent(path string, ttl time.Duration) error {
+	listener, err := agent.Listen(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	server := agent.NewServer(ttl)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		server.Stop()
+	}()
+
+	log.Info("Agent listening on " + path)
+	return server.Serve(listener)
+}
+
+// Returns the path of the agent socket, from the environment falling back
+// to the configuration
+func readAgentSocket() string {
+	if os.Getenv(agent.ENV_AGENT_SOCKET) == "" && config.Current().Agent.Socket != "" {
+		return config.Current().Agent.Socket
+	}
+
+	return agent.SocketPath()
+}
+
+// Returns the time after which the agent forgets databases, from the --ttl
+// flag falling back to the configuration
+func readAgentTTL(cmd *cobra.Command) (time.Duration, error) {
+	ttlFlag := cmd.Flag("ttl")
+	if ttlFlag != nil && ttlFlag.Changed {
+		return cmd.Flags().GetDuration("ttl")
+	}
+
+	if configured := config.Current().Agent.TTL; configured != 0 {
+		return max(configured, 0), nil
+	}
+
+	return agent.DEFAULT_TTL, nil
+}
+
+// Returns a client of the running agent, or nil if there is none or if the
+// --no-agent flag is passed
+func readAgentClient(cmd *cobra.Command) *agent.Client {
+	if noAgent, _ := cmd.Flags().GetBool("no-agent"); noAgent {
+		return nil
+	}
+
+	client := agent.NewClient(readAgentSocket())
+	if !client.IsRunning() {
+		return nil
+	}
+
+	return client
+}
+
+// Runs request against the agent. If the agent has not unlocked the database
+// yet, the passphrase is read and the request is retried.
+func askAgent(client *agent.Client, database, key string, source credentials.PassphraseSource, request func(database, key string) error) error {
+	database, key, err := absolutePaths(database, key)
+	if err != nil {
+		return err
+	}
+
+	if err := request(database, key); err != agent.ErrLocked {
+		return err
+	}
+
+	err = credentials.WithPassphrase(database, source, func(passphrase *secure.Buffer) error {
+		return client.Unlock(database, key, passphrase)
+	})
+	if err != nil {
+		return err
+	}
+
+	return request(database, key)
+}
+
+// The agent identifies databases by path, and might run in another directory
+func absolutePaths(database, key string) (string, string, error) {
+	absDatabase, err := filepath.Abs(database)
+	if err != nil {
+		return "", "", errors.MakeError("Cannot resolve "+database+": "+err.Error(), "agent")
+	}
+
+	if key == "" {
+		return absDatabase, "", nil
+	}
+
+	absKey, err := filepath.Abs(key)
+	if err != nil {
+		return "", "", errors.MakeError("Cannot resolve "+key+": "+err.Error(), "agent")
+	}
+
+	return absDatabase, absKey, nil
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/shikaan/keydex/pkg/clipboard"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+const CLEAR_COMMAND = "clear-clipboard"
+
+// Payload sent to the clear helper via stdin, to keep it off the process
+// arguments and environment
+type clearRequest struct {
+	Hash     string `json:"hash"`
+	Previous string `json:"previous"`
+}
+
+// Internal command, spawned as a detached process by commands copying to the
+// clipboard. It waits for the timeout and then restores the clipboard, so
+// that the parent command can exit right away.
+var Clear = &cobra.Command{
+	Use:    CLEAR_COMMAND + " [duration]",
+	Short:  "Restores the clipboard after a timeout.",
+	Hidden: true,
+	Args:   cobra.ExactArgs(1),
+	// Nobody is waiting for the output, and the terminal might be in use
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		after, err := time.ParseDuration(args[0])
+		if err != nil {
+			return errors.MakeError("Invalid duration: "+err.Error(), "clear")
+		}
+
+		if _, err := UseClipboardBackend(cmd); err != nil {
+			return err
+		}
+
+		request := clearRequest{}
+		if err := json.NewDecoder(os.Stdin).Decode(&request); err != nil {
+			return errors.MakeError("Invalid request: "+err.Error(), "clear")
+		}
+
+		time.Sleep(after)
+
+		if err := clipboard.RestoreIfUnchanged(request.Hash, request.Previous); err != nil {
+			log.Error("Could not clear clipboard", err)
+			return err
+		}
+
+		log.Info("Clipboard cleared")
+		return nil
+	},
+	DisableAutoGenTag: true,
+}
+
+// Copies value to the clipboard and, if after is not zero, spawns a detached
+// process restoring the clipboard once after has elapsed. The process uses
+// the clipboard backend described by backend.
+func copyAndScheduleClear(value, backend string, after time.Duration) error {
+	previous, err := clipboard.Swap(value)
+	if err != nil {
+		return err
+	}
+
+	if after <= 0 {
+		return nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.MakeTypedError(errors.ErrClipboard, "Cannot schedule clipboard clear: "+err.Error(), "copy")
+	}
+
+	helper := exec.Command(executable, CLEAR_COMMAND, "--clipboard", backend, after.String())
+	helper.SysProcAttr = detachedProcessAttributes()
+	// OSC 52 needs a terminal to write to, and the detached process has no
+	// controlling terminal: it is handed over the one of this process
+	if clipboard.Current().Name() == clipboard.BACKEND_OSC52 {
+		helper.Stderr = os.Stderr
+	}
+
+	stdin, err := helper.StdinPipe()
+	if err != nil {
+		return errors.MakeTypedError(errors.ErrClipboard, "Cannot schedule clipboard clear: "+err.Error(), "copy")
+	}
+
+	if err := helper.Start(); err != nil {
+		return errors.MakeTypedError(errors.ErrClipboard, "Cannot schedule clipboard clear: "+err.Error(), "copy")
+	}
+
+	request := clearRequest{Hash: clipboard.Hash(value), Previous: previous}
+	if err := json.NewEncoder(stdin).Encode(request); err != nil {
+		return errors.MakeTypedError(errors.ErrClipboard, "Cannot schedule clipboard clear: "+err.Error(), "copy")
+	}
+
+	if err := stdin.Close(); err != nil {
+		return errors.MakeTypedError(errors.ErrClipboard, "Cannot schedule clipboard clear: "+err.Error(), "copy")
+	}
+
+	log.Infof("Clipboard will be cleared in %s", after)
+	return helper.Process.Release()
+}
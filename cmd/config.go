@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shikaan/keydex/pkg/config"
+	"github.com/shikaan/keydex/pkg/info"
+	"github.com/spf13/cobra"
+)
+
+var Config = &cobra.Command{
+	Use:   "config",
+	Short: "Read and write the configuration file.",
+	Long: `Read and write the configuration file.
+
+Settings are identified by dotted keys, mirroring the sections of the TOML file.
+Available settings are:
+
+  database                    path to the default database
+  key                         path to the key file of the default database
+  no_passphrase               unlock the default database with the key file only
+  profile                     profile used when '--profile' is not passed
+  backups                     number of backups kept when saving, 0 to disable
+  template                    template of new entries when none is chosen, Login by default
+  clipboard.clear_after       time after which the clipboard is restored, negative to disable
+  clipboard.backend           clipboard backend, as in the '--clipboard' flag
+  generator.length            length of generated passwords
+  generator.lowercase         use lowercase letters in generated passwords
+  generator.uppercase         use uppercase letters in generated passwords
+  generator.digits            use digits in generated passwords
+  generator.symbols           use symbols in generated passwords
+  generator.profiles.<name>.* settings of the generator profile <name>, as above
+  tui.theme                   colour theme of the editor: default, dark, light, or high-contrast
+  tui.styles.<element>        style of <element>, e.g. "bold yellow on blue"
+  tui.keymap                  keybindings preset of the editor: nano (default), emacs, or vi
+  tui.keybindings.<action>    keys bound to <action> in the editor, e.g. "Ctrl+S" or "Ctrl+X Ctrl+S"
+  tui.lock_after              time of inactivity after which the editor locks the database, negative to disable
+  tui.lock_policy             unsaved changes on lock: discard (default), or keep them encrypted in memory
+  url.opener                  command line opening URLs, which receives the URL as last argument
+  url.allow_commands          run cmd:// URLs of entries as command lines
+  url.copy_password           copy the password to the clipboard when opening URLs
+  agent.ttl                   time after which the agent forgets databases, negative to disable
+  agent.socket                path of the agent socket
+  profiles.<name>.database    path to the database of profile <name>
+  profiles.<name>.key         path to the key file of profile <name>
+  profiles.<name>.no_passphrase  unlock the database of profile <name> with the key file only
+  templates.<name>.fields     fields of the template <name>, set in the configuration file
+
+Templates list their fields in order. Each field has a key, and optionally a default value,
+whether it is protected, and the generator profile filling its value: "default" for the
+generator settings, "alphanumeric", or the name of a profile.
+
+  [[templates.server.fields]]
+  key = "Password"
+  protected = true
+  generator = "pin"
+
+Entries of the "Templates" group of a database are templates too, named after their title.
+Their other values are the fields of new entries, and values like {NEWPASSWORD} or
+{NEWPASSWORD:name} are generated with the default or the named profile.
+
+Styled elements are title, dirty, read_only, label, value, focused, protected, meta,
+notification, shortcut, prompt, selected, match, and no_match. Styles list attributes
+(bold, dim, italic, underline, reverse, blink, strikethrough), a foreground colour,
+and a background colour after "on". Colours are names or hex codes like #ff8800.
+Colours are disabled when the NO_COLOR environment variable is set.
+
+Editor actions are quit, browse, save, new, delete, groups, copy, reveal, help,
+cancel, undo, redo, expand, add_field, remove_field, rename_field,
+protect_field, open_url, tree, add_group, and rename_group. Inputs also take the line editing actions
+line_start, line_end, word_left, word_right, delete_word, delete_next_word, and
+kill_line.
+
+Settings are read with the following precedence: flags, then environment variables,
+then the configuration file.`,
+	Example: `  # Open vault.kdbx when no database is passed
+  ` + info.NAME + ` config set database ~/vault.kdbx
+
+  # Define a "work" profile and use it
+  ` + info.NAME + ` config set profiles.work.database ~/work.kdbx
+  ` + info.NAME + ` open --profile work
+
+  # Keep copied values in the clipboard for 10 seconds
+  ` + info.NAME + ` config set clipboard.clear_after 10s
+
+  # Use emacs keybindings, saving with Ctrl+S
+  ` + info.NAME + ` config set tui.keymap emacs
+  ` + info.NAME + ` config set tui.keybindings.save Ctrl+S
+
+  # Use the dark theme, with yellow labels
+  ` + info.NAME + ` config set tui.theme dark
+  ` + info.NAME + ` config set tui.styles.label "bold yellow"
+
+  # Unset a setting
+  ` + info.NAME + ` config set clipboard.clear_after ""`,
+	// Configuration commands must work with broken configuration files
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+	DisableAutoGenTag: true,
+}
+
+var ConfigGet = &cobra.Command{
+	Use:   "get [key]",
+	Short: "Prints the value of a setting.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, _, err := readConfig()
+		if err != nil {
+			return err
+		}
+
+		value, err := c.Get(args[0])
+		if err != nil {
+			return err
+		}
+
+		if value != "" {
+			fmt.Println(value)
+		}
+		return nil
+	},
+	DisableAutoGenTag: true,
+}
+
+var ConfigSet = &cobra.Command{
+	Use:   "set [key] [value]",
+	Short: "Sets the value of a setting. An empty value unsets it.",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, path, err := readConfig()
+		if err != nil {
+			return err
+		}
+
+		if err := c.Set(args[0], args[1]); err != nil {
+			return err
+		}
+
+		return c.Save(path)
+	},
+	DisableAutoGenTag: true,
+}
+
+var ConfigPath = &cobra.Command{
+	Use:   "path",
+	Short: "Prints the path of the configuration file.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.Path()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(path)
+		return nil
+	},
+	DisableAutoGenTag: true,
+}
+
+func readConfig() (*config.Config, string, error) {
+	path, err := config.Path()
+	if err != nil {
+		return nil, "", err
+	}
+
+	c, err := config.Load(path)
+	return c, path, err
+}
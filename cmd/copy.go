@@ -1,9 +1,9 @@
 package cmd
 
 import (
-	"os"
+	"time"
 
-	"github.com/shikaan/keydex/pkg/clipboard"
+	"github.com/shikaan/keydex/pkg/agent"
 	"github.com/shikaan/keydex/pkg/credentials"
 	"github.com/shikaan/keydex/pkg/errors"
 	"github.com/shikaan/keydex/pkg/info"
@@ -24,6 +24,11 @@ The 'file' is the the path to the *.kdbx database. It can be passed either as an
 The 'reference' can be passed either as the last argument, or can be read from stdin - to allow piping.
 Use the 'list' command to get a list of all the references in the database.
 
+When the agent is running, the entry is read through it. See the 'agent' command.
+
+The clipboard is restored after the time set by '--clear-after', unless it has been
+changed in the meantime. Use '--clear-after 0' to keep the value in the clipboard.
+
 See "Examples" for more details.`,
 	Example: `  # Copy the password of the "github" entry in the "coding" group in the "test" database at test.kdbx
   ` + info.NAME + ` copy test.kdbx /test/coding/github
@@ -31,6 +36,12 @@ See "Examples" for more details.`,
   # Or copy the username instead
   ` + info.NAME + ` copy -f username test.kdbx /test/coding/github
 
+  # Clear the clipboard after 10 seconds
+  ` + info.NAME + ` copy --clear-after 10s test.kdbx /test/coding/github
+
+  # Copy to the local clipboard from a remote machine, over SSH
+  ` + info.NAME + ` copy --clipboard osc52 test.kdbx /test/coding/github
+
   # Or with stdin
   export ` + ENV_PASSPHRASE + `=${MY_SECRET_PHRASE}
   echo "/test/coding/github" | ` + info.NAME + ` copy test.kdbx
@@ -49,13 +60,20 @@ See "Examples" for more details.`,
 	// Initially this command only copied passowrds, hence the aliases.
 	// Keeping them around for backwards compatibility.
 	Aliases: []string{"cp", "password", "pwd", "copy-password"},
-	Args: cobra.MatchAll(
-		cobra.MaximumNArgs(2),
-		DatabaseMustBeDefined(),
-	),
+	Args:    cobra.MaximumNArgs(2),
+	PreRunE: DatabaseMustBeDefined(),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		database, reference, key := ReadDatabaseArguments(cmd, args)
 		field := cmd.Flag("field").Value.String()
+		clearAfter, err := ReadClearAfter(cmd)
+		if err != nil {
+			return err
+		}
+
+		backend, err := UseClipboardBackend(cmd)
+		if err != nil {
+			return err
+		}
 
 		log.Infof(
 			"Using: database: %s, reference: %s, key: %s",
@@ -63,14 +81,12 @@ See "Examples" for more details.`,
 			orDefault(reference),
 			orDefault(key))
 
-		passphrase := credentials.GetPassphrase(database, os.Getenv(ENV_PASSPHRASE))
-
-		return copy(database, key, passphrase, reference, field)
+		return copy(readAgentClient(cmd), readPassphraseSource(cmd, "", database), database, key, reference, field, backend, clearAfter)
 	},
 	DisableAutoGenTag: true,
 }
 
-func copy(databasePath, keyPath, passphrase, reference, field string) error {
+func copy(client *agent.Client, source credentials.PassphraseSource, databasePath, keyPath, reference, field, backend string, clearAfter time.Duration) error {
 	reference, err := ReadReferenceFromStdin(reference)
 
 	if reference == "" {
@@ -81,26 +97,37 @@ func copy(databasePath, keyPath, passphrase, reference, field string) error {
 		return err
 	}
 
-	db, err := kdbx.OpenFromPath(databasePath, passphrase, keyPath)
-	if err != nil {
-		return err
+	if field == DEFAULT_FIELD {
+		field = kdbx.PASSWORD_KEY
 	}
 
-	if entry := db.GetFirstEntryByPath(reference); entry != nil {
+	if client != nil {
 		var value string
-
-		if field == DEFAULT_FIELD {
-			value = entry.GetPassword()
-		} else {
-			value = entry.GetContent(field)
+		err := askAgent(client, databasePath, keyPath, source, func(database, key string) (err error) {
+			value, err = client.Get(database, key, reference, field)
+			return err
+		})
+		if err != nil {
+			return err
 		}
 
-		if value == "" {
-			return errors.MakeError(`Missing field "`+field+`" in entry "`+reference+`".`, "copy")
-		}
+		return copyAndScheduleClear(value, backend, clearAfter)
+	}
+
+	db, err := openDatabase(source, databasePath, keyPath)
+	if err != nil {
+		return err
+	}
+
+	entry, err := db.GetEntryByPath(reference)
+	if err != nil {
+		return err
+	}
 
-		return clipboard.Write(value)
+	value := entry.GetContent(field)
+	if value == "" {
+		return errors.MakeTypedError(errors.ErrNotFound, `Missing field "`+field+`" in entry "`+reference+`".`, "copy")
 	}
 
-	return errors.MakeError(`Missing entry at "`+reference+`".`, "copy")
+	return copyAndScheduleClear(value, backend, clearAfter)
 }
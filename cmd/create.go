@@ -6,10 +6,12 @@ import (
 	"strings"
 
 	"github.com/shikaan/keydex/pkg/cli"
+	"github.com/shikaan/keydex/pkg/config"
 	"github.com/shikaan/keydex/pkg/credentials"
 	"github.com/shikaan/keydex/pkg/errors"
 	"github.com/shikaan/keydex/pkg/info"
 	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/secure"
 	"github.com/shikaan/keydex/tui"
 	"github.com/spf13/cobra"
 )
@@ -20,14 +22,32 @@ var Create = &cobra.Command{
 	Aliases: []string{"new"},
 	Long: `Create an empty KeePass archive.
 
-Creates a new KeePass database at 'file' called 'name'. You will be prompted to set a passphrase for the new database.
+Creates a new KeePass database at 'file' called 'name'. You will be prompted to set a passphrase for the new database,
+and offered to generate a key file. '--key' uses an existing key file instead.
+
+'--no-passphrase' protects the database with the key file only, for unattended use. Anyone who can read the key
+file can unlock the database. Other commands need '--no-passphrase', or 'no_passphrase' in the configuration, to
+skip the passphrase prompt for such databases.
+
+Databases use the KDBX 4 format, AES encryption, and Argon2d key derivation by default. Flags choose
+another cipher, key derivation function, or its parameters. '--kdf-target' measures this machine, and
+tunes the key derivation to take about the given time to unlock the database.
 
 See "Examples" for more details.`,
 	Example: `  # Create a new database called "vault" at vault.kdbx
   ` + info.NAME + ` create vault.kdbx vault
 
   # Create a new database at a specific path
-  ` + info.NAME + ` create ~/passwords/work.kdbx work`,
+  ` + info.NAME + ` create ~/passwords/work.kdbx work
+
+  # Use ChaCha20, and make unlocking take about one second on this machine
+  ` + info.NAME + ` create --cipher chacha20 --kdf-target 1s vault.kdbx vault
+
+  # Create a database protected by an existing key file only
+  ` + info.NAME + ` create --no-passphrase --key automation.key automation.kdbx automation
+
+  # Create a KDBX 3.1 database, for older clients
+  ` + info.NAME + ` create --format-version 3 vault.kdbx vault`,
 	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		path := args[0]
@@ -37,25 +57,43 @@ See "Examples" for more details.`,
 			return errors.MakeError("Database file "+path+" already exists.", "create")
 		}
 
-		passphrase, err := credentials.MakePassphrase(path)
+		encryption, err := readEncryptionOptions(cmd, kdbx.DEFAULT_ENCRYPTION_OPTIONS)
 		if err != nil {
 			return err
 		}
 
-		keyfilepath := ""
-		if cli.Confirm("Do you want to create a keyfile?") {
+		keyfilepath, _ := cmd.Flags().GetString("key")
+		noPassphrase, _ := cmd.Flags().GetBool("no-passphrase")
+
+		if keyfilepath != "" {
+			if _, err := credentials.ReadKeyFile(keyfilepath); err != nil {
+				return err
+			}
+		}
+
+		passphrase := secure.NewBuffer(0)
+		if !noPassphrase {
+			if passphrase, err = credentials.MakePassphrase(path); err != nil {
+				return err
+			}
+		}
+		defer passphrase.Destroy()
+
+		generatedKey := false
+		if keyfilepath == "" && (noPassphrase || cli.Confirm("Do you want to create a keyfile?")) {
 			filename := strings.Replace(filepath.Base(path), filepath.Ext(path), "-key.xml", 1)
 			keyfilepath = filepath.Join(filepath.Dir(path), filename)
 
 			if err = credentials.CreateXMLKeyFileV2(keyfilepath); err != nil {
 				return err
 			}
+			generatedKey = true
 		}
 
 		success := false
 		defer func() {
 			if !success {
-				if keyfilepath != "" {
+				if generatedKey {
 					_ = os.Remove(keyfilepath)
 				}
 				_ = os.Remove(path)
@@ -74,7 +112,11 @@ See "Examples" for more details.`,
 			return err
 		}
 
-		if err = db.SetPasswordAndKey(passphrase, keyfilepath); err != nil {
+		if err = db.SetPassphraseAndKey(passphrase, keyfilepath); err != nil {
+			return err
+		}
+
+		if err = db.SetEncryption(encryption); err != nil {
 			return err
 		}
 		rootGroup := db.NewGroup(name)
@@ -89,6 +131,11 @@ See "Examples" for more details.`,
 		success = true
 
 		if cli.Confirm("Creation successful. Do you want to open the database?") {
+			if err := ConfigureTUI(cmd); err != nil {
+				return err
+			}
+			db.Backups = config.Current().Backups
+
 			return tui.Run(tui.State{
 				Entry:     nil,
 				Group:     nil,
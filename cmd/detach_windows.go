@@ -0,0 +1,17 @@
+//go:build windows
+
+package cmd
+
+import "syscall"
+
+const detachedProcess = 0x00000008
+const createNewProcessGroup = 0x00000200
+
+// Runs the process without a console and in a new process group, so that
+// it survives the parent
+func detachedProcessAttributes() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		CreationFlags: detachedProcess | createNewProcessGroup,
+		HideWindow:    true,
+	}
+}
@@ -19,7 +19,9 @@ var Diff = &cobra.Command{
 or modified. Output follows the unified diff format so it can be piped into other tools.
 
 The 'file-a' and 'file-b' arguments are paths to the *.kdbx archives to compare.
-Passphrases can be provided via environment variables to avoid interactive prompts.`,
+Passphrases can be provided via environment variables to avoid interactive prompts, or
+read from the '--passphrase-fd-a', '--passphrase-file-a', and '--passphrase-cmd-a' flags,
+and the ` + ENV_PASSPHRASE_COMMAND + `_A variable, and their '-b' and _B counterparts.`,
 	Use: "diff [file-a] [file-b]",
 	Args: cobra.ExactArgs(2),
 	Example: `  # Compare two archives
@@ -30,6 +32,9 @@ Passphrases can be provided via environment variables to avoid interactive promp
   export ` + ENV_PASSPHRASE_B + `=${PASSPHRASE_B}
   ` + info.NAME + ` diff old.kdbx new.kdbx
 
+  # Reading the passphrases from a password manager
+  ` + info.NAME + ` diff --passphrase-cmd-a "pass show old" --passphrase-cmd-b "pass show new" old.kdbx new.kdbx
+
   # With key files
   ` + info.NAME + ` diff --key-a old.key --key-b new.key old.kdbx new.kdbx`,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -50,21 +55,18 @@ Passphrases can be provided via environment variables to avoid interactive promp
 			}
 		}
 
-		passphraseA := credentials.GetPassphrase(fileA, os.Getenv(ENV_PASSPHRASE_A))
-		passphraseB := credentials.GetPassphrase(fileB, os.Getenv(ENV_PASSPHRASE_B))
-
-		return diff(fileA, fileB, keyA, keyB, passphraseA, passphraseB)
+		return diff(fileA, fileB, keyA, keyB, readPassphraseSource(cmd, "-a", fileA), readPassphraseSource(cmd, "-b", fileB))
 	},
 	DisableAutoGenTag: true,
 }
 
-func diff(fileA, fileB, keyA, keyB, passphraseA, passphraseB string) error {
-	dbA, err := kdbx.OpenFromPath(fileA, passphraseA, keyA)
+func diff(fileA, fileB, keyA, keyB string, sourceA, sourceB credentials.PassphraseSource) error {
+	dbA, err := openDatabase(sourceA, fileA, keyA)
 	if err != nil {
 		return err
 	}
 
-	dbB, err := kdbx.OpenFromPath(fileB, passphraseB, keyB)
+	dbB, err := openDatabase(sourceB, fileB, keyB)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shikaan/keydex/pkg/credentials"
+	"github.com/shikaan/keydex/pkg/info"
+	"github.com/spf13/cobra"
+)
+
+var keyFileFormatNames = map[credentials.KeyFileFormat]string{
+	credentials.KEY_FILE_XML_V1: "XML, version 1.0",
+	credentials.KEY_FILE_XML_V2: "XML, version 2.0",
+	credentials.KEY_FILE_BINARY: "32 bytes binary key",
+	credentials.KEY_FILE_HEX:    "64 characters hexadecimal key",
+	credentials.KEY_FILE_HASHED: "arbitrary file, hashed with SHA-256",
+}
+
+var Keyfile = &cobra.Command{
+	Use:   "keyfile",
+	Short: "Creates and checks key files.",
+	Long: `Creates and checks key files.
+
+Key files are an additional credential, used together with the passphrase to unlock a
+database. ` + info.NAME + ` reads the same formats as KeePass 2 and KeePassXC, detected in this order:
+
+  xml-v1   XML key files with a base64 key, written by KeePass 2.46 and earlier
+  xml-v2   XML key files with an hexadecimal key and its hash, written by KeePass 2.47
+           and later, and by KeePassXC. The hash is verified when present.
+  binary   files of exactly 32 bytes, used as the key
+  hex      files of exactly 64 hexadecimal characters, decoded into the key
+  hashed   any other file, whose SHA-256 hash is the key
+
+Since any file can be a key file, a damaged key file in a structured format may still be
+read as 'hashed'. Use 'keyfile inspect' to check how a key file is read.`,
+	Example: `  # Create a new key file
+  ` + info.NAME + ` keyfile create vault-key.xml
+
+  # Show the format of a key file
+  ` + info.NAME + ` keyfile inspect vault-key.xml
+
+  # Check that a key file unlocks a database
+  ` + info.NAME + ` keyfile verify vault-key.xml vault.kdbx`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+	DisableAutoGenTag: true,
+}
+
+var KeyfileCreate = &cobra.Command{
+	Use:   "create <file>",
+	Short: "Creates a new random key file.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+
+		if err := credentials.CreateKeyFile(args[0], format); err != nil {
+			return err
+		}
+
+		fmt.Println("Key file created at " + args[0] + ". Keep a copy of it: databases using it cannot be unlocked without it.")
+		return nil
+	},
+	DisableAutoGenTag: true,
+}
+
+var KeyfileInspect = &cobra.Command{
+	Use:   "inspect <file>",
+	Short: "Prints the format and the hash of a key file.",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keyFile, err := credentials.ReadKeyFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Format: %s (%s)\n", keyFile.Format, keyFileFormatNames[keyFile.Format])
+		fmt.Printf("Hash: %s\n", keyFile.Hash())
+		return nil
+	},
+	DisableAutoGenTag: true,
+}
+
+var KeyfileVerify = &cobra.Command{
+	Use:   "verify <file> [database]",
+	Short: "Checks that a key file is valid, and optionally that it unlocks a database.",
+	Long: `Checks that a key file is valid, and optionally that it unlocks a database.
+
+The key file is valid when it can be read, and its hash matches the key for XML v2 key files.
+When 'database' is passed, the passphrase is read as for the other commands and the database
+is unlocked with both.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := args[0]
+		if _, err := credentials.ReadKeyFile(path); err != nil {
+			return err
+		}
+
+		if len(args) == 1 {
+			fmt.Println("Key file " + path + " is valid.")
+			return nil
+		}
+
+		database := args[1]
+		if _, err := openDatabase(readPassphraseSource(cmd, "", database), database, path); err != nil {
+			return err
+		}
+
+		fmt.Println("Key file " + path + " unlocks " + database + ".")
+		return nil
+	},
+	DisableAutoGenTag: true,
+}
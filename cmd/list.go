@@ -2,10 +2,10 @@ package cmd
 
 import (
 	"fmt"
-	"os"
 	"sort"
 	"strings"
 
+	"github.com/shikaan/keydex/pkg/agent"
 	"github.com/shikaan/keydex/pkg/credentials"
 	"github.com/shikaan/keydex/pkg/info"
 	"github.com/shikaan/keydex/pkg/kdbx"
@@ -19,15 +19,14 @@ var List = &cobra.Command{
 
 The list of references - in the form of - /database/group/.../entry will be printed on stadout, allowing for piping.
 The 'file' is the the path to the *.kdbx database. It can be passed either as an argument or via the ` + ENV_DATABASE + ` environment variable.
+When the agent is running, the entries are read through it. See the 'agent' command.
 This command can be used in conjuction with tools such like 'fzf' or 'dmenu' to browse the databse and pipe the result to other commands.
 
 See "Examples" for more details.`,
 	Use:     "list [file]",
 	Aliases: []string{"ls"},
-	Args: cobra.MatchAll(
-		cobra.MaximumNArgs(1),
-		DatabaseMustBeDefined(),
-	),
+	Args:    cobra.MaximumNArgs(1),
+	PreRunE: DatabaseMustBeDefined(),
 	Example: `  # List all entries of vault.kdbx database
   ` + info.NAME + ` list vault.kdbx
 
@@ -43,20 +42,30 @@ See "Examples" for more details.`,
 			database,
 			orDefault(key))
 
-		passphrase := credentials.GetPassphrase(database, os.Getenv(ENV_PASSPHRASE))
-
-		return list(database, key, passphrase)
+		return list(readAgentClient(cmd), readPassphraseSource(cmd, "", database), database, key)
 	},
 	DisableAutoGenTag: true,
 }
 
-func list(database, key, passphrase string) error {
-	kdbx, err := kdbx.OpenFromPath(database, passphrase, key)
-	if err != nil {
-		return err
-	}
+func list(client *agent.Client, source credentials.PassphraseSource, database, key string) error {
+	var entries []kdbx.EntityPath
+
+	if client != nil {
+		err := askAgent(client, database, key, source, func(database, key string) (err error) {
+			entries, err = client.List(database, key)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		db, err := openDatabase(source, database, key)
+		if err != nil {
+			return err
+		}
 
-	entries := kdbx.GetEntryPaths()
+		entries = db.GetEntryPaths()
+	}
 
 	for _, k := range getSortedKeys(entries) {
 		fmt.Println(k)
@@ -3,10 +3,10 @@ package cmd
 import (
 	"os"
 
+	"github.com/shikaan/keydex/pkg/config"
 	"github.com/shikaan/keydex/pkg/credentials"
 	"github.com/shikaan/keydex/pkg/errors"
 	"github.com/shikaan/keydex/pkg/info"
-	"github.com/shikaan/keydex/pkg/kdbx"
 	"github.com/shikaan/keydex/pkg/log"
 	"github.com/shikaan/keydex/tui"
 	"github.com/spf13/cobra"
@@ -31,6 +31,14 @@ The 'file' is the the path to the *.kdbx database. It can be passed either as an
 The 'reference' can be passed as last argument; if the reference is missing, it opens the editor.
 Use the 'list' command to get a list of all the references in the database.
 
+After the time set by '--lock-after' without interactions, the database is wiped from memory
+and the editor asks for the passphrase again. Unsaved changes are discarded, unless the
+'tui.lock_policy' setting is "keep", which keeps the changes to the entry being edited.
+
+When another program changes the database, such as a sync client, the editor reloads it and
+keeps the current entry open. With unsaved changes, it asks whether to reload or to keep them.
+If the credentials changed too, it asks for the new passphrase.
+
 See "Examples" for more details.`,
 	Example: `  # Opens the "github" entry in the "coding" group in the "test" database at test.kdbx
   ` + info.NAME + ` open test.kdbx /test/coding/github
@@ -42,10 +50,8 @@ See "Examples" for more details.`,
   export ` + ENV_PASSPHRASE + `=${MY_SECRET_PHRASE}
   export ` + ENV_DATABASE + `=test.kdbx
   ` + info.NAME + ` open`,
-	Args: cobra.MatchAll(
-		cobra.MaximumNArgs(2),
-		DatabaseMustBeDefined(),
-	),
+	Args:    cobra.MaximumNArgs(2),
+	PreRunE: DatabaseMustBeDefined(),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		database, reference, key := ReadDatabaseArguments(cmd, args)
 		readOnly, err := cmd.Flags().GetBool("read-only")
@@ -53,6 +59,10 @@ See "Examples" for more details.`,
 			return err
 		}
 
+		if err := ConfigureTUI(cmd); err != nil {
+			return err
+		}
+
 		log.Infof(
 			"Using: database: %s, reference: %s, key: %s, read only: %t",
 			database,
@@ -68,18 +78,17 @@ See "Examples" for more details.`,
 			return errors.MakeError("Cannot open file: "+err.Error(), "open")
 		}
 
-		passphrase := credentials.GetPassphrase(database, os.Getenv(ENV_PASSPHRASE))
-
-		return open(database, key, passphrase, reference, readOnly)
+		return open(readPassphraseSource(cmd, "", database), database, key, reference, readOnly)
 	},
 	DisableAutoGenTag: true,
 }
 
-func open(databasePath, keyPath, passphrase, reference string, readOnly bool) error {
-	database, err := kdbx.OpenFromPath(databasePath, passphrase, keyPath)
+func open(source credentials.PassphraseSource, databasePath, keyPath, reference string, readOnly bool) error {
+	database, err := openDatabase(source, databasePath, keyPath)
 	if err != nil {
 		return err
 	}
+	database.Backups = config.Current().Backups
 
 	if reference == "" {
 		return tui.Run(tui.State{
@@ -101,5 +110,5 @@ func open(databasePath, keyPath, passphrase, reference string, readOnly bool) er
 		}
 	}
 
-	return errors.MakeError("Missing entry at "+reference+".", "open")
+	return errors.MakeTypedError(errors.ErrNotFound, "Missing entry at "+reference+".", "open")
 }
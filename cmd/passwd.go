@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shikaan/keydex/pkg/credentials"
+	"github.com/shikaan/keydex/pkg/info"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var Passwd = &cobra.Command{
+	Use:   "passwd [file]",
+	Short: "Changes the passphrase and the key file of a database.",
+	Long: `Changes the passphrase and the key file of a database.
+
+Unlocks the database at 'file' with its current credentials, then prompts for a new
+passphrase. The key file is kept, unless one of the following flags is passed:
+
+  --new-key <path>      use the key file at 'path' instead
+  --generate-key        generate a new key file, at '--new-key' if passed or next
+                        to the database otherwise
+  --remove-key          unlock the database with the passphrase only
+
+The database is encrypted again and replaces the original file only once it has been
+written completely. No backup of the previous file is made, since it would still be
+unlocked by the old credentials. Existing backups are listed, as they still are: delete
+them if the old credentials might be known to others.
+
+The 'file' is the the path to the *.kdbx database. It can be passed either as an argument or via the ` + ENV_DATABASE + ` environment variable.`,
+	Example: `  # Change the passphrase of vault.kdbx, keeping its key file
+  ` + info.NAME + ` passwd --key vault-key.xml vault.kdbx
+
+  # Change the passphrase and generate a new key file
+  ` + info.NAME + ` passwd --key vault-key.xml --generate-key --new-key vault-key-2.xml vault.kdbx
+
+  # Stop using a key file
+  ` + info.NAME + ` passwd --key vault-key.xml --remove-key vault.kdbx`,
+	Args:    cobra.MaximumNArgs(1),
+	PreRunE: DatabaseMustBeDefined(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database := ""
+		if len(args) == 1 {
+			database = args[0]
+		} else {
+			database, _, _ = ReadDatabaseArguments(cmd, args)
+		}
+		// A single argument is the database here, never a reference
+		_, _, key := ReadDatabaseArguments(cmd, []string{database, ""})
+
+		newKey, _ := cmd.Flags().GetString("new-key")
+		generateKey, _ := cmd.Flags().GetBool("generate-key")
+		removeKey, _ := cmd.Flags().GetBool("remove-key")
+
+		log.Infof("Using: database: %s, key: %s", database, orDefault(key))
+
+		db, err := openDatabase(readPassphraseSource(cmd, "", database), database, key)
+		if err != nil {
+			return err
+		}
+		// Backups would keep the database encrypted with the old credentials
+		db.Backups = 0
+
+		newPassphrase, err := credentials.MakePassphrase(database)
+		if err != nil {
+			return err
+		}
+		defer newPassphrase.Destroy()
+
+		switch {
+		case removeKey:
+			newKey = ""
+		case generateKey:
+			if newKey == "" {
+				base := filepath.Base(database)
+				filename := strings.TrimSuffix(base, filepath.Ext(base)) + "-key.xml"
+				newKey = filepath.Join(filepath.Dir(database), filename)
+			}
+
+			if err := credentials.CreateXMLKeyFileV2(newKey); err != nil {
+				return err
+			}
+		case newKey == "":
+			newKey = key
+		}
+
+		success := false
+		defer func() {
+			if generateKey && !success {
+				_ = os.Remove(newKey)
+			}
+		}()
+
+		if err := db.ChangePasswordAndKey(newPassphrase, newKey); err != nil {
+			return err
+		}
+
+		if err := db.Save(); err != nil {
+			return err
+		}
+		success = true
+
+		fmt.Println("Credentials changed for " + database + ".")
+		if generateKey {
+			fmt.Println("New key file at " + newKey + ". Keep a copy of it: the database cannot be unlocked without it.")
+		}
+
+		if backups := kdbx.ListBackups(database); len(backups) > 0 {
+			fmt.Fprintln(os.Stderr, "These backups can still be unlocked with the old credentials:")
+			for _, backup := range backups {
+				fmt.Fprintln(os.Stderr, "  "+backup)
+			}
+		}
+
+		return nil
+	},
+	DisableAutoGenTag: true,
+}
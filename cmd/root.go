@@ -1,7 +1,18 @@
 package cmd
 
 import (
+	"strconv"
+	"strings"
+
+	"github.com/shikaan/keydex/pkg/agent"
+	"github.com/shikaan/keydex/pkg/clipboard"
+	"github.com/shikaan/keydex/pkg/config"
+	"github.com/shikaan/keydex/pkg/credentials"
 	"github.com/shikaan/keydex/pkg/info"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/sshagent"
+	"github.com/shikaan/keydex/tui"
+	"github.com/shikaan/keydex/tui/theme"
 	"github.com/spf13/cobra"
 )
 
@@ -15,7 +26,18 @@ a simple, display-oriented editor inspired by the minimalism of GNU nano.
 
   - ` + ENV_PASSPHRASE + `
     When this variable is set, ` + info.NAME + ` will skip the password prompt. It can
-    be replaced by utils such as 'autoexpect'.
+    be replaced by utils such as 'autoexpect'. Environment variables can be read by
+    other processes of the same user, so prefer the sources below.
+
+  - ` + ENV_PASSPHRASE_COMMAND + `
+    Command line printing the passphrase on stdout, such as 'secret-tool lookup ...'
+    or 'gpg -d passphrase.gpg'. It runs through the shell, and can prompt on the
+    terminal. The '--passphrase-cmd' flag overrides this value, and it takes
+    precedence over ` + ENV_PASSPHRASE + `.
+
+    The '--passphrase-fd' and '--passphrase-file' flags read the passphrase from an
+    inherited file descriptor, or from a file only its owner can read. Passphrases
+    end at the first line break.
 
   - ` + ENV_DATABASE + `
     Path to the *.kbdx database to unlock. Providing 'file' inline overrides
@@ -25,15 +47,59 @@ a simple, display-oriented editor inspired by the minimalism of GNU nano.
     Path to the optional *.key file used to unlock the database. The '--key'
     flag overrides this value.
 
+  - ` + ENV_PROFILE + `
+    Profile of the configuration file to use. The '--profile' flag overrides
+    this value.
+
+  - ` + agent.ENV_AGENT_SOCKET + `
+    Path of the socket of the agent. See the 'agent' command.
+
+  - ` + theme.ENV_NO_COLOR + `
+    When this variable is set, the editor does not use colours.
+
+  - ` + ENV_CLIPBOARD + `
+    Clipboard backend, in the form 'name[:argument]'. The '--clipboard' flag
+    overrides this value. Available backends are:
+      auto              first available backend (default)
+      system            the clipboard tools of the operating system
+      osc52[:primary]   terminal escape sequences, works over SSH and tmux
+      wl-copy[:primary] Wayland clipboard
+      xclip[:primary]   X11 clipboard, or primary selection
+      xsel[:primary]    X11 clipboard, or primary selection
+      tmux              tmux paste buffer
+      command:<cmd>     a custom command reading the value from stdin
+    When 'auto' is used, the OSC 52 sequences are preferred in SSH sessions.
+    Otherwise wl-copy, xclip, xsel, tmux, and OSC 52 are tried in this order.
+
+Settings can also be stored in a configuration file, at $XDG_CONFIG_HOME/` + info.NAME + `/config.toml
+or ~/.config/` + info.NAME + `/config.toml by default. Its path can be changed with the
+` + config.ENV_CONFIG + ` environment variable. Flags take precedence over environment
+variables, which take precedence over the configuration file. See the 'config'
+command for the available settings.
+
 All the entries are identified by a path-like reference like
 /database/group1/../groupN/entry where 'database' is the database name,
 'groupN' are the (nested) groups names, and 'entry' is the entry title.
 
-Internally all the entries are referenced by a UUID, however ` + info.NAME + ` will read
-the first occurrence of a reference in cases of conflicts. Writes are always
-done via UUID and they are therefore conflict-safe.
+Internally all the entries are referenced by a UUID. The editor opens the first
+occurrence of a reference in cases of conflicts, while 'copy' and 'show' fail
+rather than guess. Writes are always done via UUID and they are therefore
+conflict-safe.
+
+Some commands use the clipboard, in absence of which ` + info.NAME + ` will fail.
 
-Some commands use the system clipboard, in absence of which ` + info.NAME + ` will fail.`,
+Prompted passphrases are asked again when they are wrong, up to ` + strconv.Itoa(credentials.PASSPHRASE_ATTEMPTS) + ` times.
+Failures exit with the following codes, so that scripts can tell them apart:
+
+  1   any other failure
+  2   wrong passphrase or key file
+  3   missing database, key file, entry, or field
+  4   reference matching more than one entry
+  5   damaged database or key file
+  6   clipboard failure`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return LoadConfig(cmd)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		cmd.Help()
 	},
@@ -45,15 +111,117 @@ func init() {
 	Root.AddCommand(Copy)
 	Root.AddCommand(List)
 	Root.AddCommand(Open)
+	Root.AddCommand(Add)
 	Root.AddCommand(Create)
 	Root.AddCommand(Diff)
+	Root.AddCommand(Clear)
+	Root.AddCommand(Config)
+	Root.AddCommand(Show)
+	Root.AddCommand(URL)
+	Root.AddCommand(Agent)
+	Root.AddCommand(Passwd)
+	Root.AddCommand(Upgrade)
+	Root.AddCommand(Keyfile)
+	Root.AddCommand(SSHAgent)
+
+	Config.AddCommand(ConfigGet)
+	Config.AddCommand(ConfigSet)
+	Config.AddCommand(ConfigPath)
+
+	Agent.AddCommand(AgentStart)
+	Agent.AddCommand(AgentStop)
+	Agent.AddCommand(AgentLock)
+	Agent.AddCommand(AgentStatus)
+
+	Keyfile.AddCommand(KeyfileCreate)
+	Keyfile.AddCommand(KeyfileInspect)
+	Keyfile.AddCommand(KeyfileVerify)
+
+	Root.PersistentFlags().String("profile", "", "profile of the configuration file to use")
 
 	Copy.PersistentFlags().StringP("key", "k", "", "path to the key file to unlock the database")
 	List.PersistentFlags().StringP("key", "k", "", "path to the key file to unlock the database")
+	Show.PersistentFlags().StringP("key", "k", "", "path to the key file to unlock the database")
+	URL.PersistentFlags().StringP("key", "k", "", "path to the key file to unlock the database")
 	Open.PersistentFlags().StringP("key", "k", "", "path to the key file to unlock the database")
+	Add.PersistentFlags().StringP("key", "k", "", "path to the key file to unlock the database")
+	Passwd.PersistentFlags().StringP("key", "k", "", "path to the current key file of the database")
+	Upgrade.PersistentFlags().StringP("key", "k", "", "path to the key file to unlock the database")
+	SSHAgent.PersistentFlags().StringP("key", "k", "", "path to the key file to unlock the database")
 
 	Copy.Flags().StringP("field", "f", DEFAULT_FIELD, "field whose value will be copied")
 	Open.Flags().Bool("read-only", false, "open "+info.NAME+" in read-only mode")
+	Open.Flags().Duration("lock-after", tui.DEFAULT_LOCK_AFTER, "lock the database after this time of inactivity, 0 to disable")
+	Add.Flags().Duration("lock-after", tui.DEFAULT_LOCK_AFTER, "lock the database after this time of inactivity, 0 to disable")
+	Show.Flags().Bool("reveal", false, "print protected fields, such as passwords")
+	URL.Flags().Bool("open", false, "open the URL rather than printing it")
+	URL.Flags().Bool("copy", false, "copy the password to the clipboard too")
+	URL.Flags().String("opener", "", "command line opening the URL (default: xdg-open, or the one of the platform)")
+	URL.Flags().Bool("allow-commands", false, "run cmd:// URLs")
+	Add.Flags().StringP("template", "t", "", "template of the entry (default: the 'template' setting, or "+kdbx.DEFAULT_TEMPLATE+")")
+	KeyfileCreate.Flags().String("format", credentials.KEY_FILE_XML_V2, "format of the key file: "+strings.Join(credentials.KEY_FILE_FORMATS, ", "))
+	Passwd.Flags().String("new-key", "", "path to the new key file of the database")
+	Passwd.Flags().Bool("generate-key", false, "generate a new key file")
+	Passwd.Flags().Bool("remove-key", false, "stop using a key file")
+	Passwd.MarkFlagsMutuallyExclusive("remove-key", "new-key")
+	Passwd.MarkFlagsMutuallyExclusive("remove-key", "generate-key")
+
+	Copy.Flags().Bool("no-agent", false, "do not use the agent, even if it is running")
+	List.Flags().Bool("no-agent", false, "do not use the agent, even if it is running")
+	Show.Flags().Bool("no-agent", false, "do not use the agent, even if it is running")
+	URL.Flags().Bool("no-agent", false, "do not use the agent, even if it is running")
+
+	AgentStart.Flags().Duration("ttl", agent.DEFAULT_TTL, "forget unlocked databases after this time, 0 to disable")
+	AgentStart.Flags().Bool("foreground", false, "run the agent in the foreground")
+
+	SSHAgent.Flags().String("socket", "", "path of the socket to listen on (default: in the runtime directory)")
+	SSHAgent.Flags().Bool("add", false, "add the keys to the agent in "+sshagent.ENV_AUTH_SOCK+" and exit")
+	SSHAgent.Flags().Bool("confirm", false, "ask for confirmation before every use of the keys")
+	SSHAgent.Flags().Duration("lifetime", 0, "remove keys after this time, unless their settings say otherwise, 0 to disable")
+	SSHAgent.MarkFlagsMutuallyExclusive("add", "socket")
+
+	Copy.Flags().Duration("clear-after", clipboard.DEFAULT_CLEAR_AFTER, "restore the clipboard after this time, 0 to disable")
+	Open.Flags().Duration("clear-after", clipboard.DEFAULT_CLEAR_AFTER, "restore the clipboard after this time, 0 to disable")
+	URL.Flags().Duration("clear-after", clipboard.DEFAULT_CLEAR_AFTER, "restore the clipboard after this time, 0 to disable")
+	Add.Flags().Duration("clear-after", clipboard.DEFAULT_CLEAR_AFTER, "restore the clipboard after this time, 0 to disable")
+
+	Copy.Flags().String("clipboard", "", "clipboard backend (e.g., auto, osc52, xclip:primary)")
+	Open.Flags().String("clipboard", "", "clipboard backend (e.g., auto, osc52, xclip:primary)")
+	URL.Flags().String("clipboard", "", "clipboard backend (e.g., auto, osc52, xclip:primary)")
+	Add.Flags().String("clipboard", "", "clipboard backend (e.g., auto, osc52, xclip:primary)")
+	Clear.Flags().String("clipboard", "", "clipboard backend")
+
+	Create.Flags().StringP("key", "k", "", "path to an existing key file for the database")
+	Create.Flags().Bool("no-passphrase", false, "protect the database with the key file only")
+	Create.Flags().Int("format-version", kdbx.DEFAULT_ENCRYPTION_OPTIONS.Version, "KDBX format version: 3 or 4")
+	Create.Flags().String("cipher", kdbx.DEFAULT_ENCRYPTION_OPTIONS.Cipher, "cipher: "+strings.Join(kdbx.CIPHERS, ", "))
+	Upgrade.Flags().String("cipher", "", "cipher: "+strings.Join(kdbx.CIPHERS, ", ")+" (default: the current one)")
+
+	for _, command := range []*cobra.Command{Create, Upgrade} {
+		command.Flags().String("kdf", kdbx.DEFAULT_ENCRYPTION_OPTIONS.KDF, "key derivation function: "+kdbx.KDF_AES+" or "+kdbx.KDF_ARGON2D)
+		command.Flags().Uint64("kdf-rounds", kdbx.DEFAULT_ENCRYPTION_OPTIONS.Rounds, "rounds of AES-KDF")
+		command.Flags().Uint64("kdf-memory", kdbx.DEFAULT_ENCRYPTION_OPTIONS.Memory/kdbx.MEBIBYTE, "memory used by Argon2, in MiB")
+		command.Flags().Uint64("kdf-iterations", kdbx.DEFAULT_ENCRYPTION_OPTIONS.Iterations, "iterations of Argon2")
+		command.Flags().Uint32("kdf-parallelism", kdbx.DEFAULT_ENCRYPTION_OPTIONS.Parallelism, "threads used by Argon2")
+		command.Flags().Duration("kdf-target", 0, "tune the key derivation to take this long on this machine")
+	}
+
+	for _, command := range []*cobra.Command{Copy, List, Show, URL, Open, Add, Passwd, Upgrade, KeyfileVerify, SSHAgent} {
+		command.Flags().Int("passphrase-fd", -1, "read the passphrase from this file descriptor")
+		command.Flags().String("passphrase-file", "", "read the passphrase from this file")
+		command.Flags().String("passphrase-cmd", "", "read the passphrase from the output of this command")
+		command.Flags().Bool("no-passphrase", false, "unlock the database with the key file only")
+		command.MarkFlagsMutuallyExclusive("passphrase-fd", "passphrase-file", "passphrase-cmd", "no-passphrase")
+	}
+
+	for _, archive := range []struct{ suffix, name string }{{"-a", "first"}, {"-b", "second"}} {
+		fd, file, command, none := "passphrase-fd"+archive.suffix, "passphrase-file"+archive.suffix, "passphrase-cmd"+archive.suffix, "no-passphrase"+archive.suffix
+		Diff.Flags().Int(fd, -1, "read the passphrase for the "+archive.name+" archive from this file descriptor")
+		Diff.Flags().String(file, "", "read the passphrase for the "+archive.name+" archive from this file")
+		Diff.Flags().String(command, "", "read the passphrase for the "+archive.name+" archive from the output of this command")
+		Diff.Flags().Bool(none, false, "unlock the "+archive.name+" archive with the key file only")
+		Diff.MarkFlagsMutuallyExclusive(fd, file, command, none)
+	}
 
 	Diff.Flags().String("key-a", "", "path to the key file for the first archive")
 	Diff.Flags().String("key-b", "", "path to the key file for the second archive")
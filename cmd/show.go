@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shikaan/keydex/pkg/agent"
+	"github.com/shikaan/keydex/pkg/credentials"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/info"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+// Replaces protected values, unless --reveal is passed
+const HIDDEN_VALUE = "********"
+
+var Show = &cobra.Command{
+	Short: "Prints the fields of a reference.",
+	Long: `Prints the fields of a reference.
+
+Reads a 'reference' from the database at 'file' and prints its fields, one per line.
+Protected fields, such as passwords, are hidden unless '--reveal' is passed.
+
+The 'file' is the the path to the *.kdbx database. It can be passed either as an argument or via the ` + ENV_DATABASE + ` environment variable.
+The 'reference' can be passed either as the last argument, or can be read from stdin - to allow piping.
+When the agent is running, the entry is read through it. See the 'agent' command.`,
+	Example: `  # Print the fields of the "github" entry in the "coding" group in the "test" database at test.kdbx
+  ` + info.NAME + ` show test.kdbx /test/coding/github
+
+  # Include the password
+  ` + info.NAME + ` show --reveal test.kdbx /test/coding/github`,
+	Use:     "show [file] [reference]",
+	Args:    cobra.MaximumNArgs(2),
+	PreRunE: DatabaseMustBeDefined(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, reference, key := ReadDatabaseArguments(cmd, args)
+		reveal, _ := cmd.Flags().GetBool("reveal")
+
+		log.Infof(
+			"Using: database: %s, reference: %s, key: %s",
+			database,
+			orDefault(reference),
+			orDefault(key))
+
+		return show(readAgentClient(cmd), readPassphraseSource(cmd, "", database), database, key, reference, reveal)
+	},
+	DisableAutoGenTag: true,
+}
+
+func show(client *agent.Client, source credentials.PassphraseSource, databasePath, keyPath, reference string, reveal bool) error {
+	reference, err := ReadReferenceFromStdin(reference)
+
+	if reference == "" {
+		return errors.MakeError(`Missing reference. Provide one as an argument or via stdin.`, "show")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fields, err := readFields(client, source, databasePath, keyPath, reference)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		if field.Value == "" {
+			continue
+		}
+
+		value := field.Value
+		if field.Protected && !reveal {
+			value = HIDDEN_VALUE
+		}
+
+		fmt.Printf("%s: %s\n", field.Key, value)
+	}
+
+	return nil
+}
+
+// Returns the fields of the entry at reference, through the agent if it is
+// running
+func readFields(client *agent.Client, source credentials.PassphraseSource, databasePath, keyPath, reference string) ([]agent.Field, error) {
+	var fields []agent.Field
+
+	if client != nil {
+		err := askAgent(client, databasePath, keyPath, source, func(database, key string) (err error) {
+			fields, err = client.Show(database, key, reference)
+			return err
+		})
+		return fields, err
+	}
+
+	db, err := openDatabase(source, databasePath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := db.GetEntryByPath(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range entry.Values {
+		fields = append(fields, agent.Field{Key: value.Key, Value: kdbx.FieldContent(value), Protected: value.Value.Protected.Bool})
+	}
+
+	return fields, nil
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/shikaan/keydex/pkg/agent"
+	"github.com/shikaan/keydex/pkg/credentials"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/info"
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/shikaan/keydex/pkg/sshagent"
+	"github.com/spf13/cobra"
+)
+
+var SSHAgent = &cobra.Command{
+	Use:   "ssh-agent [file]",
+	Short: "Serves the SSH keys stored in a database, like ssh-agent.",
+	Long: `Serves the SSH keys stored in a database, like ssh-agent.
+
+Unlocks the database at 'file', loads the SSH private keys marked for agent use, and
+serves them over the ssh-agent protocol until it is interrupted. The database itself is
+wiped from memory once the keys are loaded.
+
+Keys are stored the way KeeAgent and KeePassXC do: an entry whose '` + sshagent.SETTINGS_ATTACHMENT + `'
+attachment allows using its key and adding it when the database is opened is loaded,
+with the key in the attachment or the file the settings point to. Entries without
+settings are loaded when they have a '` + sshagent.PRIVATE_KEY_FIELD + `' field holding the key. Keys protected by a
+passphrase are decrypted with the password of their entry.
+
+Keys are added with the confirmation and lifetime constraints of their settings.
+'--confirm' asks for confirmation before every use of any key, and '--lifetime' removes
+keys without a lifetime of their own after the given time. Confirmation is asked with
+the program in SSH_ASKPASS, as ssh-agent does: without one, keys needing it are refused.
+
+The agent listens on a socket that only the current user can access, at
+$XDG_RUNTIME_DIR/` + info.NAME + `/` + sshagent.SOCKET_FILE + ` or in the temporary directory by default. Point
+` + sshagent.ENV_AUTH_SOCK + ` to it to use the keys. With '--add', the keys are added to the agent in
+` + sshagent.ENV_AUTH_SOCK + ` instead, and the command exits.
+
+The 'file' is the the path to the *.kdbx database. It can be passed either as an argument or via the ` + ENV_DATABASE + ` environment variable.`,
+	Example: `  # Serve the keys of vault.kdbx, then use them from another shell
+  ` + info.NAME + ` ssh-agent vault.kdbx
+  export ` + sshagent.ENV_AUTH_SOCK + `=$XDG_RUNTIME_DIR/` + info.NAME + `/` + sshagent.SOCKET_FILE + `
+
+  # Add the keys to the running ssh-agent, for an hour
+  ` + info.NAME + ` ssh-agent --add --lifetime 1h vault.kdbx`,
+	Args:    cobra.MaximumNArgs(1),
+	PreRunE: DatabaseMustBeDefined(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database := ""
+		if len(args) == 1 {
+			database = args[0]
+		} else {
+			database, _, _ = ReadDatabaseArguments(cmd, args)
+		}
+		// A single argument is the database here, never a reference
+		_, _, key := ReadDatabaseArguments(cmd, []string{database, ""})
+
+		log.Infof("Using: database: %s, key: %s", database, orDefault(key))
+
+		flags := cmd.Flags()
+		constraints := sshagent.Constraints{}
+		constraints.Confirm, _ = flags.GetBool("confirm")
+		constraints.Lifetime, _ = flags.GetDuration("lifetime")
+
+		keys, err := loadSSHKeys(readPassphraseSource(cmd, "", database), database, key, constraints)
+		if err != nil {
+			return err
+		}
+
+		if add, _ := flags.GetBool("add"); add {
+			socket := os.Getenv(sshagent.ENV_AUTH_SOCK)
+			if socket == "" {
+				return errors.MakeError(sshagent.ENV_AUTH_SOCK+" is not set. Start ssh-agent first.", "ssh-agent")
+			}
+
+			if err := sshagent.AddTo(socket, keys); err != nil {
+				return err
+			}
+
+			fmt.Println("Added " + strconv.Itoa(len(keys)) + " keys to the agent at " + socket + ".")
+			return nil
+		}
+
+		socket, _ := flags.GetString("socket")
+		if socket == "" {
+			socket = sshagent.SocketPath()
+		}
+
+		return serveSSHAgent(socket, keys)
+	},
+	DisableAutoGenTag: true,
+}
+
+// Returns the keys marked for agent use in database, which is wiped once
+// they are loaded. Keys that cannot be loaded are reported, and skipped.
+func loadSSHKeys(source credentials.PassphraseSource, database, key string, constraints sshagent.Constraints) ([]sshagent.Key, error) {
+	db, err := openDatabase(source, database, key)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Wipe()
+
+	keys, failures := sshagent.LoadKeys(db, constraints)
+	for _, failure := range failures {
+		fmt.Fprintln(os.Stderr, "Skipping key of "+failure.Error())
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.MakeTypedError(errors.ErrNotFound, "No SSH keys marked for agent use in "+database+".", "ssh-agent")
+	}
+
+	return keys, nil
+}
+
+// Serves keys until the process is interrupted
+func serveSSHAgent(path string, keys []sshagent.Key) error {
+	server := sshagent.NewAgent()
+	for _, key := range keys {
+		if err := server.Add(key.AddedKey); err != nil {
+			return errors.MakeError("Cannot add "+key.Reference+": "+err.Error(), "ssh-agent")
+		}
+	}
+
+	listener, err := agent.Listen(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving %d keys. To use them, run:\n", len(keys))
+	fmt.Printf("%s=%s; export %s;\n", sshagent.ENV_AUTH_SOCK, path, sshagent.ENV_AUTH_SOCK)
+
+	log.Info("SSH agent listening on " + path)
+	err = server.Serve(listener)
+	server.RemoveAll()
+	return err
+}
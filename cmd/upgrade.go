@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/shikaan/keydex/pkg/config"
+	"github.com/shikaan/keydex/pkg/info"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+var Upgrade = &cobra.Command{
+	Use:   "upgrade [file]",
+	Short: "Converts a database to KDBX 4, with new key derivation settings.",
+	Long: `Converts a database to KDBX 4, with new key derivation settings.
+
+Unlocks the database at 'file' and encrypts it again in the KDBX 4 format. The cipher is
+kept unless '--cipher' is passed, while the key derivation uses Argon2d with the default
+parameters unless flags choose otherwise. Databases already in the KDBX 4 format get the
+new settings, which is also the way to make unlocking them slower or faster.
+
+'--kdf-target' measures this machine, and tunes the key derivation to take about the given
+time to unlock the database.
+
+The 'file' is the the path to the *.kdbx database. It can be passed either as an argument or via the ` + ENV_DATABASE + ` environment variable.`,
+	Example: `  # Convert vault.kdbx to KDBX 4, with Argon2d
+  ` + info.NAME + ` upgrade vault.kdbx
+
+  # Make unlocking take about two seconds on this machine
+  ` + info.NAME + ` upgrade --kdf-target 2s vault.kdbx`,
+	Args:    cobra.MaximumNArgs(1),
+	PreRunE: DatabaseMustBeDefined(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database := ""
+		if len(args) == 1 {
+			database = args[0]
+		} else {
+			database, _, _ = ReadDatabaseArguments(cmd, args)
+		}
+		// A single argument is the database here, never a reference
+		_, _, key := ReadDatabaseArguments(cmd, []string{database, ""})
+
+		log.Infof("Using: database: %s, key: %s", database, orDefault(key))
+
+		db, err := openDatabase(readPassphraseSource(cmd, "", database), database, key)
+		if err != nil {
+			return err
+		}
+		db.Backups = config.Current().Backups
+
+		base := kdbx.DEFAULT_ENCRYPTION_OPTIONS
+		if current := db.Encryption().Cipher; current != "" {
+			base.Cipher = current
+		}
+
+		encryption, err := readEncryptionOptions(cmd, base)
+		if err != nil {
+			return err
+		}
+
+		if err := db.SetEncryption(encryption); err != nil {
+			return err
+		}
+
+		if err := db.Save(); err != nil {
+			return err
+		}
+
+		fmt.Println("Upgraded " + database + ": " + encryption.String())
+		return nil
+	},
+	DisableAutoGenTag: true,
+}
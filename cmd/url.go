@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shikaan/keydex/pkg/agent"
+	"github.com/shikaan/keydex/pkg/config"
+	"github.com/shikaan/keydex/pkg/credentials"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/info"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/shikaan/keydex/pkg/opener"
+	"github.com/spf13/cobra"
+)
+
+var URL = &cobra.Command{
+	Short: "Prints or opens the URL of a reference.",
+	Long: `Prints or opens the URL of a reference.
+
+Reads a 'reference' from the database at 'file' and prints the value of its URL field or, with '--open',
+opens it in the browser.
+
+The 'file' is the path to the *.kdbx database. It can be passed either as an argument or via the ` + ENV_DATABASE + ` environment variable.
+The 'reference' can be passed either as the last argument, or can be read from stdin - to allow piping.
+When the agent is running, the entry is read through it. See the 'agent' command.
+
+Placeholders such as {TITLE}, {USERNAME}, or {S:Field} are replaced by the fields of the entry, unless
+they are protected. Addresses without a scheme are opened over HTTPS.
+
+URLs are opened with xdg-open, open on macOS, or the URL handler on Windows. '--opener' uses another
+command line, which receives the URL as last argument. It is split on spaces, and not run through
+the shell.
+
+KeePass URLs like cmd://command run the command line, and are refused unless
+'--allow-commands' is passed. Anyone able to edit the database could otherwise run commands on this machine.
+The command line is not run through the shell: it is split on spaces, quotes keep spaces in arguments,
+and the values of placeholders are passed as single arguments.
+
+'--copy' copies the password to the clipboard too, restored after the time set by '--clear-after'.
+
+See "Examples" for more details.`,
+	Example: `  # Print the URL of the "github" entry in the "coding" group in the "test" database at test.kdbx
+  ` + info.NAME + ` url test.kdbx /test/coding/github
+
+  # Open it in the browser, and copy the password to the clipboard
+  ` + info.NAME + ` url --open --copy test.kdbx /test/coding/github
+
+  # Open it in a private window
+  ` + info.NAME + ` url --open --opener "firefox --private-window" test.kdbx /test/coding/github`,
+	Use:     "url [file] [reference]",
+	Args:    cobra.MaximumNArgs(2),
+	PreRunE: DatabaseMustBeDefined(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		database, reference, key := ReadDatabaseArguments(cmd, args)
+		open, _ := cmd.Flags().GetBool("open")
+
+		copyPassword := config.Current().URL.CopyPassword
+		if copyFlag := cmd.Flag("copy"); copyFlag.Changed {
+			copyPassword, _ = cmd.Flags().GetBool("copy")
+		}
+
+		clearAfter, err := ReadClearAfter(cmd)
+		if err != nil {
+			return err
+		}
+
+		backend := ""
+		if copyPassword {
+			if backend, err = UseClipboardBackend(cmd); err != nil {
+				return err
+			}
+		}
+
+		log.Infof(
+			"Using: database: %s, reference: %s, key: %s, open: %t",
+			database,
+			orDefault(reference),
+			orDefault(key),
+			open)
+
+		options := readOpenerOptions(cmd)
+		return url(readAgentClient(cmd), readPassphraseSource(cmd, "", database), database, key, reference, open, options, copyPassword, backend, clearAfter)
+	},
+	DisableAutoGenTag: true,
+}
+
+func url(client *agent.Client, source credentials.PassphraseSource, databasePath, keyPath, reference string, open bool, options opener.Options, copyPassword bool, backend string, clearAfter time.Duration) error {
+	reference, err := ReadReferenceFromStdin(reference)
+
+	if reference == "" {
+		return errors.MakeError(`Missing reference. Provide one as an argument or via stdin.`, "url")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	fields, err := readFields(client, source, databasePath, keyPath, reference)
+	if err != nil {
+		return err
+	}
+
+	// Protected values are left out of the URL, which ends up on command lines
+	raw, password := "", ""
+	values := map[string]string{}
+	for _, field := range fields {
+		switch field.Key {
+		case kdbx.URL_KEY:
+			raw = field.Value
+		case kdbx.PASSWORD_KEY:
+			password = field.Value
+		}
+
+		if !field.Protected {
+			values[field.Key] = field.Value
+		}
+	}
+
+	resolved, err := opener.Resolve(raw, values)
+	if err != nil {
+		return errors.MakeTypedError(errors.ErrNotFound, `Missing field "`+kdbx.URL_KEY+`" in entry "`+reference+`".`, "url")
+	}
+
+	if copyPassword && password == "" {
+		return errors.MakeTypedError(errors.ErrNotFound, `Missing field "`+kdbx.PASSWORD_KEY+`" in entry "`+reference+`".`, "url")
+	}
+
+	if !open {
+		fmt.Println(resolved)
+	} else if err := opener.Open(resolved, options); err != nil {
+		return err
+	}
+
+	if copyPassword {
+		return copyAndScheduleClear(password, backend, clearAfter)
+	}
+
+	return nil
+}
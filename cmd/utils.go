@@ -3,9 +3,22 @@ package cmd
 import (
 	"bufio"
 	"errors"
+	"fmt"
+	"maps"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/shikaan/keydex/pkg/clipboard"
+	"github.com/shikaan/keydex/pkg/config"
+	"github.com/shikaan/keydex/pkg/credentials"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/opener"
+	"github.com/shikaan/keydex/pkg/secure"
+	"github.com/shikaan/keydex/tui"
+	"github.com/shikaan/keydex/tui/keymap"
+	"github.com/shikaan/keydex/tui/theme"
 	"github.com/spf13/cobra"
 )
 
@@ -13,7 +26,10 @@ const ENV_DATABASE = "KEYDEX_DATABASE"
 const ENV_PASSPHRASE = "KEYDEX_PASSPHRASE"
 const ENV_PASSPHRASE_A = "KEYDEX_PASSPHRASE_A"
 const ENV_PASSPHRASE_B = "KEYDEX_PASSPHRASE_B"
+const ENV_PASSPHRASE_COMMAND = "KEYDEX_PASSPHRASE_COMMAND"
 const ENV_KEY = "KEYDEX_KEY"
+const ENV_CLIPBOARD = "KEYDEX_CLIPBOARD"
+const ENV_PROFILE = "KEYDEX_PROFILE"
 
 // If zero value reference is passed, reads from stdin to get the value
 func ReadReferenceFromStdin(maybeReference string) (string, error) {
@@ -45,17 +61,20 @@ func ReadReferenceFromStdin(maybeReference string) (string, error) {
 // keydex open database /ref -> OK (db: database, ref: /ref)
 // DATABASE=lol keydex open database /ref -> (db database, /ref)
 // keydex open /ref -> uses /ref as db and then fails
+//
+// When no database is passed, the one of the --profile flag is used, then
+// the environment, and then the configuration file.
 func ReadDatabaseArguments(cmd *cobra.Command, args []string) (database string, reference string, key string) {
 	if len(args) == 0 {
 		reference = ""
-		database = os.Getenv(ENV_DATABASE)
+		database = readDefaultDatabase(cmd)
 	}
 
 	if len(args) == 1 {
-		envDatabase := os.Getenv(ENV_DATABASE)
+		defaultDatabase := readDefaultDatabase(cmd)
 
-		if envDatabase != "" {
-			database = envDatabase
+		if defaultDatabase != "" {
+			database = defaultDatabase
 			reference = args[0]
 		} else {
 			database = args[0]
@@ -74,12 +93,59 @@ func ReadDatabaseArguments(cmd *cobra.Command, args []string) (database string,
 		if key == "" {
 			key = os.Getenv(ENV_KEY)
 		}
+
+		if key == "" {
+			key = readConfiguredKey(cmd, database)
+		}
 	}
 
 	return database, reference, key
 }
 
-func DatabaseMustBeDefined() cobra.PositionalArgs {
+// Returns the name of the profile from the --profile flag, falling back to
+// the environment. Empty means the default profile of the configuration.
+func readProfile(cmd *cobra.Command) string {
+	if profileFlag := cmd.Flag("profile"); profileFlag != nil && profileFlag.Value.String() != "" {
+		return profileFlag.Value.String()
+	}
+
+	return os.Getenv(ENV_PROFILE)
+}
+
+func readDefaultDatabase(cmd *cobra.Command) string {
+	configured, _, _ := config.Current().ResolveDatabase(readProfile(cmd))
+
+	if profileFlag := cmd.Flag("profile"); profileFlag != nil && profileFlag.Changed {
+		return configured
+	}
+
+	if database := os.Getenv(ENV_DATABASE); database != "" {
+		return database
+	}
+
+	return configured
+}
+
+// Returns the configured key file, provided database is the configured one
+func readConfiguredKey(cmd *cobra.Command, database string) string {
+	configured, key, _ := config.Current().ResolveDatabase(readProfile(cmd))
+	if configured == "" || configured != database {
+		return ""
+	}
+
+	return key
+}
+
+// Returns true if the configuration unlocks database with the key file
+// only, provided database is the configured one
+func readConfiguredNoPassphrase(cmd *cobra.Command, database string) bool {
+	profile, _ := config.Current().ResolveProfile(readProfile(cmd))
+	return profile.Database != "" && profile.Database == database && profile.NoPassphrase
+}
+
+// Fails when the database is neither passed nor configured. It runs as a
+// PreRunE hook, once the configuration is loaded.
+func DatabaseMustBeDefined() func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
 		database, _, _ := ReadDatabaseArguments(cmd, args)
 
@@ -90,3 +156,277 @@ func DatabaseMustBeDefined() cobra.PositionalArgs {
 		return nil
 	}
 }
+
+// Selects the clipboard backend from the --clipboard flag, falling back
+// to the environment and the configuration. Returns the selected backend spec.
+func UseClipboardBackend(cmd *cobra.Command) (string, error) {
+	spec := ""
+	if clipboardFlag := cmd.Flag("clipboard"); clipboardFlag != nil {
+		spec = clipboardFlag.Value.String()
+	}
+
+	if spec == "" {
+		spec = os.Getenv(ENV_CLIPBOARD)
+	}
+
+	if spec == "" {
+		spec = config.Current().Clipboard.Backend
+	}
+
+	return spec, clipboard.Use(spec)
+}
+
+// Returns the time after which the clipboard is restored, from the
+// --clear-after flag, falling back to the configuration
+func ReadClearAfter(cmd *cobra.Command) (time.Duration, error) {
+	clearAfterFlag := cmd.Flag("clear-after")
+	if clearAfterFlag != nil && clearAfterFlag.Changed {
+		return cmd.Flags().GetDuration("clear-after")
+	}
+
+	if configured := config.Current().Clipboard.ClearAfter; configured != 0 {
+		return max(configured, 0), nil
+	}
+
+	return clipboard.DEFAULT_CLEAR_AFTER, nil
+}
+
+// Returns the time of inactivity after which the editor locks the database,
+// from the --lock-after flag, falling back to the configuration
+func readLockAfter(cmd *cobra.Command) (time.Duration, error) {
+	lockAfterFlag := cmd.Flag("lock-after")
+	if lockAfterFlag != nil && lockAfterFlag.Changed {
+		return cmd.Flags().GetDuration("lock-after")
+	}
+
+	if configured := config.Current().TUI.LockAfter; configured != 0 {
+		return max(configured, 0), nil
+	}
+
+	return tui.DEFAULT_LOCK_AFTER, nil
+}
+
+// Returns how URLs are opened, from the --opener and --allow-commands flags,
+// falling back to the configuration
+func readOpenerOptions(cmd *cobra.Command) opener.Options {
+	configured := config.Current().URL
+	options := opener.Options{Opener: configured.Opener, AllowCommands: configured.AllowCommands}
+
+	if openerFlag := cmd.Flag("opener"); openerFlag != nil && openerFlag.Changed {
+		options.Opener = openerFlag.Value.String()
+	}
+
+	if allowFlag := cmd.Flag("allow-commands"); allowFlag != nil && allowFlag.Changed {
+		options.AllowCommands, _ = cmd.Flags().GetBool("allow-commands")
+	}
+
+	return options
+}
+
+// Applies flags and configuration to the editor
+func ConfigureTUI(cmd *cobra.Command) error {
+	clearAfter, err := ReadClearAfter(cmd)
+	if err != nil {
+		return err
+	}
+	tui.App.Settings.ClearClipboardAfter = clearAfter
+
+	if _, err := UseClipboardBackend(cmd); err != nil {
+		return err
+	}
+
+	lockAfter, err := readLockAfter(cmd)
+	if err != nil {
+		return err
+	}
+	tui.App.Settings.LockAfter = lockAfter
+
+	settings := config.Current().TUI
+	policy, err := tui.ParseLockPolicy(settings.LockPolicy)
+	if err != nil {
+		return err
+	}
+	tui.App.Settings.LockPolicy = policy
+
+	tui.App.Settings.Opener = readOpenerOptions(cmd)
+	tui.App.Settings.CopyPasswordOnOpen = config.Current().URL.CopyPassword
+
+	keys, err := keymap.New(settings.Keymap, settings.Keybindings)
+	if err != nil {
+		return err
+	}
+	tui.App.Settings.Keymap = keys
+
+	t, err := theme.New(settings.Theme, settings.Styles, theme.NoColor())
+	if err != nil {
+		return err
+	}
+	theme.Use(t)
+
+	return nil
+}
+
+// Loads the configuration file, and applies the settings that do not
+// depend on the command
+func LoadConfig(cmd *cobra.Command) error {
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	c, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	config.Use(c)
+
+	if _, _, err := c.ResolveDatabase(readProfile(cmd)); err != nil {
+		return err
+	}
+
+	kdbx.PasswordDefaults = readPasswordOptions(c.Generator, kdbx.DEFAULT_PASSWORD_OPTIONS)
+	kdbx.GeneratorProfiles = map[string]kdbx.PasswordOptions{}
+	for name, profile := range c.Generator.Profiles {
+		kdbx.GeneratorProfiles[name] = readPasswordOptions(profile, kdbx.PasswordDefaults)
+	}
+
+	kdbx.CustomTemplates = readTemplates(c.Templates)
+	if c.Template != "" {
+		kdbx.DefaultTemplate = c.Template
+	}
+	return nil
+}
+
+// Returns the settings of generator on top of base
+func readPasswordOptions(generator config.Generator, base kdbx.PasswordOptions) kdbx.PasswordOptions {
+	options := base
+
+	if generator.Length > 0 {
+		options.Length = generator.Length
+	}
+
+	for _, class := range []struct {
+		configured *bool
+		option     *bool
+	}{
+		{generator.Lowercase, &options.Lowercase},
+		{generator.Uppercase, &options.Uppercase},
+		{generator.Digits, &options.Digits},
+		{generator.Symbols, &options.Symbols},
+	} {
+		if class.configured != nil {
+			*class.option = *class.configured
+		}
+	}
+
+	return options
+}
+
+// Returns the templates of the configuration, sorted by name
+func readTemplates(templates map[string]config.Template) []kdbx.Template {
+	result := []kdbx.Template{}
+
+	for _, name := range slices.Sorted(maps.Keys(templates)) {
+		template := kdbx.Template{Name: name}
+		for _, f := range templates[name].Fields {
+			template.Fields = append(template.Fields, kdbx.TemplateField{Key: f.Key, Value: f.Value, Protected: f.Protected, Generator: f.Generator})
+		}
+		result = append(result, template)
+	}
+
+	return result
+}
+
+// Returns base, overridden by the encryption flags that were passed.
+// With --kdf-target, the key derivation is tuned to take that long.
+func readEncryptionOptions(cmd *cobra.Command, base kdbx.EncryptionOptions) (kdbx.EncryptionOptions, error) {
+	options := base
+	flags := cmd.Flags()
+
+	if flags.Changed("format-version") {
+		options.Version, _ = flags.GetInt("format-version")
+
+		// Argon2 is not available in KDBX 3
+		if options.Version == kdbx.VERSION_3 && !flags.Changed("kdf") {
+			options.KDF = kdbx.KDF_AES
+		}
+	}
+	if flags.Changed("cipher") {
+		options.Cipher, _ = flags.GetString("cipher")
+	}
+	if flags.Changed("kdf") {
+		options.KDF, _ = flags.GetString("kdf")
+	}
+	if flags.Changed("kdf-rounds") {
+		options.Rounds, _ = flags.GetUint64("kdf-rounds")
+	}
+	if flags.Changed("kdf-memory") {
+		memory, _ := flags.GetUint64("kdf-memory")
+		options.Memory = memory * kdbx.MEBIBYTE
+	}
+	if flags.Changed("kdf-iterations") {
+		options.Iterations, _ = flags.GetUint64("kdf-iterations")
+	}
+	if flags.Changed("kdf-parallelism") {
+		options.Parallelism, _ = flags.GetUint32("kdf-parallelism")
+	}
+
+	if err := options.Validate(); err != nil {
+		return options, err
+	}
+
+	if flags.Changed("kdf-target") {
+		target, _ := flags.GetDuration("kdf-target")
+		if target <= 0 {
+			return options, errors.New("kdf target must be a positive duration")
+		}
+
+		fmt.Fprintf(os.Stderr, "Tuning the key derivation to take %s...\n", target)
+		options = kdbx.TuneKDF(options, target)
+	}
+
+	return options, nil
+}
+
+// Returns where the passphrase is read from: the --passphrase-fd,
+// --passphrase-file, and --passphrase-cmd flags, falling back to the
+// environment. suffix selects the flags and variables of one of the
+// databases of diff, like "-a".
+func readPassphraseSource(cmd *cobra.Command, suffix, database string) credentials.PassphraseSource {
+	envSuffix := strings.ToUpper(strings.ReplaceAll(suffix, "-", "_"))
+	source := credentials.PassphraseSource{
+		Command:    os.Getenv(ENV_PASSPHRASE_COMMAND + envSuffix),
+		Passphrase: os.Getenv(ENV_PASSPHRASE + envSuffix),
+	}
+
+	flags := cmd.Flags()
+	if noPassphrase, _ := flags.GetBool("no-passphrase" + suffix); noPassphrase || readConfiguredNoPassphrase(cmd, database) {
+		source.None = true
+	}
+
+	if flags.Changed("passphrase-fd" + suffix) {
+		fd, _ := flags.GetInt("passphrase-fd" + suffix)
+		source.FD = &fd
+	}
+
+	if file, _ := flags.GetString("passphrase-file" + suffix); file != "" {
+		source.File = file
+	}
+
+	if command, _ := flags.GetString("passphrase-cmd" + suffix); command != "" {
+		source.Command = command
+	}
+
+	return source
+}
+
+// Opens database with the passphrase from source, prompting again while it
+// is wrong
+func openDatabase(source credentials.PassphraseSource, database, key string) (*kdbx.Database, error) {
+	var db *kdbx.Database
+	err := credentials.WithPassphrase(database, source, func(passphrase *secure.Buffer) (err error) {
+		db, err = kdbx.OpenWithPassphrase(database, passphrase, key)
+		return err
+	})
+	return db, err
+}
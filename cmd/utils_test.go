@@ -2,7 +2,10 @@ package cmd
 
 import (
 	"testing"
+	"time"
 
+	"github.com/shikaan/keydex/pkg/clipboard"
+	"github.com/shikaan/keydex/pkg/config"
 	"github.com/spf13/cobra"
 )
 
@@ -54,3 +57,92 @@ func TestReadDatabaseArguments(t *testing.T) {
 		})
 	}
 }
+
+func TestReadDatabaseArgumentsWithConfig(t *testing.T) {
+	config.Use(&config.Config{
+		Database: "configured",
+		Key:      "configured.key",
+		Profiles: map[string]config.Profile{
+			"work": {Database: "work", Key: "work.key"},
+		},
+	})
+	defer config.Use(&config.Config{})
+
+	makeProfileCmd := func(profile string) *cobra.Command {
+		cmd := makeCobraCmd("copy", "")
+		cmd.Flags().String("profile", "", "")
+		if profile != "" {
+			cmd.Flags().Set("profile", profile)
+		}
+		return cmd
+	}
+
+	tests := []struct {
+		name         string
+		cmd          *cobra.Command
+		args         []string
+		env          map[string]string
+		wantDatabase string
+		wantKey      string
+	}{
+		{"args: nil, env: nil", makeProfileCmd(""), []string{}, map[string]string{}, "configured", "configured.key"},
+		{"args: nil, env: database", makeProfileCmd(""), []string{}, map[string]string{ENV_DATABASE: "database"}, "database", ""},
+		{"args: database, env: nil", makeProfileCmd(""), []string{"database", "/ref"}, map[string]string{}, "database", ""},
+		{"args: nil, env: key", makeProfileCmd(""), []string{}, map[string]string{ENV_KEY: "env.key"}, "configured", "env.key"},
+		{"args: nil, env: profile", makeProfileCmd(""), []string{}, map[string]string{ENV_PROFILE: "work"}, "work", "work.key"},
+		{"args: nil, env: database, profile", makeProfileCmd(""), []string{}, map[string]string{ENV_DATABASE: "database", ENV_PROFILE: "work"}, "database", ""},
+		{"args: --profile, env: database", makeProfileCmd("work"), []string{}, map[string]string{ENV_DATABASE: "database"}, "work", "work.key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			gotDatabase, _, gotKey := ReadDatabaseArguments(tt.cmd, tt.args)
+			if gotDatabase != tt.wantDatabase {
+				t.Errorf("ReadDatabaseArguments() gotDatabase = %v, want %v", gotDatabase, tt.wantDatabase)
+			}
+			if gotKey != tt.wantKey {
+				t.Errorf("ReadDatabaseArguments() gotKey = %v, want %v", gotKey, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestReadClearAfter(t *testing.T) {
+	makeClearAfterCmd := func(value string) *cobra.Command {
+		cmd := &cobra.Command{Use: "copy"}
+		cmd.Flags().Duration("clear-after", clipboard.DEFAULT_CLEAR_AFTER, "")
+		if value != "" {
+			cmd.Flags().Set("clear-after", value)
+		}
+		return cmd
+	}
+
+	tests := []struct {
+		name       string
+		cmd        *cobra.Command
+		configured time.Duration
+		want       time.Duration
+	}{
+		{"uses default", makeClearAfterCmd(""), 0, clipboard.DEFAULT_CLEAR_AFTER},
+		{"uses configuration", makeClearAfterCmd(""), time.Minute, time.Minute},
+		{"disables with negative configuration", makeClearAfterCmd(""), -1, 0},
+		{"uses flag", makeClearAfterCmd("5s"), time.Minute, 5 * time.Second},
+		{"uses flag to disable", makeClearAfterCmd("0"), time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Use(&config.Config{Clipboard: config.Clipboard{ClearAfter: tt.configured}})
+			defer config.Use(&config.Config{})
+
+			got, err := ReadClearAfter(tt.cmd)
+			if err != nil {
+				t.Fatalf("ReadClearAfter() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadClearAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
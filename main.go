@@ -27,6 +27,6 @@ func main() {
 	}()
 
 	if err := cmd.Root.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(errors.ExitCode(err))
 	}
 }
@@ -0,0 +1,287 @@
+package agent
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/secure"
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+const passphrase = "test-password"
+
+func makeDatabase(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.kdbx")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, _ := kdbx.NewFromFile(file)
+	if err := db.SetPasswordAndKey(passphrase, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	group := db.NewGroup("TestDB")
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values,
+		gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: "GitHub"}},
+		gokeepasslib.ValueData{Key: "Password", Value: gokeepasslib.V{Content: "ghpass123", Protected: wrappers.NewBoolWrapper(true)}},
+	)
+	group.Entries = append(group.Entries, entry)
+	db.Content.Root.Groups = []gokeepasslib.Group{*group}
+
+	if err := db.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func startServer(t *testing.T, ttl time.Duration) (*Server, *Client) {
+	t.Helper()
+
+	// Socket paths are limited to about 100 characters
+	dir, err := os.MkdirTemp("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, SOCKET_FILE)
+	listener, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(ttl)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	return server, NewClient(path)
+}
+
+func TestListen(t *testing.T) {
+	_, client := startServer(t, 0)
+
+	stat, err := os.Stat(client.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := stat.Mode().Perm(); mode != 0600 {
+		t.Errorf("socket mode = %v, want 0600", mode)
+	}
+
+	if _, err := Listen(client.path); err == nil {
+		t.Errorf("Listen() succeeded with a running agent")
+	}
+}
+
+func TestListen_RuntimeDir(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("directory owners are not checked on this platform")
+	}
+
+	// Socket paths are limited to about 100 characters
+	dir, err := os.MkdirTemp("", "runtime")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	t.Setenv("XDG_RUNTIME_DIR", dir)
+	t.Setenv(ENV_AGENT_SOCKET, "")
+
+	if err := os.Mkdir(RuntimeDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Listen(SocketPath()); err == nil {
+		t.Fatalf("Listen() succeeded in a directory others can read")
+	}
+
+	if err := os.Chmod(RuntimeDir(), 0700); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := Listen(SocketPath())
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	listener.Close()
+}
+
+func TestClient(t *testing.T) {
+	database := makeDatabase(t)
+	_, client := startServer(t, 0)
+
+	if _, err := client.List(database, ""); err != ErrLocked {
+		t.Fatalf("Client.List() error = %v, want %v", err, ErrLocked)
+	}
+
+	if err := client.Unlock(database, "", secure.FromString("wrong")); !errors.Is(err, errors.ErrWrongCredentials) {
+		t.Fatalf("Client.Unlock() error = %v, want %v", err, errors.ErrWrongCredentials)
+	}
+
+	if err := client.Unlock(database, "", secure.FromString(passphrase)); err != nil {
+		t.Fatalf("Client.Unlock() error = %v", err)
+	}
+
+	entries, err := client.List(database, "")
+	if err != nil || !reflect.DeepEqual(entries, []string{"/TestDB/GitHub"}) {
+		t.Errorf("Client.List() = %v, %v", entries, err)
+	}
+
+	value, err := client.Get(database, "", "/TestDB/GitHub", "Password")
+	if err != nil || value != "ghpass123" {
+		t.Errorf("Client.Get() = %v, %v", value, err)
+	}
+
+	if _, err := client.Get(database, "", "/TestDB/GitHub", "URL"); err == nil {
+		t.Errorf("Client.Get() succeeded on a missing field")
+	}
+
+	fields, err := client.Show(database, "", "/TestDB/GitHub")
+	want := []Field{{Key: "Title", Value: "GitHub"}, {Key: "Password", Value: "ghpass123", Protected: true}}
+	if err != nil || !reflect.DeepEqual(fields, want) {
+		t.Errorf("Client.Show() = %v, %v, want %v", fields, err, want)
+	}
+
+	if _, err := client.Show(database, "", "/TestDB/Missing"); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("Client.Show() error = %v on a missing entry, want %v", err, errors.ErrNotFound)
+	}
+
+	if vaults, err := client.Status(); err != nil || len(vaults) != 1 || vaults[0].Database != database {
+		t.Errorf("Client.Status() = %v, %v", vaults, err)
+	}
+
+	if err := client.Lock(); err != nil {
+		t.Fatalf("Client.Lock() error = %v", err)
+	}
+
+	if _, err := client.List(database, ""); err != ErrLocked {
+		t.Errorf("Client.List() error = %v after lock, want %v", err, ErrLocked)
+	}
+}
+
+func TestClient_VerifiesAgent(t *testing.T) {
+	database := makeDatabase(t)
+	server, client := startServer(t, 0)
+
+	client.verify = func(conn net.Conn) error { return errors.MakeError("Connection from another user refused.", "agent") }
+
+	err := client.Unlock(database, "", secure.FromString(passphrase))
+	if err == nil || err == ErrNotRunning {
+		t.Fatalf("Client.Unlock() error = %v, want a refusal", err)
+	}
+
+	if vaults := server.status(); len(vaults) != 0 {
+		t.Errorf("expected the passphrase not to be sent, got %v", vaults)
+	}
+}
+
+func TestServer_TTL(t *testing.T) {
+	database := makeDatabase(t)
+	server, client := startServer(t, time.Minute)
+
+	now := time.Now()
+	server.mu.Lock()
+	server.now = func() time.Time { return now }
+	server.mu.Unlock()
+
+	if err := client.Unlock(database, "", secure.FromString(passphrase)); err != nil {
+		t.Fatal(err)
+	}
+
+	server.mu.Lock()
+	server.now = func() time.Time { return now.Add(time.Minute) }
+	server.mu.Unlock()
+
+	if _, err := client.List(database, ""); err != ErrLocked {
+		t.Errorf("Client.List() error = %v after TTL, want %v", err, ErrLocked)
+	}
+}
+
+func TestServer_Wipe(t *testing.T) {
+	database := makeDatabase(t)
+	server, client := startServer(t, time.Minute)
+
+	unlock := func() *kdbx.Database {
+		t.Helper()
+		if err := client.Unlock(database, "", secure.FromString(passphrase)); err != nil {
+			t.Fatal(err)
+		}
+
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return server.vaults[vaultID{database, ""}].db
+	}
+	isWiped := func(db *kdbx.Database) bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return db.Content == nil && db.Credentials == nil
+	}
+
+	db := unlock()
+	if err := client.Lock(); err != nil {
+		t.Fatal(err)
+	}
+	if !isWiped(db) {
+		t.Errorf("expected the database to be wiped on lock")
+	}
+
+	db = unlock()
+	server.mu.Lock()
+	server.now = func() time.Time { return time.Now().Add(time.Minute) }
+	server.mu.Unlock()
+
+	// Expired databases are wiped by a ticker every second
+	deadline := time.Now().Add(5 * time.Second)
+	for !isWiped(db) && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+	}
+	if !isWiped(db) {
+		t.Errorf("expected the database to be wiped after TTL")
+	}
+}
+
+func TestServer_ChangedOnDisk(t *testing.T) {
+	database := makeDatabase(t)
+	_, client := startServer(t, 0)
+
+	if err := client.Unlock(database, "", secure.FromString(passphrase)); err != nil {
+		t.Fatal(err)
+	}
+
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(database, later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.List(database, ""); err != ErrLocked {
+		t.Errorf("Client.List() error = %v after change, want %v", err, ErrLocked)
+	}
+}
+
+func TestClient_Stop(t *testing.T) {
+	_, client := startServer(t, 0)
+
+	if !client.IsRunning() {
+		t.Fatalf("Client.IsRunning() = false, want true")
+	}
+
+	if err := client.Stop(); err != nil {
+		t.Fatalf("Client.Stop() error = %v", err)
+	}
+
+	if client.IsRunning() {
+		t.Errorf("Client.IsRunning() = true after stop, want false")
+	}
+}
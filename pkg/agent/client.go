@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/secure"
+)
+
+// Time allowed to connect to the agent, before falling back to prompting
+const DIAL_TIMEOUT = time.Second
+
+// Returned by clients when the agent does not know the database, or when it
+// changed on disk. Unlock it and retry.
+var ErrLocked = errors.MakeError("Database is locked in the agent.", "agent")
+
+// Returned by clients when no agent is listening
+var ErrNotRunning = errors.MakeError("Agent is not running.", "agent")
+
+type Client struct {
+	path string
+	// Checks the agent is run by the current user, before sending requests
+	verify func(conn net.Conn) error
+}
+
+func NewClient(path string) *Client {
+	return &Client{path: path, verify: VerifyPeer}
+}
+
+// Sends request to the agent and waits for its response
+func (c *Client) Do(request Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", c.path, DIAL_TIMEOUT)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	defer conn.Close()
+
+	// Anyone able to create the socket would otherwise receive passphrases
+	if err := c.verify(conn); err != nil {
+		return nil, errors.MakeError("Refusing to send requests to the agent: "+err.Error(), "agent")
+	}
+
+	// The request might carry a passphrase, which is overwritten once sent
+	data, err := json.Marshal(request)
+	defer clear(data)
+	if err != nil {
+		return nil, errors.MakeError("Cannot send request: "+err.Error(), "agent")
+	}
+
+	for _, chunk := range [][]byte{data, []byte("\n")} {
+		if _, err := conn.Write(chunk); err != nil {
+			return nil, errors.MakeError("Cannot send request: "+err.Error(), "agent")
+		}
+	}
+
+	response := &Response{}
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(response); err != nil {
+		return nil, errors.MakeError("Cannot read response: "+err.Error(), "agent")
+	}
+
+	if response.Code == CODE_LOCKED {
+		return nil, ErrLocked
+	}
+
+	if response.Error != "" {
+		if kind := errors.KindByName(response.Code); kind != nil {
+			return nil, errors.MakeTypedError(kind, response.Error, "agent")
+		}
+		return nil, errors.MakeError(response.Error, "agent")
+	}
+
+	return response, nil
+}
+
+// Returns true if an agent is listening
+func (c *Client) IsRunning() bool {
+	_, err := c.Do(Request{Op: OP_STATUS})
+	return err != ErrNotRunning
+}
+
+func (c *Client) Unlock(database, key string, passphrase *secure.Buffer) error {
+	_, err := c.Do(Request{Op: OP_UNLOCK, Database: database, Key: key, Passphrase: passphrase.Bytes()})
+	return err
+}
+
+func (c *Client) List(database, key string) ([]string, error) {
+	response, err := c.Do(Request{Op: OP_LIST, Database: database, Key: key})
+	if err != nil {
+		return nil, err
+	}
+	return response.Entries, nil
+}
+
+func (c *Client) Get(database, key, reference, field string) (string, error) {
+	response, err := c.Do(Request{Op: OP_GET, Database: database, Key: key, Reference: reference, Field: field})
+	if err != nil {
+		return "", err
+	}
+	return response.Value, nil
+}
+
+func (c *Client) Show(database, key, reference string) ([]Field, error) {
+	response, err := c.Do(Request{Op: OP_SHOW, Database: database, Key: key, Reference: reference})
+	if err != nil {
+		return nil, err
+	}
+	return response.Fields, nil
+}
+
+func (c *Client) Lock() error {
+	_, err := c.Do(Request{Op: OP_LOCK})
+	return err
+}
+
+func (c *Client) Status() ([]Vault, error) {
+	response, err := c.Do(Request{Op: OP_STATUS})
+	if err != nil {
+		return nil, err
+	}
+	return response.Vaults, nil
+}
+
+func (c *Client) Stop() error {
+	_, err := c.Do(Request{Op: OP_STOP})
+	return err
+}
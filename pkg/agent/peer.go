@@ -0,0 +1,27 @@
+package agent
+
+import (
+	"net"
+	"syscall"
+
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+// Runs cb with the file descriptor of conn
+func control(conn net.Conn, cb func(fd uintptr)) error {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return errors.MakeError("Cannot read peer credentials: not a socket.", "agent")
+	}
+
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return errors.MakeError("Cannot read peer credentials: "+err.Error(), "agent")
+	}
+
+	if err := raw.Control(cb); err != nil {
+		return errors.MakeError("Cannot read peer credentials: "+err.Error(), "agent")
+	}
+
+	return nil
+}
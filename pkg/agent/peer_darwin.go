@@ -0,0 +1,28 @@
+//go:build darwin
+
+package agent
+
+import (
+	"net"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// Rejects connections from processes of other users
+func VerifyPeer(conn net.Conn) error {
+	var cred *unix.Xucred
+	var credErr error
+
+	if err := control(conn, func(fd uintptr) {
+		cred, credErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	}); err != nil {
+		return err
+	}
+
+	if credErr != nil {
+		return errors.MakeError("Cannot read peer credentials: "+credErr.Error(), "agent")
+	}
+
+	return verifyUID(int(cred.Uid))
+}
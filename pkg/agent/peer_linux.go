@@ -0,0 +1,28 @@
+//go:build linux
+
+package agent
+
+import (
+	"net"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// Rejects connections from processes of other users
+func VerifyPeer(conn net.Conn) error {
+	var cred *unix.Ucred
+	var credErr error
+
+	if err := control(conn, func(fd uintptr) {
+		cred, credErr = unix.GetsockoptUcred(int(fd), unix.SOL_SOCKET, unix.SO_PEERCRED)
+	}); err != nil {
+		return err
+	}
+
+	if credErr != nil {
+		return errors.MakeError("Cannot read peer credentials: "+credErr.Error(), "agent")
+	}
+
+	return verifyUID(int(cred.Uid))
+}
@@ -0,0 +1,30 @@
+//go:build !linux && !darwin
+
+package agent
+
+import (
+	"net"
+	"os"
+
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+// Peer credentials are not available on this platform: access is restricted
+// by the permissions of the socket and of its directory only
+func VerifyPeer(conn net.Conn) error {
+	return nil
+}
+
+// Ownership is not available on this platform: directories are only
+// checked not to be links
+func verifyDirectory(dir string) error {
+	stat, err := os.Lstat(dir)
+	if err != nil {
+		return errors.MakeError("Cannot check socket directory: "+err.Error(), "agent")
+	}
+
+	if !stat.IsDir() {
+		return errors.MakeError("Unsafe socket directory "+dir+": it is not a directory.", "agent")
+	}
+	return nil
+}
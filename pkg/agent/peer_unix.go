@@ -0,0 +1,36 @@
+//go:build linux || darwin
+
+package agent
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+func verifyUID(uid int) error {
+	if uid != os.Getuid() {
+		return errors.MakeError("Connection from user "+strconv.Itoa(uid)+" refused.", "agent")
+	}
+	return nil
+}
+
+// Rejects directories other users could have created, or can write to
+func verifyDirectory(dir string) error {
+	stat, err := os.Lstat(dir)
+	if err != nil {
+		return errors.MakeError("Cannot check socket directory: "+err.Error(), "agent")
+	}
+
+	owner := -1
+	if sys, ok := stat.Sys().(*syscall.Stat_t); ok {
+		owner = int(sys.Uid)
+	}
+
+	if !stat.IsDir() || owner != os.Getuid() || stat.Mode().Perm() != 0700 {
+		return errors.MakeError("Unsafe socket directory "+dir+": it must be a directory of the current user, with mode 0700.", "agent")
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/shikaan/keydex/pkg/info"
+)
+
+// Overrides the location of the agent socket
+const ENV_AGENT_SOCKET = "KEYDEX_AGENT_SOCKET"
+
+const SOCKET_FILE = "agent.sock"
+
+// Default time after which unlocked databases are forgotten
+const DEFAULT_TTL = 15 * time.Minute
+
+// Operations understood by the agent
+const (
+	// Unlocks a database with a passphrase, and keeps it for the TTL
+	OP_UNLOCK = "unlock"
+	// Lists the references of an unlocked database
+	OP_LIST = "list"
+	// Returns the value of a field of an entry
+	OP_GET = "get"
+	// Returns all the fields of an entry
+	OP_SHOW = "show"
+	// Forgets all the unlocked databases
+	OP_LOCK = "lock"
+	// Lists the unlocked databases
+	OP_STATUS = "status"
+	// Forgets all the unlocked databases and stops the agent
+	OP_STOP = "stop"
+)
+
+// Error codes, telling apart failures clients can recover from. Other
+// failures have the name of their errors.Kind as code, if any.
+const (
+	// The database is not unlocked in the agent, or it changed on disk
+	CODE_LOCKED = "locked"
+)
+
+// Requests are sent as one JSON object per line. Databases are identified
+// by the absolute paths of the database and of its key file. The passphrase
+// is a byte slice, so that it can be overwritten once used.
+type Request struct {
+	Op         string `json:"op"`
+	Database   string `json:"database,omitempty"`
+	Key        string `json:"key,omitempty"`
+	Passphrase []byte `json:"passphrase,omitempty"`
+	Reference  string `json:"reference,omitempty"`
+	Field      string `json:"field,omitempty"`
+}
+
+type Response struct {
+	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
+
+	Entries []string `json:"entries,omitempty"`
+	Value   string   `json:"value,omitempty"`
+	Fields  []Field  `json:"fields,omitempty"`
+	Vaults  []Vault  `json:"vaults,omitempty"`
+}
+
+type Field struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	Protected bool   `json:"protected,omitempty"`
+}
+
+// Database unlocked in the agent
+type Vault struct {
+	Database string    `json:"database"`
+	Key      string    `json:"key,omitempty"`
+	Expires  time.Time `json:"expires,omitzero"`
+}
+
+// Returns the path of the agent socket. It honours KEYDEX_AGENT_SOCKET,
+// defaulting to RuntimeDir.
+func SocketPath() string {
+	if path := os.Getenv(ENV_AGENT_SOCKET); path != "" {
+		return path
+	}
+
+	return filepath.Join(RuntimeDir(), SOCKET_FILE)
+}
+
+// Returns the directory of the sockets of the current user. It honours
+// XDG_RUNTIME_DIR, defaulting to a per-user directory in the temp folder.
+func RuntimeDir() string {
+	if runtime := os.Getenv("XDG_RUNTIME_DIR"); runtime != "" {
+		return filepath.Join(runtime, info.NAME)
+	}
+
+	return filepath.Join(os.TempDir(), info.NAME+"-"+strconv.Itoa(os.Getuid()))
+}
@@ -0,0 +1,335 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/shikaan/keydex/pkg/secure"
+)
+
+// Server keeps unlocked databases in memory, and serves their entries to
+// clients of the same user
+type Server struct {
+	// Time after which unlocked databases are forgotten, 0 to keep them
+	// until the agent is locked
+	TTL time.Duration
+
+	mu       sync.Mutex
+	vaults   map[vaultID]*vault
+	listener net.Listener
+	done     chan struct{}
+	now      func() time.Time
+}
+
+type vaultID struct {
+	database string
+	key      string
+}
+
+type vault struct {
+	db      *kdbx.Database
+	expires time.Time
+	// Used to detect changes made to the file after unlocking it
+	modTime time.Time
+	size    int64
+}
+
+func NewServer(ttl time.Duration) *Server {
+	return &Server{
+		TTL:    ttl,
+		vaults: map[vaultID]*vault{},
+		done:   make(chan struct{}),
+		now:    time.Now,
+	}
+}
+
+// Listens on a socket at path, readable only by the current user
+func Listen(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.MakeError("Cannot create socket directory: "+err.Error(), "agent")
+	}
+
+	// The default directory is in a shared place, where other users might
+	// have created it first. Directories chosen by the user are trusted.
+	if dir == RuntimeDir() {
+		if err := verifyDirectory(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sockets left behind by agents that did not shut down cleanly
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, errors.MakeError("An agent is already listening on "+path+".", "agent")
+	}
+	os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, errors.MakeError("Cannot listen on "+path+": "+err.Error(), "agent")
+	}
+
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, errors.MakeError("Cannot restrict socket permissions: "+err.Error(), "agent")
+	}
+
+	return listener, nil
+}
+
+// Serves requests until the agent is stopped or the listener is closed
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	s.listener = listener
+	select {
+	case <-s.done:
+		listener.Close()
+	default:
+	}
+	s.mu.Unlock()
+
+	go s.expire()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+				return errors.MakeError("Cannot accept connections: "+err.Error(), "agent")
+			}
+		}
+
+		go s.handle(conn)
+	}
+}
+
+// Forgets all the unlocked databases and stops serving requests
+func (s *Server) Stop() {
+	s.lock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+		if s.listener != nil {
+			s.listener.Close()
+		}
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	if err := VerifyPeer(conn); err != nil {
+		log.Error("Rejected agent connection", err)
+		return
+	}
+
+	scanner := bufio.NewScanner(conn)
+	encoder := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		request := Request{}
+		response := Response{}
+
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			response.Error = "Invalid request: " + err.Error()
+		} else {
+			response = s.Do(request)
+		}
+		// The request might carry a passphrase
+		clear(scanner.Bytes())
+		clear(request.Passphrase)
+
+		if err := encoder.Encode(response); err != nil {
+			return
+		}
+
+		if request.Op == OP_STOP && response.Error == "" {
+			s.Stop()
+			return
+		}
+	}
+}
+
+// Executes request, and returns its response
+func (s *Server) Do(request Request) Response {
+	switch request.Op {
+	case OP_UNLOCK:
+		return s.unlock(request)
+	case OP_LIST:
+		return s.withDatabase(request, func(db *kdbx.Database) Response {
+			return Response{Entries: db.GetEntryPaths()}
+		})
+	case OP_GET:
+		return s.withEntry(request, func(entry *kdbx.Entry) Response {
+			value := entry.GetContent(request.Field)
+			if value == "" {
+				return Response{Error: `Missing field "` + request.Field + `" in entry "` + request.Reference + `".`, Code: errors.ErrNotFound.Name}
+			}
+			return Response{Value: value}
+		})
+	case OP_SHOW:
+		return s.withEntry(request, func(entry *kdbx.Entry) Response {
+			fields := []Field{}
+			for _, value := range entry.Values {
+				fields = append(fields, Field{Key: value.Key, Value: kdbx.FieldContent(value), Protected: value.Value.Protected.Bool})
+			}
+			return Response{Fields: fields}
+		})
+	case OP_LOCK:
+		s.lock()
+		return Response{}
+	case OP_STATUS:
+		return Response{Vaults: s.status()}
+	case OP_STOP:
+		return Response{}
+	}
+
+	return Response{Error: `Unknown operation "` + request.Op + `".`}
+}
+
+func (s *Server) unlock(request Request) Response {
+	stat, err := os.Stat(request.Database)
+	if err != nil {
+		return Response{Error: "Cannot open " + request.Database + ": " + err.Error(), Code: errors.ErrNotFound.Name}
+	}
+
+	passphrase := secure.FromBytes(request.Passphrase)
+	defer passphrase.Destroy()
+
+	db, err := kdbx.OpenWithPassphrase(request.Database, passphrase, request.Key)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v := &vault{db: db, modTime: stat.ModTime(), size: stat.Size()}
+	if s.TTL > 0 {
+		v.expires = s.now().Add(s.TTL)
+	}
+	id := vaultID{request.Database, request.Key}
+	s.forget(id)
+	s.vaults[id] = v
+
+	log.Info("Agent unlocked " + request.Database)
+	return Response{}
+}
+
+// Runs cb with the unlocked database of request, and answers with a locked
+// response if it is not available
+func (s *Server) withDatabase(request Request, cb func(db *kdbx.Database) Response) Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := vaultID{request.Database, request.Key}
+	v, ok := s.vaults[id]
+	if !ok || v.isExpired(s.now()) {
+		s.forget(id)
+		return Response{Error: "Database is locked.", Code: CODE_LOCKED}
+	}
+
+	// The cached copy would be stale, and saving over it would lose changes
+	if stat, err := os.Stat(request.Database); err != nil || !stat.ModTime().Equal(v.modTime) || stat.Size() != v.size {
+		s.forget(id)
+		return Response{Error: "Database changed on disk.", Code: CODE_LOCKED}
+	}
+
+	return cb(v.db)
+}
+
+func (s *Server) withEntry(request Request, cb func(entry *kdbx.Entry) Response) Response {
+	return s.withDatabase(request, func(db *kdbx.Database) Response {
+		entry, err := db.GetEntryByPath(request.Reference)
+		if err != nil {
+			return errorResponse(err)
+		}
+		return cb(entry)
+	})
+}
+
+// Returns a response for err, whose code is the name of its kind
+func errorResponse(err error) Response {
+	response := Response{Error: err.Error()}
+	if kind := errors.KindOf(err); kind != nil {
+		response.Code = kind.Name
+	}
+	return response
+}
+
+func (s *Server) lock() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id := range s.vaults {
+		s.forget(id)
+	}
+	log.Info("Agent locked")
+}
+
+// Wipes the unlocked database of id from memory, if any. It must be called
+// with the lock held.
+func (s *Server) forget(id vaultID) {
+	if v, ok := s.vaults[id]; ok {
+		v.db.Wipe()
+		delete(s.vaults, id)
+	}
+}
+
+func (s *Server) status() []Vault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	vaults := []Vault{}
+	for id, v := range s.vaults {
+		if !v.isExpired(s.now()) {
+			vaults = append(vaults, Vault{Database: id.database, Key: id.key, Expires: v.expires})
+		}
+	}
+
+	sort.Slice(vaults, func(i, j int) bool { return vaults[i].Database < vaults[j].Database })
+	return vaults
+}
+
+// Forgets expired databases, so that they do not linger in memory
+func (s *Server) expire() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			for id, v := range s.vaults {
+				if v.isExpired(s.now()) {
+					s.forget(id)
+					log.Info("Agent forgot " + id.database)
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (v *vault) isExpired(now time.Time) bool {
+	return !v.expires.IsZero() && !now.Before(v.expires)
+}
@@ -7,11 +7,14 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/shikaan/keydex/pkg/secure"
 	"golang.org/x/term"
 )
 
-var ReadSecret = func(prompt string) string {
-	result := ""
+// Reads a secret from the terminal, without echoing it. The secret is
+// returned in a locked buffer: Destroy it once used.
+var ReadSecret = func(prompt string) *secure.Buffer {
+	result := secure.NewBuffer(0)
 	fmt.Fprint(os.Stderr, prompt)
 
 	for {
@@ -19,8 +22,8 @@ var ReadSecret = func(prompt string) string {
 		if err != nil {
 			break
 		}
-		result = string(pw)
-		if result != "" {
+		result = secure.FromBytes(pw)
+		if result.Len() != 0 {
 			break
 		}
 	}
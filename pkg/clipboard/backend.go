@@ -0,0 +1,149 @@
+package clipboard
+
+import (
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+// Backend is a way of reaching a clipboard
+type Backend interface {
+	// Identifies the backend in flags, environment, and configuration
+	Name() string
+	// Returns true if the backend can be used in the current environment
+	Available() bool
+	Write(msg string) error
+	// Returns ErrUnsupported for write-only backends
+	Read() (string, error)
+}
+
+type Selection = string
+
+const (
+	SELECTION_CLIPBOARD Selection = "clipboard"
+	SELECTION_PRIMARY   Selection = "primary"
+)
+
+// Backend names, as used in flags, environment, and configuration
+const (
+	BACKEND_AUTO    = "auto"
+	BACKEND_SYSTEM  = "system"
+	BACKEND_OSC52   = "osc52"
+	BACKEND_WAYLAND = "wl-copy"
+	BACKEND_XCLIP   = "xclip"
+	BACKEND_XSEL    = "xsel"
+	BACKEND_TMUX    = "tmux"
+	BACKEND_COMMAND = "command"
+)
+
+var ErrUnsupported = errors.MakeTypedError(errors.ErrClipboard, "Reading is not supported by this clipboard backend.", "clipboard")
+
+var current Backend
+
+// Selects the backend described by spec, in the form "name[:argument]".
+// The argument is the selection (clipboard or primary) for osc52, xclip,
+// and xsel, and the command line for the command backend. For example:
+//
+//	auto
+//	xclip:primary
+//	command:my-copy-tool --flag
+//
+// An empty spec is equivalent to "auto", which picks the first available
+// backend in the fallback chain. See Fallbacks.
+func Use(spec string) error {
+	backend, err := Parse(spec)
+	if err != nil {
+		return err
+	}
+
+	current = backend
+	return nil
+}
+
+// Returns the backend in use, resolving it automatically if none was set
+func Current() Backend {
+	if current == nil {
+		current = auto()
+	}
+	return current
+}
+
+// Returns the backend described by spec. See Use.
+func Parse(spec string) (Backend, error) {
+	name, argument, _ := strings.Cut(strings.TrimSpace(spec), ":")
+
+	selection := SELECTION_CLIPBOARD
+	if argument != "" && name != BACKEND_COMMAND {
+		if argument != SELECTION_CLIPBOARD && argument != SELECTION_PRIMARY {
+			return nil, errors.MakeError(`Unknown selection "`+argument+`". Use "clipboard" or "primary".`, "clipboard")
+		}
+		selection = argument
+	}
+
+	switch name {
+	case "", BACKEND_AUTO:
+		return auto(), nil
+	case BACKEND_SYSTEM:
+		return &systemBackend{}, nil
+	case BACKEND_OSC52:
+		return newOSC52Backend(selection), nil
+	case BACKEND_WAYLAND:
+		return newWaylandBackend(selection), nil
+	case BACKEND_XCLIP:
+		return newXclipBackend(selection), nil
+	case BACKEND_XSEL:
+		return newXselBackend(selection), nil
+	case BACKEND_TMUX:
+		return newTmuxBackend(), nil
+	case BACKEND_COMMAND:
+		if argument == "" {
+			return nil, errors.MakeError(`Missing command. Use "command:<command line>".`, "clipboard")
+		}
+		return newCustomBackend(argument), nil
+	}
+
+	return nil, errors.MakeError(`Unknown clipboard backend "`+name+`".`, "clipboard")
+}
+
+// Returns the backends tried, in order, when none is explicitly selected.
+//
+// Over SSH, OSC 52 comes first, as the local clipboard tools would write
+// on the remote machine. On macOS and Windows, the system clipboard is
+// used. Elsewhere wl-copy, xclip, and xsel are tried, then the tmux buffer,
+// and OSC 52 as a last resort.
+func Fallbacks() []Backend {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		return []Backend{&systemBackend{}, newOSC52Backend(SELECTION_CLIPBOARD)}
+	}
+
+	chain := []Backend{
+		newWaylandBackend(SELECTION_CLIPBOARD),
+		newXclipBackend(SELECTION_CLIPBOARD),
+		newXselBackend(SELECTION_CLIPBOARD),
+		newTmuxBackend(),
+		newOSC52Backend(SELECTION_CLIPBOARD),
+	}
+
+	if isRemoteSession() {
+		chain = append([]Backend{newOSC52Backend(SELECTION_CLIPBOARD)}, chain...)
+	}
+
+	return chain
+}
+
+func auto() Backend {
+	for _, backend := range Fallbacks() {
+		if backend.Available() {
+			return backend
+		}
+	}
+
+	// Surfaces the usual "missing utilities" error on write
+	return &systemBackend{}
+}
+
+func isRemoteSession() bool {
+	return os.Getenv("SSH_TTY") != "" || os.Getenv("SSH_CONNECTION") != ""
+}
@@ -0,0 +1,202 @@
+package clipboard
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+type fakeBackend struct {
+	content  string
+	readable bool
+	writes   int
+}
+
+func (b *fakeBackend) Name() string    { return "fake" }
+func (b *fakeBackend) Available() bool { return true }
+func (b *fakeBackend) Write(msg string) error {
+	b.content = msg
+	b.writes++
+	return nil
+}
+func (b *fakeBackend) Read() (string, error) {
+	if !b.readable {
+		return "", ErrUnsupported
+	}
+	return b.content, nil
+}
+
+func useFake(t *testing.T, b *fakeBackend) {
+	t.Helper()
+	previous := current
+	current = b
+	t.Cleanup(func() { current = previous })
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantName string
+		wantErr  bool
+	}{
+		{"osc52", BACKEND_OSC52, false},
+		{"osc52:primary", BACKEND_OSC52, false},
+		{"xclip:primary", BACKEND_XCLIP, false},
+		{"xsel:clipboard", BACKEND_XSEL, false},
+		{"wl-copy", BACKEND_WAYLAND, false},
+		{"tmux", BACKEND_TMUX, false},
+		{"system", BACKEND_SYSTEM, false},
+		{"command:cat", BACKEND_COMMAND, false},
+		{"command", "", true},
+		{"xclip:secondary", "", true},
+		{"unknown", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Name() != tt.wantName {
+				t.Errorf("Parse() = %v, want %v", got.Name(), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestParse_Selection(t *testing.T) {
+	backend, _ := Parse("xclip:primary")
+	if args := backend.(*commandBackend).write; args[len(args)-1] != SELECTION_PRIMARY {
+		t.Errorf("Parse() write command = %v, want primary selection", args)
+	}
+}
+
+func TestOSC52Sequence(t *testing.T) {
+	tests := []struct {
+		name      string
+		selection Selection
+		inTmux    bool
+		want      string
+	}{
+		{"clipboard", SELECTION_CLIPBOARD, false, "\x1b]52;c;c2VjcmV0\x07"},
+		{"primary", SELECTION_PRIMARY, false, "\x1b]52;p;c2VjcmV0\x07"},
+		{"tmux passthrough", SELECTION_CLIPBOARD, true, "\x1bPtmux;\x1b\x1b]52;c;c2VjcmV0\x07\x1b\\"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := osc52Sequence("secret", tt.selection, tt.inTmux); got != tt.want {
+				t.Errorf("osc52Sequence() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCustomBackend(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	destination := filepath.Join(t.TempDir(), "clipboard")
+	backend, err := Parse("command:cat > " + destination)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backend.Write("secret"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	content, _ := os.ReadFile(destination)
+	if string(content) != "secret" {
+		t.Errorf("Write() wrote %q, want %q", content, "secret")
+	}
+
+	if _, err := backend.Read(); err != ErrUnsupported {
+		t.Errorf("Read() error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestCustomBackend_BackgroundChild(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	// As xclip, which leaves a child owning the selection
+	backend, err := Parse("command:cat > /dev/null; sleep 5 &")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := backend.Write("secret"); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Write() waited %v for the child to exit", elapsed)
+	}
+}
+
+func TestRestoreIfUnchanged(t *testing.T) {
+	t.Run("restores the previous content", func(t *testing.T) {
+		b := &fakeBackend{content: "secret", readable: true}
+		useFake(t, b)
+
+		if err := RestoreIfUnchanged(Hash("secret"), "previous"); err != nil {
+			t.Fatal(err)
+		}
+		if b.content != "previous" {
+			t.Errorf("RestoreIfUnchanged() content = %q, want %q", b.content, "previous")
+		}
+	})
+
+	t.Run("keeps content changed in the meantime", func(t *testing.T) {
+		b := &fakeBackend{content: "something else", readable: true}
+		useFake(t, b)
+
+		if err := RestoreIfUnchanged(Hash("secret"), "previous"); err != nil {
+			t.Fatal(err)
+		}
+		if b.content != "something else" {
+			t.Errorf("RestoreIfUnchanged() content = %q, want %q", b.content, "something else")
+		}
+	})
+
+	t.Run("clears write-only backends", func(t *testing.T) {
+		b := &fakeBackend{content: "something else"}
+		useFake(t, b)
+
+		if err := RestoreIfUnchanged(Hash("secret"), ""); err != nil {
+			t.Fatal(err)
+		}
+		if b.content != "" {
+			t.Errorf("RestoreIfUnchanged() content = %q, want empty", b.content)
+		}
+	})
+}
+
+func TestSwap(t *testing.T) {
+	t.Run("returns the previous content", func(t *testing.T) {
+		b := &fakeBackend{content: "previous", readable: true}
+		useFake(t, b)
+
+		previous, err := Swap("secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if previous != "previous" || b.content != "secret" {
+			t.Errorf("Swap() = %q, content %q", previous, b.content)
+		}
+	})
+
+	t.Run("does not return the same secret", func(t *testing.T) {
+		b := &fakeBackend{content: "secret", readable: true}
+		useFake(t, b)
+
+		if previous, _ := Swap("secret"); previous != "" {
+			t.Errorf("Swap() = %q, want empty", previous)
+		}
+	})
+}
@@ -1,16 +1,68 @@
 package clipboard
 
 import (
-	"github.com/atotto/clipboard"
-	"github.com/shikaan/keydex/pkg/errors"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
 )
 
+// Default time after which copied secrets are removed from the clipboard
+const DEFAULT_CLEAR_AFTER = 30 * time.Second
+
+// Writes msg to the clipboard, using the current Backend
 func Write(msg string) error {
-	err := clipboard.WriteAll(msg)
+	return Current().Write(msg)
+}
+
+// Reads the clipboard, using the current Backend
+func Read() (string, error) {
+	return Current().Read()
+}
+
+// Writes msg to the clipboard, returning the previous content so that it
+// can be restored later. Clipboards that cannot be read yield no content.
+func Swap(msg string) (string, error) {
+	// An empty or unreadable clipboard is not an issue here: it will be
+	// cleared rather than restored
+	previous, _ := Read()
+
+	// Copying the same secret twice must not restore the secret itself
+	if previous == msg {
+		previous = ""
+	}
+
+	if err := Write(msg); err != nil {
+		return "", err
+	}
+
+	return previous, nil
+}
+
+// Returns a digest of a secret, to verify whether the clipboard still holds
+// it without keeping the secret itself around
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Replaces the clipboard content with previous, only if the clipboard
+// still holds the secret whose Hash is hash. This prevents overwriting
+// something the user copied in the meantime.
+//
+// Write-only backends cannot verify the content: they are cleared anyway.
+func RestoreIfUnchanged(hash, previous string) error {
+	content, err := Read()
+	if err == ErrUnsupported {
+		return Write(previous)
+	}
 
 	if err != nil {
-		return errors.MakeError("Clipboard error: "+err.Error(), "clipboard")
+		return err
+	}
+
+	if Hash(content) != hash {
+		return nil
 	}
 
-	return nil
+	return Write(previous)
 }
@@ -0,0 +1,25 @@
+package clipboard
+
+import "testing"
+
+func TestHash(t *testing.T) {
+	tests := []struct {
+		name   string
+		a, b   string
+		wantEq bool
+	}{
+		{"same secrets have the same hash", "secret", "secret", true},
+		{"different secrets have different hashes", "secret", "secreT", false},
+		{"empty secret", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Hash(tt.a) == Hash(tt.b); got != tt.wantEq {
+				t.Errorf("Hash(%q) == Hash(%q) is %v, want %v", tt.a, tt.b, got, tt.wantEq)
+			}
+			if Hash(tt.a) == tt.a && tt.a != "" {
+				t.Errorf("Hash(%q) returned the secret", tt.a)
+			}
+		})
+	}
+}
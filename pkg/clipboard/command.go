@@ -0,0 +1,161 @@
+package clipboard
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+// Time given to the output of commands to be closed once they exit
+const COMMAND_WAIT_DELAY = 500 * time.Millisecond
+
+// Backend piping the content through external commands
+type commandBackend struct {
+	name string
+	// Command line receiving the content on stdin
+	write []string
+	// Command line printing the content on stdout. Nil for write-only
+	read []string
+	// Returns true if the environment allows this backend
+	available func() bool
+}
+
+func (b *commandBackend) Name() string {
+	return b.name
+}
+
+func (b *commandBackend) Available() bool {
+	if _, err := exec.LookPath(b.write[0]); err != nil {
+		return false
+	}
+	return b.available == nil || b.available()
+}
+
+func (b *commandBackend) Write(msg string) error {
+	cmd := exec.Command(b.write[0], b.write[1:]...)
+	cmd.Stdin = strings.NewReader(msg)
+	// Tools such as xclip and wl-copy leave a child owning the selection,
+	// which inherits stderr. Waiting for it to close would block until
+	// another program takes the selection.
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	cmd.WaitDelay = COMMAND_WAIT_DELAY
+
+	if err := cmd.Run(); err != nil && err != exec.ErrWaitDelay {
+		return errors.MakeTypedError(errors.ErrClipboard, "Clipboard error: "+commandError(err, stderr.Bytes()), "clipboard")
+	}
+
+	return nil
+}
+
+func (b *commandBackend) Read() (string, error) {
+	if b.read == nil {
+		return "", ErrUnsupported
+	}
+
+	cmd := exec.Command(b.read[0], b.read[1:]...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", errors.MakeTypedError(errors.ErrClipboard, "Clipboard error: "+commandError(err, stderr.Bytes()), "clipboard")
+	}
+
+	return string(output), nil
+}
+
+func commandError(err error, output []byte) string {
+	if msg := strings.TrimSpace(string(output)); msg != "" {
+		return msg
+	}
+	return err.Error()
+}
+
+func newWaylandBackend(selection Selection) Backend {
+	write, read := []string{"wl-copy"}, []string{"wl-paste", "--no-newline"}
+	if selection == SELECTION_PRIMARY {
+		write, read = append(write, "--primary"), append(read, "--primary")
+	}
+
+	return &commandBackend{
+		name:      BACKEND_WAYLAND,
+		write:     write,
+		read:      read,
+		available: func() bool { return os.Getenv("WAYLAND_DISPLAY") != "" },
+	}
+}
+
+func newXclipBackend(selection Selection) Backend {
+	return &commandBackend{
+		name:      BACKEND_XCLIP,
+		write:     []string{"xclip", "-in", "-selection", selection},
+		read:      []string{"xclip", "-out", "-selection", selection},
+		available: func() bool { return os.Getenv("DISPLAY") != "" },
+	}
+}
+
+func newXselBackend(selection Selection) Backend {
+	return &commandBackend{
+		name:      BACKEND_XSEL,
+		write:     []string{"xsel", "--input", "--" + selection},
+		read:      []string{"xsel", "--output", "--" + selection},
+		available: func() bool { return os.Getenv("DISPLAY") != "" },
+	}
+}
+
+// The tmux buffer is reachable with the paste-buffer binding (prefix + ])
+// and, when tmux's set-clipboard option is on, tmux forwards it to the
+// terminal clipboard too.
+func newTmuxBackend() Backend {
+	return &commandBackend{
+		name:      BACKEND_TMUX,
+		write:     []string{"tmux", "load-buffer", "-w", "-"},
+		read:      []string{"tmux", "save-buffer", "-"},
+		available: func() bool { return os.Getenv("TMUX") != "" },
+	}
+}
+
+// Runs a user-defined command line through the shell, which receives the
+// content on stdin. Custom commands are write-only.
+func newCustomBackend(commandLine string) Backend {
+	shell := []string{"sh", "-c", commandLine}
+	if runtime.GOOS == "windows" {
+		shell = []string{"cmd", "/C", commandLine}
+	}
+
+	return &commandBackend{name: BACKEND_COMMAND, write: shell}
+}
+
+// Backend using the platform tools: pbcopy on macOS, clip.exe on Windows,
+// and the first available of wl-copy, xclip, xsel, termux elsewhere
+type systemBackend struct{}
+
+func (b *systemBackend) Name() string {
+	return BACKEND_SYSTEM
+}
+
+func (b *systemBackend) Available() bool {
+	return !clipboard.Unsupported
+}
+
+func (b *systemBackend) Write(msg string) error {
+	if err := clipboard.WriteAll(msg); err != nil {
+		return errors.MakeTypedError(errors.ErrClipboard, "Clipboard error: "+err.Error(), "clipboard")
+	}
+	return nil
+}
+
+func (b *systemBackend) Read() (string, error) {
+	msg, err := clipboard.ReadAll()
+	if err != nil {
+		return "", errors.MakeTypedError(errors.ErrClipboard, "Clipboard error: "+err.Error(), "clipboard")
+	}
+	return msg, nil
+}
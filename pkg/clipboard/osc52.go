@@ -0,0 +1,93 @@
+package clipboard
+
+import (
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"golang.org/x/term"
+)
+
+// Backend writing to the clipboard of the terminal emulator through the
+// OSC 52 escape sequence. It works over SSH, since the sequence travels
+// with the rest of the output up to the local terminal. It is write-only.
+type osc52Backend struct {
+	selection Selection
+	// Opens the terminal to write the sequence to
+	openTerminal func() (io.WriteCloser, error)
+}
+
+func (b *osc52Backend) Name() string {
+	return BACKEND_OSC52
+}
+
+func (b *osc52Backend) Available() bool {
+	t, err := b.openTerminal()
+	if err != nil {
+		return false
+	}
+	t.Close()
+	return true
+}
+
+func (b *osc52Backend) Write(msg string) error {
+	t, err := b.openTerminal()
+	if err != nil {
+		return errors.MakeTypedError(errors.ErrClipboard, "Clipboard error: "+err.Error(), "clipboard")
+	}
+	defer t.Close()
+
+	if _, err := io.WriteString(t, osc52Sequence(msg, b.selection, os.Getenv("TMUX") != "")); err != nil {
+		return errors.MakeTypedError(errors.ErrClipboard, "Clipboard error: "+err.Error(), "clipboard")
+	}
+
+	return nil
+}
+
+func (b *osc52Backend) Read() (string, error) {
+	// Terminals either do not support queries, or answer them on stdin,
+	// where they would mix with user input
+	return "", ErrUnsupported
+}
+
+// Returns the escape sequence setting the clipboard to msg. Inside tmux,
+// the sequence is wrapped in a passthrough one, doubling the escapes, so
+// that tmux forwards it to the outer terminal.
+func osc52Sequence(msg string, selection Selection, inTmux bool) string {
+	target := "c"
+	if selection == SELECTION_PRIMARY {
+		target = "p"
+	}
+
+	sequence := "\x1b]52;" + target + ";" + base64.StdEncoding.EncodeToString([]byte(msg)) + "\x07"
+
+	if inTmux {
+		return "\x1bPtmux;" + strings.ReplaceAll(sequence, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+
+	return sequence
+}
+
+// Opens the controlling terminal or, lacking one (e.g., in detached
+// processes), standard error if it is a terminal
+func openTerminal() (io.WriteCloser, error) {
+	if tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0); err == nil {
+		return tty, nil
+	}
+
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		return nopCloser{os.Stderr}, nil
+	}
+
+	return nil, errors.MakeTypedError(errors.ErrClipboard, "No terminal available.", "clipboard")
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
+
+func newOSC52Backend(selection Selection) Backend {
+	return &osc52Backend{selection: selection, openTerminal: openTerminal}
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/info"
+)
+
+const CONFIG_FILE = "config.toml"
+
+// Overrides the location of the configuration file
+const ENV_CONFIG = "KEYDEX_CONFIG"
+
+// Config mirrors the configuration file. Every setting is optional: zero
+// values mean "not set", and callers fall back to their defaults.
+//
+// Settings are read with the following precedence: flags, then
+// environment variables, then the configuration file.
+type Config struct {
+	// Path to the default database
+	Database string `toml:"database,omitempty"`
+	// Path to the key file for the default database
+	Key string `toml:"key,omitempty"`
+	// The default database is unlocked with the key file only
+	NoPassphrase bool `toml:"no_passphrase,omitempty"`
+	// Profile used when none is passed via --profile
+	Profile string `toml:"profile,omitempty"`
+	// Number of backups kept when saving a database
+	Backups int `toml:"backups,omitzero"`
+	// Name of the template used by new entries when none is chosen
+	Template string `toml:"template,omitempty"`
+
+	Clipboard Clipboard           `toml:"clipboard,omitempty"`
+	Generator Generator           `toml:"generator,omitempty"`
+	TUI       TUI                 `toml:"tui,omitempty"`
+	Agent     Agent               `toml:"agent,omitempty"`
+	URL       URL                 `toml:"url,omitempty"`
+	Profiles  map[string]Profile  `toml:"profiles,omitempty"`
+	Templates map[string]Template `toml:"templates,omitempty"`
+}
+
+// Profile is a named database, selected with --profile
+type Profile struct {
+	Database     string `toml:"database,omitempty"`
+	Key          string `toml:"key,omitempty"`
+	NoPassphrase bool   `toml:"no_passphrase,omitempty"`
+}
+
+type Clipboard struct {
+	// Time after which copied values are removed. Use a negative value
+	// to disable clearing
+	ClearAfter time.Duration `toml:"clear_after,omitzero"`
+	// Backend spec, like the --clipboard flag
+	Backend string `toml:"backend,omitempty"`
+}
+
+// Defaults for generated passwords
+type Generator struct {
+	Length int `toml:"length,omitzero"`
+	// Character classes. Pointers tell "not set" apart from false
+	Lowercase *bool `toml:"lowercase,omitempty"`
+	Uppercase *bool `toml:"uppercase,omitempty"`
+	Digits    *bool `toml:"digits,omitempty"`
+	Symbols   *bool `toml:"symbols,omitempty"`
+	// Named settings, used by the fields of templates. Unset values fall
+	// back to the settings above
+	Profiles map[string]Generator `toml:"profiles,omitempty"`
+}
+
+// Template describes the fields of new entries
+type Template struct {
+	Fields []TemplateField `toml:"fields,omitempty"`
+}
+
+type TemplateField struct {
+	Key       string `toml:"key"`
+	Value     string `toml:"value,omitempty"`
+	Protected bool   `toml:"protected,omitempty"`
+	// Name of the generator profile filling the value, if any
+	Generator string `toml:"generator,omitempty"`
+}
+
+type TUI struct {
+	// Name of the colour theme
+	Theme string `toml:"theme,omitempty"`
+	// Styles by element name, overriding the theme
+	Styles map[string]string `toml:"styles,omitempty"`
+	// Name of the keybindings preset
+	Keymap string `toml:"keymap,omitempty"`
+	// Key sequences by action name, overriding the preset
+	Keybindings map[string]string `toml:"keybindings,omitempty"`
+	// Time of inactivity after which the database is locked. Use a
+	// negative value to disable locking
+	LockAfter time.Duration `toml:"lock_after,omitzero"`
+	// What happens to unsaved changes on lock: "discard" or "keep"
+	LockPolicy string `toml:"lock_policy,omitempty"`
+}
+
+type Agent struct {
+	// Path of the agent socket
+	Socket string `toml:"socket,omitempty"`
+	// Time after which the agent forgets unlocked databases. Use a negative
+	// value to keep them until the agent is locked
+	TTL time.Duration `toml:"ttl,omitzero"`
+}
+
+type URL struct {
+	// Command line opening URLs, such as "firefox --private-window"
+	Opener string `toml:"opener,omitempty"`
+	// Runs cmd:// URLs as command lines
+	AllowCommands bool `toml:"allow_commands,omitempty"`
+	// Copies the password of entries whose URL is opened
+	CopyPassword bool `toml:"copy_password,omitempty"`
+}
+
+// Returns the path of the configuration file. It honours KEYDEX_CONFIG and
+// XDG_CONFIG_HOME, defaulting to ~/.config/keydex/config.toml.
+func Path() (string, error) {
+	if path := os.Getenv(ENV_CONFIG); path != "" {
+		return path, nil
+	}
+
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, info.NAME, CONFIG_FILE), nil
+	}
+
+	if runtime.GOOS == "windows" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return "", errors.MakeError("Cannot find configuration directory: "+err.Error(), "config")
+		}
+		return filepath.Join(dir, info.NAME, CONFIG_FILE), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.MakeError("Cannot read home directory: "+err.Error(), "config")
+	}
+
+	return filepath.Join(home, ".config", info.NAME, CONFIG_FILE), nil
+}
+
+// Reads the configuration file at path. A missing file is an empty
+// configuration.
+func Load(path string) (*Config, error) {
+	c := &Config{}
+
+	meta, err := toml.DecodeFile(path, c)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+
+	if err != nil {
+		return nil, errors.MakeError("Cannot read "+path+": "+err.Error(), "config")
+	}
+
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return nil, errors.MakeError("Unknown setting \""+undecoded[0].String()+"\" in "+path+".", "config")
+	}
+
+	return c, nil
+}
+
+// Writes the configuration to path, creating the parent directories
+func (c *Config) Save(path string) error {
+	buffer := &bytes.Buffer{}
+	if err := toml.NewEncoder(buffer).Encode(c); err != nil {
+		return errors.MakeError("Cannot write configuration: "+err.Error(), "config")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return errors.MakeError("Cannot write configuration: "+err.Error(), "config")
+	}
+
+	if err := os.WriteFile(path, buffer.Bytes(), 0o600); err != nil {
+		return errors.MakeError("Cannot write configuration: "+err.Error(), "config")
+	}
+
+	return nil
+}
+
+// Returns the named profile or, if name is empty, the default profile.
+// Falls back to the top-level settings when no profile is selected.
+func (c *Config) ResolveProfile(name string) (Profile, error) {
+	if name == "" {
+		name = c.Profile
+	}
+
+	if name == "" {
+		return Profile{Database: c.Database, Key: c.Key, NoPassphrase: c.NoPassphrase}, nil
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, errors.MakeError("Unknown profile \""+name+"\".", "config")
+	}
+
+	return profile, nil
+}
+
+// Returns the database and key file of the named profile, as ResolveProfile
+func (c *Config) ResolveDatabase(name string) (database, key string, err error) {
+	profile, err := c.ResolveProfile(name)
+	return profile.Database, profile.Key, err
+}
+
+var current = &Config{}
+
+// Returns the configuration loaded with Use
+func Current() *Config {
+	return current
+}
+
+// Makes c the configuration returned by Current
+func Use(c *Config) {
+	current = c
+}
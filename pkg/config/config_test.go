@@ -0,0 +1,247 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPath(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want string
+	}{
+		{"uses " + ENV_CONFIG, map[string]string{ENV_CONFIG: "/custom.toml", "XDG_CONFIG_HOME": "/xdg"}, "/custom.toml"},
+		{"uses XDG_CONFIG_HOME", map[string]string{ENV_CONFIG: "", "XDG_CONFIG_HOME": "/xdg"}, filepath.Join("/xdg", "keydex", CONFIG_FILE)},
+		{"uses home", map[string]string{ENV_CONFIG: "", "XDG_CONFIG_HOME": "", "HOME": "/home/user"}, filepath.Join("/home/user", ".config", "keydex", CONFIG_FILE)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			got, err := Path()
+			if err != nil {
+				t.Fatalf("Path() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Path() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	write := func(content string) string {
+		path := filepath.Join(t.TempDir(), CONFIG_FILE)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("returns empty configuration for missing file", func(t *testing.T) {
+		c, err := Load(filepath.Join(t.TempDir(), "missing.toml"))
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if c.Database != "" || c.Profiles != nil {
+			t.Errorf("Load() = %v, want empty configuration", c)
+		}
+	})
+
+	t.Run("reads settings", func(t *testing.T) {
+		c, err := Load(write(`
+database = "vault.kdbx"
+backups = 3
+
+[clipboard]
+clear_after = "10s"
+
+[profiles.work]
+database = "work.kdbx"
+key = "work.key"
+
+[[templates.server.fields]]
+key = "Host"
+value = "localhost"
+
+[[templates.server.fields]]
+key = "Password"
+protected = true
+generator = "pin"
+`))
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if c.Database != "vault.kdbx" || c.Backups != 3 {
+			t.Errorf("Load() = %v, unexpected top-level settings", c)
+		}
+		if c.Clipboard.ClearAfter != 10*time.Second {
+			t.Errorf("Load() clear_after = %v, want %v", c.Clipboard.ClearAfter, 10*time.Second)
+		}
+		if c.Profiles["work"].Key != "work.key" {
+			t.Errorf("Load() profiles = %v, unexpected work profile", c.Profiles)
+		}
+		if fields := c.Templates["server"].Fields; len(fields) != 2 || !fields[1].Protected || fields[1].Generator != "pin" {
+			t.Errorf("Load() templates = %v, unexpected server template", c.Templates)
+		}
+	})
+
+	t.Run("fails on unknown settings", func(t *testing.T) {
+		if _, err := Load(write(`databse = "typo.kdbx"`)); err == nil {
+			t.Error("Load() expected error for unknown setting")
+		}
+	})
+
+	t.Run("fails on invalid files", func(t *testing.T) {
+		if _, err := Load(write(`database = `)); err == nil {
+			t.Error("Load() expected error for invalid file")
+		}
+	})
+}
+
+func TestConfig_Save(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", CONFIG_FILE)
+
+	c := &Config{Database: "vault.kdbx", Clipboard: Clipboard{ClearAfter: time.Minute}}
+	if err := c.Save(path); err != nil {
+		t.Fatalf("Config.Save() error = %v", err)
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got.Database != c.Database || got.Clipboard.ClearAfter != c.Clipboard.ClearAfter {
+		t.Errorf("Load() = %v, want %v", got, c)
+	}
+}
+
+func TestConfig_ResolveDatabase(t *testing.T) {
+	c := &Config{
+		Database: "default.kdbx",
+		Key:      "default.key",
+		Profiles: map[string]Profile{
+			"work": {Database: "work.kdbx", Key: "work.key"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		profile      string
+		defaultName  string
+		wantDatabase string
+		wantKey      string
+		wantErr      bool
+	}{
+		{"uses top-level settings", "", "", "default.kdbx", "default.key", false},
+		{"uses named profile", "work", "", "work.kdbx", "work.key", false},
+		{"uses default profile", "", "work", "work.kdbx", "work.key", false},
+		{"fails on unknown profile", "home", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c.Profile = tt.defaultName
+
+			database, key, err := c.ResolveDatabase(tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Config.ResolveDatabase() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if database != tt.wantDatabase || key != tt.wantKey {
+				t.Errorf("Config.ResolveDatabase() = %v, %v, want %v, %v", database, key, tt.wantDatabase, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestConfig_ResolveProfile(t *testing.T) {
+	c := &Config{
+		Database:     "default.kdbx",
+		NoPassphrase: true,
+		Profiles: map[string]Profile{
+			"work": {Database: "work.kdbx", Key: "work.key"},
+		},
+	}
+
+	if profile, _ := c.ResolveProfile(""); !profile.NoPassphrase || profile.Database != "default.kdbx" {
+		t.Errorf("Config.ResolveProfile() = %v, want top-level settings", profile)
+	}
+
+	if profile, _ := c.ResolveProfile("work"); profile.NoPassphrase {
+		t.Errorf("Config.ResolveProfile() = %v, want passphrase for profile", profile)
+	}
+}
+
+func TestConfig_GetSet(t *testing.T) {
+	tests := []struct {
+		key     string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"database", "vault.kdbx", "vault.kdbx", false},
+		{"backups", "3", "3", false},
+		{"backups", "many", "", true},
+		{"clipboard.clear_after", "1m", "1m0s", false},
+		{"clipboard.clear_after", "soon", "", true},
+		{"generator.symbols", "false", "false", false},
+		{"generator.symbols", "", "", false},
+		{"generator.profiles.pin.length", "6", "6", false},
+		{"template", "Wi-Fi", "Wi-Fi", false},
+		{"no_passphrase", "true", "true", false},
+		{"url.allow_commands", "yes", "", true},
+		{"templates.server.fields", "Host", "", true},
+		{"profiles.work.database", "work.kdbx", "work.kdbx", false},
+		{"tui.keybindings.quit", "Ctrl+Q", "Ctrl+Q", false},
+		{"clipboard", "osc52", "", true},
+		{"unknown", "value", "", true},
+		{"database.nested", "value", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.value, func(t *testing.T) {
+			c := &Config{}
+
+			err := c.Set(tt.key, tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Config.Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			got, err := c.Get(tt.key)
+			if err != nil {
+				t.Fatalf("Config.Get() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Config.Get() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unsetting removes empty profiles", func(t *testing.T) {
+		c := &Config{}
+		_ = c.Set("profiles.work.database", "work.kdbx")
+		_ = c.Set("profiles.work.database", "")
+
+		if _, ok := c.Profiles["work"]; ok {
+			t.Errorf("Config.Set() left empty profile: %v", c.Profiles)
+		}
+	})
+
+	t.Run("returns sections", func(t *testing.T) {
+		c := &Config{Clipboard: Clipboard{Backend: "osc52"}}
+
+		got, err := c.Get("clipboard")
+		if err != nil {
+			t.Fatalf("Config.Get() error = %v", err)
+		}
+		if got != `backend = "osc52"` {
+			t.Errorf("Config.Get() = %v, want section", got)
+		}
+	})
+}
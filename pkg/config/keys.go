@@ -0,0 +1,174 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+const KEY_SEPARATOR = "."
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Returns the value of the setting at key, a dotted path such as
+// "clipboard.clear_after" or "profiles.work.database". Sections are
+// returned in TOML. Unset values are returned as empty strings.
+func (c *Config) Get(key string) (string, error) {
+	value, err := lookup(reflect.ValueOf(c).Elem(), key, strings.Split(key, KEY_SEPARATOR))
+	if err != nil {
+		return "", err
+	}
+
+	return format(value)
+}
+
+// Sets the setting at key (see Get) to value. An empty value unsets it.
+func (c *Config) Set(key, value string) error {
+	return assign(reflect.ValueOf(c).Elem(), key, strings.Split(key, KEY_SEPARATOR), value)
+}
+
+func lookup(v reflect.Value, key string, path []string) (reflect.Value, error) {
+	if len(path) == 0 {
+		return v, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := fieldByTag(v, path[0])
+		if !ok {
+			return reflect.Value{}, unknownKey(key)
+		}
+		return lookup(field, key, path[1:])
+	case reflect.Map:
+		item := v.MapIndex(reflect.ValueOf(path[0]))
+		if !item.IsValid() {
+			item = reflect.New(v.Type().Elem()).Elem()
+		}
+		return lookup(item, key, path[1:])
+	}
+
+	return reflect.Value{}, unknownKey(key)
+}
+
+func assign(v reflect.Value, key string, path []string, value string) error {
+	if len(path) == 0 {
+		return parse(v, key, value)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := fieldByTag(v, path[0])
+		if !ok {
+			return unknownKey(key)
+		}
+		return assign(field, key, path[1:], value)
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+
+		// Map items are not addressable: they are copied, updated, and put back
+		name := reflect.ValueOf(path[0])
+		item := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(name); existing.IsValid() {
+			item.Set(existing)
+		}
+
+		if err := assign(item, key, path[1:], value); err != nil {
+			return err
+		}
+
+		if item.IsZero() {
+			v.SetMapIndex(name, reflect.Value{})
+		} else {
+			v.SetMapIndex(name, item)
+		}
+		return nil
+	}
+
+	return unknownKey(key)
+}
+
+func parse(v reflect.Value, key, value string) error {
+	if value == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return errors.MakeError(`Invalid duration "`+value+`" for "`+key+`".`, "config")
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+	case v.Kind() == reflect.Int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.MakeError(`Invalid number "`+value+`" for "`+key+`".`, "config")
+		}
+		v.SetInt(int64(i))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.MakeError(`Invalid boolean "`+value+`" for "`+key+`".`, "config")
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.MakeError(`Invalid boolean "`+value+`" for "`+key+`".`, "config")
+		}
+		v.Set(reflect.ValueOf(&b))
+	default:
+		return errors.MakeError(`"`+key+`" is a section. Set its settings one by one.`, "config")
+	}
+
+	return nil
+}
+
+func format(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	} else if v.IsZero() {
+		return "", nil
+	}
+
+	switch {
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String(), nil
+	case v.Kind() == reflect.String:
+		return v.String(), nil
+	case v.Kind() == reflect.Int:
+		return strconv.Itoa(int(v.Int())), nil
+	case v.Kind() == reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	}
+
+	section, err := toml.Marshal(v.Interface())
+	if err != nil {
+		return "", errors.MakeError("Cannot read configuration: "+err.Error(), "config")
+	}
+
+	return strings.TrimSpace(string(section)), nil
+}
+
+func fieldByTag(v reflect.Value, name string) (reflect.Value, bool) {
+	for i := 0; i < v.NumField(); i++ {
+		tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("toml"), ",")
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func unknownKey(key string) error {
+	return errors.MakeError(`Unknown setting "`+key+`".`, "config")
+}
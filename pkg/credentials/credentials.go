@@ -1,68 +1,104 @@
 package credentials
 
 import (
-	"crypto/rand"
-	"crypto/sha256"
 	"fmt"
 	"os"
 
 	"github.com/shikaan/keydex/pkg/cli"
 	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/secure"
 )
 
-// Retrieves a locally stored passphrase, if any, otherwise
-// prompts the user to insert one
-func GetPassphrase(database, passphrase string) string {
-	if passphrase != "" {
-		return passphrase
-	}
+// Where the passphrase of a database is read from, when it is not typed.
+// Sources are tried in the order of the fields, and the passphrase is
+// prompted for when none is set.
+type PassphraseSource struct {
+	// File descriptor inherited from the parent process, like gpg's
+	// --passphrase-fd. Nil when not set
+	FD *int
+	// File holding the passphrase, readable only by its owner
+	File string
+	// Command line printing the passphrase, such as "secret-tool lookup ..."
+	Command string
+	// The passphrase itself, from the environment
+	Passphrase string
+	// The database is unlocked with the key file only, and no passphrase
+	// is read
+	None bool
+}
+
+// Times a prompted passphrase is asked for, while it is wrong
+const PASSPHRASE_ATTEMPTS = 3
 
-	return cli.ReadSecret(fmt.Sprintf("Passphrase for \"%s\": ", database))
+// Returns true if the passphrase is prompted for
+func (s PassphraseSource) IsInteractive() bool {
+	return !s.None && s.FD == nil && s.File == "" && s.Command == "" && s.Passphrase == ""
 }
 
-func MakePassphrase(database string) (string, error) {
-	passphrase := cli.ReadSecret(fmt.Sprintf("Create a passphrase for \"%s\": ", database))
-	repeated := cli.ReadSecret("Repeat: ")
+// Calls unlock with the passphrase from source, which is destroyed
+// afterwards. Prompted passphrases are asked again while unlock fails with
+// wrong credentials, up to PASSPHRASE_ATTEMPTS times
+func WithPassphrase(database string, source PassphraseSource, unlock func(passphrase *secure.Buffer) error) error {
+	attempts := 1
+	if source.IsInteractive() {
+		attempts = PASSPHRASE_ATTEMPTS
+	}
+
+	for attempt := 1; ; attempt++ {
+		passphrase, err := GetPassphrase(database, source)
+		if err != nil {
+			return err
+		}
 
-	if passphrase != repeated {
-		return "", errors.MakeError("Passphrase mismatch.", "credentials")
+		err = unlock(passphrase)
+		passphrase.Destroy()
+		if attempt == attempts || !errors.Is(err, errors.ErrWrongCredentials) {
+			return err
+		}
+
+		fmt.Fprintln(os.Stderr, "Wrong passphrase or key file. Try again.")
 	}
+}
 
-	if passphrase == "" {
-		return "", errors.MakeError("Passphrase cannot be empty.", "credentials")
+// Retrieves the passphrase from source if any is set, otherwise prompts
+// the user to insert one. Destroy it once used.
+func GetPassphrase(database string, source PassphraseSource) (*secure.Buffer, error) {
+	switch {
+	case source.None:
+		return secure.NewBuffer(0), nil
+	case source.FD != nil:
+		return readPassphraseFromFD(*source.FD)
+	case source.File != "":
+		return readPassphraseFromFile(source.File)
+	case source.Command != "":
+		return readPassphraseFromCommand(source.Command)
+	case source.Passphrase != "":
+		return secure.FromString(source.Passphrase), nil
 	}
 
-	return passphrase, nil
+	return cli.ReadSecret(fmt.Sprintf("Passphrase for \"%s\": ", database)), nil
 }
 
-func CreateXMLKeyFileV2(path string) error {
-	if _, err := os.Stat(path); err == nil {
-		return errors.MakeError("Key file at "+path+" already exists.", "credentials")
-	}
+// Prompts for a new passphrase twice. Destroy it once used.
+func MakePassphrase(database string) (*secure.Buffer, error) {
+	passphrase := cli.ReadSecret(fmt.Sprintf("Create a passphrase for \"%s\": ", database))
+	repeated := cli.ReadSecret("Repeat: ")
+	defer repeated.Destroy()
 
-	key := make([]byte, 32)
-	if _, err := rand.Read(key); err != nil {
-		return errors.MakeError("Cannot generate key: "+err.Error(), "credentials")
+	if !passphrase.Equal(repeated) {
+		passphrase.Destroy()
+		return nil, errors.MakeError("Passphrase mismatch.", "credentials")
 	}
 
-	hash := sha256.Sum256(key)
-	hashPrefix := fmt.Sprintf("%X", hash[:4])
-	hexKey := fmt.Sprintf("%X", key)
-
-	data := []byte(
-		"<KeyFile>\n" +
-			"  <Meta>\n" +
-			"    <Version>2.0</Version>\n" +
-			"  </Meta>\n" +
-			"  <Key>\n" +
-			"    <Data Hash=\"" + hashPrefix + "\">" + hexKey + "</Data>\n" +
-			"  </Key>\n" +
-			"</KeyFile>\n",
-	)
-
-	if err := os.WriteFile(path, data, 0o600); err != nil {
-		return errors.MakeError("Cannot generate key: "+err.Error(), "credentials")
+	if passphrase.Len() == 0 {
+		passphrase.Destroy()
+		return nil, errors.MakeError("Passphrase cannot be empty.", "credentials")
 	}
 
-	return nil
+	return passphrase, nil
+}
+
+// Writes a new random key file at path, in the XML v2 format
+func CreateXMLKeyFileV2(path string) error {
+	return CreateKeyFile(path, KEY_FILE_XML_V2)
 }
@@ -1,24 +1,144 @@
 package credentials
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/shikaan/keydex/pkg/cli"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/secure"
+)
 
 func TestGetPassphrase(t *testing.T) {
 	type args struct {
-		database   string
-		passphrase string
+		database string
+		source   PassphraseSource
 	}
 	tests := []struct {
 		name string
 		args args
 		want string
 	}{
-		{"returns passed passphrase", args{database: "", passphrase: "phrase"}, "phrase"},
+		{"returns passed passphrase", args{database: "", source: PassphraseSource{Passphrase: "phrase"}}, "phrase"},
+		{"prefers commands to passed passphrases", args{database: "", source: PassphraseSource{Command: "echo command", Passphrase: "phrase"}}, "command"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := GetPassphrase(tt.args.database, tt.args.passphrase); got != tt.want {
-				t.Errorf("GetPassphrase() = %v, want %v", got, tt.want)
+			if got, err := GetPassphrase(tt.args.database, tt.args.source); err != nil || string(got.Bytes()) != tt.want {
+				t.Errorf("GetPassphrase() = %q, %v, want %q", got.Bytes(), err, tt.want)
 			}
 		})
 	}
 }
+
+func TestGetPassphrase_file(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "passphrase")
+	if err := os.WriteFile(path, []byte("from file\nignored\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := GetPassphrase("", PassphraseSource{File: path}); err != nil || string(got.Bytes()) != "from file" {
+		t.Errorf("GetPassphrase() = %q, %v, want %q", got.Bytes(), err, "from file")
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetPassphrase("", PassphraseSource{File: path}); err == nil {
+		t.Errorf("GetPassphrase() accepted a file readable by other users")
+	}
+}
+
+func TestGetPassphrase_fd(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("from fd\r\n")
+	w.Close()
+
+	fd := int(r.Fd())
+	if got, err := GetPassphrase("", PassphraseSource{FD: &fd}); err != nil || string(got.Bytes()) != "from fd" {
+		t.Errorf("GetPassphrase() = %q, %v, want %q", got.Bytes(), err, "from fd")
+	}
+}
+
+func TestGetPassphrase_command(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	if _, err := GetPassphrase("", PassphraseSource{Command: "exit 1"}); err == nil {
+		t.Errorf("GetPassphrase() accepted a failing command")
+	}
+
+	if _, err := GetPassphrase("", PassphraseSource{Command: "true"}); err == nil {
+		t.Errorf("GetPassphrase() accepted an empty passphrase")
+	}
+}
+
+func TestWithPassphrase(t *testing.T) {
+	originalReadSecret := cli.ReadSecret
+	defer func() {
+		cli.ReadSecret = originalReadSecret
+	}()
+
+	wrong := errors.MakeTypedError(errors.ErrWrongCredentials, "Wrong password?", "test")
+	unlock := func(calls *int) func(*secure.Buffer) error {
+		return func(passphrase *secure.Buffer) error {
+			*calls++
+			if string(passphrase.Bytes()) != "right" {
+				return wrong
+			}
+			return nil
+		}
+	}
+
+	t.Run("prompts again while the passphrase is wrong", func(t *testing.T) {
+		prompts := []string{"wrong", "right"}
+		cli.ReadSecret = func(string) *secure.Buffer {
+			passphrase := prompts[0]
+			prompts = prompts[1:]
+			return secure.FromString(passphrase)
+		}
+
+		calls := 0
+		if err := WithPassphrase("", PassphraseSource{}, unlock(&calls)); err != nil || calls != 2 {
+			t.Errorf("WithPassphrase() = %v after %d attempts, want success after 2", err, calls)
+		}
+	})
+
+	t.Run("gives up after PASSPHRASE_ATTEMPTS", func(t *testing.T) {
+		cli.ReadSecret = func(string) *secure.Buffer { return secure.FromString("wrong") }
+
+		calls := 0
+		if err := WithPassphrase("", PassphraseSource{}, unlock(&calls)); err != wrong || calls != PASSPHRASE_ATTEMPTS {
+			t.Errorf("WithPassphrase() = %v after %d attempts, want %v after %d", err, calls, wrong, PASSPHRASE_ATTEMPTS)
+		}
+	})
+
+	t.Run("destroys the passphrase once used", func(t *testing.T) {
+		var used *secure.Buffer
+		WithPassphrase("", PassphraseSource{Passphrase: "right"}, func(passphrase *secure.Buffer) error {
+			used = passphrase
+			return nil
+		})
+
+		if used.Bytes() != nil {
+			t.Errorf("WithPassphrase() kept the passphrase %q", used.Bytes())
+		}
+	})
+
+	t.Run("does not retry passphrases from other sources", func(t *testing.T) {
+		calls := 0
+		if err := WithPassphrase("", PassphraseSource{Passphrase: "wrong"}, unlock(&calls)); err != wrong || calls != 1 {
+			t.Errorf("WithPassphrase() = %v after %d attempts, want %v after 1", err, calls, wrong)
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+// Format of a key file, as written by KeePass and KeePassXC
+type KeyFileFormat = string
+
+const (
+	// XML with a base64 key, written by KeePass 2.0 to 2.46
+	KEY_FILE_XML_V1 KeyFileFormat = "xml-v1"
+	// XML with an hexadecimal key and its hash, written by KeePass 2.47+ and KeePassXC
+	KEY_FILE_XML_V2 KeyFileFormat = "xml-v2"
+	// Exactly 32 bytes, used as the key
+	KEY_FILE_BINARY KeyFileFormat = "binary"
+	// Exactly 64 hexadecimal characters, decoded into the key
+	KEY_FILE_HEX KeyFileFormat = "hex"
+	// Any other file, whose SHA-256 hash is the key
+	KEY_FILE_HASHED KeyFileFormat = "hashed"
+)
+
+// Formats that can be created
+var KEY_FILE_FORMATS = []KeyFileFormat{KEY_FILE_XML_V2, KEY_FILE_HEX, KEY_FILE_BINARY}
+
+const KEY_SIZE = 32
+
+// Length of the hash prefix stored in XML v2 key files
+const keyHashSize = 4
+
+// Returned when the key of an XML v2 key file does not match its hash
+var ErrKeyHashMismatch = errors.MakeTypedError(errors.ErrCorrupt, "Key hash mismatch. The key file is damaged or was edited.", "credentials")
+
+type KeyFile struct {
+	Format KeyFileFormat
+	// The key composed with the passphrase
+	Key []byte
+}
+
+type xmlKeyFile struct {
+	XMLName xml.Name `xml:"KeyFile"`
+	Meta    struct {
+		Version string `xml:"Version"`
+	} `xml:"Meta"`
+	Key struct {
+		Data struct {
+			Hash  string `xml:"Hash,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"Data"`
+	} `xml:"Key"`
+}
+
+// Returns a short fingerprint of the key, like the hash of XML v2 key files
+func (k *KeyFile) Hash() string {
+	hash := sha256.Sum256(k.Key)
+	return fmt.Sprintf("%X", hash[:keyHashSize])
+}
+
+// Reads the key file at path
+func ReadKeyFile(path string) (*KeyFile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, errors.MakeTypedError(errors.ErrNotFound, "Cannot read key file: "+err.Error(), "credentials")
+	}
+
+	if err != nil {
+		return nil, errors.MakeError("Cannot read key file: "+err.Error(), "credentials")
+	}
+
+	return ParseKeyFile(data)
+}
+
+// Returns the key in data, detecting its format the way KeePass does: XML
+// key files first, then 32 bytes keys, then hexadecimal keys, and the hash
+// of the content for any other file
+func ParseKeyFile(data []byte) (*KeyFile, error) {
+	if keyFile, err := parseXMLKeyFile(data); keyFile != nil || err != nil {
+		return keyFile, err
+	}
+
+	if len(data) == KEY_SIZE {
+		return &KeyFile{Format: KEY_FILE_BINARY, Key: bytes.Clone(data)}, nil
+	}
+
+	if len(data) == 2*KEY_SIZE {
+		if key, err := hex.DecodeString(string(data)); err == nil {
+			return &KeyFile{Format: KEY_FILE_HEX, Key: key}, nil
+		}
+	}
+
+	hash := sha256.Sum256(data)
+	return &KeyFile{Format: KEY_FILE_HASHED, Key: hash[:]}, nil
+}
+
+// Returns nil without errors if data is not an XML key file
+func parseXMLKeyFile(data []byte) (*KeyFile, error) {
+	parsed := xmlKeyFile{}
+	if err := xml.Unmarshal(data, &parsed); err != nil {
+		return nil, nil
+	}
+
+	value := strings.Join(strings.Fields(parsed.Key.Data.Value), "")
+
+	switch parsed.Meta.Version {
+	case "1.0", "1.00":
+		key, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, errors.MakeTypedError(errors.ErrCorrupt, "Malformed key file data: "+err.Error(), "credentials")
+		}
+
+		return &KeyFile{Format: KEY_FILE_XML_V1, Key: key}, nil
+	case "2.0":
+		key, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, errors.MakeTypedError(errors.ErrCorrupt, "Malformed key file data: "+err.Error(), "credentials")
+		}
+
+		keyFile := &KeyFile{Format: KEY_FILE_XML_V2, Key: key}
+
+		// The hash is optional, and guards against typos in printed keys
+		if hash := parsed.Key.Data.Hash; hash != "" && !strings.EqualFold(hash, keyFile.Hash()) {
+			return nil, ErrKeyHashMismatch
+		}
+
+		return keyFile, nil
+	}
+
+	return nil, errors.MakeError(`Unsupported XML key file version "`+parsed.Meta.Version+`".`, "credentials")
+}
+
+// Writes a new random key file at path, in the given format
+func CreateKeyFile(path string, format KeyFileFormat) error {
+	if !slices.Contains(KEY_FILE_FORMATS, format) {
+		return errors.MakeError(`Cannot create key files in format "`+format+`". Use one of: `+strings.Join(KEY_FILE_FORMATS, ", ")+".", "credentials")
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		return errors.MakeError("Key file at "+path+" already exists.", "credentials")
+	}
+
+	key := make([]byte, KEY_SIZE)
+	if _, err := rand.Read(key); err != nil {
+		return errors.MakeError("Cannot generate key: "+err.Error(), "credentials")
+	}
+
+	var data []byte
+	switch format {
+	case KEY_FILE_BINARY:
+		data = key
+	case KEY_FILE_HEX:
+		data = []byte(fmt.Sprintf("%X", key))
+	case KEY_FILE_XML_V2:
+		data = []byte(
+			"<KeyFile>\n" +
+				"  <Meta>\n" +
+				"    <Version>2.0</Version>\n" +
+				"  </Meta>\n" +
+				"  <Key>\n" +
+				"    <Data Hash=\"" + (&KeyFile{Key: key}).Hash() + "\">" + fmt.Sprintf("%X", key) + "</Data>\n" +
+				"  </Key>\n" +
+				"</KeyFile>\n",
+		)
+	}
+
+	// O_EXCL, so that a file created in the meantime is not overwritten
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return errors.MakeError("Cannot generate key: "+err.Error(), "credentials")
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return errors.MakeError("Cannot generate key: "+err.Error(), "credentials")
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testKey = bytes.Repeat([]byte{0xAB}, KEY_SIZE)
+
+func xmlV2(key []byte, hash string) []byte {
+	return []byte(`<?xml version="1.0" encoding="utf-8"?>
+<KeyFile>
+	<Meta>
+		<Version>2.0</Version>
+	</Meta>
+	<Key>
+		<Data Hash="` + hash + `">
+			` + fmt.Sprintf("%X", key[:16]) + `
+			` + fmt.Sprintf("%X", key[16:]) + `
+		</Data>
+	</Key>
+</KeyFile>`)
+}
+
+func TestParseKeyFile(t *testing.T) {
+	hash := (&KeyFile{Key: testKey}).Hash()
+	arbitrary := []byte("any file can be a key file")
+	arbitraryHash := sha256.Sum256(arbitrary)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantFormat KeyFileFormat
+		wantKey    []byte
+		wantErr    bool
+	}{
+		{"reads XML v1", []byte(`<KeyFile><Meta><Version>1.00</Version></Meta><Key><Data>` + base64.StdEncoding.EncodeToString(testKey) + `</Data></Key></KeyFile>`), KEY_FILE_XML_V1, testKey, false},
+		{"reads XML v2", xmlV2(testKey, hash), KEY_FILE_XML_V2, testKey, false},
+		{"reads XML v2 with lowercase hash", xmlV2(testKey, strings.ToLower(hash)), KEY_FILE_XML_V2, testKey, false},
+		{"reads XML v2 without hash", xmlV2(testKey, ""), KEY_FILE_XML_V2, testKey, false},
+		{"rejects XML v2 with wrong hash", xmlV2(testKey, "00000000"), "", nil, true},
+		{"rejects unsupported XML versions", []byte(`<KeyFile><Meta><Version>3.0</Version></Meta></KeyFile>`), "", nil, true},
+		{"reads binary keys", testKey, KEY_FILE_BINARY, testKey, false},
+		{"reads hex keys", []byte(fmt.Sprintf("%x", testKey)), KEY_FILE_HEX, testKey, false},
+		{"hashes other files", arbitrary, KEY_FILE_HASHED, arbitraryHash[:], false},
+		{"hashes other XML files", []byte(`<Other/>`), KEY_FILE_HASHED, nil, false},
+		{"hashes 64 characters that are not hex", bytes.Repeat([]byte("z"), 2*KEY_SIZE), KEY_FILE_HASHED, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKeyFile(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKeyFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got.Format != tt.wantFormat {
+				t.Errorf("ParseKeyFile() format = %v, want %v", got.Format, tt.wantFormat)
+			}
+			if tt.wantKey != nil && !bytes.Equal(got.Key, tt.wantKey) {
+				t.Errorf("ParseKeyFile() key = %X, want %X", got.Key, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestParseKeyFile_hashMismatch(t *testing.T) {
+	if _, err := ParseKeyFile(xmlV2(testKey, "00000000")); err != ErrKeyHashMismatch {
+		t.Errorf("ParseKeyFile() error = %v, want %v", err, ErrKeyHashMismatch)
+	}
+}
+
+func TestCreateKeyFile(t *testing.T) {
+	for _, format := range KEY_FILE_FORMATS {
+		t.Run(format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "key")
+
+			if err := CreateKeyFile(path, format); err != nil {
+				t.Fatalf("CreateKeyFile() error = %v", err)
+			}
+
+			keyFile, err := ReadKeyFile(path)
+			if err != nil {
+				t.Fatalf("ReadKeyFile() error = %v", err)
+			}
+			if keyFile.Format != format || len(keyFile.Key) != KEY_SIZE {
+				t.Errorf("ReadKeyFile() = %v, %d bytes, want %v", keyFile.Format, len(keyFile.Key), format)
+			}
+
+			if stat, _ := os.Stat(path); stat.Mode().Perm() != 0o600 {
+				t.Errorf("expected mode 0600, got %v", stat.Mode().Perm())
+			}
+
+			if err := CreateKeyFile(path, format); err == nil {
+				t.Errorf("CreateKeyFile() overwrote an existing file")
+			}
+		})
+	}
+
+	if err := CreateKeyFile(filepath.Join(t.TempDir(), "key"), KEY_FILE_HASHED); err == nil {
+		t.Errorf("CreateKeyFile() accepted an unsupported format")
+	}
+}
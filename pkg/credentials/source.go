@@ -0,0 +1,127 @@
+package credentials
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/secure"
+)
+
+// Longest passphrase read from file descriptors, files, and commands
+const MAX_PASSPHRASE_LENGTH = 4096
+
+func readPassphraseFromFD(fd int) (*secure.Buffer, error) {
+	file := os.NewFile(uintptr(fd), "fd"+strconv.Itoa(fd))
+	if file == nil {
+		return nil, errors.MakeError("Invalid passphrase file descriptor "+strconv.Itoa(fd)+".", "credentials")
+	}
+	// Closing stdin would break later prompts
+	if fd > 2 {
+		defer file.Close()
+	}
+
+	passphrase, err := readFirstLine(file)
+	if err != nil {
+		return nil, errors.MakeError("Cannot read passphrase from file descriptor "+strconv.Itoa(fd)+": "+err.Error(), "credentials")
+	}
+
+	return passphrase, nil
+}
+
+func readPassphraseFromFile(path string) (*secure.Buffer, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.MakeError("Cannot read passphrase file: "+err.Error(), "credentials")
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, errors.MakeError("Cannot read passphrase file: "+err.Error(), "credentials")
+	}
+
+	// Windows permissions do not map onto mode bits
+	if runtime.GOOS != "windows" && stat.Mode().Perm()&0o077 != 0 {
+		return nil, errors.MakeError(fmt.Sprintf("Passphrase file %s is accessible by other users (mode %#o). Run 'chmod 600 %s'.", path, stat.Mode().Perm(), path), "credentials")
+	}
+
+	passphrase, err := readFirstLine(file)
+	if err != nil {
+		return nil, errors.MakeError("Cannot read passphrase file: "+err.Error(), "credentials")
+	}
+
+	return passphrase, nil
+}
+
+// Runs commandLine through the shell. The command can prompt on the
+// terminal, and prints the passphrase on stdout.
+func readPassphraseFromCommand(commandLine string) (*secure.Buffer, error) {
+	shell := []string{"sh", "-c", commandLine}
+	if runtime.GOOS == "windows" {
+		shell = []string{"cmd", "/C", commandLine}
+	}
+
+	var stdout bytes.Buffer
+	command := exec.Command(shell[0], shell[1:]...)
+	command.Stdin = os.Stdin
+	command.Stdout = &stdout
+	command.Stderr = os.Stderr
+
+	// The output buffer might have been reallocated, and earlier copies are
+	// left to the garbage collector
+	defer clear(stdout.Bytes())
+
+	if err := command.Run(); err != nil {
+		return nil, errors.MakeError("Passphrase command failed: "+err.Error(), "credentials")
+	}
+
+	passphrase, err := readFirstLine(&stdout)
+	if err != nil {
+		return nil, errors.MakeError("Cannot read passphrase command output: "+err.Error(), "credentials")
+	}
+
+	return passphrase, nil
+}
+
+// Passphrases end at the first line break, so that files and commands can
+// end with one. Bytes are read one at a time into locked memory, so that no
+// copy is left behind.
+func readFirstLine(r io.Reader) (*secure.Buffer, error) {
+	buffer := secure.NewBuffer(MAX_PASSPHRASE_LENGTH)
+	defer buffer.Destroy()
+
+	data := buffer.Bytes()
+	length := 0
+
+	for {
+		if length == len(data) {
+			return nil, errors.MakeError(fmt.Sprintf("Passphrase is longer than %d bytes.", MAX_PASSPHRASE_LENGTH), "credentials")
+		}
+
+		n, err := r.Read(data[length : length+1])
+		if n == 1 && data[length] == '\n' {
+			break
+		}
+		length += n
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	line := bytes.TrimSuffix(data[:length], []byte("\r"))
+	if len(line) == 0 {
+		return nil, errors.MakeError("Passphrase is empty.", "credentials")
+	}
+
+	return secure.FromBytes(line), nil
+}
@@ -1,11 +1,98 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/shikaan/keydex/pkg/info"
 )
 
+// Exit code of errors without a kind
+const EXIT_FAILURE = 1
+
+// Kind of an error, telling scripts what went wrong through the exit code
+type Kind struct {
+	Name     string
+	ExitCode int
+}
+
+func (k *Kind) Error() string {
+	return k.Name
+}
+
+var (
+	// The passphrase or the key file do not unlock the database
+	ErrWrongCredentials = &Kind{Name: "wrong-credentials", ExitCode: 2}
+	// The entry, field, or file does not exist
+	ErrNotFound = &Kind{Name: "not-found", ExitCode: 3}
+	// The reference matches more than one entry
+	ErrAmbiguous = &Kind{Name: "ambiguous", ExitCode: 4}
+	// The database or the key file cannot be read
+	ErrCorrupt = &Kind{Name: "corrupt", ExitCode: 5}
+	// The clipboard cannot be read or written
+	ErrClipboard = &Kind{Name: "clipboard", ExitCode: 6}
+)
+
+var KINDS = []*Kind{ErrWrongCredentials, ErrNotFound, ErrAmbiguous, ErrCorrupt, ErrClipboard}
+
+type typedError struct {
+	kind    *Kind
+	message string
+}
+
+func (e *typedError) Error() string {
+	return e.message
+}
+
+func (e *typedError) Unwrap() error {
+	return e.kind
+}
+
 func MakeError(msg string, namespace string) error {
 	return fmt.Errorf("%s(%s): %s", info.NAME, namespace, msg)
 }
+
+// Returns an error like MakeError, which is of the given kind for Is
+func MakeTypedError(kind *Kind, msg string, namespace string) error {
+	return &typedError{kind: kind, message: MakeError(msg, namespace).Error()}
+}
+
+// Reports whether err, or any error it wraps, is target
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+// Returns the kind of err, or nil if it has none
+func KindOf(err error) *Kind {
+	kind := &Kind{}
+	if errors.As(err, &kind) {
+		return kind
+	}
+
+	return nil
+}
+
+// Returns the kind called name, or nil if there is none
+func KindByName(name string) *Kind {
+	for _, kind := range KINDS {
+		if kind.Name == name {
+			return kind
+		}
+	}
+
+	return nil
+}
+
+// Returns the process exit code for err: 0 without errors, the code of its
+// kind, or EXIT_FAILURE
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	if kind := KindOf(err); kind != nil {
+		return kind.ExitCode
+	}
+
+	return EXIT_FAILURE
+}
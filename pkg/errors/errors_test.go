@@ -0,0 +1,40 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"returns 0 without errors", nil, 0},
+		{"returns the code of the kind", MakeTypedError(ErrNotFound, "Missing entry.", "test"), ErrNotFound.ExitCode},
+		{"returns the code of wrapped errors", fmt.Errorf("wrapped: %w", MakeTypedError(ErrCorrupt, "Damaged.", "test")), ErrCorrupt.ExitCode},
+		{"returns EXIT_FAILURE for other errors", MakeError("Failure.", "test"), EXIT_FAILURE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExitCode(tt.err); got != tt.want {
+				t.Errorf("ExitCode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeTypedError(t *testing.T) {
+	err := MakeTypedError(ErrWrongCredentials, "Cannot unlock.", "test")
+
+	if err.Error() != MakeError("Cannot unlock.", "test").Error() {
+		t.Errorf("MakeTypedError() = %q, want the message of MakeError", err.Error())
+	}
+	if !Is(err, ErrWrongCredentials) || Is(err, ErrNotFound) {
+		t.Errorf("MakeTypedError() is not of kind %v", ErrWrongCredentials)
+	}
+	if KindByName(ErrWrongCredentials.Name) != ErrWrongCredentials {
+		t.Errorf("KindByName() does not return %v", ErrWrongCredentials)
+	}
+}
@@ -0,0 +1,41 @@
+package kdbx
+
+import (
+	"encoding/base64"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// Returns the content of the file called name attached to entry
+func (d *Database) GetAttachment(entry *Entry, name string) ([]byte, error) {
+	for _, reference := range entry.Binaries {
+		if reference.Name != name {
+			continue
+		}
+
+		binary := d.FindBinary(reference.Value.ID)
+		if binary == nil {
+			return nil, errors.MakeTypedError(errors.ErrCorrupt, `Missing content of attachment "`+name+`".`, "kdbx")
+		}
+
+		content, err := d.getBinaryContent(binary)
+		if err != nil {
+			return nil, errors.MakeTypedError(errors.ErrCorrupt, `Cannot read attachment "`+name+`": `+err.Error(), "kdbx")
+		}
+
+		return content, nil
+	}
+
+	return nil, errors.MakeTypedError(errors.ErrNotFound, `Missing attachment "`+name+`".`, "kdbx")
+}
+
+func (d *Database) getBinaryContent(binary *gokeepasslib.Binary) ([]byte, error) {
+	// The library decodes uncompressed KDBX 3 attachments into a buffer
+	// padded with zeros
+	if !d.Header.IsKdbx4() && !binary.Compressed.Bool {
+		return base64.StdEncoding.DecodeString(string(binary.Content))
+	}
+
+	return binary.GetContentBytes()
+}
@@ -0,0 +1,42 @@
+package kdbx
+
+import (
+	"testing"
+
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+func TestDatabase_GetAttachment(t *testing.T) {
+	tests := []struct {
+		name    string
+		options EncryptionOptions
+	}{
+		{"KDBX 3", EncryptionOptions{Version: VERSION_3, Cipher: CIPHER_AES, KDF: KDF_AES, Rounds: 1000}},
+		{"KDBX 4", testArgon2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, path := makeEncryptedDatabase(t)
+			if err := d.SetEncryption(tt.options); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			reopened, err := OpenFromPath(path, "password", "")
+			if err != nil {
+				t.Fatalf("cannot open database: %v", err)
+			}
+			entry := reopened.GetFirstEntryByPath("/Test/GitHub")
+
+			if got, err := reopened.GetAttachment(entry, "file.txt"); err != nil || string(got) != "attachment" {
+				t.Errorf("Database.GetAttachment() = %q, %v, want %q", got, err, "attachment")
+			}
+
+			if _, err := reopened.GetAttachment(entry, "missing.txt"); !errors.Is(err, errors.ErrNotFound) {
+				t.Errorf("Database.GetAttachment() = %v, want a not found error", err)
+			}
+		})
+	}
+}
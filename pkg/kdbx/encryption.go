@@ -0,0 +1,295 @@
+package kdbx
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/rand"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/tobischo/argon2"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// Cipher encrypting the content of the database
+type Cipher = string
+
+const (
+	CIPHER_AES      Cipher = "aes"
+	CIPHER_CHACHA20 Cipher = "chacha20"
+	CIPHER_TWOFISH  Cipher = "twofish"
+)
+
+var CIPHERS = []Cipher{CIPHER_AES, CIPHER_CHACHA20, CIPHER_TWOFISH}
+
+// Key derivation function, turning the credentials into the encryption key
+type KDF = string
+
+const (
+	KDF_AES      KDF = "aes-kdf"
+	KDF_ARGON2D  KDF = "argon2d"
+	KDF_ARGON2ID KDF = "argon2id"
+)
+
+var KDFS = []KDF{KDF_AES, KDF_ARGON2D, KDF_ARGON2ID}
+
+// Versions of the KDBX file format
+const (
+	VERSION_3 = 3
+	VERSION_4 = 4
+)
+
+const MEBIBYTE = 1024 * 1024
+
+// How the database is encrypted
+type EncryptionOptions struct {
+	Version int
+	Cipher  Cipher
+	KDF     KDF
+	// Transformations applied by AES-KDF
+	Rounds uint64
+	// Memory used by Argon2, in bytes
+	Memory uint64
+	// Passes over the memory made by Argon2
+	Iterations uint64
+	// Threads used by Argon2
+	Parallelism uint32
+}
+
+var DEFAULT_ENCRYPTION_OPTIONS = EncryptionOptions{
+	Version:     VERSION_4,
+	Cipher:      CIPHER_AES,
+	KDF:         KDF_ARGON2D,
+	Rounds:      600_000,
+	Memory:      64 * MEBIBYTE,
+	Iterations:  10,
+	Parallelism: 2,
+}
+
+var ciphers = map[Cipher][]byte{
+	CIPHER_AES:      gokeepasslib.CipherAES,
+	CIPHER_CHACHA20: gokeepasslib.CipherChaCha20,
+	CIPHER_TWOFISH:  gokeepasslib.CipherTwoFish,
+}
+
+// Returns an error if the options cannot be written, or read back
+func (o EncryptionOptions) Validate() error {
+	if o.Version != VERSION_3 && o.Version != VERSION_4 {
+		return errors.MakeError(fmt.Sprintf("Unknown format version %d. Use 3 or 4.", o.Version), "kdbx")
+	}
+
+	if !slices.Contains(CIPHERS, o.Cipher) {
+		return errors.MakeError(`Unknown cipher "`+o.Cipher+`". Use one of: `+strings.Join(CIPHERS, ", ")+".", "kdbx")
+	}
+
+	if !slices.Contains(KDFS, o.KDF) {
+		return errors.MakeError(`Unknown key derivation function "`+o.KDF+`". Use one of: `+strings.Join(KDFS, ", ")+".", "kdbx")
+	}
+
+	// The decoder would derive the key with Argon2d, and never unlock it
+	if o.KDF == KDF_ARGON2ID {
+		return errors.MakeError("Argon2id is not supported yet. Use "+KDF_ARGON2D+" instead.", "kdbx")
+	}
+
+	if o.Version == VERSION_3 {
+		if o.KDF != KDF_AES {
+			return errors.MakeError("KDBX 3 supports only "+KDF_AES+". Use format version 4 for Argon2.", "kdbx")
+		}
+		if o.Cipher == CIPHER_CHACHA20 {
+			return errors.MakeError("KDBX 3 does not support ChaCha20. Use format version 4.", "kdbx")
+		}
+	}
+
+	if o.KDF == KDF_AES && o.Rounds == 0 {
+		return errors.MakeError("AES-KDF rounds must be greater than 0.", "kdbx")
+	}
+
+	if o.KDF == KDF_ARGON2D {
+		// Argon2 needs at least 8 KiB per thread
+		if o.Parallelism == 0 || o.Iterations == 0 || o.Memory < uint64(o.Parallelism)*8*1024 {
+			return errors.MakeError("Argon2 memory, iterations, and parallelism must be greater than 0.", "kdbx")
+		}
+		if o.Parallelism > 255 {
+			return errors.MakeError("Argon2 parallelism must be at most 255.", "kdbx")
+		}
+	}
+
+	return nil
+}
+
+// Returns a description like "KDBX 4, AES, Argon2d (64 MiB, 10 iterations, 2 threads)"
+func (o EncryptionOptions) String() string {
+	cipher := map[Cipher]string{CIPHER_AES: "AES", CIPHER_CHACHA20: "ChaCha20", CIPHER_TWOFISH: "Twofish"}[o.Cipher]
+
+	kdf := fmt.Sprintf("AES-KDF (%d rounds)", o.Rounds)
+	if o.KDF == KDF_ARGON2D || o.KDF == KDF_ARGON2ID {
+		name := "Argon2d"
+		if o.KDF == KDF_ARGON2ID {
+			name = "Argon2id"
+		}
+		kdf = fmt.Sprintf("%s (%d MiB, %d iterations, %d threads)", name, o.Memory/MEBIBYTE, o.Iterations, o.Parallelism)
+	}
+
+	return fmt.Sprintf("KDBX %d, %s, %s", o.Version, cipher, kdf)
+}
+
+// Returns how the database is encrypted
+func (d *Database) Encryption() EncryptionOptions {
+	headers := d.Header.FileHeaders
+	options := EncryptionOptions{Version: VERSION_3, KDF: KDF_AES, Rounds: headers.TransformRounds}
+
+	for name, id := range ciphers {
+		if bytes.Equal(headers.CipherID, id) {
+			options.Cipher = name
+		}
+	}
+
+	if d.Header.IsKdbx4() {
+		options.Version = VERSION_4
+		kdf := headers.KdfParameters
+
+		if bytes.Equal(kdf.UUID, gokeepasslib.KdfArgon2) {
+			options.KDF = KDF_ARGON2D
+			options.Rounds = 0
+			options.Memory = kdf.Memory
+			options.Iterations = kdf.Iterations
+			options.Parallelism = kdf.Parallelism
+		} else {
+			options.Rounds = kdf.Rounds
+		}
+	}
+
+	return options
+}
+
+// Replaces the header of the database, so that the next Save encrypts it
+// according to options. Protected values must be unlocked.
+func (d *Database) SetEncryption(options EncryptionOptions) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
+	if d.Header.IsKdbx4() && options.Version == VERSION_3 {
+		return errors.MakeError("Cannot convert a KDBX 4 database to KDBX 3.", "kdbx")
+	}
+
+	var header *gokeepasslib.DBHeader
+	if options.Version == VERSION_3 {
+		header = gokeepasslib.NewKDBX3Header()
+		header.FileHeaders.TransformRounds = options.Rounds
+	} else {
+		header = gokeepasslib.NewKDBX4Header()
+		kdf := header.FileHeaders.KdfParameters
+
+		if options.KDF == KDF_AES {
+			kdf.UUID = gokeepasslib.KdfAES4
+			kdf.Rounds = options.Rounds
+			kdf.Memory, kdf.Iterations, kdf.Parallelism, kdf.Version = 0, 0, 0, 0
+		} else {
+			kdf.Memory = options.Memory
+			kdf.Iterations = options.Iterations
+			kdf.Parallelism = options.Parallelism
+		}
+	}
+
+	header.FileHeaders.CipherID = ciphers[options.Cipher]
+	// ChaCha20 uses 96 bit nonces, block ciphers 128 bit IVs
+	if options.Cipher != CIPHER_CHACHA20 {
+		header.FileHeaders.EncryptionIV = make([]byte, 16)
+		if _, err := rand.Read(header.FileHeaders.EncryptionIV); err != nil {
+			return errors.MakeError("Cannot generate IV: "+err.Error(), "kdbx")
+		}
+	}
+
+	if options.Version == VERSION_4 && !d.Header.IsKdbx4() {
+		if err := d.upgradeContent(); err != nil {
+			return err
+		}
+	}
+
+	d.Header = header
+	return nil
+}
+
+// Moves what KDBX 4 stores in the inner header out of the XML content
+func (d *Database) upgradeContent() error {
+	key := make([]byte, 64)
+	if _, err := rand.Read(key); err != nil {
+		return errors.MakeError("Cannot generate stream key: "+err.Error(), "kdbx")
+	}
+
+	inner := &gokeepasslib.InnerHeader{InnerRandomStreamID: gokeepasslib.ChaChaStreamID, InnerRandomStreamKey: key}
+
+	ids := map[int]int{}
+	for _, binary := range d.Content.Meta.Binaries {
+		content, err := binary.GetContentBytes()
+		if err != nil {
+			return errors.MakeError("Cannot read attachment: "+err.Error(), "kdbx")
+		}
+		ids[binary.ID] = inner.Binaries.Add(content, gokeepasslib.WithKDBXv4Binary).ID
+	}
+
+	for i := range d.Content.Root.Groups {
+		remapBinaries(&d.Content.Root.Groups[i], ids)
+	}
+
+	d.Content.InnerHeader = inner
+	d.Content.Meta.Binaries = nil
+	d.Content.Meta.HeaderHash = ""
+	return nil
+}
+
+func remapBinaries(group *Group, ids map[int]int) {
+	remap := func(entry *gokeepasslib.Entry) {
+		for i := range entry.Binaries {
+			if id, ok := ids[entry.Binaries[i].Value.ID]; ok {
+				entry.Binaries[i].Value.ID = id
+			}
+		}
+	}
+
+	for i := range group.Entries {
+		remap(&group.Entries[i])
+		for j := range group.Entries[i].Histories {
+			for k := range group.Entries[i].Histories[j].Entries {
+				remap(&group.Entries[i].Histories[j].Entries[k])
+			}
+		}
+	}
+
+	for i := range group.Groups {
+		remapBinaries(&group.Groups[i], ids)
+	}
+}
+
+// Returns options whose key derivation takes about target on this machine.
+// Argon2 is tuned through iterations, AES-KDF through rounds.
+func TuneKDF(options EncryptionOptions, target time.Duration) EncryptionOptions {
+	key := make([]byte, 32)
+	salt := make([]byte, 32)
+
+	if options.KDF == KDF_AES {
+		const sample = 100_000
+		block, _ := aes.NewCipher(salt)
+
+		start := time.Now()
+		for i := 0; i < sample; i++ {
+			block.Encrypt(key, key)
+			block.Encrypt(key[16:], key[16:])
+		}
+		elapsed := max(time.Since(start), time.Microsecond)
+
+		options.Rounds = max(uint64(float64(sample)*float64(target)/float64(elapsed)), 1)
+		return options
+	}
+
+	start := time.Now()
+	argon2.DKey(key, salt, 1, uint32(options.Memory/1024), uint8(options.Parallelism), 32)
+	elapsed := max(time.Since(start), time.Microsecond)
+
+	options.Iterations = max(uint64(target/elapsed), 1)
+	return options
+}
@@ -0,0 +1,157 @@
+package kdbx
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+// Cheap enough for tests
+var testArgon2 = EncryptionOptions{Version: VERSION_4, Cipher: CIPHER_CHACHA20, KDF: KDF_ARGON2D, Memory: MEBIBYTE, Iterations: 1, Parallelism: 1}
+
+func makeEncryptedDatabase(t *testing.T) (*Database, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.kdbx")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, _ := NewFromFile(file)
+	if err := d.SetPasswordAndKey("password", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values,
+		gokeepasslib.ValueData{Key: TITLE_KEY, Value: gokeepasslib.V{Content: "GitHub"}},
+		gokeepasslib.ValueData{Key: PASSWORD_KEY, Value: gokeepasslib.V{Content: "secret", Protected: wrappers.NewBoolWrapper(true)}},
+	)
+	binary := d.AddBinary([]byte("attachment"))
+	entry.Binaries = append(entry.Binaries, binary.CreateReference("file.txt"))
+
+	group := d.NewGroup("Test")
+	group.Entries = append(group.Entries, entry)
+	d.Content.Root.Groups = []Group{*group}
+
+	return d, path
+}
+
+func TestEncryptionOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options EncryptionOptions
+		wantErr bool
+	}{
+		{"accepts defaults", DEFAULT_ENCRYPTION_OPTIONS, false},
+		{"accepts KDBX 3", EncryptionOptions{Version: VERSION_3, Cipher: CIPHER_AES, KDF: KDF_AES, Rounds: 1}, false},
+		{"rejects unknown version", EncryptionOptions{Version: 2, Cipher: CIPHER_AES, KDF: KDF_AES, Rounds: 1}, true},
+		{"rejects unknown cipher", EncryptionOptions{Version: VERSION_4, Cipher: "des", KDF: KDF_AES, Rounds: 1}, true},
+		{"rejects Argon2 on KDBX 3", EncryptionOptions{Version: VERSION_3, Cipher: CIPHER_AES, KDF: KDF_ARGON2D, Memory: MEBIBYTE, Iterations: 1, Parallelism: 1}, true},
+		{"rejects ChaCha20 on KDBX 3", EncryptionOptions{Version: VERSION_3, Cipher: CIPHER_CHACHA20, KDF: KDF_AES, Rounds: 1}, true},
+		{"rejects Argon2id", EncryptionOptions{Version: VERSION_4, Cipher: CIPHER_AES, KDF: KDF_ARGON2ID, Memory: MEBIBYTE, Iterations: 1, Parallelism: 1}, true},
+		{"rejects no rounds", EncryptionOptions{Version: VERSION_4, Cipher: CIPHER_AES, KDF: KDF_AES}, true},
+		{"rejects no iterations", EncryptionOptions{Version: VERSION_4, Cipher: CIPHER_AES, KDF: KDF_ARGON2D, Memory: MEBIBYTE, Parallelism: 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.options.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("EncryptionOptions.Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDatabase_SetEncryption(t *testing.T) {
+	tests := []struct {
+		name    string
+		options EncryptionOptions
+	}{
+		{"KDBX 3 with AES", EncryptionOptions{Version: VERSION_3, Cipher: CIPHER_AES, KDF: KDF_AES, Rounds: 1000}},
+		{"KDBX 3 with Twofish", EncryptionOptions{Version: VERSION_3, Cipher: CIPHER_TWOFISH, KDF: KDF_AES, Rounds: 1000}},
+		{"KDBX 4 with AES-KDF", EncryptionOptions{Version: VERSION_4, Cipher: CIPHER_AES, KDF: KDF_AES, Rounds: 1000}},
+		{"KDBX 4 with Argon2d", testArgon2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, path := makeEncryptedDatabase(t)
+
+			if err := d.SetEncryption(tt.options); err != nil {
+				t.Fatalf("Database.SetEncryption() error = %v", err)
+			}
+			if err := d.Save(); err != nil {
+				t.Fatalf("Database.Save() error = %v", err)
+			}
+
+			reopened, err := OpenFromPath(path, "password", "")
+			if err != nil {
+				t.Fatalf("cannot open database: %v", err)
+			}
+
+			if got := reopened.Encryption(); got != tt.options {
+				t.Errorf("Database.Encryption() = %v, want %v", got, tt.options)
+			}
+
+			entry := reopened.GetFirstEntryByPath("/Test/GitHub")
+			if entry == nil || entry.GetPassword() != "secret" {
+				t.Fatalf("expected protected values to be preserved, got %v", entry)
+			}
+		})
+	}
+}
+
+func TestDatabase_SetEncryptionUpgrade(t *testing.T) {
+	d, path := makeEncryptedDatabase(t)
+	if err := d.SaveAndUnlockEntries(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := d.Encryption().Version; got != VERSION_3 {
+		t.Fatalf("expected a KDBX 3 database, got %d", got)
+	}
+
+	if err := d.SetEncryption(testArgon2); err != nil {
+		t.Fatalf("Database.SetEncryption() error = %v", err)
+	}
+	if err := d.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := OpenFromPath(path, "password", "")
+	if err != nil {
+		t.Fatalf("cannot open upgraded database: %v", err)
+	}
+
+	entry := reopened.GetFirstEntryByPath("/Test/GitHub")
+	if entry == nil || entry.GetPassword() != "secret" {
+		t.Fatalf("expected protected values to be preserved, got %v", entry)
+	}
+
+	binary := reopened.FindBinary(entry.Binaries[0].Value.ID)
+	if binary == nil {
+		t.Fatal("expected attachment to be preserved")
+	}
+	if content, err := binary.GetContentBytes(); err != nil || string(content) != "attachment" {
+		t.Errorf("expected attachment content, got %q, %v", content, err)
+	}
+
+	if err := reopened.SetEncryption(EncryptionOptions{Version: VERSION_3, Cipher: CIPHER_AES, KDF: KDF_AES, Rounds: 1}); err == nil {
+		t.Errorf("expected downgrade to be rejected")
+	}
+}
+
+func TestTuneKDF(t *testing.T) {
+	if got := TuneKDF(testArgon2, time.Millisecond); got.Iterations < 1 {
+		t.Errorf("TuneKDF() iterations = %d, want at least 1", got.Iterations)
+	}
+
+	aesKDF := EncryptionOptions{Version: VERSION_4, Cipher: CIPHER_AES, KDF: KDF_AES}
+	if got := TuneKDF(aesKDF, 10*time.Millisecond); got.Rounds < 1 {
+		t.Errorf("TuneKDF() rounds = %d, want at least 1", got.Rounds)
+	}
+}
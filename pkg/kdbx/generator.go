@@ -0,0 +1,107 @@
+package kdbx
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+const (
+	LOWERCASE_CHARACTERS = "abcdefghijklmnopqrstuvwxyz"
+	UPPERCASE_CHARACTERS = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	DIGIT_CHARACTERS     = "0123456789"
+	SYMBOL_CHARACTERS    = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+)
+
+type PasswordOptions struct {
+	Length    int
+	Lowercase bool
+	Uppercase bool
+	Digits    bool
+	Symbols   bool
+}
+
+var DEFAULT_PASSWORD_OPTIONS = PasswordOptions{
+	Length:    20,
+	Lowercase: true,
+	Uppercase: true,
+	Digits:    true,
+	Symbols:   true,
+}
+
+// Options used for the passwords of new entries
+var PasswordDefaults = DEFAULT_PASSWORD_OPTIONS
+
+// Returns a random password with at least one character of each of the
+// enabled classes
+func GeneratePassword(options PasswordOptions) (string, error) {
+	classes := []string{}
+	for _, class := range []struct {
+		enabled    bool
+		characters string
+	}{
+		{options.Lowercase, LOWERCASE_CHARACTERS},
+		{options.Uppercase, UPPERCASE_CHARACTERS},
+		{options.Digits, DIGIT_CHARACTERS},
+		{options.Symbols, SYMBOL_CHARACTERS},
+	} {
+		if class.enabled {
+			classes = append(classes, class.characters)
+		}
+	}
+
+	if len(classes) == 0 {
+		return "", errors.MakeError("At least one character class must be enabled.", "kdbx")
+	}
+
+	if options.Length < len(classes) {
+		return "", errors.MakeError("Password length must be at least the number of character classes.", "kdbx")
+	}
+
+	all := ""
+	password := make([]byte, 0, options.Length)
+	for _, class := range classes {
+		all += class
+		c, err := randomCharacter(class)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	for len(password) < options.Length {
+		c, err := randomCharacter(all)
+		if err != nil {
+			return "", err
+		}
+		password = append(password, c)
+	}
+
+	// Moves the mandatory characters away from the head
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return "", err
+		}
+		password[i], password[j] = password[j], password[i]
+	}
+
+	return string(password), nil
+}
+
+func randomCharacter(characters string) (byte, error) {
+	i, err := randomInt(len(characters))
+	if err != nil {
+		return 0, err
+	}
+	return characters[i], nil
+}
+
+func randomInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, errors.MakeError("Cannot generate password: "+err.Error(), "kdbx")
+	}
+	return int(n.Int64()), nil
+}
@@ -0,0 +1,46 @@
+package kdbx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		options PasswordOptions
+		want    []string
+		wantErr bool
+	}{
+		{"uses all classes", DEFAULT_PASSWORD_OPTIONS, []string{LOWERCASE_CHARACTERS, UPPERCASE_CHARACTERS, DIGIT_CHARACTERS, SYMBOL_CHARACTERS}, false},
+		{"uses digits only", PasswordOptions{Length: 6, Digits: true}, []string{DIGIT_CHARACTERS}, false},
+		{"uses letters", PasswordOptions{Length: 2, Lowercase: true, Uppercase: true}, []string{LOWERCASE_CHARACTERS, UPPERCASE_CHARACTERS}, false},
+		{"fails without classes", PasswordOptions{Length: 10}, nil, true},
+		{"fails when too short", PasswordOptions{Length: 1, Lowercase: true, Digits: true}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GeneratePassword(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GeneratePassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(got) != tt.options.Length {
+				t.Errorf("GeneratePassword() length = %v, want %v", len(got), tt.options.Length)
+			}
+
+			for _, class := range tt.want {
+				if !strings.ContainsAny(got, class) {
+					t.Errorf("GeneratePassword() = %v, missing one of %v", got, class)
+				}
+			}
+
+			if strings.Trim(got, strings.Join(tt.want, "")) != "" {
+				t.Errorf("GeneratePassword() = %v, contains disabled classes", got)
+			}
+		})
+	}
+}
@@ -2,18 +2,32 @@ package kdbx
 
 import (
 	"crypto/rand"
-	"encoding/base64"
+	"crypto/sha256"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/shikaan/keydex/pkg/credentials"
 	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/secure"
 	"github.com/tobischo/gokeepasslib/v3"
 	"github.com/tobischo/gokeepasslib/v3/wrappers"
 )
 
 type Database struct {
 	file *os.File
+	// Path to the key file used to unlock the database, if any
+	keyPath string
+	// Number of copies of the previous versions kept on Save
+	Backups int
+	// Locked memory holding the credentials, destroyed by Wipe
+	secrets []*secure.Buffer
 
 	gokeepasslib.Database
 }
@@ -31,10 +45,25 @@ const PATH_SEPARATOR = "/"
 const TITLE_KEY = "Title"
 const PASSWORD_KEY = "Password"
 const USERNAME_KEY = "UserName"
+const NOTES_KEY = "Notes"
+const URL_KEY = "URL"
 
 func OpenFromPath(filepath, password, keypath string) (*Database, error) {
+	passphrase := secure.FromString(password)
+	defer passphrase.Destroy()
+
+	return OpenWithPassphrase(filepath, passphrase, keypath)
+}
+
+// Opens the database at filepath like OpenFromPath, reading the passphrase
+// from locked memory. The passphrase can be destroyed afterwards.
+func OpenWithPassphrase(filepath string, passphrase *secure.Buffer, keypath string) (*Database, error) {
 	file, err := os.Open(filepath)
 
+	if os.IsNotExist(err) {
+		return nil, errors.MakeTypedError(errors.ErrNotFound, "Cannot open "+filepath+": "+err.Error(), "kdbx")
+	}
+
 	if err != nil {
 		return nil, errors.MakeError("Cannot open "+filepath+": "+err.Error(), "kdbx")
 	}
@@ -44,35 +73,126 @@ func OpenFromPath(filepath, password, keypath string) (*Database, error) {
 		return nil, errors.MakeError("Cannot open "+filepath+": "+err.Error(), "kdbx")
 	}
 
-	if err := kdbx.UnlockWithPasswordAndKey(password, keypath); err != nil {
+	if err := kdbx.SetPassphraseAndKey(passphrase, keypath); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if err := kdbx.unlock(); err != nil {
+		file.Close()
 		return nil, err
 	}
 
 	return kdbx, nil
 }
 
+// Opens the file of the database again with the same credentials, such as
+// once another program changed it. The database itself is left untouched.
+func (d *Database) Reopen() (*Database, error) {
+	if d.Credentials == nil {
+		return nil, errors.MakeError("Cannot reopen without credentials.", "kdbx")
+	}
+
+	path := d.Path()
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, errors.MakeTypedError(errors.ErrNotFound, "Cannot open "+path+": "+err.Error(), "kdbx")
+	}
+	if err != nil {
+		return nil, errors.MakeError("Cannot open "+path+": "+err.Error(), "kdbx")
+	}
+
+	reopened, _ := NewFromFile(file)
+	reopened.keyPath = d.keyPath
+	reopened.Backups = d.Backups
+	reopened.Credentials = &gokeepasslib.DBCredentials{}
+
+	// Each database destroys its own credentials when wiped
+	copySecret := func(secret []byte) []byte {
+		if secret == nil {
+			return nil
+		}
+		copied := secure.NewBuffer(len(secret))
+		copy(copied.Bytes(), secret)
+		reopened.secrets = append(reopened.secrets, copied)
+		return copied.Bytes()
+	}
+	reopened.Credentials.Passphrase = copySecret(d.Credentials.Passphrase)
+	reopened.Credentials.Key = copySecret(d.Credentials.Key)
+
+	if err := reopened.unlock(); err != nil {
+		reopened.Wipe()
+		return nil, err
+	}
+
+	return reopened, nil
+}
+
 func NewFromFile(file *os.File) (*Database, error) {
 	if file == nil {
 		return nil, errors.MakeError("File must be valid and not nil.", "kdbx")
 	}
-	return &Database{file, *gokeepasslib.NewDatabase()}, nil
+	return &Database{file: file, Database: *gokeepasslib.NewDatabase()}, nil
 }
 
+// Sets the credentials used to unlock and save the database. An empty
+// password with a key file means the database is unlocked with the key file
+// only.
 func (d *Database) SetPasswordAndKey(password, keypath string) error {
-	if keypath == "" {
-		d.Credentials = gokeepasslib.NewPasswordCredentials(password)
-	} else {
-		credentials, err := gokeepasslib.NewPasswordAndKeyCredentials(password, keypath)
+	passphrase := secure.FromString(password)
+	defer passphrase.Destroy()
 
+	return d.SetPassphraseAndKey(passphrase, keypath)
+}
+
+// Sets the credentials like SetPasswordAndKey. Their derived keys are kept in
+// locked memory, so the passphrase can be destroyed afterwards.
+func (d *Database) SetPassphraseAndKey(passphrase *secure.Buffer, keypath string) error {
+	dbCredentials := &gokeepasslib.DBCredentials{}
+	secrets := []*secure.Buffer{}
+
+	if passphrase.Len() > 0 || keypath == "" {
+		hashedPassword := sha256.Sum256(passphrase.Bytes())
+		hashed := secure.FromBytes(hashedPassword[:])
+		secrets = append(secrets, hashed)
+		dbCredentials.Passphrase = hashed.Bytes()
+	}
+
+	if keypath != "" {
+		keyFile, err := credentials.ReadKeyFile(keypath)
 		if err != nil {
-			return errors.MakeError("Cannot create credentials: "+err.Error(), "kdbx")
+			destroy(secrets)
+			return err
 		}
 
-		d.Credentials = credentials
+		key := secure.FromBytes(keyFile.Key)
+		secrets = append(secrets, key)
+		dbCredentials.Key = key.Bytes()
 	}
+
+	d.keyPath = keypath
+	d.Credentials = dbCredentials
+	// The previous credentials are not used anymore
+	destroy(d.secrets)
+	d.secrets = secrets
 	return nil
 }
 
+// Returns true if the database is unlocked with the key file only
+func (d *Database) IsKeyOnly() bool {
+	return d.Credentials != nil && d.Credentials.Passphrase == nil && d.Credentials.Key != nil
+}
+
+// Returns the path of the database file
+func (d *Database) Path() string {
+	return d.file.Name()
+}
+
+// Returns the path of the key file used to unlock the database, if any
+func (d *Database) KeyPath() string {
+	return d.keyPath
+}
+
 func (d *Database) UnlockWithPasswordAndKey(password, keypath string) error {
 	if err := d.SetPasswordAndKey(password, keypath); err != nil {
 		return err
@@ -90,6 +210,34 @@ func (d *Database) GetEntryPaths() []EntityPath {
 	return result
 }
 
+// Returns the paths of the last `limit` accessed entries, most recent first
+func (d *Database) GetRecentEntryPaths(limit int) []EntityPath {
+	type accessedPath struct {
+		path       EntityPath
+		accessedAt time.Time
+	}
+
+	accessed := []accessedPath{}
+	for _, uEP := range d.getEntryPathsAndUUIDs() {
+		entry := d.GetEntry(uEP.uuid)
+		if entry == nil || entry.Times.LastAccessTime == nil {
+			continue
+		}
+		accessed = append(accessed, accessedPath{uEP.path, entry.Times.LastAccessTime.Time})
+	}
+
+	sort.SliceStable(accessed, func(i, j int) bool {
+		return accessed[i].accessedAt.After(accessed[j].accessedAt)
+	})
+
+	result := []EntityPath{}
+	for i := 0; i < len(accessed) && i < limit; i++ {
+		result = append(result, accessed[i].path)
+	}
+
+	return result
+}
+
 func (d *Database) GetGroupPaths() []EntityPath {
 	result := []EntityPath{}
 
@@ -113,6 +261,26 @@ func (d *Database) GetFirstEntryByPath(p EntityPath) *Entry {
 	return nil
 }
 
+// Returns the only entry matching the entry path provided, failing when
+// there is none or more than one
+func (d *Database) GetEntryByPath(p EntityPath) (*Entry, error) {
+	var uuids []gokeepasslib.UUID
+	for _, uEP := range d.getEntryPathsAndUUIDs() {
+		if uEP.path == p {
+			uuids = append(uuids, uEP.uuid)
+		}
+	}
+
+	switch len(uuids) {
+	case 0:
+		return nil, errors.MakeTypedError(errors.ErrNotFound, `Missing entry at "`+p+`".`, "kdbx")
+	case 1:
+		return d.GetEntry(uuids[0]), nil
+	}
+
+	return nil, errors.MakeTypedError(errors.ErrAmbiguous, fmt.Sprintf(`%d entries at "%s". Rename them to tell them apart.`, len(uuids), p), "kdbx")
+}
+
 // Returns the first group matching the entry path provided.
 // Note that the path might not be unique. Use the UUID method
 // when identifying a precise group is necessary
@@ -215,7 +383,7 @@ func (d *Database) NewEntry() *Entry {
 	entry := gokeepasslib.NewEntry()
 	entry.Values = append(entry.Values, gokeepasslib.ValueData{
 		Key:   TITLE_KEY,
-		Value: gokeepasslib.V{Content: "New"},
+		Value: gokeepasslib.V{Content: NEW_ENTRY_TITLE},
 	})
 	entry.Values = append(entry.Values, gokeepasslib.ValueData{
 		Key:   USERNAME_KEY,
@@ -224,7 +392,7 @@ func (d *Database) NewEntry() *Entry {
 	entry.Values = append(entry.Values, gokeepasslib.ValueData{
 		Key: PASSWORD_KEY,
 		Value: gokeepasslib.V{
-			Content:   generateRandomString(16),
+			Content:   sealValue(newEntryPassword()),
 			Protected: wrappers.NewBoolWrapper(true),
 		},
 	})
@@ -272,35 +440,181 @@ func (d *Database) GetRootGroup() *Group {
 	return nil
 }
 
+// Encodes the database to a temporary file next to the original, and
+// replaces the original with it, so that a failure never leaves a truncated
+// database behind
 func (d *Database) Save() error {
+	if err := d.openProtectedValues(); err != nil {
+		return errors.MakeError("Cannot save database: "+err.Error(), "kdbx")
+	}
+
 	if err := d.Database.LockProtectedEntries(); err != nil {
 		return errors.MakeError("Cannot save database: "+err.Error(), "kdbx")
 	}
 
+	// Open files cannot be replaced on some platforms
 	if err := d.file.Close(); err != nil {
 		return errors.MakeError("Cannot save database: "+err.Error(), "kdbx")
 	}
 
-	file, err := os.Create(d.file.Name())
-	if err != nil {
+	path := d.file.Name()
+	if err := d.replaceFile(path); err != nil {
+		// Keeps the handle usable, should the database be saved again
+		d.reopen(path)
 		return errors.MakeError("Cannot save database: "+err.Error(), "kdbx")
 	}
 
+	if err := d.reopen(path); err != nil {
+		return errors.MakeError("Cannot reopen database: "+err.Error(), "kdbx")
+	}
+
+	return nil
+}
+
+// Replaces the handle of the database, closed already, with a new one to path
+func (d *Database) reopen(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	d.file = file
+	return nil
+}
+
+// Writes the database to a temporary file, and moves it to path
+func (d *Database) replaceFile(path string) error {
+	// Replacing a symbolic link would detach it from its target
+	target, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		target = path
+	} else if err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	saved := false
 	defer func() {
-		if d.file != file {
-			file.Close()
+		if !saved {
+			temp.Close()
+			os.Remove(temp.Name())
 		}
 	}()
 
-	if err := gokeepasslib.NewEncoder(file).Encode(&d.Database); err != nil {
-		return errors.MakeError("Cannot save database: "+err.Error(), "kdbx")
+	if stat, err := os.Stat(target); err == nil {
+		if err := temp.Chmod(stat.Mode().Perm()); err != nil {
+			return err
+		}
 	}
 
-	d.file = file
+	if err := gokeepasslib.NewEncoder(temp).Encode(&d.Database); err != nil {
+		return err
+	}
+
+	if err := temp.Sync(); err != nil {
+		return err
+	}
+
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	if err := rotateBackups(target, d.Backups); err != nil {
+		return err
+	}
+
+	if err := os.Rename(temp.Name(), target); err != nil {
+		return err
+	}
+	saved = true
 
 	return nil
 }
 
+// Replaces the credentials of the database, which are used from the next
+// Save on. Seeds are renewed, so that nothing derived from the previous
+// credentials can decrypt the new file.
+func (d *Database) ChangePasswordAndKey(passphrase *secure.Buffer, keypath string) error {
+	if err := d.SetPassphraseAndKey(passphrase, keypath); err != nil {
+		return err
+	}
+
+	if err := d.renewSeeds(); err != nil {
+		return errors.MakeError("Cannot change credentials: "+err.Error(), "kdbx")
+	}
+
+	now := wrappers.Now()
+	d.Content.Meta.MasterKeyChanged = &now
+	return nil
+}
+
+func (d *Database) renewSeeds() error {
+	headers := d.Header.FileHeaders
+
+	for _, seed := range [][]byte{headers.MasterSeed, headers.TransformSeed, headers.EncryptionIV} {
+		if _, err := rand.Read(seed); err != nil {
+			return err
+		}
+	}
+
+	if headers.KdfParameters != nil {
+		if _, err := rand.Read(headers.KdfParameters.Salt[:]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Returns the path of the n-th backup of the database at path
+func BackupPath(path string, n int) string {
+	return path + "." + strconv.Itoa(n) + ".bak"
+}
+
+// Returns the paths of the existing backups of the database at path, newest
+// first
+func ListBackups(path string) []string {
+	backups := []string{}
+	for n := 1; ; n++ {
+		if _, err := os.Stat(BackupPath(path, n)); err != nil {
+			return backups
+		}
+		backups = append(backups, BackupPath(path, n))
+	}
+}
+
+// Copies the file at path to its first backup, shifting the existing ones
+// and dropping those exceeding count
+func rotateBackups(path string, count int) error {
+	if count <= 0 {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(BackupPath(path, count)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for n := count - 1; n > 0; n-- {
+		if err := os.Rename(BackupPath(path, n), BackupPath(path, n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.WriteFile(BackupPath(path, 1), content, 0o600)
+}
+
 func (d *Database) SaveAndUnlockEntries() error {
 	err := d.Save()
 	if err != nil {
@@ -312,6 +626,7 @@ func (d *Database) SaveAndUnlockEntries() error {
 		return err
 	}
 
+	d.sealProtectedValues()
 	return nil
 }
 
@@ -343,17 +658,38 @@ func (d *Database) getGroupPathsAndUUIDs() []uniqueEntityPath {
 // Decodes and unlocks a database whose credentials are known.
 // Use UnlockWith* methods to store credentials
 func (d *Database) unlock() error {
-	// TODO: it's probably possible to unlock without password,
-	// since you can create credentials-less archives
+	// Archives can be created without credentials
 	if d.Credentials == nil {
-		return errors.MakeError("Cannot unlock without credentials.", "kdbx")
+		d.Credentials = &gokeepasslib.DBCredentials{}
 	}
 
-	if err := gokeepasslib.NewDecoder(d.file).Decode(&d.Database); err != nil {
-		return errors.MakeError("Cannot unlock database: "+err.Error(), "kdbx")
+	err := gokeepasslib.NewDecoder(d.file).Decode(&d.Database)
+
+	// Some clients store an empty passphrase along with the key file, rather
+	// than the key file only
+	if err != nil && d.IsKeyOnly() {
+		if _, seekErr := d.file.Seek(0, io.SeekStart); seekErr == nil {
+			hashedPassword := sha256.Sum256(nil)
+			d.Credentials.Passphrase = hashedPassword[:]
+
+			if err = gokeepasslib.NewDecoder(d.file).Decode(&d.Database); err != nil {
+				d.Credentials.Passphrase = nil
+			}
+		}
+	}
+
+	// The decoder does not export its errors. Wrong credentials are
+	// detected by the header HMAC (KDBX 4) or the stream start bytes (KDBX 3)
+	if err != nil && strings.HasPrefix(err.Error(), "Wrong password?") {
+		return errors.MakeTypedError(errors.ErrWrongCredentials, "Cannot unlock database: "+err.Error(), "kdbx")
+	}
+
+	if err != nil {
+		return errors.MakeTypedError(errors.ErrCorrupt, "Cannot unlock database: "+err.Error(), "kdbx")
 	}
 
 	d.Database.UnlockProtectedEntries()
+	d.sealProtectedValues()
 	return nil
 }
 
@@ -421,21 +757,131 @@ func sanitizePathPortion(s string) string {
 	return strings.ReplaceAll(s, PATH_SEPARATOR, "")
 }
 
-func generateRandomString(length uint) string {
-	b := make([]byte, length)
-	_, err := rand.Read(b)
+func newEntryPassword() string {
+	password, err := GeneratePassword(PasswordDefaults)
 	if err != nil {
 		return "change-me"
 	}
-	return base64.RawStdEncoding.EncodeToString(b)
+	return password
 }
 
+// Sets the value of key, adding the field if it is missing. Protected values
+// are sealed in memory.
 func (e *Entry) SetValue(key string, value string) {
 	v := e.Get(key)
+	if v == nil {
+		e.Values = append(e.Values, EntryField{Key: key})
+		v = &e.Values[len(e.Values)-1]
+	}
+
+	if v.Value.Protected.Bool && key != TITLE_KEY {
+		value = sealValue(value)
+	}
 	v.Value.Content = value
 }
 
+// Adds an empty field called key
+func (e *Entry) AddField(key string, protected bool) error {
+	if err := e.checkFieldName(key); err != nil {
+		return err
+	}
+
+	e.Values = append(e.Values, EntryField{
+		Key:   key,
+		Value: gokeepasslib.V{Protected: wrappers.NewBoolWrapper(protected)},
+	})
+	return nil
+}
+
+// Removes the field called key. The title cannot be removed.
+func (e *Entry) RemoveField(key string) error {
+	if key == TITLE_KEY {
+		return errors.MakeError("Cannot remove the title.", "kdbx")
+	}
+
+	index := slices.IndexFunc(e.Values, func(v EntryField) bool { return v.Key == key })
+	if index < 0 {
+		return errors.MakeTypedError(errors.ErrNotFound, `Missing field "`+key+`".`, "kdbx")
+	}
+
+	e.Values = slices.Delete(e.Values, index, index+1)
+	return nil
+}
+
+// Renames the field called key, keeping its place among the others. The
+// title cannot be renamed.
+func (e *Entry) RenameField(key, name string) error {
+	if key == TITLE_KEY {
+		return errors.MakeError("Cannot rename the title.", "kdbx")
+	}
+
+	v := e.Get(key)
+	if v == nil {
+		return errors.MakeTypedError(errors.ErrNotFound, `Missing field "`+key+`".`, "kdbx")
+	}
+
+	if name == key {
+		return nil
+	}
+	if err := e.checkFieldName(name); err != nil {
+		return err
+	}
+
+	v.Key = name
+	return nil
+}
+
+// Protects the value of key, sealing it in memory, or stops protecting it.
+// Titles are never protected, since they build the paths.
+func (e *Entry) SetProtected(key string, protected bool) error {
+	if key == TITLE_KEY {
+		return errors.MakeError("Cannot protect the title.", "kdbx")
+	}
+
+	v := e.Get(key)
+	if v == nil {
+		return errors.MakeTypedError(errors.ErrNotFound, `Missing field "`+key+`".`, "kdbx")
+	}
+
+	if protected {
+		v.Value.Content = sealValue(v.Value.Content)
+	} else {
+		buffer, err := openValue(v.Value.Content)
+		if err != nil {
+			return errors.MakeError("Cannot open protected value of "+key+": "+err.Error(), "kdbx")
+		}
+		defer buffer.Destroy()
+
+		v.Value.Content = string(buffer.Bytes())
+	}
+
+	v.Value.Protected = wrappers.NewBoolWrapper(protected)
+	return nil
+}
+
+// Replaces the fields with those of other, such as a copy being edited
+func (e *Entry) SetFields(other *Entry) {
+	e.Values = slices.Clone(other.Values)
+}
+
+func (e *Entry) checkFieldName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.MakeError("Field names cannot be empty.", "kdbx")
+	}
+
+	if e.Get(name) != nil {
+		return errors.MakeError(`Field "`+name+`" already exists.`, "kdbx")
+	}
+
+	return nil
+}
+
 func (e *Entry) SetLastUpdated() {
 	now := wrappers.Now()
 	e.Times.LastModificationTime = &now
 }
+
+func (e *Entry) SetLastAccessed() {
+	now := wrappers.Now()
+	e.Times.LastAccessTime = &now
+}
@@ -1,11 +1,18 @@
 package kdbx
 
 import (
+	"crypto/sha256"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
 	"testing"
 	"time"
 
+	"github.com/shikaan/keydex/pkg/credentials"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/secure"
 	"github.com/tobischo/gokeepasslib/v3"
 	"github.com/tobischo/gokeepasslib/v3/wrappers"
 )
@@ -43,7 +50,7 @@ func makeGroup(name string, entries ...Entry) gokeepasslib.Group {
 
 func makeDatabase(_ string, groups ...gokeepasslib.Group) *Database {
 	gdb := gokeepasslib.NewDatabase()
-	db := &Database{&os.File{}, *gdb}
+	db := &Database{file: &os.File{}, Database: *gdb}
 	gdb.Content.Root.Groups = groups
 
 	return db
@@ -174,6 +181,309 @@ func TestDatabase_Save(t *testing.T) {
 	}
 }
 
+func TestDatabase_SaveBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.kdbx")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Database{file: file, Database: *gokeepasslib.NewDatabase(), Backups: 2}
+
+	versions := []string{}
+	for i := 0; i < 4; i++ {
+		d.Content.Meta.DatabaseName = strconv.Itoa(i)
+		if err := d.Save(); err != nil {
+			t.Fatalf("Database.Save() error = %v", err)
+		}
+
+		content, _ := os.ReadFile(path)
+		versions = append(versions, string(content))
+	}
+
+	for n, want := range map[int]string{1: versions[2], 2: versions[1]} {
+		got, err := os.ReadFile(BackupPath(path, n))
+		if err != nil {
+			t.Fatalf("missing backup %d: %v", n, err)
+		}
+		if string(got) != want {
+			t.Errorf("backup %d has unexpected content", n)
+		}
+	}
+
+	if _, err := os.Stat(BackupPath(path, 3)); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, got %v", err)
+	}
+
+	want := []string{BackupPath(path, 1), BackupPath(path, 2)}
+	if got := ListBackups(path); !reflect.DeepEqual(got, want) {
+		t.Errorf("ListBackups() = %v, want %v", got, want)
+	}
+}
+
+func TestDatabase_SaveReplacesFile(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target.kdbx")
+	link := filepath.Join(dir, "link.kdbx")
+
+	if err := os.WriteFile(target, nil, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Database{file: file, Database: *gokeepasslib.NewDatabase()}
+	if err := d.Save(); err != nil {
+		t.Fatalf("Database.Save() error = %v", err)
+	}
+
+	if stat, err := os.Lstat(link); err != nil || stat.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("expected link to be preserved, got %v, %v", stat, err)
+	}
+
+	stat, err := os.Stat(target)
+	if err != nil || stat.Size() == 0 {
+		t.Fatalf("expected target to be written, got %v, %v", stat, err)
+	}
+	if mode := stat.Mode().Perm(); mode != 0o640 {
+		t.Errorf("expected mode 0640, got %v", mode)
+	}
+
+	if leftovers, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(leftovers) > 0 {
+		t.Errorf("expected no temporary files, got %v", leftovers)
+	}
+
+	if d.Path() != link {
+		t.Errorf("expected path %s, got %s", link, d.Path())
+	}
+}
+
+func TestDatabase_SaveClosesFiles(t *testing.T) {
+	descriptors, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("cannot count open files")
+	}
+
+	path := filepath.Join(t.TempDir(), "test.kdbx")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Database{file: file, Database: *gokeepasslib.NewDatabase()}
+	for i := 0; i < 10; i++ {
+		if err := d.Save(); err != nil {
+			t.Fatalf("Database.Save() error = %v", err)
+		}
+	}
+
+	after, _ := os.ReadDir("/proc/self/fd")
+	if len(after) > len(descriptors)+1 {
+		t.Errorf("expected one open file, got %d more", len(after)-len(descriptors))
+	}
+
+	// Saving where the database cannot be replaced fails, and keeps the
+	// database usable
+	os.Chmod(filepath.Dir(path), 0o500)
+	defer os.Chmod(filepath.Dir(path), 0o700)
+	if err := d.Save(); err == nil && os.Geteuid() != 0 {
+		t.Errorf("expected Database.Save() to fail in a read-only directory")
+	}
+	os.Chmod(filepath.Dir(path), 0o700)
+
+	if err := d.Save(); err != nil {
+		t.Errorf("Database.Save() error = %v after a failure", err)
+	}
+}
+
+func TestDatabase_ChangePasswordAndKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.kdbx")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Database{file: file, Database: *gokeepasslib.NewDatabase()}
+	if err := d.SetPasswordAndKey("old", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	seed := slices.Clone(d.Header.FileHeaders.MasterSeed)
+	before := time.Now().Add(-time.Second)
+
+	if err := d.ChangePasswordAndKey(secure.FromString("new"), ""); err != nil {
+		t.Fatalf("Database.ChangePasswordAndKey() error = %v", err)
+	}
+	if err := d.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if reflect.DeepEqual(seed, d.Header.FileHeaders.MasterSeed) {
+		t.Errorf("expected master seed to be renewed")
+	}
+
+	if _, err := OpenFromPath(path, "old", ""); !errors.Is(err, errors.ErrWrongCredentials) {
+		t.Errorf("expected old passphrase to be rejected, got %v", err)
+	}
+
+	reopened, err := OpenFromPath(path, "new", "")
+	if err != nil {
+		t.Fatalf("expected new passphrase to be accepted, got %v", err)
+	}
+
+	changed := reopened.Content.Meta.MasterKeyChanged
+	if changed == nil || changed.Time.Before(before) {
+		t.Errorf("expected MasterKeyChanged to be updated, got %v", changed)
+	}
+}
+
+func TestDatabase_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.kdbx")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Database{file: file, Database: *gokeepasslib.NewDatabase()}
+	d.Content.Root.Groups = []gokeepasslib.Group{makeGroup("Group", makeEntry("Entry"))}
+	if err := d.SetPasswordAndKey("password", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SaveAndUnlockEntries(); err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := OpenFromPath(path, "password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.GetFirstEntryByPath("/Group/Entry").SetValue(TITLE_KEY, "Changed")
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := d.Reopen()
+	if err != nil {
+		t.Fatalf("Database.Reopen() error = %v", err)
+	}
+	if reopened.GetFirstEntryByPath("/Group/Changed") == nil {
+		t.Errorf("expected the changes on disk, got %v", reopened.GetEntryPaths())
+	}
+
+	reopened.Wipe()
+	if d.GetFirstEntryByPath("/Group/Entry") == nil || d.Credentials.Passphrase == nil {
+		t.Fatalf("expected the original database to be untouched")
+	}
+
+	if err := other.ChangePasswordAndKey(secure.FromString("changed"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.Reopen(); !errors.Is(err, errors.ErrWrongCredentials) {
+		t.Errorf("expected changed credentials to be rejected, got %v", err)
+	}
+}
+
+func TestDatabase_KeyOnly(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "test.key")
+	if err := credentials.CreateKeyFile(keyPath, credentials.KEY_FILE_HEX); err != nil {
+		t.Fatal(err)
+	}
+
+	save := func(t *testing.T, password string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "test.kdbx")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		d := &Database{file: file, Database: *gokeepasslib.NewDatabase()}
+		if err := d.SetPasswordAndKey(password, keyPath); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Save(); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("unlocks with the key file only", func(t *testing.T) {
+		path := save(t, "")
+
+		d, err := OpenFromPath(path, "", keyPath)
+		if err != nil {
+			t.Fatalf("expected key file to unlock the database, got %v", err)
+		}
+		if !d.IsKeyOnly() {
+			t.Errorf("expected database to be key-only")
+		}
+
+		if _, err := OpenFromPath(path, "password", keyPath); err == nil {
+			t.Errorf("expected a passphrase to be rejected")
+		}
+	})
+
+	t.Run("unlocks databases with an empty passphrase and a key file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "test.kdbx")
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keyFile, err := credentials.ReadKeyFile(keyPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		empty := sha256.Sum256(nil)
+		d := &Database{file: file, Database: *gokeepasslib.NewDatabase()}
+		d.Credentials = &gokeepasslib.DBCredentials{Passphrase: empty[:], Key: keyFile.Key}
+		if err := d.Save(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := OpenFromPath(path, "", keyPath); err != nil {
+			t.Fatalf("expected key file to unlock the database, got %v", err)
+		}
+	})
+
+	t.Run("requires the key file", func(t *testing.T) {
+		path := save(t, "")
+
+		if _, err := OpenFromPath(path, "", ""); err == nil {
+			t.Errorf("expected database not to unlock without the key file")
+		}
+	})
+}
+
+func TestDatabase_GetEntryByPath(t *testing.T) {
+	db := makeDatabase("test.kdbx", makeGroup("Test", makeEntry("Twin"), makeEntry("Twin"), makeEntry("Single")))
+
+	if entry, err := db.GetEntryByPath("/Test/Single"); err != nil || entry.GetTitle() != "Single" {
+		t.Errorf("Database.GetEntryByPath() = %v, %v, want the entry", entry, err)
+	}
+	if _, err := db.GetEntryByPath("/Test/Twin"); !errors.Is(err, errors.ErrAmbiguous) {
+		t.Errorf("Database.GetEntryByPath() error = %v, want %v", err, errors.ErrAmbiguous)
+	}
+	if _, err := db.GetEntryByPath("/Test/Missing"); !errors.Is(err, errors.ErrNotFound) {
+		t.Errorf("Database.GetEntryByPath() error = %v, want %v", err, errors.ErrNotFound)
+	}
+}
+
 func TestDatabase_NewEntry(t *testing.T) {
 	db := makeDatabase("test.kdbx")
 
@@ -631,6 +941,104 @@ func TestEntry_SetValue(t *testing.T) {
 			t.Errorf("SetValue() title = %v, want UpdatedTitle", entry.GetTitle())
 		}
 	})
+
+	t.Run("adds missing fields", func(t *testing.T) {
+		entry.SetValue("URL", "https://example.com")
+
+		if got := entry.GetContent("URL"); got != "https://example.com" {
+			t.Errorf("SetValue() value = %v, want https://example.com", got)
+		}
+	})
+}
+
+func TestEntry_Fields(t *testing.T) {
+	keys := func(e Entry) []string {
+		result := []string{}
+		for _, v := range e.Values {
+			result = append(result, v.Key)
+		}
+		return result
+	}
+
+	t.Run("adds fields", func(t *testing.T) {
+		entry := makeEntry("TestEntry")
+
+		if err := entry.AddField("Recovery codes", true); err != nil {
+			t.Fatalf("AddField() error = %v", err)
+		}
+		if v := entry.Get("Recovery codes"); v == nil || !v.Value.Protected.Bool {
+			t.Errorf("AddField() did not add a protected field")
+		}
+
+		for _, name := range []string{"Recovery codes", TITLE_KEY, " "} {
+			if err := entry.AddField(name, false); err == nil {
+				t.Errorf("AddField(%q) error = nil", name)
+			}
+		}
+	})
+
+	t.Run("removes fields", func(t *testing.T) {
+		entry := makeEntry("TestEntry")
+		entry.SetValue("URL", "https://example.com")
+
+		if err := entry.RemoveField("URL"); err != nil {
+			t.Fatalf("RemoveField() error = %v", err)
+		}
+		if !reflect.DeepEqual(keys(entry), []string{TITLE_KEY}) {
+			t.Errorf("RemoveField() fields = %v", keys(entry))
+		}
+
+		if err := entry.RemoveField("URL"); !errors.Is(err, errors.ErrNotFound) {
+			t.Errorf("RemoveField() error = %v, want not found", err)
+		}
+		if err := entry.RemoveField(TITLE_KEY); err == nil {
+			t.Errorf("RemoveField() removed the title")
+		}
+	})
+
+	t.Run("renames fields in place", func(t *testing.T) {
+		entry := makeEntry("TestEntry")
+		entry.SetValue("Website", "https://example.com")
+		entry.SetValue(USERNAME_KEY, "user")
+
+		if err := entry.RenameField("Website", "URL"); err != nil {
+			t.Fatalf("RenameField() error = %v", err)
+		}
+		if !reflect.DeepEqual(keys(entry), []string{TITLE_KEY, "URL", USERNAME_KEY}) {
+			t.Errorf("RenameField() fields = %v", keys(entry))
+		}
+
+		if err := entry.RenameField("URL", USERNAME_KEY); err == nil {
+			t.Errorf("RenameField() allowed a duplicate")
+		}
+		if err := entry.RenameField(TITLE_KEY, "Name"); err == nil {
+			t.Errorf("RenameField() renamed the title")
+		}
+	})
+
+	t.Run("protects fields", func(t *testing.T) {
+		entry := makeEntry("TestEntry")
+		entry.SetValue("PIN", "1234")
+
+		if err := entry.SetProtected("PIN", true); err != nil {
+			t.Fatalf("SetProtected() error = %v", err)
+		}
+		v := entry.Get("PIN")
+		if !v.Value.Protected.Bool || v.Value.Content == "1234" || entry.GetContent("PIN") != "1234" {
+			t.Errorf("SetProtected() did not seal the value")
+		}
+
+		if err := entry.SetProtected("PIN", false); err != nil {
+			t.Fatalf("SetProtected() error = %v", err)
+		}
+		if v := entry.Get("PIN"); v.Value.Protected.Bool || v.Value.Content != "1234" {
+			t.Errorf("SetProtected() did not open the value")
+		}
+
+		if err := entry.SetProtected(TITLE_KEY, true); err == nil {
+			t.Errorf("SetProtected() protected the title")
+		}
+	})
 }
 
 func TestEntry_SetLastUpdated(t *testing.T) {
@@ -699,3 +1107,44 @@ func TestDatabase_NewGroup(t *testing.T) {
 		})
 	}
 }
+
+func TestDatabase_GetRecentEntryPaths(t *testing.T) {
+	now := time.Now()
+	older := makeEntry("Older")
+	older.Times.LastAccessTime = &wrappers.TimeWrapper{Time: now.Add(-2 * time.Hour)}
+	newer := makeEntry("Newer")
+	newer.Times.LastAccessTime = &wrappers.TimeWrapper{Time: now.Add(-1 * time.Hour)}
+	oldest := makeEntry("Oldest")
+	oldest.Times.LastAccessTime = &wrappers.TimeWrapper{Time: now.Add(-3 * time.Hour)}
+
+	db := makeDatabase("db", makeGroup("Group", older, newer, oldest))
+
+	t.Run("sorts by access time", func(t *testing.T) {
+		got := db.GetRecentEntryPaths(10)
+		want := []string{"/Group/Newer", "/Group/Older", "/Group/Oldest"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetRecentEntryPaths() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("honours the limit", func(t *testing.T) {
+		got := db.GetRecentEntryPaths(1)
+		want := []string{"/Group/Newer"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetRecentEntryPaths() = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestEntry_SetLastAccessed(t *testing.T) {
+	entry := makeEntry("TestEntry")
+
+	pastTime := time.Now().Add(-1 * time.Hour)
+	entry.Times.LastAccessTime = &wrappers.TimeWrapper{Time: pastTime}
+
+	entry.SetLastAccessed()
+
+	if !entry.Times.LastAccessTime.Time.After(pastTime) {
+		t.Error("SetLastAccessed() did not update the time to a more recent time")
+	}
+}
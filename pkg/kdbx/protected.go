@@ -0,0 +1,151 @@
+package kdbx
+
+import (
+	"strings"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/secure"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// Marks protected values which are sealed in memory. It cannot be typed,
+// so no plaintext value starts with it.
+const SEALED_PREFIX = "\x00sealed\x00"
+
+// Returns value sealed in memory, so that its plaintext is not kept around
+func sealValue(value string) string {
+	if value == "" || isSealed(value) {
+		return value
+	}
+
+	return SEALED_PREFIX + string(secure.Seal([]byte(value)))
+}
+
+func isSealed(value string) bool {
+	return strings.HasPrefix(value, SEALED_PREFIX)
+}
+
+// Returns the plaintext of a value in locked memory. Destroy it once used.
+func openValue(value string) (*secure.Buffer, error) {
+	if !isSealed(value) {
+		return secure.FromString(value), nil
+	}
+
+	return secure.Open([]byte(strings.TrimPrefix(value, SEALED_PREFIX)))
+}
+
+// Returns the plaintext of field in locked memory, such as a password.
+// Destroy it once used.
+func OpenField(field EntryField) (*secure.Buffer, error) {
+	return openValue(field.Value.Content)
+}
+
+// Returns the plaintext of field. Prefer OpenField for protected values,
+// since strings cannot be overwritten.
+func FieldContent(field EntryField) string {
+	buffer, err := OpenField(field)
+	if err != nil {
+		return ""
+	}
+	defer buffer.Destroy()
+
+	return string(buffer.Bytes())
+}
+
+// Returns the plaintext value of key, or an empty string if it is missing
+func (e *Entry) GetContent(key string) string {
+	if v := e.Get(key); v != nil {
+		return FieldContent(*v)
+	}
+
+	return ""
+}
+
+// Returns the plaintext password of the entry
+func (e *Entry) GetPassword() string {
+	return e.GetContent(PASSWORD_KEY)
+}
+
+// Seals the protected values unlocked by the library, including those of
+// the history. Titles are kept as they are, since they build the paths.
+func (d *Database) sealProtectedValues() {
+	d.eachValue(func(value *gokeepasslib.ValueData) error {
+		if value.Value.Protected.Bool && value.Key != TITLE_KEY {
+			value.Value.Content = sealValue(value.Value.Content)
+		}
+		return nil
+	})
+}
+
+// Replaces the sealed values with their plaintext, which the library
+// encrypts on save
+func (d *Database) openProtectedValues() error {
+	return d.eachValue(func(value *gokeepasslib.ValueData) error {
+		if !isSealed(value.Value.Content) {
+			return nil
+		}
+
+		buffer, err := openValue(value.Value.Content)
+		if err != nil {
+			return errors.MakeError("Cannot open protected value of "+value.Key+": "+err.Error(), "kdbx")
+		}
+		defer buffer.Destroy()
+
+		value.Value.Content = string(buffer.Bytes())
+		return nil
+	})
+}
+
+func (d *Database) eachValue(fn func(value *gokeepasslib.ValueData) error) error {
+	if d.Content == nil || d.Content.Root == nil {
+		return nil
+	}
+
+	for i := range d.Content.Root.Groups {
+		if err := eachGroupValue(&d.Content.Root.Groups[i], fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func eachGroupValue(g *Group, fn func(value *gokeepasslib.ValueData) error) error {
+	for i := range g.Entries {
+		if err := eachEntryValue(&g.Entries[i], fn); err != nil {
+			return err
+		}
+
+		for j := range g.Entries[i].Histories {
+			for k := range g.Entries[i].Histories[j].Entries {
+				if err := eachEntryValue(&g.Entries[i].Histories[j].Entries[k], fn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	for i := range g.Groups {
+		if err := eachGroupValue(&g.Groups[i], fn); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func eachEntryValue(e *gokeepasslib.Entry, fn func(value *gokeepasslib.ValueData) error) error {
+	for i := range e.Values {
+		if err := fn(&e.Values[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func destroy(buffers []*secure.Buffer) {
+	for _, buffer := range buffers {
+		buffer.Destroy()
+	}
+}
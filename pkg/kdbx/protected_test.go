@@ -0,0 +1,92 @@
+package kdbx
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+func TestDatabase_SealsProtectedValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.kdbx")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := makeEntry("Entry")
+	entry.Values = append(entry.Values, gokeepasslib.ValueData{Key: PASSWORD_KEY, Value: gokeepasslib.V{Content: "secret", Protected: wrappers.NewBoolWrapper(true)}})
+
+	group := gokeepasslib.NewGroup()
+	group.Name = "Group"
+	group.Entries = append(group.Entries, *entry.Entry)
+
+	d := &Database{file: file, Database: *gokeepasslib.NewDatabase()}
+	d.Content.Root.Groups = []gokeepasslib.Group{group}
+	if err := d.SetPasswordAndKey("password", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := OpenFromPath(path, "password", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened := db.GetFirstEntryByPath("/Group/Entry")
+	if content := opened.Get(PASSWORD_KEY).Value.Content; strings.Contains(content, "secret") {
+		t.Errorf("expected the password to be sealed in memory, got %q", content)
+	}
+	if got := opened.GetPassword(); got != "secret" {
+		t.Errorf("Entry.GetPassword() = %q, want %q", got, "secret")
+	}
+	if got := opened.GetTitle(); got != "Entry" {
+		t.Errorf("Entry.GetTitle() = %q, want %q", got, "Entry")
+	}
+
+	t.Run("seals values set on protected fields", func(t *testing.T) {
+		opened.SetValue(PASSWORD_KEY, "changed")
+
+		if content := opened.Get(PASSWORD_KEY).Value.Content; strings.Contains(content, "changed") {
+			t.Errorf("expected the password to be sealed in memory, got %q", content)
+		}
+		if got := opened.GetPassword(); got != "changed" {
+			t.Errorf("Entry.GetPassword() = %q, want %q", got, "changed")
+		}
+	})
+
+	t.Run("saves the plaintext and seals it again", func(t *testing.T) {
+		if err := db.SaveAndUnlockEntries(); err != nil {
+			t.Fatal(err)
+		}
+
+		if content := opened.Get(PASSWORD_KEY).Value.Content; !isSealed(content) {
+			t.Errorf("expected the password to be sealed after saving, got %q", content)
+		}
+
+		reopened, err := OpenFromPath(path, "password", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := reopened.GetFirstEntryByPath("/Group/Entry").GetPassword(); got != "changed" {
+			t.Errorf("Entry.GetPassword() = %q, want %q", got, "changed")
+		}
+	})
+}
+
+func TestFieldContent(t *testing.T) {
+	field := EntryField{Key: "Plain", Value: gokeepasslib.V{Content: "value"}}
+	if got := FieldContent(field); got != "value" {
+		t.Errorf("FieldContent() = %q, want %q", got, "value")
+	}
+
+	field.Value.Content = sealValue("value")
+	if got := FieldContent(field); got != "value" {
+		t.Errorf("FieldContent() = %q, want %q", got, "value")
+	}
+}
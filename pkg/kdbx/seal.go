@@ -0,0 +1,82 @@
+package kdbx
+
+import (
+	"bytes"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// Encrypts a copy of entry with the credentials of the database, so that it
+// can be kept in memory once the database is wiped. Use UnsealEntry on the
+// database unlocked again to read it back.
+func (d *Database) SealEntry(entry *Entry) ([]byte, error) {
+	if d.Credentials == nil {
+		return nil, errors.MakeError("Cannot seal without credentials.", "kdbx")
+	}
+
+	// Locking protected values encrypts them in place
+	copied := entry.Clone()
+	copied.UUID = entry.UUID
+
+	group := gokeepasslib.NewGroup()
+	group.Entries = append(group.Entries, copied)
+
+	sealed := gokeepasslib.NewDatabase()
+	sealed.Credentials = d.Credentials
+	sealed.Content.Root.Groups = []gokeepasslib.Group{group}
+
+	if err := sealed.LockProtectedEntries(); err != nil {
+		return nil, errors.MakeError("Cannot seal entry: "+err.Error(), "kdbx")
+	}
+
+	buffer := bytes.Buffer{}
+	if err := gokeepasslib.NewEncoder(&buffer).Encode(sealed); err != nil {
+		return nil, errors.MakeError("Cannot seal entry: "+err.Error(), "kdbx")
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// Decrypts an entry encrypted by SealEntry with the same credentials
+func (d *Database) UnsealEntry(data []byte) (*Entry, error) {
+	if d.Credentials == nil {
+		return nil, errors.MakeError("Cannot unseal without credentials.", "kdbx")
+	}
+
+	sealed := gokeepasslib.NewDatabase()
+	sealed.Credentials = d.Credentials
+
+	if err := gokeepasslib.NewDecoder(bytes.NewReader(data)).Decode(sealed); err != nil {
+		return nil, errors.MakeError("Cannot unseal entry: "+err.Error(), "kdbx")
+	}
+
+	if err := sealed.UnlockProtectedEntries(); err != nil {
+		return nil, errors.MakeError("Cannot unseal entry: "+err.Error(), "kdbx")
+	}
+
+	groups := sealed.Content.Root.Groups
+	if len(groups) == 0 || len(groups[0].Entries) == 0 {
+		return nil, errors.MakeError("Cannot unseal entry: no entry found.", "kdbx")
+	}
+
+	return &Entry{&groups[0].Entries[0]}, nil
+}
+
+// Overwrites the credentials and drops the content of the database, which
+// cannot be used anymore. Open the file again to read it.
+func (d *Database) Wipe() {
+	if c := d.Credentials; c != nil {
+		clear(c.Passphrase)
+		clear(c.Key)
+		clear(c.Windows)
+	}
+	destroy(d.secrets)
+	d.secrets = nil
+
+	d.Credentials = nil
+	d.Content = nil
+	if d.file != nil {
+		d.file.Close()
+	}
+}
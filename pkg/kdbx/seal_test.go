@@ -0,0 +1,65 @@
+package kdbx
+
+import (
+	"testing"
+
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+func TestDatabase_SealEntry(t *testing.T) {
+	db := makeDatabase("test.kdbx")
+	db.SetPasswordAndKey("password", "")
+
+	entry := makeEntry("Entry")
+	entry.Values = append(entry.Values, gokeepasslib.ValueData{Key: PASSWORD_KEY, Value: gokeepasslib.V{Content: "secret", Protected: wrappers.NewBoolWrapper(true)}})
+
+	sealed, err := db.SealEntry(&entry)
+	if err != nil {
+		t.Fatalf("Database.SealEntry() error = %v", err)
+	}
+
+	if entry.GetPassword() != "secret" {
+		t.Errorf("Database.SealEntry() changed the entry, password = %v", entry.GetPassword())
+	}
+
+	t.Run("unseals with the same credentials", func(t *testing.T) {
+		other := makeDatabase("test.kdbx")
+		other.SetPasswordAndKey("password", "")
+
+		got, err := other.UnsealEntry(sealed)
+		if err != nil {
+			t.Fatalf("Database.UnsealEntry() error = %v", err)
+		}
+		if got.UUID != entry.UUID || got.GetTitle() != "Entry" || got.GetPassword() != "secret" {
+			t.Errorf("Database.UnsealEntry() = %v, want %v", got.Values, entry.Values)
+		}
+	})
+
+	t.Run("fails with other credentials", func(t *testing.T) {
+		other := makeDatabase("test.kdbx")
+		other.SetPasswordAndKey("wrong", "")
+
+		if _, err := other.UnsealEntry(sealed); err == nil {
+			t.Errorf("Database.UnsealEntry() succeeded with wrong credentials")
+		}
+	})
+}
+
+func TestDatabase_Wipe(t *testing.T) {
+	db := makeDatabase("test.kdbx")
+	db.SetPasswordAndKey("password", "")
+	// The passphrase hash lives in locked memory, which is released
+	secrets := db.secrets
+
+	db.Wipe()
+
+	for _, secret := range secrets {
+		if secret.Bytes() != nil {
+			t.Fatalf("Database.Wipe() left the passphrase hash in memory")
+		}
+	}
+	if db.Credentials != nil || db.Content != nil {
+		t.Errorf("Database.Wipe() kept credentials or content")
+	}
+}
@@ -0,0 +1,269 @@
+package kdbx
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+// Name of the group holding the templates of a database, when its metadata
+// does not point to one
+const TEMPLATES_GROUP = "Templates"
+
+// Template used when none is chosen, unless DefaultTemplate says otherwise
+const DEFAULT_TEMPLATE = "Login"
+
+// Title of entries whose template does not set one
+const NEW_ENTRY_TITLE = "New"
+
+// Generator profile using the password defaults
+const DEFAULT_GENERATOR = "default"
+
+// Values of template entries starting with this placeholder are generated.
+// "{NEWPASSWORD}" uses the default profile, "{NEWPASSWORD:name}" the profile
+// called name.
+const GENERATOR_PLACEHOLDER = "{NEWPASSWORD"
+
+// Template describes the fields of new entries
+type Template struct {
+	Name   string
+	Fields []TemplateField
+}
+
+type TemplateField struct {
+	Key       string
+	Value     string
+	Protected bool
+	// Name of the generator profile filling the value, if any
+	Generator string
+}
+
+var BUILTIN_TEMPLATES = []Template{
+	{Name: DEFAULT_TEMPLATE, Fields: []TemplateField{
+		{Key: USERNAME_KEY, Value: "user"},
+		{Key: PASSWORD_KEY, Protected: true, Generator: DEFAULT_GENERATOR},
+		{Key: URL_KEY},
+		{Key: NOTES_KEY},
+	}},
+	{Name: "Secure Note", Fields: []TemplateField{
+		{Key: NOTES_KEY},
+	}},
+	{Name: "SSH Key", Fields: []TemplateField{
+		{Key: USERNAME_KEY},
+		{Key: PASSWORD_KEY, Protected: true},
+		{Key: "PrivateKey", Protected: true},
+		{Key: "PublicKey"},
+		{Key: NOTES_KEY},
+	}},
+	{Name: "Credit Card", Fields: []TemplateField{
+		{Key: "Cardholder"},
+		{Key: "Number", Protected: true},
+		{Key: "Expiry"},
+		{Key: "CVV", Protected: true},
+		{Key: "PIN", Protected: true},
+		{Key: NOTES_KEY},
+	}},
+	{Name: "Wi-Fi", Fields: []TemplateField{
+		{Key: "SSID"},
+		{Key: PASSWORD_KEY, Protected: true, Generator: DEFAULT_GENERATOR},
+		{Key: "Security", Value: "WPA2"},
+		{Key: NOTES_KEY},
+	}},
+	{Name: "API Token", Fields: []TemplateField{
+		{Key: USERNAME_KEY},
+		{Key: PASSWORD_KEY, Protected: true, Generator: "alphanumeric"},
+		{Key: URL_KEY},
+		{Key: "Expires"},
+		{Key: NOTES_KEY},
+	}},
+	{Name: "Database Connection", Fields: []TemplateField{
+		{Key: "Host", Value: "localhost"},
+		{Key: "Port"},
+		{Key: "Database"},
+		{Key: USERNAME_KEY},
+		{Key: PASSWORD_KEY, Protected: true, Generator: DEFAULT_GENERATOR},
+		{Key: NOTES_KEY},
+	}},
+}
+
+var BUILTIN_GENERATOR_PROFILES = map[string]PasswordOptions{
+	"alphanumeric": {Length: 32, Lowercase: true, Uppercase: true, Digits: true},
+}
+
+// Templates defined by the user, on top of the built-in ones
+var CustomTemplates = []Template{}
+
+// Generator profiles defined by the user, on top of the built-in ones
+var GeneratorProfiles = map[string]PasswordOptions{}
+
+// Name of the template used when none is chosen
+var DefaultTemplate = DEFAULT_TEMPLATE
+
+// Returns the options of the named generator profile
+func GetGeneratorProfile(name string) (PasswordOptions, error) {
+	if name == DEFAULT_GENERATOR {
+		return PasswordDefaults, nil
+	}
+
+	if options, ok := GeneratorProfiles[name]; ok {
+		return options, nil
+	}
+
+	if options, ok := BUILTIN_GENERATOR_PROFILES[name]; ok {
+		return options, nil
+	}
+
+	return PasswordOptions{}, errors.MakeTypedError(errors.ErrNotFound, `Unknown generator profile "`+name+`".`, "kdbx")
+}
+
+// Returns the templates available for new entries: the built-in ones, those
+// defined by the user, and those of the database, in this order. Templates
+// with the name of a previous one replace it. The default template comes
+// first.
+func (d *Database) GetTemplates() []Template {
+	templates := []Template{}
+
+	for _, t := range slices.Concat(BUILTIN_TEMPLATES, CustomTemplates, d.databaseTemplates()) {
+		index := slices.IndexFunc(templates, func(other Template) bool { return strings.EqualFold(other.Name, t.Name) })
+		if index < 0 {
+			templates = append(templates, t)
+		} else {
+			templates[index] = t
+		}
+	}
+
+	if index := slices.IndexFunc(templates, func(t Template) bool { return strings.EqualFold(t.Name, DefaultTemplate) }); index > 0 {
+		t := templates[index]
+		templates = slices.Insert(slices.Delete(templates, index, index+1), 0, t)
+	}
+
+	return templates
+}
+
+// Returns the template called name, ignoring case
+func (d *Database) GetTemplate(name string) (Template, error) {
+	templates := d.GetTemplates()
+	for _, t := range templates {
+		if strings.EqualFold(t.Name, name) {
+			return t, nil
+		}
+	}
+
+	names := []string{}
+	for _, t := range templates {
+		names = append(names, t.Name)
+	}
+
+	return Template{}, errors.MakeTypedError(errors.ErrNotFound, `Unknown template "`+name+`". Available templates are: `+strings.Join(names, ", ")+".", "kdbx")
+}
+
+// Returns a new entry with the fields of template. Generated values are
+// filled with the generator profile of their field.
+func (d *Database) NewEntryFromTemplate(template Template) (*Entry, error) {
+	e := gokeepasslib.NewEntry()
+	entry := &Entry{&e}
+
+	if !slices.ContainsFunc(template.Fields, func(f TemplateField) bool { return f.Key == TITLE_KEY }) {
+		entry.SetValue(TITLE_KEY, NEW_ENTRY_TITLE)
+	}
+
+	for _, f := range template.Fields {
+		value := f.Value
+		if f.Generator != "" {
+			options, err := GetGeneratorProfile(f.Generator)
+			if err != nil {
+				return nil, err
+			}
+
+			if value, err = GeneratePassword(options); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := entry.AddField(f.Key, f.Protected && f.Key != TITLE_KEY); err != nil {
+			return nil, err
+		}
+		entry.SetValue(f.Key, value)
+	}
+
+	return entry, nil
+}
+
+// Returns the templates stored as entries of the templates group
+func (d *Database) databaseTemplates() []Template {
+	group := d.getTemplatesGroup()
+	if group == nil {
+		return nil
+	}
+
+	templates := []Template{}
+	for i := range group.Entries {
+		entry := &Entry{&group.Entries[i]}
+		template := Template{Name: entry.GetTitle()}
+
+		for _, v := range entry.Values {
+			// The title names the template, not the entries made from it
+			if v.Key == TITLE_KEY {
+				continue
+			}
+
+			f := TemplateField{Key: v.Key, Value: FieldContent(v), Protected: v.Value.Protected.Bool}
+			if profile, ok := parseGeneratorPlaceholder(f.Value); ok {
+				f.Value, f.Generator = "", profile
+			}
+			template.Fields = append(template.Fields, f)
+		}
+
+		templates = append(templates, template)
+	}
+
+	return templates
+}
+
+// Returns the generator profile of placeholders like "{NEWPASSWORD:name}"
+func parseGeneratorPlaceholder(value string) (string, bool) {
+	rest, ok := strings.CutPrefix(value, GENERATOR_PLACEHOLDER)
+	if !ok || !strings.HasSuffix(rest, "}") {
+		return "", false
+	}
+
+	rest = strings.TrimSuffix(rest, "}")
+	if rest == "" {
+		return DEFAULT_GENERATOR, true
+	}
+
+	name, ok := strings.CutPrefix(rest, ":")
+	return name, ok && name != ""
+}
+
+// Returns the group the metadata marks as holding templates or, like other
+// clients, a top-level group called "Templates"
+func (d *Database) getTemplatesGroup() *Group {
+	if d.Content == nil {
+		return nil
+	}
+
+	if d.Content.Meta != nil && d.Content.Meta.EntryTemplatesGroup != "" {
+		var uuid UUID
+		if err := uuid.UnmarshalText([]byte(d.Content.Meta.EntryTemplatesGroup)); err == nil {
+			if group := d.GetGroup(uuid); group != nil {
+				return group
+			}
+		}
+	}
+
+	root := d.GetRootGroup()
+	if root == nil {
+		return nil
+	}
+
+	for i := range root.Groups {
+		if root.Groups[i].Name == TEMPLATES_GROUP {
+			return &root.Groups[i]
+		}
+	}
+
+	return nil
+}
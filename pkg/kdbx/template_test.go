@@ -0,0 +1,188 @@
+package kdbx
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+)
+
+func makeTemplateEntry(title string, values map[string]string) Entry {
+	entry := makeEntry(title)
+	for key, value := range values {
+		entry.Values = append(entry.Values, gokeepasslib.ValueData{Key: key, Value: gokeepasslib.V{Content: value, Protected: wrappers.NewBoolWrapper(key == PASSWORD_KEY)}})
+	}
+	return entry
+}
+
+func templateNames(templates []Template) []string {
+	names := []string{}
+	for _, t := range templates {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func TestDatabase_NewEntryFromTemplate(t *testing.T) {
+	db := makeDatabase("test.kdbx", makeGroup("Root"))
+
+	t.Run("sets the fields of the template", func(t *testing.T) {
+		template := Template{Name: "Server", Fields: []TemplateField{
+			{Key: "Host", Value: "localhost"},
+			{Key: PASSWORD_KEY, Protected: true, Generator: "alphanumeric"},
+		}}
+
+		entry, err := db.NewEntryFromTemplate(template)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.GetTitle() != NEW_ENTRY_TITLE || entry.GetContent("Host") != "localhost" {
+			t.Errorf("NewEntryFromTemplate() title = %q, host = %q", entry.GetTitle(), entry.GetContent("Host"))
+		}
+
+		password := entry.Get(PASSWORD_KEY)
+		if !password.Value.Protected.Bool || !isSealed(password.Value.Content) {
+			t.Errorf("NewEntryFromTemplate() password is not protected")
+		}
+		if got := entry.GetPassword(); len(got) != 32 || strings.ContainsAny(got, SYMBOL_CHARACTERS) {
+			t.Errorf("NewEntryFromTemplate() password = %q, want 32 alphanumeric characters", got)
+		}
+	})
+
+	t.Run("keeps the title of the template", func(t *testing.T) {
+		entry, err := db.NewEntryFromTemplate(Template{Fields: []TemplateField{{Key: TITLE_KEY, Value: "Server"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.GetTitle() != "Server" || len(entry.Values) != 1 {
+			t.Errorf("NewEntryFromTemplate() title = %q, fields = %d", entry.GetTitle(), len(entry.Values))
+		}
+	})
+
+	t.Run("uses templates of the database", func(t *testing.T) {
+		root := makeGroup("Root")
+		root.Groups = append(root.Groups, makeGroup(TEMPLATES_GROUP,
+			makeTemplateEntry("Server", map[string]string{"Host": "localhost", PASSWORD_KEY: "{NEWPASSWORD:alphanumeric}"}),
+		))
+		db := makeDatabase("test.kdbx", root)
+
+		template, err := db.GetTemplate("Server")
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, err := db.NewEntryFromTemplate(template)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.GetTitle() != NEW_ENTRY_TITLE || entry.GetContent("Host") != "localhost" {
+			t.Errorf("NewEntryFromTemplate() title = %q, host = %q", entry.GetTitle(), entry.GetContent("Host"))
+		}
+		if got := entry.GetPassword(); len(got) != 32 {
+			t.Errorf("NewEntryFromTemplate() password = %q, want a generated one", got)
+		}
+	})
+
+	t.Run("fails on unknown generator profiles", func(t *testing.T) {
+		_, err := db.NewEntryFromTemplate(Template{Fields: []TemplateField{{Key: PASSWORD_KEY, Generator: "missing"}}})
+		if !errors.Is(err, errors.ErrNotFound) {
+			t.Errorf("NewEntryFromTemplate() error = %v, want not found", err)
+		}
+	})
+
+	t.Run("uses custom generator profiles", func(t *testing.T) {
+		GeneratorProfiles = map[string]PasswordOptions{"pin": {Length: 4, Digits: true}}
+		defer func() { GeneratorProfiles = map[string]PasswordOptions{} }()
+
+		entry, err := db.NewEntryFromTemplate(Template{Fields: []TemplateField{{Key: "PIN", Generator: "pin"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := entry.GetContent("PIN"); len(got) != 4 || strings.Trim(got, DIGIT_CHARACTERS) != "" {
+			t.Errorf("NewEntryFromTemplate() PIN = %q", got)
+		}
+	})
+}
+
+func TestDatabase_GetTemplates(t *testing.T) {
+	t.Run("returns the built-in templates", func(t *testing.T) {
+		db := makeDatabase("test.kdbx", makeGroup("Root"))
+
+		got := templateNames(db.GetTemplates())
+		want := templateNames(BUILTIN_TEMPLATES)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("GetTemplates() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("adds custom and database templates", func(t *testing.T) {
+		root := makeGroup("Root")
+		root.Groups = append(root.Groups, makeGroup(TEMPLATES_GROUP,
+			makeTemplateEntry("Server", map[string]string{PASSWORD_KEY: "{NEWPASSWORD:alphanumeric}"}),
+			makeTemplateEntry("login", map[string]string{"Site": ""}),
+		))
+		db := makeDatabase("test.kdbx", root)
+
+		CustomTemplates = []Template{{Name: "Router", Fields: []TemplateField{{Key: "IP"}}}}
+		DefaultTemplate = "Router"
+		defer func() {
+			CustomTemplates = []Template{}
+			DefaultTemplate = DEFAULT_TEMPLATE
+		}()
+
+		templates := db.GetTemplates()
+		if templates[0].Name != "Router" {
+			t.Errorf("GetTemplates() starts with %q, want the default template", templates[0].Name)
+		}
+
+		login, err := db.GetTemplate("LOGIN")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if login.Name != "login" || len(templates) != len(BUILTIN_TEMPLATES)+2 {
+			t.Errorf("GetTemplate() = %q out of %d templates, want the database one", login.Name, len(templates))
+		}
+
+		server, err := db.GetTemplate("Server")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if password := server.Fields[0]; password.Generator != "alphanumeric" || password.Value != "" || !password.Protected {
+			t.Errorf("GetTemplate() password = %+v, want a generated value", password)
+		}
+	})
+
+	t.Run("fails on unknown templates", func(t *testing.T) {
+		db := makeDatabase("test.kdbx", makeGroup("Root"))
+		if _, err := db.GetTemplate("Missing"); !errors.Is(err, errors.ErrNotFound) {
+			t.Errorf("GetTemplate() error = %v, want not found", err)
+		}
+	})
+}
+
+func TestParseGeneratorPlaceholder(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"{NEWPASSWORD}", DEFAULT_GENERATOR, false},
+		{"{NEWPASSWORD:pin}", "pin", false},
+		{"{NEWPASSWORD:}", "", true},
+		{"{NEWPASSWORDS}", "", true},
+		{"password", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseGeneratorPlaceholder(tt.value)
+			if ok == tt.wantErr || (ok && got != tt.want) {
+				t.Errorf("parseGeneratorPlaceholder() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
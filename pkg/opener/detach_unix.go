@@ -0,0 +1,11 @@
+//go:build !windows
+
+package opener
+
+import "syscall"
+
+// Runs the process in a new session, so that it survives the parent
+// and it is not affected by signals sent to the terminal
+func detachedProcessAttributes() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
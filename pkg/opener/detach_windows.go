@@ -0,0 +1,15 @@
+//go:build windows
+
+package opener
+
+import "syscall"
+
+const createNewProcessGroup = 0x00000200
+
+// Runs the process in a new process group, so that it survives the parent
+func detachedProcessAttributes() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{
+		CreationFlags: createNewProcessGroup,
+		HideWindow:    true,
+	}
+}
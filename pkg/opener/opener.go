@@ -0,0 +1,176 @@
+package opener
+
+import (
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode"
+
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+// Scheme of KeePass URLs running a command line, such as "cmd://putty host"
+const COMMAND_SCHEME = "cmd://"
+
+type Options struct {
+	// Command line opening URLs, which receives the URL as last argument.
+	// It is split on spaces, and not run through the shell. Empty means
+	// the one of the platform, such as xdg-open.
+	Opener string
+	// Runs cmd:// URLs. They are refused otherwise, since anyone editing
+	// the database could run commands on this machine.
+	AllowCommands bool
+}
+
+// Matches placeholders such as {USERNAME} or {S:Custom Field}
+var placeholder = regexp.MustCompile(`\{(TITLE|USERNAME|URL|S:[^}]+)\}`)
+
+// Matches schemes such as "https:" or "mailto:", but not ports as in
+// "localhost:8080"
+var scheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:[^0-9]`)
+
+var PLACEHOLDER_FIELDS = map[string]string{
+	"TITLE":    "Title",
+	"USERNAME": "UserName",
+	"URL":      "URL",
+}
+
+// Returns raw ready to be opened: placeholders such as {USERNAME} or
+// {S:Name} are replaced by the values in fields, and addresses without a
+// scheme are opened over HTTPS. Only pass fields which are not protected:
+// URLs end up on command lines, which other processes can read.
+//
+// In cmd:// URLs, values are quoted, so that each is a single argument
+// whatever it holds.
+func Resolve(raw string, fields map[string]string) (string, error) {
+	url := strings.TrimSpace(raw)
+	if url == "" {
+		return "", errors.MakeTypedError(errors.ErrNotFound, "Missing URL.", "opener")
+	}
+	isCommand := IsCommand(url)
+
+	url = placeholder.ReplaceAllStringFunc(url, func(match string) string {
+		name := strings.Trim(match, "{}")
+		key, ok := PLACEHOLDER_FIELDS[name]
+		if !ok {
+			key = strings.TrimPrefix(name, "S:")
+		}
+
+		// Placeholders of fields left out are kept, so that the URL reads as
+		// a broken one rather than a different one
+		if value, ok := fields[key]; ok && isCommand {
+			return quote(value)
+		} else if ok {
+			return value
+		}
+		return match
+	})
+
+	if !isCommand && !scheme.MatchString(url) {
+		url = "https://" + url
+	}
+
+	return url, nil
+}
+
+// Returns value as a single argument of a command line
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'"'"'`) + "'"
+}
+
+// Splits commandLine into arguments on spaces. Single and double quotes
+// keep spaces in arguments, and nothing else is interpreted: there are no
+// variables, escapes, pipes, or redirections.
+func splitCommandLine(commandLine string) ([]string, error) {
+	args := []string{}
+	current := &strings.Builder{}
+	inArgument := false
+	var quote rune
+
+	for _, char := range commandLine {
+		switch {
+		case quote != 0 && char == quote:
+			quote = 0
+		case quote != 0:
+			current.WriteRune(char)
+		case char == '\'' || char == '"':
+			quote, inArgument = char, true
+		case unicode.IsSpace(char):
+			if inArgument {
+				args = append(args, current.String())
+				current.Reset()
+				inArgument = false
+			}
+		default:
+			current.WriteRune(char)
+			inArgument = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.MakeError("Cannot run command: unbalanced quotes.", "opener")
+	}
+	if inArgument {
+		args = append(args, current.String())
+	}
+	if len(args) == 0 {
+		return nil, errors.MakeError("Cannot run command: missing command.", "opener")
+	}
+
+	return args, nil
+}
+
+// Returns true for cmd:// URLs
+func IsCommand(url string) bool {
+	return len(url) >= len(COMMAND_SCHEME) && strings.EqualFold(url[:len(COMMAND_SCHEME)], COMMAND_SCHEME)
+}
+
+// Opens url with the opener or, for cmd:// URLs, runs its command line,
+// split as splitCommandLine does. Neither goes through the shell. It does
+// not wait for the program to exit.
+func Open(url string, options Options) error {
+	var command *exec.Cmd
+
+	switch {
+	case IsCommand(url) && !options.AllowCommands:
+		return errors.MakeError("Running cmd:// URLs is not allowed.", "opener")
+	case IsCommand(url):
+		argv, err := splitCommandLine(url[len(COMMAND_SCHEME):])
+		if err != nil {
+			return err
+		}
+		command = exec.Command(argv[0], argv[1:]...)
+	default:
+		argv := platformOpener()
+		if custom := strings.Fields(options.Opener); len(custom) > 0 {
+			argv = custom
+		}
+		// The URL is an argument of its own: through the shell, it could
+		// run commands
+		command = exec.Command(argv[0], append(argv[1:], url)...)
+	}
+
+	// Programs are not attached to the terminal, which might be in use by
+	// the editor
+	command.SysProcAttr = detachedProcessAttributes()
+
+	if err := command.Start(); err != nil {
+		return errors.MakeError("Cannot open URL: "+err.Error(), "opener")
+	}
+
+	// Reaps the process once it exits
+	go command.Wait()
+	return nil
+}
+
+// Returns the command line opening URLs with the default programs
+func platformOpener() []string {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"open"}
+	case "windows":
+		return []string{"rundll32", "url.dll,FileProtocolHandler"}
+	}
+	return []string{"xdg-open"}
+}
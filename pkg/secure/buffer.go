@@ -0,0 +1,72 @@
+package secure
+
+import (
+	"crypto/subtle"
+)
+
+// Buffer holds a secret outside of the garbage collected heap, so that it is
+// never copied around. Where the platform allows it, its memory is locked
+// into RAM and excluded from core dumps. Destroy overwrites it.
+type Buffer struct {
+	data []byte
+	// The whole allocation, which is larger than data when mapped
+	memory []byte
+	mapped bool
+}
+
+// Returns a zeroed buffer of size bytes
+func NewBuffer(size int) *Buffer {
+	if size == 0 {
+		return &Buffer{data: []byte{}}
+	}
+
+	memory, mapped := allocate(size)
+	return &Buffer{data: memory[:size], memory: memory, mapped: mapped}
+}
+
+// Returns a buffer holding data, which is overwritten
+func FromBytes(data []byte) *Buffer {
+	b := NewBuffer(len(data))
+	copy(b.data, data)
+	clear(data)
+	return b
+}
+
+// Returns a buffer holding s. Strings cannot be overwritten: use it only
+// for values which are already strings, such as environment variables.
+func FromString(s string) *Buffer {
+	b := NewBuffer(len(s))
+	copy(b.data, s)
+	return b
+}
+
+// Returns the content of the buffer. It must not be used after Destroy.
+func (b *Buffer) Bytes() []byte {
+	if b == nil {
+		return nil
+	}
+	return b.data
+}
+
+func (b *Buffer) Len() int {
+	return len(b.Bytes())
+}
+
+// Returns true if the buffers hold the same content, in constant time
+func (b *Buffer) Equal(other *Buffer) bool {
+	return subtle.ConstantTimeCompare(b.Bytes(), other.Bytes()) == 1
+}
+
+// Overwrites and releases the buffer. It is safe to call more than once.
+func (b *Buffer) Destroy() {
+	if b == nil || b.data == nil {
+		return
+	}
+
+	clear(b.memory)
+	if b.memory != nil {
+		release(b.memory, b.mapped)
+	}
+
+	b.data, b.memory = nil, nil
+}
@@ -0,0 +1,7 @@
+//go:build darwin
+
+package secure
+
+// Core dumps are disabled by default on macOS, and there is no way to
+// exclude single pages from them
+func excludeFromDumps(memory []byte) {}
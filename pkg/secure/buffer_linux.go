@@ -0,0 +1,9 @@
+//go:build linux
+
+package secure
+
+import "golang.org/x/sys/unix"
+
+func excludeFromDumps(memory []byte) {
+	_ = unix.Madvise(memory, unix.MADV_DONTDUMP)
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package secure
+
+// Memory cannot be locked on this platform: buffers live on the heap, and
+// are only overwritten on Destroy
+func allocate(size int) ([]byte, bool) {
+	return make([]byte, size), false
+}
+
+func release(memory []byte, mapped bool) {}
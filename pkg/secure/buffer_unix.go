@@ -0,0 +1,36 @@
+//go:build linux || darwin
+
+package secure
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Maps whole pages, which the garbage collector never moves, and locks them
+// into RAM. Falls back to the heap when mapping fails.
+func allocate(size int) ([]byte, bool) {
+	pageSize := os.Getpagesize()
+	length := (size + pageSize - 1) / pageSize * pageSize
+
+	memory, err := unix.Mmap(-1, 0, length, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return make([]byte, size), false
+	}
+
+	// Locking fails past RLIMIT_MEMLOCK, and the memory is still usable
+	_ = unix.Mlock(memory)
+	excludeFromDumps(memory)
+
+	return memory, true
+}
+
+func release(memory []byte, mapped bool) {
+	if !mapped {
+		return
+	}
+
+	_ = unix.Munlock(memory)
+	_ = unix.Munmap(memory)
+}
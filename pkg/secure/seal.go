@@ -0,0 +1,67 @@
+package secure
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"sync"
+
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+const KEY_SIZE = 32
+
+var (
+	aead     cipher.AEAD
+	aeadOnce sync.Once
+	// The key never leaves locked memory. The cipher keeps its own
+	// expanded copy on the heap, which is unavoidable.
+	key *Buffer
+)
+
+func getAEAD() cipher.AEAD {
+	aeadOnce.Do(func() {
+		key = NewBuffer(KEY_SIZE)
+		if _, err := rand.Read(key.Bytes()); err != nil {
+			panic("cannot generate sealing key: " + err.Error())
+		}
+
+		block, _ := aes.NewCipher(key.Bytes())
+		aead, _ = cipher.NewGCM(block)
+	})
+
+	return aead
+}
+
+// Encrypts plaintext with a key generated for the process, so that the
+// result can be kept in ordinary memory. Use Open to read it back.
+func Seal(plaintext []byte) []byte {
+	aead := getAEAD()
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic("cannot generate nonce: " + err.Error())
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, nil)
+}
+
+// Decrypts a value encrypted by Seal into a new buffer
+func Open(sealed []byte) (*Buffer, error) {
+	aead := getAEAD()
+
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, errors.MakeError("Cannot open sealed value: too short.", "secure")
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	buffer := NewBuffer(len(ciphertext) - aead.Overhead())
+
+	// Decrypts into the buffer, which has the capacity for the plaintext
+	if _, err := aead.Open(buffer.Bytes()[:0], nonce, ciphertext, nil); err != nil {
+		buffer.Destroy()
+		return nil, errors.MakeError("Cannot open sealed value: "+err.Error(), "secure")
+	}
+
+	return buffer, nil
+}
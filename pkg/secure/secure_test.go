@@ -0,0 +1,60 @@
+package secure
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFromBytes(t *testing.T) {
+	data := []byte("secret")
+	b := FromBytes(data)
+
+	if string(b.Bytes()) != "secret" {
+		t.Errorf("FromBytes() = %q, want %q", b.Bytes(), "secret")
+	}
+	if !bytes.Equal(data, make([]byte, len(data))) {
+		t.Errorf("FromBytes() did not overwrite its argument, got %q", data)
+	}
+
+	b.Destroy()
+	b.Destroy()
+	if b.Bytes() != nil {
+		t.Errorf("Buffer.Bytes() = %q after Destroy, want nil", b.Bytes())
+	}
+}
+
+func TestBuffer_Equal(t *testing.T) {
+	if !FromString("secret").Equal(FromString("secret")) {
+		t.Errorf("Buffer.Equal() = false for equal buffers")
+	}
+	if FromString("secret").Equal(FromString("other")) {
+		t.Errorf("Buffer.Equal() = true for different buffers")
+	}
+	if !NewBuffer(0).Equal(nil) {
+		t.Errorf("Buffer.Equal() = false for empty buffers")
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	for _, plaintext := range []string{"", "secret", string(bytes.Repeat([]byte("a"), 10000))} {
+		sealed := Seal([]byte(plaintext))
+		if plaintext != "" && bytes.Contains(sealed, []byte(plaintext)) {
+			t.Errorf("Seal() leaked the plaintext")
+		}
+
+		opened, err := Open(sealed)
+		if err != nil || string(opened.Bytes()) != plaintext {
+			t.Errorf("Open() = %q, %v, want %q", opened.Bytes(), err, plaintext)
+		}
+	}
+
+	sealed := Seal([]byte("secret"))
+	sealed[len(sealed)-1] ^= 1
+	if _, err := Open(sealed); err == nil {
+		t.Errorf("Open() accepted a tampered value")
+	}
+
+	if _, err := Open([]byte("short")); err == nil {
+		t.Errorf("Open() accepted a short value")
+	}
+}
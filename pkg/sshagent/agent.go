@@ -0,0 +1,180 @@
+package sshagent
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	keydexagent "github.com/shikaan/keydex/pkg/agent"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/log"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const SOCKET_FILE = "ssh-agent.sock"
+
+// Environment variable with the socket of the running ssh-agent
+const ENV_AUTH_SOCK = "SSH_AUTH_SOCK"
+
+// Asks the user to allow a use of a key, with the program in SSH_ASKPASS
+// like ssh-agent does. Without one, keys needing confirmation are refused.
+var Confirm = func(prompt string) bool {
+	askpass := os.Getenv("SSH_ASKPASS")
+	if askpass == "" {
+		log.Info("Refused to use a key needing confirmation: SSH_ASKPASS is not set")
+		return false
+	}
+
+	command := exec.Command(askpass, prompt)
+	command.Env = append(os.Environ(), "SSH_ASKPASS_PROMPT=confirm")
+	return command.Run() == nil
+}
+
+// Agent keeps private keys in memory, and signs with them over the
+// ssh-agent protocol. Keys added with ConfirmBeforeUse are used only once
+// Confirm allows it.
+type Agent struct {
+	keyring agent.ExtendedAgent
+
+	mu sync.Mutex
+	// Comments of the keys needing confirmation, by marshalled public key
+	confirm map[string]string
+}
+
+func NewAgent() *Agent {
+	return &Agent{
+		keyring: agent.NewKeyring().(agent.ExtendedAgent),
+		confirm: map[string]string{},
+	}
+}
+
+// Returns the path of the socket to serve keys on, next to the one of the
+// keydex agent
+func SocketPath() string {
+	return filepath.Join(keydexagent.RuntimeDir(), SOCKET_FILE)
+}
+
+// Serves the agent until the listener is closed. Connections from other
+// users are refused, where the operating system allows it.
+func (a *Agent) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			return errors.MakeError("Cannot accept connections: "+err.Error(), "sshagent")
+		}
+
+		go a.handle(conn)
+	}
+}
+
+func (a *Agent) handle(conn net.Conn) {
+	defer conn.Close()
+
+	if err := keydexagent.VerifyPeer(conn); err != nil {
+		log.Error("Rejected SSH agent connection", err)
+		return
+	}
+
+	// Clients close the connection once done
+	_ = agent.ServeAgent(a, conn)
+}
+
+// Adds keys to the agent listening on socket, such as the one in
+// SSH_AUTH_SOCK
+func AddTo(socket string, keys []Key) error {
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return errors.MakeError("Cannot connect to the SSH agent at "+socket+": "+err.Error(), "sshagent")
+	}
+	defer conn.Close()
+
+	client := agent.NewClient(conn)
+	for _, key := range keys {
+		if err := client.Add(key.AddedKey); err != nil {
+			return errors.MakeError("Cannot add "+key.Reference+" to the SSH agent: "+err.Error(), "sshagent")
+		}
+	}
+
+	return nil
+}
+
+func (a *Agent) List() ([]*agent.Key, error) {
+	return a.keyring.List()
+}
+
+func (a *Agent) Sign(key ssh.PublicKey, data []byte) (*ssh.Signature, error) {
+	return a.SignWithFlags(key, data, 0)
+}
+
+func (a *Agent) SignWithFlags(key ssh.PublicKey, data []byte, flags agent.SignatureFlags) (*ssh.Signature, error) {
+	a.mu.Lock()
+	comment, needsConfirmation := a.confirm[string(key.Marshal())]
+	a.mu.Unlock()
+
+	if needsConfirmation && !Confirm("Allow use of key "+comment+"?\nKey fingerprint "+ssh.FingerprintSHA256(key)+".") {
+		return nil, errors.MakeError("Use of key "+comment+" refused.", "sshagent")
+	}
+
+	return a.keyring.SignWithFlags(key, data, flags)
+}
+
+func (a *Agent) Add(key agent.AddedKey) error {
+	if err := a.keyring.Add(key); err != nil {
+		return err
+	}
+
+	signer, err := ssh.NewSignerFromKey(key.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	publicKey := string(signer.PublicKey().Marshal())
+	if key.ConfirmBeforeUse {
+		a.confirm[publicKey] = key.Comment
+	} else {
+		delete(a.confirm, publicKey)
+	}
+
+	return nil
+}
+
+func (a *Agent) Remove(key ssh.PublicKey) error {
+	a.mu.Lock()
+	delete(a.confirm, string(key.Marshal()))
+	a.mu.Unlock()
+
+	return a.keyring.Remove(key)
+}
+
+func (a *Agent) RemoveAll() error {
+	a.mu.Lock()
+	clear(a.confirm)
+	a.mu.Unlock()
+
+	return a.keyring.RemoveAll()
+}
+
+func (a *Agent) Lock(passphrase []byte) error {
+	return a.keyring.Lock(passphrase)
+}
+
+func (a *Agent) Unlock(passphrase []byte) error {
+	return a.keyring.Unlock(passphrase)
+}
+
+func (a *Agent) Signers() ([]ssh.Signer, error) {
+	return a.keyring.Signers()
+}
+
+func (a *Agent) Extension(extensionType string, contents []byte) ([]byte, error) {
+	return nil, agent.ErrExtensionUnsupported
+}
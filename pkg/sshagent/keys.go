@@ -0,0 +1,173 @@
+package sshagent
+
+import (
+	"crypto/x509"
+	"os"
+	"time"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Constraints applied to every key, on top of those of its settings
+type Constraints struct {
+	// Asks for confirmation before each use of the keys
+	Confirm bool
+	// Time after which keys without a lifetime of their own are removed, 0
+	// to keep them
+	Lifetime time.Duration
+}
+
+// Private key of an entry, ready to be added to an agent
+type Key struct {
+	Reference kdbx.EntityPath
+	agent.AddedKey
+}
+
+// Entry whose key cannot be loaded
+type Failure struct {
+	Reference kdbx.EntityPath
+	Err       error
+}
+
+func (f *Failure) Error() string {
+	return f.Reference + ": " + f.Err.Error()
+}
+
+func (f *Failure) Unwrap() error {
+	return f.Err
+}
+
+// Returns the keys of the entries marked for agent use: those whose KeeAgent
+// settings allow using the key and adding it when the database is opened,
+// and those with a PrivateKey field and no settings. Entries whose key
+// cannot be read are returned as a Failure, and skipped.
+func LoadKeys(db *kdbx.Database, constraints Constraints) (keys []Key, failures []error) {
+	for _, reference := range db.GetEntryPaths() {
+		entry, err := db.GetEntryByPath(reference)
+		if err != nil {
+			// Entries sharing a path are told apart by the user first
+			failures = append(failures, &Failure{reference, err})
+			continue
+		}
+
+		key, err := loadKey(db, entry, constraints)
+		if err != nil {
+			failures = append(failures, &Failure{reference, err})
+			continue
+		}
+
+		if key != nil {
+			key.Reference = reference
+			key.Comment = reference
+			keys = append(keys, *key)
+		}
+	}
+
+	return keys, failures
+}
+
+// Returns the key of entry, or nil if it is not marked for agent use
+func loadKey(db *kdbx.Database, entry *kdbx.Entry, constraints Constraints) (*Key, error) {
+	settings := Settings{Location: Location{SelectedType: LOCATION_ATTACHMENT}}
+
+	data, err := db.GetAttachment(entry, SETTINGS_ATTACHMENT)
+	switch {
+	case errors.Is(err, errors.ErrNotFound):
+		if entry.GetContent(PRIVATE_KEY_FIELD) == "" {
+			return nil, nil
+		}
+		settings.AllowUseOfSshKey, settings.AddAtDatabaseOpen = true, true
+	case err != nil:
+		return nil, err
+	default:
+		if settings, err = ParseSettings(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if !settings.AllowUseOfSshKey || !settings.AddAtDatabaseOpen {
+		return nil, nil
+	}
+
+	pem, err := readPrivateKey(db, entry, settings.Location)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := parsePrivateKey(pem, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{AddedKey: agent.AddedKey{PrivateKey: privateKey}}
+	key.ConfirmBeforeUse = settings.UseConfirmConstraintWhenAdding || constraints.Confirm
+
+	if settings.UseLifetimeConstraintWhenAdding && settings.LifetimeConstraintDuration > 0 {
+		key.LifetimeSecs = settings.LifetimeConstraintDuration
+	} else if constraints.Lifetime > 0 {
+		key.LifetimeSecs = uint32(max(constraints.Lifetime/time.Second, 1))
+	}
+
+	return key, nil
+}
+
+// Reads the private key from the attachment or the file of the settings,
+// falling back to the PrivateKey field
+func readPrivateKey(db *kdbx.Database, entry *kdbx.Entry, location Location) ([]byte, error) {
+	switch {
+	case location.SelectedType == LOCATION_FILE && location.FileName != "":
+		data, err := os.ReadFile(location.FileName)
+		if os.IsNotExist(err) {
+			return nil, errors.MakeTypedError(errors.ErrNotFound, "Missing key file "+location.FileName+".", "sshagent")
+		}
+		if err != nil {
+			return nil, errors.MakeError("Cannot read "+location.FileName+": "+err.Error(), "sshagent")
+		}
+		return data, nil
+	case location.SelectedType == LOCATION_ATTACHMENT && location.AttachmentName != "":
+		return db.GetAttachment(entry, location.AttachmentName)
+	}
+
+	if value := entry.GetContent(PRIVATE_KEY_FIELD); value != "" {
+		return []byte(value), nil
+	}
+
+	return nil, errors.MakeTypedError(errors.ErrNotFound, "Missing private key.", "sshagent")
+}
+
+// Parses a private key, decrypting it with the password of entry if it is
+// protected by a passphrase
+func parsePrivateKey(pem []byte, entry *kdbx.Entry) (any, error) {
+	key, err := ssh.ParseRawPrivateKey(pem)
+	if _, ok := err.(*ssh.PassphraseMissingError); ok {
+		field := entry.Get(kdbx.PASSWORD_KEY)
+		if field == nil {
+			return nil, errors.MakeTypedError(errors.ErrWrongCredentials, "The private key is protected by a passphrase, and the entry has no password.", "sshagent")
+		}
+
+		password, err := kdbx.OpenField(*field)
+		if err != nil {
+			return nil, err
+		}
+		defer password.Destroy()
+
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(pem, password.Bytes())
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, errors.MakeTypedError(errors.ErrWrongCredentials, "The password of the entry does not decrypt the private key.", "sshagent")
+		}
+		if err != nil {
+			return nil, errors.MakeTypedError(errors.ErrCorrupt, "Cannot read private key: "+err.Error(), "sshagent")
+		}
+
+		return key, nil
+	}
+
+	if err != nil {
+		return nil, errors.MakeTypedError(errors.ErrCorrupt, "Cannot read private key: "+err.Error(), "sshagent")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,77 @@
+package sshagent
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"unicode/utf16"
+
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+// Name of the attachment holding the KeeAgent settings of an entry
+const SETTINGS_ATTACHMENT = "KeeAgent.settings"
+
+// Field holding the private key of entries without a key attachment
+const PRIVATE_KEY_FIELD = "PrivateKey"
+
+// Locations of a private key in KeeAgent settings
+const (
+	LOCATION_ATTACHMENT = "attachment"
+	LOCATION_FILE       = "file"
+)
+
+// Settings of an entry, as written by KeeAgent and KeePassXC
+type Settings struct {
+	AllowUseOfSshKey                bool     `xml:"AllowUseOfSshKey"`
+	AddAtDatabaseOpen               bool     `xml:"AddAtDatabaseOpen"`
+	UseConfirmConstraintWhenAdding  bool     `xml:"UseConfirmConstraintWhenAdding"`
+	UseLifetimeConstraintWhenAdding bool     `xml:"UseLifetimeConstraintWhenAdding"`
+	LifetimeConstraintDuration      uint32   `xml:"LifetimeConstraintDuration"`
+	Location                        Location `xml:"Location"`
+}
+
+type Location struct {
+	SelectedType   string `xml:"SelectedType"`
+	AttachmentName string `xml:"AttachmentName"`
+	FileName       string `xml:"FileName"`
+}
+
+// Parses KeeAgent settings. KeeAgent writes them in UTF-16, and KeePassXC
+// in UTF-8 while declaring UTF-16: both are read.
+func ParseSettings(data []byte) (Settings, error) {
+	settings := Settings{}
+
+	decoder := xml.NewDecoder(bytes.NewReader(toUTF8(data)))
+	// The content is UTF-8 by now, whatever the declaration says
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	if err := decoder.Decode(&settings); err != nil {
+		return settings, errors.MakeTypedError(errors.ErrCorrupt, "Cannot read "+SETTINGS_ATTACHMENT+": "+err.Error(), "sshagent")
+	}
+
+	return settings, nil
+}
+
+// Converts data to UTF-8 according to its byte order mark, which is dropped
+func toUTF8(data []byte) []byte {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		order = binary.BigEndian
+	default:
+		return bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+	}
+
+	units := make([]uint16, (len(data)-2)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2+2*i:])
+	}
+
+	return []byte(string(utf16.Decode(units)))
+}
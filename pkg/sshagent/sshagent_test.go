@@ -0,0 +1,250 @@
+package sshagent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/tobischo/gokeepasslib/v3"
+	"github.com/tobischo/gokeepasslib/v3/wrappers"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+const SETTINGS = `<?xml version="1.0" encoding="UTF-16"?>
+<EntrySettings xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <AllowUseOfSshKey>true</AllowUseOfSshKey>
+  <AddAtDatabaseOpen>true</AddAtDatabaseOpen>
+  <RemoveAtDatabaseClose>true</RemoveAtDatabaseClose>
+  <UseConfirmConstraintWhenAdding>true</UseConfirmConstraintWhenAdding>
+  <UseLifetimeConstraintWhenAdding>true</UseLifetimeConstraintWhenAdding>
+  <LifetimeConstraintDuration>600</LifetimeConstraintDuration>
+  <Location>
+    <SelectedType>attachment</SelectedType>
+    <AttachmentName>id_ed25519</AttachmentName>
+    <SaveAttachmentToTempFile>false</SaveAttachmentToTempFile>
+  </Location>
+</EntrySettings>`
+
+func encodeUTF16(text string) []byte {
+	data := []byte{0xFF, 0xFE}
+	for _, unit := range utf16.Encode([]rune(text)) {
+		data = binary.LittleEndian.AppendUint16(data, unit)
+	}
+	return data
+}
+
+func makeKey(t *testing.T, passphrase string) (ssh.PublicKey, []byte) {
+	t.Helper()
+
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(private, "")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(private, "", []byte(passphrase))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, _ := ssh.NewPublicKey(public)
+	return publicKey, pem.EncodeToMemory(block)
+}
+
+func makeDatabase(t *testing.T, entries ...gokeepasslib.Entry) *kdbx.Database {
+	t.Helper()
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "test.kdbx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	db, _ := kdbx.NewFromFile(file)
+	group := db.NewGroup("Keys")
+	group.Entries = entries
+	db.Content.Root.Groups = []gokeepasslib.Group{*group}
+
+	return db
+}
+
+func makeEntry(title string, values map[string]string) gokeepasslib.Entry {
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values, gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: title}})
+	for key, value := range values {
+		entry.Values = append(entry.Values, gokeepasslib.ValueData{Key: key, Value: gokeepasslib.V{Content: value, Protected: wrappers.NewBoolWrapper(true)}})
+	}
+	return entry
+}
+
+func attach(db *kdbx.Database, entry *gokeepasslib.Entry, name string, content []byte) {
+	binary := db.AddBinary(content)
+	entry.Binaries = append(entry.Binaries, binary.CreateReference(name))
+}
+
+func TestParseSettings(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"reads UTF-16 settings from KeeAgent", encodeUTF16(SETTINGS)},
+		{"reads UTF-8 settings declaring UTF-16", []byte(SETTINGS)},
+		{"reads UTF-8 settings with a byte order mark", append([]byte{0xEF, 0xBB, 0xBF}, SETTINGS...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSettings(tt.data)
+			if err != nil {
+				t.Fatalf("ParseSettings() error = %v", err)
+			}
+
+			want := Settings{
+				AllowUseOfSshKey:                true,
+				AddAtDatabaseOpen:               true,
+				UseConfirmConstraintWhenAdding:  true,
+				UseLifetimeConstraintWhenAdding: true,
+				LifetimeConstraintDuration:      600,
+				Location:                        Location{SelectedType: LOCATION_ATTACHMENT, AttachmentName: "id_ed25519"},
+			}
+			if got != want {
+				t.Errorf("ParseSettings() = %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	if _, err := ParseSettings([]byte("not xml")); !errors.Is(err, errors.ErrCorrupt) {
+		t.Errorf("ParseSettings() = %v, want a corrupt error", err)
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	attachedPublic, attached := makeKey(t, "")
+	fieldPublic, field := makeKey(t, "entry-password")
+	_, skipped := makeKey(t, "")
+
+	withSettings := makeEntry("Attached", nil)
+	withField := makeEntry("Field", map[string]string{kdbx.PASSWORD_KEY: "entry-password", PRIVATE_KEY_FIELD: string(field)})
+	notAllowed := makeEntry("Not allowed", nil)
+	wrongPassword := makeEntry("Wrong password", map[string]string{kdbx.PASSWORD_KEY: "wrong", PRIVATE_KEY_FIELD: string(field)})
+	plain := makeEntry("Plain", nil)
+
+	db := makeDatabase(t)
+	attach(db, &withSettings, SETTINGS_ATTACHMENT, encodeUTF16(SETTINGS))
+	attach(db, &withSettings, "id_ed25519", attached)
+	attach(db, &notAllowed, SETTINGS_ATTACHMENT, []byte(`<EntrySettings><AllowUseOfSshKey>false</AllowUseOfSshKey></EntrySettings>`))
+	attach(db, &notAllowed, "id_ed25519", skipped)
+	db.Content.Root.Groups[0].Entries = []gokeepasslib.Entry{withSettings, withField, notAllowed, wrongPassword, plain}
+
+	keys, failures := LoadKeys(db, Constraints{Lifetime: time.Hour})
+
+	if len(failures) != 1 || !errors.Is(failures[0], errors.ErrWrongCredentials) {
+		t.Errorf("LoadKeys() failures = %v, want the entry with a wrong password", failures)
+	}
+
+	if len(keys) != 2 {
+		t.Fatalf("LoadKeys() = %d keys, want 2", len(keys))
+	}
+
+	tests := []struct {
+		key       Key
+		reference string
+		public    ssh.PublicKey
+		confirm   bool
+		lifetime  uint32
+	}{
+		{keys[0], "/Keys/Attached", attachedPublic, true, 600},
+		{keys[1], "/Keys/Field", fieldPublic, false, 3600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			if tt.key.Reference != tt.reference || tt.key.Comment != tt.reference {
+				t.Errorf("Reference = %q, Comment = %q, want %q", tt.key.Reference, tt.key.Comment, tt.reference)
+			}
+
+			signer, err := ssh.NewSignerFromKey(tt.key.PrivateKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(signer.PublicKey().Marshal()) != string(tt.public.Marshal()) {
+				t.Errorf("loaded a different key")
+			}
+
+			if tt.key.ConfirmBeforeUse != tt.confirm || tt.key.LifetimeSecs != tt.lifetime {
+				t.Errorf("ConfirmBeforeUse = %v, LifetimeSecs = %d, want %v, %d", tt.key.ConfirmBeforeUse, tt.key.LifetimeSecs, tt.confirm, tt.lifetime)
+			}
+		})
+	}
+}
+
+func TestAgent_Serve(t *testing.T) {
+	originalConfirm := Confirm
+	defer func() { Confirm = originalConfirm }()
+
+	// Socket paths are limited to about 100 characters
+	dir, err := os.MkdirTemp("", "sshagent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	listener, err := net.Listen("unix", filepath.Join(dir, SOCKET_FILE))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewAgent()
+	done := make(chan error)
+	go func() { done <- server.Serve(listener) }()
+
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	conn, err := net.Dial("unix", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	client := agent.NewClient(conn)
+	if err := client.Add(agent.AddedKey{PrivateKey: private, Comment: "/Keys/Confirm", ConfirmBeforeUse: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := client.List()
+	if err != nil || len(keys) != 1 || keys[0].Comment != "/Keys/Confirm" {
+		t.Fatalf("List() = %v, %v, want the added key", keys, err)
+	}
+
+	prompts := 0
+	for _, allowed := range []bool{false, true} {
+		Confirm = func(string) bool {
+			prompts++
+			return allowed
+		}
+
+		_, err := client.Sign(keys[0], []byte("data"))
+		if allowed != (err == nil) {
+			t.Errorf("Sign() = %v when confirmation is %v", err, allowed)
+		}
+	}
+	if prompts != 2 {
+		t.Errorf("asked for confirmation %d times, want 2", prompts)
+	}
+
+	listener.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Serve() = %v, want nil once the listener is closed", err)
+	}
+}
@@ -8,11 +8,18 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/shikaan/keydex/cmd"
+	"github.com/shikaan/keydex/pkg/agent"
 	"github.com/shikaan/keydex/pkg/cli"
+	"github.com/shikaan/keydex/pkg/config"
+	"github.com/shikaan/keydex/pkg/credentials"
+	"github.com/shikaan/keydex/pkg/errors"
 	"github.com/shikaan/keydex/pkg/info"
 	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/secure"
+	"github.com/spf13/cobra"
 	"github.com/tobischo/gokeepasslib/v3"
 	"github.com/tobischo/gokeepasslib/v3/wrappers"
 )
@@ -121,11 +128,14 @@ func runKeydex(t *testing.T, env map[string]string, args ...string) (stdout, std
 	cmd.Stdout = &outBuf
 	cmd.Stderr = &errBuf
 
-	// Start from a clean env to avoid inheriting KEYDEX_* vars from the
-	// developer's shell, then layer in the caller's overrides.
+	// Start from a clean env to avoid inheriting KEYDEX_* vars and the
+	// configuration from the developer's shell, then layer in the caller's
+	// overrides.
 	cmd.Env = []string{
 		"PATH=" + os.Getenv("PATH"),
 		"HOME=" + os.Getenv("HOME"),
+		config.ENV_CONFIG + "=" + filepath.Join(t.TempDir(), "config.toml"),
+		agent.ENV_AGENT_SOCKET + "=" + filepath.Join(t.TempDir(), agent.SOCKET_FILE),
 	}
 	for k, v := range env {
 		cmd.Env = append(cmd.Env, k+"="+v)
@@ -366,6 +376,390 @@ func TestCommandCopy(t *testing.T) {
 	}
 }
 
+func TestCommandCopyWithCustomClipboard(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	t.Run("copies with a custom command", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "clipboard")
+
+		_, stderr, exitCode := runKeydex(t, map[string]string{
+			"KEYDEX_PASSPHRASE": fixturePassword,
+		}, "copy", "--clear-after", "0", "--clipboard", "command:cat > "+destination, fixtureDB, "/TestDB/Coding/GitHub")
+
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+
+		content, _ := os.ReadFile(destination)
+		if string(content) != "ghpass123" {
+			t.Errorf("expected clipboard to contain the password, got %q", content)
+		}
+	})
+
+	t.Run("reads the backend from env", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "clipboard")
+
+		_, stderr, exitCode := runKeydex(t, map[string]string{
+			"KEYDEX_PASSPHRASE": fixturePassword,
+			"KEYDEX_CLIPBOARD":  "command:cat > " + destination,
+		}, "copy", "--clear-after", "0", "-f", "UserName", fixtureDB, "/TestDB/Coding/GitHub")
+
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+
+		content, _ := os.ReadFile(destination)
+		if string(content) != "ghuser" {
+			t.Errorf("expected clipboard to contain the username, got %q", content)
+		}
+	})
+
+	t.Run("clears the clipboard after the timeout", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "clipboard")
+
+		start := time.Now()
+		_, stderr, exitCode := runKeydex(t, map[string]string{
+			"KEYDEX_PASSPHRASE": fixturePassword,
+		}, "copy", "--clear-after", "2s", "--clipboard", "command:cat > "+destination, fixtureDB, "/TestDB/Coding/GitHub")
+
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if time.Since(start) > 1500*time.Millisecond {
+			t.Errorf("expected copy to exit before the timeout")
+		}
+
+		content, _ := os.ReadFile(destination)
+		if string(content) != "ghpass123" {
+			t.Errorf("expected clipboard to contain the password, got %q", content)
+		}
+
+		deadline := time.Now().Add(10 * time.Second)
+		for time.Now().Before(deadline) {
+			if content, _ := os.ReadFile(destination); len(content) == 0 {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		t.Error("expected clipboard to be cleared after the timeout")
+	})
+
+	t.Run("fails with unknown backend", func(t *testing.T) {
+		_, stderr, exitCode := runKeydex(t, map[string]string{
+			"KEYDEX_PASSPHRASE": fixturePassword,
+		}, "copy", "--clipboard", "unknown", fixtureDB, "/TestDB/Coding/GitHub")
+
+		if exitCode == 0 {
+			t.Fatal("expected non-zero exit code")
+		}
+		if !strings.Contains(stderr, "Unknown clipboard backend") {
+			t.Errorf("expected 'Unknown clipboard backend' in stderr, got:\n%s", stderr)
+		}
+	})
+}
+
+func TestCommandConfig(t *testing.T) {
+	t.Run("prints the path", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "custom.toml")
+
+		stdout, stderr, exitCode := runKeydex(t, map[string]string{
+			config.ENV_CONFIG: path,
+		}, "config", "path")
+
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if strings.TrimSpace(stdout) != path {
+			t.Errorf("expected %q, got %q", path, stdout)
+		}
+	})
+
+	t.Run("sets and gets settings", func(t *testing.T) {
+		env := map[string]string{config.ENV_CONFIG: filepath.Join(t.TempDir(), "config.toml")}
+
+		if _, stderr, exitCode := runKeydex(t, env, "config", "set", "clipboard.clear_after", "10s"); exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+
+		stdout, stderr, exitCode := runKeydex(t, env, "config", "get", "clipboard.clear_after")
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if strings.TrimSpace(stdout) != "10s" {
+			t.Errorf("expected 10s, got %q", stdout)
+		}
+	})
+
+	t.Run("fails with unknown settings", func(t *testing.T) {
+		_, stderr, exitCode := runKeydex(t, nil, "config", "set", "unknown", "value")
+
+		if exitCode == 0 {
+			t.Fatal("expected non-zero exit code")
+		}
+		if !strings.Contains(stderr, "Unknown setting") {
+			t.Errorf("expected 'Unknown setting' in stderr, got:\n%s", stderr)
+		}
+	})
+
+	t.Run("reads the database from the configuration", func(t *testing.T) {
+		env := map[string]string{
+			config.ENV_CONFIG:   filepath.Join(t.TempDir(), "config.toml"),
+			"KEYDEX_PASSPHRASE": fixturePassword,
+		}
+		runKeydex(t, env, "config", "set", "database", fixtureDB)
+
+		stdout, stderr, exitCode := runKeydex(t, env, "list")
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "/TestDB/Coding/GitHub") {
+			t.Errorf("expected entries in stdout, got:\n%s", stdout)
+		}
+	})
+
+	t.Run("reads the database from profiles", func(t *testing.T) {
+		env := map[string]string{
+			config.ENV_CONFIG:   filepath.Join(t.TempDir(), "config.toml"),
+			"KEYDEX_PASSPHRASE": fixturePassword,
+			"KEYDEX_DATABASE":   "missing.kdbx",
+		}
+		runKeydex(t, env, "config", "set", "profiles.test.database", fixtureDB)
+
+		stdout, stderr, exitCode := runKeydex(t, env, "list", "--profile", "test")
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "/TestDB/Coding/GitHub") {
+			t.Errorf("expected entries in stdout, got:\n%s", stdout)
+		}
+	})
+
+	t.Run("fails with unknown profiles", func(t *testing.T) {
+		_, stderr, exitCode := runKeydex(t, map[string]string{
+			"KEYDEX_PASSPHRASE": fixturePassword,
+		}, "list", "--profile", "unknown", fixtureDB)
+
+		if exitCode == 0 {
+			t.Fatal("expected non-zero exit code")
+		}
+		if !strings.Contains(stderr, "Unknown profile") {
+			t.Errorf("expected 'Unknown profile' in stderr, got:\n%s", stderr)
+		}
+	})
+}
+
+func TestCommandShow(t *testing.T) {
+	env := map[string]string{"KEYDEX_PASSPHRASE": fixturePassword}
+
+	t.Run("hides protected fields", func(t *testing.T) {
+		stdout, stderr, exitCode := runKeydex(t, env, "show", fixtureDB, "/TestDB/Coding/GitHub")
+
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "UserName: ghuser") {
+			t.Errorf("expected username in stdout, got:\n%s", stdout)
+		}
+		if strings.Contains(stdout, "ghpass123") || !strings.Contains(stdout, "Password: "+cmd.HIDDEN_VALUE) {
+			t.Errorf("expected hidden password in stdout, got:\n%s", stdout)
+		}
+	})
+
+	t.Run("reveals protected fields", func(t *testing.T) {
+		stdout, stderr, exitCode := runKeydex(t, env, "show", "--reveal", fixtureDB, "/TestDB/Coding/GitHub")
+
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "Password: ghpass123") {
+			t.Errorf("expected password in stdout, got:\n%s", stdout)
+		}
+	})
+
+	t.Run("fails with missing entries", func(t *testing.T) {
+		_, stderr, exitCode := runKeydex(t, env, "show", fixtureDB, "/TestDB/Missing")
+
+		if exitCode == 0 {
+			t.Fatal("expected non-zero exit code")
+		}
+		if !strings.Contains(stderr, "Missing entry") {
+			t.Errorf("expected 'Missing entry' in stderr, got:\n%s", stderr)
+		}
+	})
+}
+
+func createURLDB(t *testing.T, urls map[string]string) string {
+	path := filepath.Join(t.TempDir(), "urls.kdbx")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, _ := kdbx.NewFromFile(file)
+	if err := db.SetPasswordAndKey(fixturePassword, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	group := db.NewGroup("URLs")
+	for title, url := range urls {
+		entry := gokeepasslib.NewEntry()
+		entry.Values = append(entry.Values,
+			gokeepasslib.ValueData{Key: "Title", Value: gokeepasslib.V{Content: title}},
+			gokeepasslib.ValueData{Key: "UserName", Value: gokeepasslib.V{Content: "me"}},
+			gokeepasslib.ValueData{Key: "Password", Value: gokeepasslib.V{Content: "secret", Protected: wrappers.NewBoolWrapper(true)}},
+			gokeepasslib.ValueData{Key: "URL", Value: gokeepasslib.V{Content: url}},
+		)
+		group.Entries = append(group.Entries, entry)
+	}
+
+	db.Content.Root.Groups = []gokeepasslib.Group{*group}
+	if err := db.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCommandURL(t *testing.T) {
+	env := map[string]string{"KEYDEX_PASSPHRASE": fixturePassword}
+
+	t.Run("prints the resolved URL", func(t *testing.T) {
+		path := createURLDB(t, map[string]string{"Site": "example.com/{USERNAME}/{PASSWORD}"})
+		stdout, stderr, exitCode := runKeydex(t, env, "url", path, "/URLs/Site")
+
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if strings.TrimSpace(stdout) != "https://example.com/me/{PASSWORD}" {
+			t.Errorf("expected resolved URL in stdout, got:\n%s", stdout)
+		}
+	})
+
+	t.Run("fails with entries without URL", func(t *testing.T) {
+		_, stderr, exitCode := runKeydex(t, env, "url", fixtureDB, "/TestDB/Coding/GitHub")
+
+		if exitCode != errors.ErrNotFound.ExitCode {
+			t.Fatalf("expected exit code %d, got %d. stderr: %s", errors.ErrNotFound.ExitCode, exitCode, stderr)
+		}
+		if !strings.Contains(stderr, `Missing field "URL"`) {
+			t.Errorf("expected 'Missing field' in stderr, got:\n%s", stderr)
+		}
+	})
+
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell")
+	}
+
+	t.Run("opens with a custom opener", func(t *testing.T) {
+		path := createURLDB(t, map[string]string{"Site": "https://example.com"})
+		destination := filepath.Join(t.TempDir(), "opened")
+		clipboard := filepath.Join(t.TempDir(), "clipboard")
+		opener := filepath.Join(t.TempDir(), "opener")
+		if err := os.WriteFile(opener, []byte("#!/bin/sh\nprintf %s \"$1\" > "+destination+"\n"), 0o700); err != nil {
+			t.Fatal(err)
+		}
+
+		_, stderr, exitCode := runKeydex(t, env, "url", "--open", "--opener", opener, "--copy", "--clear-after", "0", "--clipboard", "command:cat > "+clipboard, path, "/URLs/Site")
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if content, _ := os.ReadFile(destination); string(content) == "https://example.com" {
+				break
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		if content, _ := os.ReadFile(destination); string(content) != "https://example.com" {
+			t.Errorf("expected the opener to receive the URL, got %q", content)
+		}
+		if content, _ := os.ReadFile(clipboard); string(content) != "secret" {
+			t.Errorf("expected clipboard to contain the password, got %q", content)
+		}
+	})
+
+	t.Run("refuses cmd URLs unless allowed", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "ran")
+		path := createURLDB(t, map[string]string{"Command": "cmd://touch " + destination})
+
+		_, stderr, exitCode := runKeydex(t, env, "url", "--open", path, "/URLs/Command")
+		if exitCode == 0 {
+			t.Fatal("expected non-zero exit code")
+		}
+		if !strings.Contains(stderr, "not allowed") {
+			t.Errorf("expected 'not allowed' in stderr, got:\n%s", stderr)
+		}
+
+		_, stderr, exitCode = runKeydex(t, env, "url", "--open", "--allow-commands", path, "/URLs/Command")
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, err := os.Stat(destination); err == nil {
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+		t.Error("expected the command to run")
+	})
+}
+
+func TestCommandAgent(t *testing.T) {
+	// Socket paths are limited to about 100 characters
+	dir, err := os.MkdirTemp("", "agent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	env := map[string]string{agent.ENV_AGENT_SOCKET: filepath.Join(dir, agent.SOCKET_FILE)}
+
+	if _, stderr, exitCode := runKeydex(t, env, "agent", "start"); exitCode != 0 {
+		t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+	}
+	defer runKeydex(t, env, "agent", "stop")
+
+	t.Run("unlocks databases once", func(t *testing.T) {
+		unlockEnv := map[string]string{agent.ENV_AGENT_SOCKET: env[agent.ENV_AGENT_SOCKET], "KEYDEX_PASSPHRASE": fixturePassword}
+		if _, stderr, exitCode := runKeydex(t, unlockEnv, "list", fixtureDB); exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+
+		stdout, stderr, exitCode := runKeydex(t, env, "show", "--reveal", fixtureDB, "/TestDB/Coding/GitHub")
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "Password: ghpass123") {
+			t.Errorf("expected password in stdout, got:\n%s", stdout)
+		}
+
+		stdout, _, _ = runKeydex(t, env, "agent", "status")
+		if !strings.Contains(stdout, fixtureDB) {
+			t.Errorf("expected database in status, got:\n%s", stdout)
+		}
+	})
+
+	t.Run("forgets databases when locked", func(t *testing.T) {
+		if _, stderr, exitCode := runKeydex(t, env, "agent", "lock"); exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+
+		stdout, _, _ := runKeydex(t, env, "agent", "status")
+		if strings.TrimSpace(stdout) != "" {
+			t.Errorf("expected no databases in status, got:\n%s", stdout)
+		}
+
+		_, _, exitCode := runKeydex(t, env, "show", fixtureDB, "/TestDB/Coding/GitHub")
+		if exitCode == 0 {
+			t.Error("expected non-zero exit code without passphrase")
+		}
+	})
+}
+
 func TestCommandOpen(t *testing.T) {
 	aliases := []string{"open", "edit"}
 
@@ -590,7 +984,7 @@ func TestCommandCreateWithPassphrase(t *testing.T) {
 
 	t.Run("creates database successfully", func(t *testing.T) {
 		password := "test-create-password"
-		cli.ReadSecret = func(prompt string) string { return password }
+		cli.ReadSecret = func(prompt string) *secure.Buffer { return secure.FromString(password) }
 		cli.Confirm = func(prompt string) bool { return false }
 
 		dbPath := filepath.Join(t.TempDir(), "new.kdbx")
@@ -620,12 +1014,12 @@ func TestCommandCreateWithPassphrase(t *testing.T) {
 
 	t.Run("errors on passphrase mismatch", func(t *testing.T) {
 		callCount := 0
-		cli.ReadSecret = func(prompt string) string {
+		cli.ReadSecret = func(prompt string) *secure.Buffer {
 			callCount++
 			if callCount == 1 {
-				return "password1"
+				return secure.FromString("password1")
 			}
-			return "password2"
+			return secure.FromString("password2")
 		}
 		cli.Confirm = func(prompt string) bool { return false }
 
@@ -646,7 +1040,7 @@ func TestCommandCreateWithPassphrase(t *testing.T) {
 
 	t.Run("creates database with keyfile when confirmed", func(t *testing.T) {
 		password := "test-create-password"
-		cli.ReadSecret = func(prompt string) string { return password }
+		cli.ReadSecret = func(prompt string) *secure.Buffer { return secure.FromString(password) }
 		cli.Confirm = func(prompt string) bool {
 			return strings.Contains(prompt, "keyfile")
 		}
@@ -683,7 +1077,7 @@ func TestCommandCreateWithPassphrase(t *testing.T) {
 	})
 
 	t.Run("errors on empty passphrase", func(t *testing.T) {
-		cli.ReadSecret = func(prompt string) string { return "" }
+		cli.ReadSecret = func(prompt string) *secure.Buffer { return secure.FromString("") }
 		cli.Confirm = func(prompt string) bool { return false }
 
 		dbPath := filepath.Join(t.TempDir(), "empty.kdbx")
@@ -703,7 +1097,7 @@ func TestCommandCreateWithPassphrase(t *testing.T) {
 
 	t.Run("errors when keyfile already exists", func(t *testing.T) {
 		password := "test-create-password"
-		cli.ReadSecret = func(prompt string) string { return password }
+		cli.ReadSecret = func(prompt string) *secure.Buffer { return secure.FromString(password) }
 		cli.Confirm = func(prompt string) bool {
 			return strings.Contains(prompt, "keyfile")
 		}
@@ -923,7 +1317,7 @@ func TestCommandOpenWithPassphrase(t *testing.T) {
 	t.Setenv(cmd.ENV_PASSPHRASE, "")
 
 	t.Run("opens database with correct passphrase from prompt", func(t *testing.T) {
-		cli.ReadSecret = func(prompt string) string { return fixturePassword }
+		cli.ReadSecret = func(prompt string) *secure.Buffer { return secure.FromString(fixturePassword) }
 
 		err := cmd.Open.RunE(cmd.Open, []string{fixtureDB, "/TestDB/Coding/NonExistent"})
 		if err == nil {
@@ -935,7 +1329,7 @@ func TestCommandOpenWithPassphrase(t *testing.T) {
 	})
 
 	t.Run("fails with wrong passphrase from prompt", func(t *testing.T) {
-		cli.ReadSecret = func(prompt string) string { return "wrong-password" }
+		cli.ReadSecret = func(prompt string) *secure.Buffer { return secure.FromString("wrong-password") }
 
 		err := cmd.Open.RunE(cmd.Open, []string{fixtureDB, "/TestDB/Coding/GitHub"})
 		if err == nil {
@@ -946,3 +1340,614 @@ func TestCommandOpenWithPassphrase(t *testing.T) {
 		}
 	})
 }
+
+func TestCommandPasswd(t *testing.T) {
+	originalReadSecret := cli.ReadSecret
+	defer func() {
+		cli.ReadSecret = originalReadSecret
+	}()
+
+	t.Setenv(cmd.ENV_PASSPHRASE, "")
+
+	copyFixture := func(t *testing.T) string {
+		t.Helper()
+		content, err := os.ReadFile(fixtureDB)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "passwd.kdbx")
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	prompt := func(current, next string) func(string) *secure.Buffer {
+		return func(prompt string) *secure.Buffer {
+			if strings.HasPrefix(prompt, "Passphrase for") {
+				return secure.FromString(current)
+			}
+			return secure.FromString(next)
+		}
+	}
+
+	t.Run("changes the passphrase", func(t *testing.T) {
+		path := copyFixture(t)
+		cli.ReadSecret = prompt(fixturePassword, "new-password")
+
+		if err := cmd.Passwd.RunE(cmd.Passwd, []string{path}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if _, err := kdbx.OpenFromPath(path, fixturePassword, ""); err == nil {
+			t.Error("expected old passphrase to be rejected")
+		}
+
+		db, err := kdbx.OpenFromPath(path, "new-password", "")
+		if err != nil {
+			t.Fatalf("failed to open database with new passphrase: %v", err)
+		}
+		if db.GetFirstEntryByPath("/TestDB/Coding/GitHub") == nil {
+			t.Error("expected entries to be preserved")
+		}
+	})
+
+	t.Run("fails with wrong current passphrase", func(t *testing.T) {
+		path := copyFixture(t)
+		cli.ReadSecret = prompt("wrong-password", "new-password")
+
+		err := cmd.Passwd.RunE(cmd.Passwd, []string{path})
+		if err == nil || !strings.Contains(err.Error(), "Wrong password?") {
+			t.Fatalf("expected 'Wrong password?' error, got: %v", err)
+		}
+
+		if _, err := kdbx.OpenFromPath(path, fixturePassword, ""); err != nil {
+			t.Errorf("expected database to be unchanged, got: %v", err)
+		}
+	})
+
+	t.Run("generates and removes a key file", func(t *testing.T) {
+		path := copyFixture(t)
+		keyPath := filepath.Join(filepath.Dir(path), "passwd-key.xml")
+		cli.ReadSecret = prompt(fixturePassword, "new-password")
+		setCommandFlags(t, cmd.Passwd, map[string]string{"generate-key": "true"})
+
+		if err := cmd.Passwd.RunE(cmd.Passwd, []string{path}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if _, err := kdbx.OpenFromPath(path, "new-password", ""); err == nil {
+			t.Error("expected the key file to be required")
+		}
+		if _, err := kdbx.OpenFromPath(path, "new-password", keyPath); err != nil {
+			t.Fatalf("failed to open database with generated key: %v", err)
+		}
+
+		cli.ReadSecret = prompt("new-password", "newer-password")
+		setCommandFlags(t, cmd.Passwd, map[string]string{"generate-key": "false", "key": keyPath, "remove-key": "true"})
+
+		if err := cmd.Passwd.RunE(cmd.Passwd, []string{path}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if _, err := kdbx.OpenFromPath(path, "newer-password", ""); err != nil {
+			t.Errorf("failed to open database without key: %v", err)
+		}
+	})
+
+	t.Run("does not back up the old credentials", func(t *testing.T) {
+		path := copyFixture(t)
+		if err := os.WriteFile(kdbx.BackupPath(path, 1), []byte("backup"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		previous := config.Current()
+		config.Use(&config.Config{Backups: 3})
+		defer config.Use(previous)
+		cli.ReadSecret = prompt(fixturePassword, "new-password")
+
+		if err := cmd.Passwd.RunE(cmd.Passwd, []string{path}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if content, _ := os.ReadFile(kdbx.BackupPath(path, 1)); string(content) != "backup" {
+			t.Error("expected existing backups to be left alone")
+		}
+		if _, err := os.Stat(kdbx.BackupPath(path, 2)); !os.IsNotExist(err) {
+			t.Errorf("expected no backup of the old credentials, got %v", err)
+		}
+	})
+
+	t.Run("generates the key file next to a database without extension", func(t *testing.T) {
+		path := strings.TrimSuffix(copyFixture(t), ".kdbx")
+		if err := os.Rename(path+".kdbx", path); err != nil {
+			t.Fatal(err)
+		}
+		cli.ReadSecret = prompt(fixturePassword, "new-password")
+		setCommandFlags(t, cmd.Passwd, map[string]string{"generate-key": "true"})
+
+		if err := cmd.Passwd.RunE(cmd.Passwd, []string{path}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if _, err := kdbx.OpenFromPath(path, "new-password", path+"-key.xml"); err != nil {
+			t.Errorf("failed to open database with generated key: %v", err)
+		}
+	})
+
+	t.Run("errors on passphrase mismatch", func(t *testing.T) {
+		path := copyFixture(t)
+		calls := 0
+		cli.ReadSecret = func(prompt string) *secure.Buffer {
+			calls++
+			switch calls {
+			case 1:
+				return secure.FromString(fixturePassword)
+			case 2:
+				return secure.FromString("new-password")
+			}
+			return secure.FromString("other-password")
+		}
+
+		err := cmd.Passwd.RunE(cmd.Passwd, []string{path})
+		if err == nil || !strings.Contains(err.Error(), "mismatch") {
+			t.Fatalf("expected mismatch error, got: %v", err)
+		}
+
+		if _, err := kdbx.OpenFromPath(path, fixturePassword, ""); err != nil {
+			t.Errorf("expected database to be unchanged, got: %v", err)
+		}
+	})
+}
+
+// Sets flags of command for the duration of the test
+func setCommandFlags(t *testing.T, command *cobra.Command, flags map[string]string) {
+	t.Helper()
+
+	for name, value := range flags {
+		flag := command.Flags().Lookup(name)
+		defaultValue := flag.DefValue
+
+		if err := command.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() {
+			flag.Value.Set(defaultValue)
+			flag.Changed = false
+		})
+	}
+}
+
+func TestCommandCreateWithEncryption(t *testing.T) {
+	originalReadSecret := cli.ReadSecret
+	originalConfirm := cli.Confirm
+	defer func() {
+		cli.ReadSecret = originalReadSecret
+		cli.Confirm = originalConfirm
+	}()
+
+	cli.ReadSecret = func(prompt string) *secure.Buffer { return secure.FromString("test-create-password") }
+	cli.Confirm = func(prompt string) bool { return false }
+
+	t.Run("creates database with the chosen encryption", func(t *testing.T) {
+		setCommandFlags(t, cmd.Create, map[string]string{
+			"cipher":          "chacha20",
+			"kdf-memory":      "1",
+			"kdf-iterations":  "1",
+			"kdf-parallelism": "1",
+		})
+
+		dbPath := filepath.Join(t.TempDir(), "chacha.kdbx")
+		if err := cmd.Create.RunE(cmd.Create, []string{dbPath, "TestVault"}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		db, err := kdbx.OpenFromPath(dbPath, "test-create-password", "")
+		if err != nil {
+			t.Fatalf("failed to open created database: %v", err)
+		}
+
+		want := kdbx.EncryptionOptions{Version: 4, Cipher: "chacha20", KDF: "argon2d", Memory: kdbx.MEBIBYTE, Iterations: 1, Parallelism: 1}
+		if got := db.Encryption(); got != want {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	})
+
+	t.Run("creates KDBX 3 database with AES-KDF", func(t *testing.T) {
+		setCommandFlags(t, cmd.Create, map[string]string{"format-version": "3", "kdf-rounds": "1000"})
+
+		dbPath := filepath.Join(t.TempDir(), "v3.kdbx")
+		if err := cmd.Create.RunE(cmd.Create, []string{dbPath, "TestVault"}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		db, err := kdbx.OpenFromPath(dbPath, "test-create-password", "")
+		if err != nil {
+			t.Fatalf("failed to open created database: %v", err)
+		}
+
+		if got := db.Encryption(); got.Version != 3 || got.KDF != "aes-kdf" || got.Rounds != 1000 {
+			t.Errorf("expected KDBX 3 with 1000 AES-KDF rounds, got %v", got)
+		}
+	})
+
+	t.Run("rejects unsupported combinations", func(t *testing.T) {
+		setCommandFlags(t, cmd.Create, map[string]string{"format-version": "3", "cipher": "chacha20"})
+
+		dbPath := filepath.Join(t.TempDir(), "invalid.kdbx")
+		err := cmd.Create.RunE(cmd.Create, []string{dbPath, "TestVault"})
+		if err == nil || !strings.Contains(err.Error(), "ChaCha20") {
+			t.Fatalf("expected ChaCha20 error, got: %v", err)
+		}
+
+		if _, err := os.Stat(dbPath); !os.IsNotExist(err) {
+			t.Error("expected no database file to be created")
+		}
+	})
+}
+
+func TestCommandUpgrade(t *testing.T) {
+	t.Setenv(cmd.ENV_PASSPHRASE, fixturePassword)
+
+	content, err := os.ReadFile(fixtureDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dbPath := filepath.Join(t.TempDir(), "upgrade.kdbx")
+	if err := os.WriteFile(dbPath, content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	setCommandFlags(t, cmd.Upgrade, map[string]string{"kdf-memory": "1", "kdf-iterations": "1", "kdf-parallelism": "1"})
+
+	if err := cmd.Upgrade.RunE(cmd.Upgrade, []string{dbPath}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+
+	db, err := kdbx.OpenFromPath(dbPath, fixturePassword, "")
+	if err != nil {
+		t.Fatalf("failed to open upgraded database: %v", err)
+	}
+
+	want := kdbx.EncryptionOptions{Version: 4, Cipher: "aes", KDF: "argon2d", Memory: kdbx.MEBIBYTE, Iterations: 1, Parallelism: 1}
+	if got := db.Encryption(); got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	entry := db.GetFirstEntryByPath("/TestDB/Coding/GitHub")
+	if entry == nil || entry.GetPassword() != "ghpass123" {
+		t.Errorf("expected entries to be preserved, got %v", entry)
+	}
+}
+
+func TestCommandKeyfile(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key.hex")
+
+	t.Run("creates key files", func(t *testing.T) {
+		_, stderr, exitCode := runKeydex(t, nil, "keyfile", "create", "--format", "hex", keyPath)
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+
+		_, _, exitCode = runKeydex(t, nil, "keyfile", "create", keyPath)
+		if exitCode == 0 {
+			t.Error("expected existing key files not to be overwritten")
+		}
+	})
+
+	t.Run("inspects key files", func(t *testing.T) {
+		stdout, stderr, exitCode := runKeydex(t, nil, "keyfile", "inspect", keyPath)
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "Format: hex") {
+			t.Errorf("expected hex format in stdout, got:\n%s", stdout)
+		}
+	})
+
+	t.Run("verifies key files against a database", func(t *testing.T) {
+		dbPath := filepath.Join(dir, "keyed.kdbx")
+		file, err := os.Create(dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db, _ := kdbx.NewFromFile(file)
+		if err := db.SetPasswordAndKey(fixturePassword, keyPath); err != nil {
+			t.Fatal(err)
+		}
+		db.Content.Root.Groups = []gokeepasslib.Group{*db.NewGroup("Keyed")}
+		if err := db.Save(); err != nil {
+			t.Fatal(err)
+		}
+
+		env := map[string]string{"KEYDEX_PASSPHRASE": fixturePassword}
+		stdout, stderr, exitCode := runKeydex(t, env, "keyfile", "verify", keyPath, dbPath)
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "unlocks") {
+			t.Errorf("expected confirmation in stdout, got:\n%s", stdout)
+		}
+
+		otherKey := filepath.Join(dir, "other.xml")
+		runKeydex(t, nil, "keyfile", "create", otherKey)
+		if _, _, exitCode := runKeydex(t, env, "keyfile", "verify", otherKey, dbPath); exitCode == 0 {
+			t.Error("expected another key file not to unlock the database")
+		}
+	})
+
+	t.Run("rejects key files with a wrong hash", func(t *testing.T) {
+		damaged := filepath.Join(dir, "damaged.xml")
+		data := `<KeyFile><Meta><Version>2.0</Version></Meta><Key><Data Hash="00000000">` + strings.Repeat("AB", 32) + `</Data></Key></KeyFile>`
+		if err := os.WriteFile(damaged, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		_, stderr, exitCode := runKeydex(t, nil, "keyfile", "verify", damaged)
+		if exitCode == 0 {
+			t.Fatal("expected non-zero exit code")
+		}
+		if !strings.Contains(stderr, "hash mismatch") {
+			t.Errorf("expected hash mismatch in stderr, got:\n%s", stderr)
+		}
+	})
+}
+
+func TestExitCodes(t *testing.T) {
+	env := map[string]string{cmd.ENV_PASSPHRASE: fixturePassword}
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want int
+	}{
+		{"wrong passphrase", map[string]string{cmd.ENV_PASSPHRASE: "wrong-password"}, []string{"list", fixtureDB}, errors.ErrWrongCredentials.ExitCode},
+		{"missing database", env, []string{"list", "missing.kdbx"}, errors.ErrNotFound.ExitCode},
+		{"missing entry", env, []string{"show", fixtureDB, "/TestDB/Missing"}, errors.ErrNotFound.ExitCode},
+		{"missing field", env, []string{"copy", "--field", "Missing", fixtureDB, "/TestDB/Coding/GitHub"}, errors.ErrNotFound.ExitCode},
+		{"usage errors", nil, []string{"show", "a", "b", "c"}, errors.EXIT_FAILURE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, exitCode := runKeydex(t, tt.env, tt.args...)
+			if exitCode != tt.want {
+				t.Errorf("expected exit code %d, got %d. stderr: %s", tt.want, exitCode, stderr)
+			}
+		})
+	}
+}
+
+func TestPassphraseRetries(t *testing.T) {
+	originalReadSecret := cli.ReadSecret
+	defer func() {
+		cli.ReadSecret = originalReadSecret
+	}()
+
+	t.Setenv(cmd.ENV_PASSPHRASE, "")
+
+	t.Run("prompts again after a wrong passphrase", func(t *testing.T) {
+		prompts := 0
+		cli.ReadSecret = func(prompt string) *secure.Buffer {
+			prompts++
+			if prompts == 1 {
+				return secure.FromString("wrong-password")
+			}
+			return secure.FromString(fixturePassword)
+		}
+
+		if err := cmd.List.RunE(cmd.List, []string{fixtureDB}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if prompts != 2 {
+			t.Errorf("expected 2 prompts, got %d", prompts)
+		}
+	})
+
+	t.Run("gives up after too many attempts", func(t *testing.T) {
+		prompts := 0
+		cli.ReadSecret = func(prompt string) *secure.Buffer {
+			prompts++
+			return secure.FromString("wrong-password")
+		}
+
+		err := cmd.List.RunE(cmd.List, []string{fixtureDB})
+		if !errors.Is(err, errors.ErrWrongCredentials) {
+			t.Fatalf("expected wrong credentials error, got: %v", err)
+		}
+		if prompts != credentials.PASSPHRASE_ATTEMPTS {
+			t.Errorf("expected %d prompts, got %d", credentials.PASSPHRASE_ATTEMPTS, prompts)
+		}
+	})
+}
+
+func TestKeyOnlyDatabases(t *testing.T) {
+	originalReadSecret := cli.ReadSecret
+	originalConfirm := cli.Confirm
+	defer func() {
+		cli.ReadSecret = originalReadSecret
+		cli.Confirm = originalConfirm
+	}()
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "automation.key")
+	dbPath := filepath.Join(dir, "automation.kdbx")
+	if err := credentials.CreateKeyFile(keyPath, credentials.KEY_FILE_HEX); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("creates databases without passphrase", func(t *testing.T) {
+		cli.ReadSecret = func(prompt string) *secure.Buffer {
+			t.Errorf("unexpected prompt %q", prompt)
+			return secure.FromString("")
+		}
+		cli.Confirm = func(prompt string) bool { return false }
+		setCommandFlags(t, cmd.Create, map[string]string{"no-passphrase": "true", "key": keyPath, "kdf": kdbx.KDF_AES, "kdf-rounds": "1000"})
+
+		if err := cmd.Create.RunE(cmd.Create, []string{dbPath, "Automation"}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		if _, err := kdbx.OpenFromPath(dbPath, "", keyPath); err != nil {
+			t.Fatalf("expected key file to unlock the database, got: %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "automation-key.xml")); !os.IsNotExist(err) {
+			t.Error("expected no key file to be generated")
+		}
+	})
+
+	t.Run("generates a key file without passphrase", func(t *testing.T) {
+		cli.Confirm = func(prompt string) bool { return false }
+		setCommandFlags(t, cmd.Create, map[string]string{"no-passphrase": "true", "kdf": kdbx.KDF_AES, "kdf-rounds": "1000"})
+
+		path := filepath.Join(t.TempDir(), "generated.kdbx")
+		if err := cmd.Create.RunE(cmd.Create, []string{path, "Generated"}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+
+		generated := filepath.Join(filepath.Dir(path), "generated-key.xml")
+		if _, err := kdbx.OpenFromPath(path, "", generated); err != nil {
+			t.Fatalf("expected generated key file to unlock the database, got: %v", err)
+		}
+	})
+
+	t.Run("lists entries with --no-passphrase", func(t *testing.T) {
+		_, stderr, exitCode := runKeydex(t, nil, "list", "--no-passphrase", "--key", keyPath, dbPath)
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if strings.Contains(stderr, "Passphrase") {
+			t.Errorf("expected no passphrase prompt, got:\n%s", stderr)
+		}
+	})
+
+	t.Run("skips the prompt when configured", func(t *testing.T) {
+		configPath := filepath.Join(t.TempDir(), "config.toml")
+		c := &config.Config{Database: dbPath, Key: keyPath, NoPassphrase: true}
+		if err := c.Save(configPath); err != nil {
+			t.Fatal(err)
+		}
+
+		_, stderr, exitCode := runKeydex(t, map[string]string{config.ENV_CONFIG: configPath}, "list")
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+	})
+
+	t.Run("rejects --no-passphrase for databases with a passphrase", func(t *testing.T) {
+		_, _, exitCode := runKeydex(t, nil, "list", "--no-passphrase", fixtureDB)
+		if exitCode == 0 {
+			t.Error("expected non-zero exit code")
+		}
+	})
+}
+
+func TestPassphraseSources(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a POSIX shell and file modes")
+	}
+
+	passphraseFile := func(t *testing.T, content string, mode os.FileMode) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "passphrase")
+		if err := os.WriteFile(path, []byte(content), mode); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("reads the passphrase from a file", func(t *testing.T) {
+		path := passphraseFile(t, fixturePassword+"\n", 0o600)
+
+		stdout, stderr, exitCode := runKeydex(t, nil, "list", "--passphrase-file", path, fixtureDB)
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "/TestDB/Coding/GitHub") {
+			t.Errorf("expected entries in stdout, got:\n%s", stdout)
+		}
+	})
+
+	t.Run("rejects passphrase files readable by others", func(t *testing.T) {
+		path := passphraseFile(t, fixturePassword, 0o644)
+
+		_, stderr, exitCode := runKeydex(t, nil, "list", "--passphrase-file", path, fixtureDB)
+		if exitCode == 0 {
+			t.Fatal("expected non-zero exit code")
+		}
+		if !strings.Contains(stderr, "chmod 600") {
+			t.Errorf("expected permission hint in stderr, got:\n%s", stderr)
+		}
+	})
+
+	t.Run("reads the passphrase from a command", func(t *testing.T) {
+		stdout, stderr, exitCode := runKeydex(t, nil, "show", "--passphrase-cmd", "echo "+fixturePassword, fixtureDB, "/TestDB/Coding/GitHub")
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "UserName: ghuser") {
+			t.Errorf("expected fields in stdout, got:\n%s", stdout)
+		}
+	})
+
+	t.Run("reads the command from the environment", func(t *testing.T) {
+		env := map[string]string{
+			cmd.ENV_PASSPHRASE_COMMAND: "echo " + fixturePassword,
+			cmd.ENV_PASSPHRASE:         "wrong-password",
+		}
+
+		_, stderr, exitCode := runKeydex(t, env, "list", fixtureDB)
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+	})
+
+	t.Run("fails when the command fails", func(t *testing.T) {
+		_, _, exitCode := runKeydex(t, nil, "list", "--passphrase-cmd", "exit 3", fixtureDB)
+		if exitCode == 0 {
+			t.Fatal("expected non-zero exit code")
+		}
+	})
+
+	t.Run("reads the passphrase from a file descriptor", func(t *testing.T) {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.WriteString(fixturePassword + "\n")
+		w.Close()
+		defer r.Close()
+
+		command := exec.Command(binaryPath, "list", "--passphrase-fd", "3", fixtureDB)
+		command.Env = []string{
+			"PATH=" + os.Getenv("PATH"),
+			config.ENV_CONFIG + "=" + filepath.Join(t.TempDir(), "config.toml"),
+			agent.ENV_AGENT_SOCKET + "=" + filepath.Join(t.TempDir(), agent.SOCKET_FILE),
+		}
+		command.ExtraFiles = []*os.File{r}
+
+		output, err := command.CombinedOutput()
+		if err != nil {
+			t.Fatalf("expected no error, got %v: %s", err, output)
+		}
+		if !strings.Contains(string(output), "/TestDB/Coding/GitHub") {
+			t.Errorf("expected entries in output, got:\n%s", output)
+		}
+	})
+
+	t.Run("reads the passphrases of diff separately", func(t *testing.T) {
+		path := passphraseFile(t, fixturePassword2, 0o600)
+
+		stdout, stderr, exitCode := runKeydex(t, nil, "diff",
+			"--passphrase-cmd-a", "echo "+fixturePassword,
+			"--passphrase-file-b", path,
+			fixtureDB, fixtureDB2)
+		if exitCode != 0 {
+			t.Fatalf("expected exit code 0, got %d. stderr: %s", exitCode, stderr)
+		}
+		if !strings.Contains(stdout, "+/") {
+			t.Errorf("expected added entries in output, got:\n%s", stdout)
+		}
+	})
+}
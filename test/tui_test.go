@@ -197,8 +197,10 @@ func TestCreateEntryAndSave(t *testing.T) {
 	db := openTestDatabase(t, filePath, password)
 	screen := startApp(t, tui.State{Database: db}, false)
 
-	// Create new entry (^N)
+	// Create new entry (^N) from the default template
 	screen.InjectKey(tcell.KeyCtrlN, 0, tcell.ModCtrl)
+	waitFor(t, screen, "Select template", e2eTimeout)
+	screen.InjectKey(tcell.KeyEnter, 0, 0)
 	waitFor(t, screen, "New", e2eTimeout)
 	waitFor(t, screen, "[MODIFIED]", e2eTimeout)
 
@@ -227,6 +229,8 @@ func TestCreateEntryAndDismissSave(t *testing.T) {
 	screen := startApp(t, tui.State{Database: db}, false)
 
 	screen.InjectKey(tcell.KeyCtrlN, 0, tcell.ModCtrl)
+	waitFor(t, screen, "Select template", e2eTimeout)
+	screen.InjectKey(tcell.KeyEnter, 0, 0)
 	waitFor(t, screen, "New", e2eTimeout)
 
 	// Save -> Dismiss (N)
@@ -246,6 +250,8 @@ func TestCreateEntryModifyThenCancel(t *testing.T) {
 	screen := startApp(t, tui.State{Database: db}, false)
 
 	screen.InjectKey(tcell.KeyCtrlN, 0, tcell.ModCtrl)
+	waitFor(t, screen, "Select template", e2eTimeout)
+	screen.InjectKey(tcell.KeyEnter, 0, 0)
 	waitFor(t, screen, "New", e2eTimeout)
 
 	// Type in a field
@@ -258,6 +264,22 @@ func TestCreateEntryModifyThenCancel(t *testing.T) {
 	waitFor(t, screen, "Help", e2eTimeout)
 }
 
+func TestCreateEntryFromTemplate(t *testing.T) {
+	filePath, password := makeTestKdbxFile(t)
+	db := openTestDatabase(t, filePath, password)
+	screen := startApp(t, tui.State{Database: db}, false)
+
+	screen.InjectKey(tcell.KeyCtrlN, 0, tcell.ModCtrl)
+	waitFor(t, screen, "Select template", e2eTimeout)
+	typeText(screen, "wi-fi")
+	screen.InjectKey(tcell.KeyEnter, 0, 0)
+
+	// Empty fields of the template are shown, to be filled in
+	waitFor(t, screen, "SSID", e2eTimeout)
+	waitFor(t, screen, "WPA2", e2eTimeout)
+	waitFor(t, screen, "[MODIFIED]", e2eTimeout)
+}
+
 func TestViewEntryAndRevealPassword(t *testing.T) {
 	filePath, password := makeTestKdbxFile(t)
 	db := openTestDatabase(t, filePath, password)
@@ -916,3 +938,33 @@ func TestViewAndDeleteWithRef(t *testing.T) {
 	waitFor(t, screen, "Search", e2eTimeout)
 	waitForAbsent(t, screen, "Coding/GitHub", e2eTimeout)
 }
+
+func TestIdleLock(t *testing.T) {
+	filePath, password := makeTestKdbxFile(t)
+	db := openTestDatabase(t, filePath, password)
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("failed to init simulation screen: %v", err)
+	}
+	screen.SetSize(80, 24)
+
+	tui.App = &tui.Application{Settings: tui.Settings{LockAfter: 300 * time.Millisecond}}
+	tui.Setup(screen, tui.State{Database: db}, false)
+	go tui.App.Run()
+
+	waitFor(t, screen, "Locked", e2eTimeout)
+	waitFor(t, screen, "Passphrase:", e2eTimeout)
+
+	typeText(screen, "wrong")
+	screen.InjectKey(tcell.KeyEnter, 0, 0)
+	waitFor(t, screen, "Could not unlock", e2eTimeout)
+
+	typeText(screen, password)
+	screen.InjectKey(tcell.KeyEnter, 0, 0)
+	waitFor(t, screen, "Help Text", e2eTimeout)
+	waitForAbsent(t, screen, "Passphrase:", e2eTimeout)
+
+	// Ends locked, so that the timer does not outlive the test
+	waitFor(t, screen, "Passphrase:", e2eTimeout)
+}
@@ -1,12 +1,16 @@
 package tui
 
 import (
+	"time"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/gdamore/tcell/v2/views"
 	"github.com/shikaan/keydex/pkg/errors"
 	"github.com/shikaan/keydex/pkg/kdbx"
 	"github.com/shikaan/keydex/pkg/log"
+	"github.com/shikaan/keydex/pkg/opener"
 	"github.com/shikaan/keydex/tui/components"
+	"github.com/shikaan/keydex/tui/keymap"
 )
 
 type Application struct {
@@ -20,17 +24,52 @@ type Application struct {
 	lastView   func(tcell.Screen) views.Widget
 	isDirty    bool
 	isReadOnly bool
+	clipboard  clipboardGuard
+	idle       idleGuard
+	watch      fileWatcher
+	locked     *lockedSession
+	tree       treeState
+
+	Settings Settings
 
 	views.Application
 }
 
+// Settings tweak the behaviour of the application. Set them before Run.
+type Settings struct {
+	// Time after which copied values are removed from the clipboard.
+	// Zero disables the removal.
+	ClearClipboardAfter time.Duration
+	// Keybindings of the editor. Nil means the default ones.
+	Keymap *keymap.Keymap
+	// Time of inactivity after which the database is locked.
+	// Zero disables locking.
+	LockAfter time.Duration
+	// What happens to unsaved changes when the database is locked
+	LockPolicy LockPolicy
+	// How the URLs of entries are opened
+	Opener opener.Options
+	// Copies the password of entries whose URL is opened
+	CopyPasswordOnOpen bool
+}
+
+// Returns the keymap in use
+func (a *Application) Keys() *keymap.Keymap {
+	if a.Settings.Keymap == nil {
+		a.Settings.Keymap = keymap.Default()
+	}
+	return a.Settings.Keymap
+}
+
 func (a *Application) RefreshCurrentView() {
-	a.layout.SetContent(a.lastView(a.screen))
+	a.lastWidget = a.lastView(a.screen)
+	a.layout.SetContent(a.lastWidget)
 }
 
 func (a *Application) NavigateToWithoutDirtyGuard(newView func(tcell.Screen) views.Widget) {
 	a.lastView = newView
-	a.layout.SetContent(newView(a.screen))
+	a.lastWidget = newView(a.screen)
+	a.layout.SetContent(a.lastWidget)
 }
 
 func (a *Application) NavigateTo(newView func(tcell.Screen) views.Widget) {
@@ -79,6 +118,36 @@ func (a *Application) Confirm(msg string, onAccept func(), onReject func()) {
 	)
 }
 
+// Asks for a line of text in the status bar, starting with value
+func (a *Application) Ask(msg, value string, onAnswer func(answer string), onReject func()) {
+	if a.LastFocused != nil {
+		a.LastFocused.SetFocus(false)
+	}
+
+	a.layout.Status.Ask(
+		msg,
+		value,
+		func(answer string) {
+			if onAnswer != nil {
+				onAnswer(answer)
+			}
+
+			if a.LastFocused != nil {
+				a.LastFocused.SetFocus(true)
+			}
+		},
+		func() {
+			if onReject != nil {
+				onReject()
+			}
+
+			if a.LastFocused != nil {
+				a.LastFocused.SetFocus(true)
+			}
+		},
+	)
+}
+
 func (a *Application) SetTitle(title string) {
 	a.layout.Title.SetTitle(title)
 }
@@ -92,8 +161,19 @@ func (a *Application) IsDirty() bool {
 	return a.isDirty
 }
 
+// Saves the database, which is then not reloaded as changed on disk
+func (a *Application) SaveDatabase() error {
+	if err := a.State.Database.SaveAndUnlockEntries(); err != nil {
+		return err
+	}
+
+	a.rememberDatabaseFile()
+	return nil
+}
+
 func (a *Application) LockCurrentDatabase(e error) {
 	a.isReadOnly = true
+	a.layout.Title.SetReadOnly(true)
 	msg := "Could not save. Switching to read-only to preserve database integrity."
 	a.Notify(msg)
 	log.Error(msg, e)
@@ -105,19 +185,48 @@ func (a *Application) IsReadOnly() bool {
 
 func (a *Application) Quit() {
 	if !a.IsDirty() {
-		a.Application.Quit()
+		a.quit()
 		return
 	}
 
 	a.Confirm(
 		"Are you sure you want to quit and lose unsaved changes?",
-		func() { a.Application.Quit() },
+		func() { a.quit() },
 		nil,
 	)
 }
 
+func (a *Application) quit() {
+	if a.idle.timer != nil {
+		a.idle.timer.Stop()
+	}
+	a.stopWatchingDatabase()
+	a.FlushClipboard()
+	a.Application.Quit()
+}
+
 func (a *Application) CreateEmptyEntry() error {
-	entry := a.State.Database.NewEntry()
+	return a.createEntry(a.State.Database.NewEntry())
+}
+
+// Creates an entry with the fields of the named template
+func (a *Application) CreateEntryFromTemplate(name string) error {
+	template, err := a.State.Database.GetTemplate(name)
+	if err != nil {
+		return err
+	}
+
+	entry, err := a.State.Database.NewEntryFromTemplate(template)
+	if err != nil {
+		return err
+	}
+
+	return a.createEntry(entry)
+}
+
+// Makes entry the current one, in the current group or in the root group.
+// The entry is added to the group when saved.
+func (a *Application) createEntry(entry *kdbx.Entry) error {
 	a.State.Entry = entry
 
 	if a.State.Group == nil {
@@ -155,12 +264,22 @@ func Setup(screen tcell.Screen, state State, readOnly bool) {
 	App.layout = NewLayout(screen)
 	App.SetRootWidget(App.layout)
 	App.isReadOnly = readOnly
+	App.layout.Title.SetReadOnly(readOnly)
+	App.locked = nil
+	App.tree = treeState{}
+	App.ResetIdleTimer()
 
 	if state.Reference == "" {
 		App.NavigateTo(NewHelpView)
 	} else {
 		App.NavigateTo(NewEntryView)
 	}
+
+	// Entries not in the database yet, such as those of the add command,
+	// are unsaved changes
+	if state.Entry != nil && state.Database.GetGroupForEntry(state.Entry) == nil {
+		App.SetDirty(true)
+	}
 }
 
 func Run(state State, readOnly bool) error {
@@ -169,5 +288,8 @@ func Run(state State, readOnly bool) error {
 		return errors.MakeError("Unable to start screen.", "tui")
 	}
 	Setup(screen, state, readOnly)
+	// Pasted text reaches inputs as a single change
+	App.EnablePaste(true)
+	App.WatchDatabase()
 	return App.Run()
 }
@@ -0,0 +1,82 @@
+package tui
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shikaan/keydex/pkg/clipboard"
+	"github.com/shikaan/keydex/pkg/log"
+)
+
+// Restores the clipboard after a secret has been copied from the editor
+type clipboardGuard struct {
+	timer *time.Timer
+	// Hash of the secret currently in the clipboard
+	hash string
+	// Content of the clipboard before the first copy
+	previous string
+	// Incremented on every copy, so that stale timers are ignored
+	generation int
+
+	mu sync.Mutex
+}
+
+// Copies value to the clipboard and schedules its removal after the
+// configured timeout. Subsequent copies postpone the removal.
+func (a *Application) CopyToClipboard(value string) error {
+	g := &a.clipboard
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	previous, err := clipboard.Swap(value)
+	if err != nil {
+		return err
+	}
+
+	after := a.Settings.ClearClipboardAfter
+	if after <= 0 {
+		return nil
+	}
+
+	// Keep restoring what was there before keydex touched the clipboard
+	if g.timer == nil || !g.timer.Stop() {
+		g.previous = previous
+	}
+
+	g.generation++
+	generation := g.generation
+
+	g.hash = clipboard.Hash(value)
+	g.timer = time.AfterFunc(after, func() { g.restore(generation) })
+	return nil
+}
+
+// Restores the clipboard right away if a removal is pending.
+func (a *Application) FlushClipboard() {
+	g := &a.clipboard
+	g.mu.Lock()
+	pending := g.timer != nil && g.timer.Stop()
+	generation := g.generation
+	g.mu.Unlock()
+
+	if pending {
+		g.restore(generation)
+	}
+}
+
+func (g *clipboardGuard) restore(generation int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if generation != g.generation {
+		return
+	}
+
+	if err := clipboard.RestoreIfUnchanged(g.hash, g.previous); err != nil {
+		log.Error("Could not clear clipboard", err)
+	}
+
+	g.timer = nil
+	g.hash = ""
+	g.previous = ""
+}
@@ -2,14 +2,14 @@ package autocomplete
 
 import (
 	"fmt"
-	"math"
 	"strings"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/gdamore/tcell/v2/views"
-	"github.com/lithammer/fuzzysearch/fuzzy"
 	"github.com/mattn/go-runewidth"
 	"github.com/shikaan/keydex/tui/components"
+	"github.com/shikaan/keydex/tui/keymap"
+	"github.com/shikaan/keydex/tui/theme"
 )
 
 type AutoComplete struct {
@@ -17,7 +17,7 @@ type AutoComplete struct {
 
 	options AutoCompleteOptions
 
-	list    *components.WithFocusables
+	list    *List
 	counter *views.SimpleStyledText
 	search  *Search
 
@@ -31,6 +31,10 @@ type AutoCompleteOptions struct {
 	TotalCount int
 	MaxX       int
 	MaxY       int
+	// Entries recently used, most recent first. They rank higher in results
+	Recent []string
+	// Bindings of the line editing actions. Nil means the default ones.
+	Keys *keymap.Keymap
 
 	OnSelect func(entry string) bool
 	OnFocus  func() bool
@@ -45,20 +49,12 @@ func NewAutoComplete(options AutoCompleteOptions) *AutoComplete {
 	autoComplete.options = options
 
 	search := NewSearch()
+	search.SetKeymap(options.Keys)
 	autoComplete.search = search
 	autoComplete.AddWidget(search, 0)
 
 	search.OnChange(func(ev tcell.Event) bool {
-		entries := options.Entries
-		content := search.GetContent()
-
-		if len(content) > 0 {
-			entries = fuzzy.FindFold(content, options.Entries)
-		}
-
-		autoComplete.drawList(entries)
-		autoComplete.drawCounter(entries)
-
+		autoComplete.filter()
 		return false
 	})
 
@@ -67,8 +63,18 @@ func NewAutoComplete(options AutoCompleteOptions) *AutoComplete {
 	autoComplete.counter = counter
 	autoComplete.AddWidget(counter, 0)
 
-	autoComplete.drawList(options.Entries)
-	autoComplete.drawCounter(options.Entries)
+	list := NewList(options.MaxX, options.MaxY)
+	list.OnSelect(func(match Match) bool {
+		return options.OnSelect(match.Entry)
+	})
+	list.OnChange(func(match Match) {
+		autoComplete.CurrentEntry = match.Entry
+		autoComplete.drawCounter()
+	})
+	autoComplete.list = list
+	autoComplete.AddWidget(list, 0)
+
+	autoComplete.filter()
 
 	return autoComplete
 }
@@ -85,72 +91,48 @@ func (ac *AutoComplete) HasFocus() bool {
 	return ac.search.HasFocus()
 }
 
-func (ac *AutoComplete) drawList(entries []string) {
-	container := &components.WithFocusables{}
-	container.SetOrientation(views.Vertical)
-
-	// The 2 characters are used for when the entry is selected
-	// and you have "> " prepended
-	maxLineLength := ac.options.MaxX - 2
+// Ranks the entries against the current search and updates the list
+func (ac *AutoComplete) filter() {
+	input := ac.search.GetContent()
+	matches := Rank(input, ac.options.Entries, ac.options.Recent)
 
-	if len(entries) == 0 {
+	if len(matches) == 0 {
+		ac.CurrentEntry = ""
 		if ac.options.OnEmpty == nil {
-			line := views.NewSimpleStyledText()
-			line.SetText(runewidth.FillRight("--- No Results ---", ac.options.MaxX))
-			container.AddWidget(line, 0)
+			ac.list.SetEmpty("--- No Results ---", nil)
 		} else {
-			input := ac.search.GetContent()
-			line := newOption()
-			line.SetContent(
-				runewidth.FillRight(
-					ac.options.FormatEmptyMessage(input),
-					ac.options.MaxX,
-				),
-			)
-			line.OnSelect(func() bool {
+			ac.list.SetEmpty(ac.options.FormatEmptyMessage(input), func() bool {
 				return ac.options.OnEmpty(input)
 			})
-			line.SetFocus(true)
-			container.AddWidget(line, 0)
 		}
 	}
 
-	for i, entry := range entries {
-		if i >= ac.options.MaxY {
-			break
-		}
-
-		line := newOption()
-		line.SetContent((runewidth.FillRight(runewidth.Truncate(entry, maxLineLength, ""), maxLineLength)))
-
-		// For memoization
-		e := entry
-
-		line.OnSelect(func() bool {
-			return ac.options.OnSelect(e)
-		})
+	ac.list.SetMatches(matches)
+	ac.drawCounter()
+}
 
-		line.OnFocus(func() bool {
-			ac.CurrentEntry = strings.TrimSpace(line.GetContent())
-			return true
-		})
+func (ac *AutoComplete) drawCounter() {
+	matched := len(ac.list.model.matches)
+	counter := fmt.Sprintf("%d/%d", matched, len(ac.options.Entries))
 
-		container.AddWidget(line, 0)
-		if i == 0 {
-			line.SetFocus(true)
-		}
+	if matched > 0 {
+		first, last, hasAbove, hasBelow := ac.list.VisibleRange()
+		counter = fmt.Sprintf("%s %d-%d %s  %s",
+			indicator("▴", hasAbove), first+1, last+1, indicator("▾", hasBelow), counter)
 	}
 
-	ac.RemoveWidget(ac.list)
-	ac.list = container
-	ac.AddWidget(ac.list, 0)
+	style := tcell.StyleDefault
+	if matched == 0 {
+		style = theme.Get(theme.STYLE_NO_MATCH)
+	}
+	ac.counter.SetStyle(style)
+	ac.counter.SetText(counter)
 }
 
-func (ac *AutoComplete) drawCounter(entries []string) {
-	matched := int(math.Min(float64(len(entries)), float64(ac.options.MaxY)))
-	counter := fmt.Sprintf("%d/%d", matched, len(ac.options.Entries))
-
-	ac.counter.SetStyle(tcell.StyleDefault.Bold(matched == 0))
-
-	ac.counter.SetText(fmt.Sprintf("% s", counter))
+// Returns symbol when shown is true, or a blank of the same width otherwise
+func indicator(symbol string, shown bool) string {
+	if shown {
+		return symbol
+	}
+	return strings.Repeat(" ", runewidth.StringWidth(symbol))
 }
@@ -0,0 +1,24 @@
+package autocomplete
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestAutoComplete_HomeEnd(t *testing.T) {
+	entries := []string{}
+	for _, match := range makeMatches(10) {
+		entries = append(entries, match.Entry)
+	}
+
+	ac := NewAutoComplete(AutoCompleteOptions{Entries: entries, TotalCount: len(entries), MaxX: 20, MaxY: 5, OnSelect: func(string) bool { return true }})
+	ac.SetFocus(true)
+
+	if !ac.HandleEvent(key(tcell.KeyEnd)) || ac.list.Index() != 9 {
+		t.Errorf("expected End to select the last match, got %d", ac.list.Index())
+	}
+	if !ac.HandleEvent(key(tcell.KeyHome)) || ac.list.Index() != 0 {
+		t.Errorf("expected Home to select the first match, got %d", ac.list.Index())
+	}
+}
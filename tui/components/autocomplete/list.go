@@ -0,0 +1,241 @@
+package autocomplete
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/views"
+	"github.com/mattn/go-runewidth"
+)
+
+// List is a virtualized list of matches. It holds a fixed pool of Option
+// widgets, one per visible row, which are recycled while scrolling or
+// filtering: only the visible slice of the matches is ever rendered.
+type List struct {
+	model *listModel
+	rows  []*Option
+
+	views.BoxLayout
+}
+
+type listModel struct {
+	matches []Match
+	// Index of the selected match
+	selected int
+	// True when the selection was moved by the user, and should therefore
+	// survive filter updates. Otherwise the selection follows the top match
+	pinned bool
+	// Index of the first visible match
+	offset int
+	// Number of visible rows
+	height int
+	width  int
+
+	// Message shown in place of the results when there are no matches
+	emptyMessage string
+
+	// Handle select events: triggered when pressing Enter on a match
+	// Returns true if handled, false if needs cascading
+	selectHandler func(match Match) bool
+	// Handle empty select events: triggered when pressing Enter without matches
+	// Returns true if handled, false if needs cascading
+	emptyHandler func() bool
+	// Handle selection changes: triggered every time the selected match changes
+	changeHandler func(match Match)
+}
+
+// Replaces the matches in the list. An entry selected by the user is
+// preserved across updates, if still present; otherwise the selection goes
+// to the first match.
+func (l *List) SetMatches(matches []Match) {
+	m := l.model
+	current, hasCurrent := l.Selected()
+
+	m.matches = matches
+	m.selected = 0
+
+	if hasCurrent && m.pinned {
+		m.pinned = false
+		for i, match := range matches {
+			if match.Entry == current.Entry {
+				m.selected = i
+				m.pinned = true
+				break
+			}
+		}
+	}
+
+	l.scrollToSelection()
+	l.draw()
+	l.notifyChange()
+}
+
+// Sets the message displayed when there are no matches. If a handler is
+// provided, the message becomes selectable.
+func (l *List) SetEmpty(message string, onSelect func() bool) {
+	l.model.emptyMessage = message
+	l.model.emptyHandler = onSelect
+	l.draw()
+}
+
+// Returns the selected match, if any
+func (l *List) Selected() (Match, bool) {
+	m := l.model
+	if m.selected < 0 || m.selected >= len(m.matches) {
+		return Match{}, false
+	}
+	return m.matches[m.selected], true
+}
+
+// Returns the index of the selected match, -1 if there is none
+func (l *List) Index() int {
+	if _, ok := l.Selected(); !ok {
+		return -1
+	}
+	return l.model.selected
+}
+
+// Returns the index of the first and last visible matches, and whether
+// there are matches hidden above and below the visible ones
+func (l *List) VisibleRange() (first, last int, hasAbove, hasBelow bool) {
+	m := l.model
+	last = min(m.offset+m.height, len(m.matches)) - 1
+	return m.offset, last, m.offset > 0, last < len(m.matches)-1
+}
+
+func (l *List) OnSelect(cb func(match Match) bool) func() {
+	l.model.selectHandler = cb
+	return func() {
+		l.model.selectHandler = nil
+	}
+}
+
+func (l *List) OnChange(cb func(match Match)) func() {
+	l.model.changeHandler = cb
+	return func() {
+		l.model.changeHandler = nil
+	}
+}
+
+func (l *List) HandleEvent(ev tcell.Event) bool {
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		m := l.model
+		count := len(m.matches)
+
+		switch ev.Key() {
+		case tcell.KeyEnter:
+			if match, ok := l.Selected(); ok {
+				return m.selectHandler != nil && m.selectHandler(match)
+			}
+			return m.emptyHandler != nil && m.emptyHandler()
+		case tcell.KeyUp, tcell.KeyBacktab:
+			if count > 0 {
+				l.Select((m.selected - 1 + count) % count)
+			}
+			return true
+		case tcell.KeyDown, tcell.KeyTab:
+			if count > 0 {
+				l.Select((m.selected + 1) % count)
+			}
+			return true
+		case tcell.KeyPgUp:
+			l.Select(m.selected - m.height)
+			return true
+		case tcell.KeyPgDn:
+			l.Select(m.selected + m.height)
+			return true
+		case tcell.KeyHome:
+			l.Select(0)
+			return true
+		case tcell.KeyEnd:
+			l.Select(count - 1)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Selects the match at index, scrolling it into view
+func (l *List) Select(index int) {
+	m := l.model
+	if len(m.matches) == 0 {
+		return
+	}
+
+	index = max(min(index, len(m.matches)-1), 0)
+	m.pinned = true
+	if index == m.selected {
+		return
+	}
+
+	m.selected = index
+	l.scrollToSelection()
+	l.draw()
+	l.notifyChange()
+}
+
+func (l *List) scrollToSelection() {
+	m := l.model
+	if m.selected < m.offset {
+		m.offset = m.selected
+	}
+	if m.selected >= m.offset+m.height {
+		m.offset = m.selected - m.height + 1
+	}
+	m.offset = max(min(m.offset, len(m.matches)-m.height), 0)
+}
+
+func (l *List) notifyChange() {
+	if match, ok := l.Selected(); ok && l.model.changeHandler != nil {
+		l.model.changeHandler(match)
+	}
+}
+
+// Updates the content of the visible rows
+func (l *List) draw() {
+	m := l.model
+
+	// The 2 characters are used for when the entry is selected
+	// and you have "> " prepended
+	maxLineLength := m.width - 2
+
+	for i, row := range l.rows {
+		row.SetFocus(false)
+		index := m.offset + i
+
+		if len(m.matches) == 0 && i == 0 {
+			row.SetContent(runewidth.FillRight(m.emptyMessage, m.width))
+			row.SetHighlights(nil)
+			row.SetFocus(m.emptyHandler != nil)
+			continue
+		}
+
+		if index >= len(m.matches) {
+			row.SetContent(runewidth.FillRight("", m.width))
+			row.SetHighlights(nil)
+			continue
+		}
+
+		match := m.matches[index]
+		row.SetContent(runewidth.FillRight(runewidth.Truncate(match.Entry, maxLineLength, ""), maxLineLength))
+		row.SetHighlights(match.Positions)
+		row.SetFocus(index == m.selected)
+	}
+}
+
+// Returns a list rendering at most height rows of width cells
+func NewList(width, height int) *List {
+	l := &List{}
+	l.SetOrientation(views.Vertical)
+	l.model = &listModel{width: width, height: max(height, 1)}
+
+	l.rows = make([]*Option, l.model.height)
+	for i := range l.rows {
+		row := newOption()
+		l.rows[i] = row
+		l.AddWidget(row, 0)
+	}
+
+	l.draw()
+	return l
+}
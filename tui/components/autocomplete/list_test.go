@@ -0,0 +1,142 @@
+package autocomplete
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func makeMatches(count int) []Match {
+	matches := []Match{}
+	for i := range count {
+		matches = append(matches, Match{Entry: fmt.Sprintf("/db/entry%03d", i)})
+	}
+	return matches
+}
+
+func key(k tcell.Key) *tcell.EventKey {
+	return tcell.NewEventKey(k, 0, 0)
+}
+
+func TestList_HandleEvent(t *testing.T) {
+	tests := []struct {
+		name         string
+		keys         []tcell.Key
+		wantSelected int
+		wantOffset   int
+	}{
+		{"down moves selection", []tcell.Key{tcell.KeyDown}, 1, 0},
+		{"down scrolls past the last row", []tcell.Key{tcell.KeyDown, tcell.KeyDown, tcell.KeyDown, tcell.KeyDown, tcell.KeyDown}, 5, 1},
+		{"up wraps to the end", []tcell.Key{tcell.KeyUp}, 99, 95},
+		{"page down moves by a page", []tcell.Key{tcell.KeyPgDn}, 5, 1},
+		{"page up stops at the start", []tcell.Key{tcell.KeyPgDn, tcell.KeyPgUp, tcell.KeyPgUp}, 0, 0},
+		{"end goes to the last match", []tcell.Key{tcell.KeyEnd}, 99, 95},
+		{"home goes to the first match", []tcell.Key{tcell.KeyEnd, tcell.KeyHome}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewList(20, 5)
+			l.SetMatches(makeMatches(100))
+
+			for _, k := range tt.keys {
+				if !l.HandleEvent(key(k)) {
+					t.Fatalf("List.HandleEvent() did not handle %v", k)
+				}
+			}
+
+			if l.model.selected != tt.wantSelected {
+				t.Errorf("List.HandleEvent() selected = %d, want %d", l.model.selected, tt.wantSelected)
+			}
+			if l.model.offset != tt.wantOffset {
+				t.Errorf("List.HandleEvent() offset = %d, want %d", l.model.offset, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestList_SetMatches(t *testing.T) {
+	t.Run("follows the top match when not navigated", func(t *testing.T) {
+		l := NewList(20, 5)
+		l.SetMatches(makeMatches(10))
+		l.SetMatches(makeMatches(10)[3:])
+
+		if got, _ := l.Selected(); got.Entry != "/db/entry003" {
+			t.Errorf("List.SetMatches() selected = %s, want /db/entry003", got.Entry)
+		}
+	})
+
+	t.Run("keeps a user selection across updates", func(t *testing.T) {
+		l := NewList(20, 5)
+		l.SetMatches(makeMatches(10))
+		l.Select(7)
+		l.SetMatches(makeMatches(10)[3:])
+
+		if got, _ := l.Selected(); got.Entry != "/db/entry007" {
+			t.Errorf("List.SetMatches() selected = %s, want /db/entry007", got.Entry)
+		}
+		if l.model.selected != 4 {
+			t.Errorf("List.SetMatches() selected index = %d, want 4", l.model.selected)
+		}
+	})
+
+	t.Run("resets when the selection is filtered out", func(t *testing.T) {
+		l := NewList(20, 5)
+		l.SetMatches(makeMatches(10))
+		l.Select(1)
+		l.SetMatches(makeMatches(10)[3:])
+
+		if got, _ := l.Selected(); got.Entry != "/db/entry003" {
+			t.Errorf("List.SetMatches() selected = %s, want /db/entry003", got.Entry)
+		}
+	})
+
+	t.Run("recycles rows", func(t *testing.T) {
+		l := NewList(20, 5)
+		rows := append([]*Option{}, l.rows...)
+		l.SetMatches(makeMatches(1000))
+		l.HandleEvent(key(tcell.KeyEnd))
+
+		for i := range rows {
+			if rows[i] != l.rows[i] {
+				t.Fatalf("List rows were rebuilt")
+			}
+		}
+		if len(l.Widgets()) != 5 {
+			t.Errorf("List rendered %d rows, want 5", len(l.Widgets()))
+		}
+		if got := strings.TrimSpace(l.rows[4].GetContent()); got != "/db/entry999" {
+			t.Errorf("List last row = %q, want /db/entry999", got)
+		}
+	})
+}
+
+func TestList_Empty(t *testing.T) {
+	t.Run("selects the empty message with a handler", func(t *testing.T) {
+		l := NewList(20, 5)
+		spy := NewHandlerSpy(true)
+		l.SetEmpty("Create", spy.handler)
+		l.SetMatches([]Match{})
+
+		if !l.HandleEvent(key(tcell.KeyEnter)) {
+			t.Error("List.HandleEvent() did not handle Enter")
+		}
+		if spy.calls != 1 {
+			t.Errorf("List.HandleEvent() called empty handler %d times, want 1", spy.calls)
+		}
+	})
+
+	t.Run("does not select without a handler", func(t *testing.T) {
+		l := NewList(20, 5)
+		l.SetEmpty("No results", nil)
+		l.SetMatches([]Match{})
+
+		if l.HandleEvent(key(tcell.KeyEnter)) {
+			t.Error("List.HandleEvent() handled Enter without handler")
+		}
+		if l.rows[0].HasFocus() {
+			t.Error("List empty message should not be focused without handler")
+		}
+	})
+}
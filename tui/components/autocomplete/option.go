@@ -9,6 +9,7 @@ import (
 	"github.com/mattn/go-runewidth"
 	"github.com/shikaan/keydex/tui/components"
 	"github.com/shikaan/keydex/tui/components/line"
+	"github.com/shikaan/keydex/tui/theme"
 )
 
 type Option struct {
@@ -20,11 +21,13 @@ type Option struct {
 }
 
 type optionModel struct {
-	content       string
-	runes         line.PaddedLine
-	width         int
-	style         tcell.Style
-	hasFocus      bool
+	content  string
+	runes    line.PaddedLine
+	width    int
+	style    tcell.Style
+	hasFocus bool
+	// Cells of the content (without the focus caret) to be highlighted
+	highlights    []bool
 	selectHandler func() bool
 	focusHandler  func() bool
 }
@@ -34,13 +37,27 @@ func (m *optionModel) GetCell(x, y int) (rune, tcell.Style, []rune, int) {
 		return line.EMPTY_CELL, m.style, nil, 1
 	}
 
+	style := m.style
+	if m.isHighlighted(x) {
+		style = theme.Merge(style, theme.Get(theme.STYLE_MATCH))
+	}
+
 	if char := m.runes[x]; unicode.IsPrint(char) {
-		return char, m.style, nil, runewidth.RuneWidth(char)
+		return char, style, nil, runewidth.RuneWidth(char)
 	}
 
 	return line.EMPTY_CELL, m.style, nil, 1
 }
 
+func (m *optionModel) isHighlighted(x int) bool {
+	if m.hasFocus {
+		// Skips the "> " caret
+		x -= 2
+	}
+
+	return x >= 0 && x < len(m.highlights) && m.highlights[x]
+}
+
 func (m *optionModel) GetBounds() (int, int) {
 	return m.width, 1
 }
@@ -77,6 +94,10 @@ func (i *Option) SetFocus(on bool) {
 	}
 
 	i.model.hasFocus = on
+	i.model.style = tcell.StyleDefault
+	if on {
+		i.model.style = theme.Get(theme.STYLE_SELECTED)
+	}
 	i.CellView.SetModel(i.model)
 
 	if i.model.focusHandler != nil {
@@ -92,6 +113,29 @@ func (i *Option) SetContent(text string) {
 	i.CellView.SetModel(i.model)
 }
 
+// Highlights the runes of the content at the given positions. Positions are
+// rune indices, as returned by Rank, and are converted here to cells.
+func (i *Option) SetHighlights(positions []int) {
+	i.Init()
+	content := i.GetContent()
+	highlights := make([]bool, runewidth.StringWidth(content))
+
+	wanted := make(map[int]bool, len(positions))
+	for _, p := range positions {
+		wanted[p] = true
+	}
+
+	cell := 0
+	for index, char := range []rune(content) {
+		if wanted[index] && cell < len(highlights) {
+			highlights[cell] = true
+		}
+		cell += runewidth.RuneWidth(char)
+	}
+
+	i.model.highlights = highlights
+}
+
 func (i *Option) GetContent() string {
 	if i.model.hasFocus {
 		if len(i.model.content) >= 2 {
@@ -155,3 +155,38 @@ func TestOptionModel_GetCell(t *testing.T) {
 		})
 	}
 }
+
+func TestOption_SetHighlights(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		positions []int
+		focus     bool
+		want      []int
+	}{
+		{"highlights cells", "github", []int{0, 3}, false, []int{0, 3}},
+		{"accounts for the caret", "github", []int{0, 3}, true, []int{2, 5}},
+		{"accounts for wide runes", "🤖bot", []int{1}, false, []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newOption()
+			o.SetContent(tt.content)
+			o.SetHighlights(tt.positions)
+			o.SetFocus(tt.focus)
+
+			got := []int{}
+			for x := range len(o.model.runes) {
+				_, style, _, _ := o.model.GetCell(x, 0)
+				if _, _, attrs := style.Decompose(); attrs&tcell.AttrUnderline != 0 {
+					got = append(got, x)
+				}
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Option.SetHighlights() highlighted cells = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package autocomplete
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scoring weights. They are loosely inspired by fzf: a plain match is worth
+// little, while matches at word boundaries, consecutive runs, and matches
+// in the title (the last portion of the reference) weigh more.
+const (
+	SCORE_MATCH           = 16
+	BONUS_BOUNDARY        = 10
+	BONUS_FIRST_CHAR      = 6
+	BONUS_CONSECUTIVE     = 8
+	BONUS_TITLE           = 6
+	BONUS_RECENT          = 30
+	PENALTY_GAP_START     = 3
+	PENALTY_GAP_EXTENSION = 1
+)
+
+const noScore = -1 << 30
+
+// Match is a ranked result of a fuzzy search
+type Match struct {
+	Entry string
+	Score int
+	// Indices of the runes of Entry matching the query
+	Positions []int
+}
+
+// Rank filters entries fuzzy-matching query and sorts them by match quality.
+// Matching is case-insensitive. Entries in recent get an extra bonus, the
+// more recent the bigger; recent is expected to be sorted most recent first.
+//
+// An empty query matches everything, sorting recent entries first and
+// preserving the original order for the rest.
+func Rank(query string, entries []string, recent []string) []Match {
+	recency := make(map[string]int, len(recent))
+	for i, r := range recent {
+		if _, ok := recency[r]; !ok {
+			recency[r] = len(recent) - i
+		}
+	}
+
+	q := []rune(strings.ToLower(query))
+	matches := []Match{}
+
+	for _, entry := range entries {
+		score, positions := score(q, entry)
+		if score == noScore {
+			continue
+		}
+
+		if r, ok := recency[entry]; ok {
+			score += BONUS_RECENT * r / len(recent)
+		}
+
+		matches = append(matches, Match{Entry: entry, Score: score, Positions: positions})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+
+		// On a tie, shorter entries are likely more relevant
+		if len(query) > 0 {
+			return len(matches[i].Entry) < len(matches[j].Entry)
+		}
+
+		return false
+	})
+
+	return matches
+}
+
+// Scores entry against the lowercase query q, returning the best score and
+// the matching positions. Returns noScore if entry does not match.
+//
+// This is a dynamic programming approach: best[i][j] holds the best score
+// for matching q[:i+1] with q[i] matched at entry[j].
+func score(q []rune, entry string) (int, []int) {
+	if len(q) == 0 {
+		return 0, []int{}
+	}
+
+	text := []rune(entry)
+	lower := []rune(strings.ToLower(entry))
+	n, m := len(q), len(text)
+
+	// ToLower can change the rune count for a handful of runes; bail out
+	// to a case-sensitive comparison in that case to keep indices aligned
+	if len(lower) != m {
+		lower = text
+	}
+
+	if n > m {
+		return noScore, nil
+	}
+
+	titleStart := strings.LastIndex(entry, "/") + 1
+	titleStart = len([]rune(entry[:titleStart]))
+
+	bonus := make([]int, m)
+	for j := range text {
+		bonus[j] = boundaryBonus(text, j)
+		if j >= titleStart {
+			bonus[j] += BONUS_TITLE
+		}
+	}
+
+	best := make([][]int, n)
+	from := make([][]int, n)
+	for i := range n {
+		best[i] = make([]int, m)
+		from[i] = make([]int, m)
+		for j := range m {
+			best[i][j] = noScore
+			from[i][j] = -1
+		}
+	}
+
+	for i := range n {
+		// Best (score, position) so far for the previous query rune,
+		// accounting for the gap penalty to reach the current position
+		runningScore, runningFrom := noScore, -1
+
+		for j := i; j < m; j++ {
+			if i > 0 && j > 1 {
+				// Every step away from the previous match widens the gap
+				if runningScore != noScore {
+					runningScore -= PENALTY_GAP_EXTENSION
+				}
+				// A match at j-2 leaves a gap of exactly one rune
+				if prev := best[i-1][j-2]; prev != noScore && prev-PENALTY_GAP_START > runningScore {
+					runningScore, runningFrom = prev-PENALTY_GAP_START, j-2
+				}
+			}
+
+			if lower[j] != q[i] {
+				continue
+			}
+
+			char := SCORE_MATCH + bonus[j]
+			if i == 0 {
+				if j == 0 {
+					char += BONUS_FIRST_CHAR
+				}
+				best[i][j] = char - j*PENALTY_GAP_EXTENSION/2
+				continue
+			}
+
+			candidate, origin := noScore, -1
+			if j > 0 && best[i-1][j-1] != noScore {
+				candidate, origin = best[i-1][j-1]+char+BONUS_CONSECUTIVE, j-1
+			}
+			if runningScore != noScore && runningScore+char > candidate {
+				candidate, origin = runningScore+char, runningFrom
+			}
+
+			best[i][j], from[i][j] = candidate, origin
+		}
+	}
+
+	end, top := -1, noScore
+	for j := range m {
+		if best[n-1][j] > top {
+			end, top = j, best[n-1][j]
+		}
+	}
+
+	if end == -1 {
+		return noScore, nil
+	}
+
+	positions := make([]int, n)
+	for i, j := n-1, end; i >= 0; i-- {
+		positions[i] = j
+		j = from[i][j]
+	}
+
+	return top, positions
+}
+
+// Returns a bonus for runes starting a word: beginning of the string,
+// after a separator, or a lower-to-upper case transition (camelCase)
+func boundaryBonus(text []rune, j int) int {
+	if j == 0 {
+		return BONUS_BOUNDARY
+	}
+
+	prev, curr := text[j-1], text[j]
+	if isSeparator(prev) && !isSeparator(curr) {
+		return BONUS_BOUNDARY
+	}
+
+	if unicode.IsLower(prev) && unicode.IsUpper(curr) {
+		return BONUS_BOUNDARY - 2
+	}
+
+	if unicode.IsLetter(prev) != unicode.IsLetter(curr) && !isSeparator(curr) {
+		return BONUS_BOUNDARY / 2
+	}
+
+	return 0
+}
+
+func isSeparator(r rune) bool {
+	return r == '/' || r == ' ' || r == '-' || r == '_' || r == '.' || r == ':' || r == '@'
+}
@@ -0,0 +1,120 @@
+package autocomplete
+
+import (
+	"reflect"
+	"testing"
+)
+
+func entriesOf(matches []Match) []string {
+	result := []string{}
+	for _, m := range matches {
+		result = append(result, m.Entry)
+	}
+	return result
+}
+
+func TestRank(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		entries []string
+		recent  []string
+		want    []string
+	}{
+		{
+			"empty query keeps order",
+			"",
+			[]string{"/db/b", "/db/a"},
+			nil,
+			[]string{"/db/b", "/db/a"},
+		},
+		{
+			"empty query shows recent first",
+			"",
+			[]string{"/db/b", "/db/a", "/db/c"},
+			[]string{"/db/c"},
+			[]string{"/db/c", "/db/b", "/db/a"},
+		},
+		{
+			"filters non matching entries",
+			"hub",
+			[]string{"/db/coding/GitHub", "/db/coding/GitLab"},
+			nil,
+			[]string{"/db/coding/GitHub"},
+		},
+		{
+			"is case insensitive",
+			"GITHUB",
+			[]string{"/db/coding/github"},
+			nil,
+			[]string{"/db/coding/github"},
+		},
+		{
+			"prefers word boundaries",
+			"gh",
+			[]string{"/db/laughing/things", "/db/coding/GitHub", "/db/gh"},
+			nil,
+			[]string{"/db/gh", "/db/coding/GitHub", "/db/laughing/things"},
+		},
+		{
+			"prefers title matches",
+			"mail",
+			[]string{"/db/mail/Work", "/db/personal/Mail"},
+			nil,
+			[]string{"/db/personal/Mail", "/db/mail/Work"},
+		},
+		{
+			"prefers consecutive matches",
+			"bank",
+			[]string{"/db/b/a/n/k", "/db/Bank"},
+			nil,
+			[]string{"/db/Bank", "/db/b/a/n/k"},
+		},
+		{
+			"boosts recent entries",
+			"mail",
+			[]string{"/db/Mail", "/db/Email"},
+			[]string{"/db/Email"},
+			[]string{"/db/Email", "/db/Mail"},
+		},
+		{
+			"query longer than entry",
+			"longer query",
+			[]string{"/db/a"},
+			nil,
+			[]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := entriesOf(Rank(tt.query, tt.entries, tt.recent))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rank() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRank_Positions(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		entry string
+		want  []int
+	}{
+		{"consecutive", "git", "/db/GitHub", []int{4, 5, 6}},
+		{"word starts", "gh", "/db/GitHub", []int{4, 7}},
+		{"unicode", "ü", "/db/Tür", []int{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := Rank(tt.query, []string{tt.entry}, nil)
+			if len(matches) != 1 {
+				t.Fatalf("Rank() returned %d matches, want 1", len(matches))
+			}
+			if !reflect.DeepEqual(matches[0].Positions, tt.want) {
+				t.Errorf("Rank() positions = %v, want %v", matches[0].Positions, tt.want)
+			}
+		})
+	}
+}
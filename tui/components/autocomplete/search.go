@@ -10,6 +10,8 @@ import (
 	"github.com/mattn/go-runewidth"
 	"github.com/shikaan/keydex/tui/components"
 	"github.com/shikaan/keydex/tui/components/line"
+	"github.com/shikaan/keydex/tui/keymap"
+	"github.com/shikaan/keydex/tui/theme"
 	"golang.org/x/exp/slices"
 )
 
@@ -33,6 +35,16 @@ type searchModel struct {
 	hasFocus      bool
 	changeHandler func(ev tcell.Event) bool
 	focusHandler  func() bool
+	keys          *keymap.Keymap
+	paste         line.Paste
+}
+
+// Returns the keymap in use
+func (m *searchModel) keymap() *keymap.Keymap {
+	if m.keys == nil {
+		m.keys = keymap.Default()
+	}
+	return m.keys
 }
 
 func (m *searchModel) GetCell(x, y int) (rune, tcell.Style, []rune, int) {
@@ -104,14 +116,87 @@ func (s *Search) GetContent() string {
 	return s.model.content
 }
 
+// Sets the bindings of the line editing actions. Nil means the default ones.
+func (s *Search) SetKeymap(keys *keymap.Keymap) {
+	s.Init()
+	s.model.keys = keys
+}
+
 func (s *Search) HandleEvent(ev tcell.Event) bool {
 	if !s.HasFocus() {
 		return false
 	}
 
+	if consumed, ended := s.model.paste.Feed(ev); consumed {
+		if ended {
+			return s.insertText(ev, s.model.paste.Take())
+		}
+		return true
+	}
+
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
+		m := s.model
+
+		switch line.EditOf(m.keymap(), ev) {
+		// Home and End are left to the list, which selects the first and
+		// the last match with them
+		case line.EDIT_LINE_START:
+			if !m.keymap().Is(ev, keymap.ACTION_LINE_START) {
+				return false
+			}
+			m.x = 0
+			return true
+		case line.EDIT_LINE_END:
+			if !m.keymap().Is(ev, keymap.ACTION_LINE_END) {
+				return false
+			}
+			m.x = len(m.runes)
+			return true
+		case line.EDIT_WORD_LEFT:
+			m.x = line.PreviousWord(m.runes, m.x)
+			return true
+		case line.EDIT_WORD_RIGHT:
+			m.x = line.NextWord(m.runes, m.x)
+			return true
+		case line.EDIT_DELETE_WORD:
+			return s.handleContentUpdate(ev, func() int {
+				start := line.PreviousWord(m.runes, m.x)
+				m.runes = slices.Delete(m.runes, start, m.x)
+				return start - m.x
+			})
+		case line.EDIT_DELETE_NEXT_WORD:
+			return s.handleContentUpdate(ev, func() int {
+				m.runes = slices.Delete(m.runes, m.x, line.NextWord(m.runes, m.x))
+				return 0
+			})
+		case line.EDIT_KILL_LINE:
+			return s.handleContentUpdate(ev, func() int {
+				m.runes = m.runes[:m.x]
+				return 0
+			})
+		}
+
 		switch ev.Key() {
+		case tcell.KeyLeft:
+			if m.x > 0 {
+				_, m.x = line.GetRune(m.runes, m.x-1)
+			}
+			return true
+		case tcell.KeyRight:
+			if m.x < len(m.runes) {
+				m.x = min(m.x+runewidth.RuneWidth(m.runes[m.x]), len(m.runes))
+			}
+			return true
+		case tcell.KeyDelete:
+			if m.x >= len(m.runes) {
+				return false
+			}
+			return s.handleContentUpdate(ev, func() int {
+				char, _ := line.GetRune(m.runes, m.x)
+				m.runes = slices.Delete(m.runes, m.x, m.x+runewidth.RuneWidth(char))
+				return 0
+			})
 		case tcell.KeyRune:
 			return s.handleContentUpdate(ev, func() int {
 				char := ev.Rune()
@@ -133,6 +218,21 @@ func (s *Search) HandleEvent(ev tcell.Event) bool {
 	return false
 }
 
+// Inserts text at the cursor as a single change, such as once pasted. Search
+// is a single line, so line breaks are dropped.
+func (s *Search) insertText(ev tcell.Event, text string) bool {
+	text = strings.NewReplacer("\r", "", "\n", "").Replace(text)
+	if text == "" {
+		return true
+	}
+
+	return s.handleContentUpdate(ev, func() int {
+		inserted := line.NewPaddedLine(text)
+		s.model.runes = slices.Insert(s.model.runes, s.model.x, inserted...)
+		return len(inserted)
+	})
+}
+
 func (s *Search) handleContentUpdate(ev tcell.Event, cb func() int) bool {
 	offset := cb()
 	s.SetContent(toString(s.model.runes))
@@ -156,6 +256,7 @@ func (i *Search) Init() {
 func NewSearch() *Search {
 	s := &Search{}
 	s.Init()
+	s.model.style = theme.Get(theme.STYLE_VALUE)
 	return s
 }
 
@@ -167,6 +167,66 @@ func TestSearch_HandleEvent(t *testing.T) {
 			wantHandled: true,
 			wantContent: "ello",
 		},
+		{
+			name: "deletes the previous word",
+			fields: fields{
+				model: &searchModel{
+					content:  "foo bar",
+					runes:    line.NewPaddedLine("foo bar"),
+					x:        7,
+					style:    tcell.StyleDefault,
+					hasFocus: true,
+				},
+			},
+			ev:          tcell.NewEventKey(tcell.KeyCtrlW, 0, tcell.ModCtrl),
+			wantHandled: true,
+			wantContent: "foo ",
+		},
+		{
+			name: "deletes the next word",
+			fields: fields{
+				model: &searchModel{
+					content:  "foo bar",
+					runes:    line.NewPaddedLine("foo bar"),
+					x:        0,
+					style:    tcell.StyleDefault,
+					hasFocus: true,
+				},
+			},
+			ev:          tcell.NewEventKey(tcell.KeyRune, 'd', tcell.ModAlt),
+			wantHandled: true,
+			wantContent: " bar",
+		},
+		{
+			name: "kills to the end",
+			fields: fields{
+				model: &searchModel{
+					content:  "foo bar",
+					runes:    line.NewPaddedLine("foo bar"),
+					x:        3,
+					style:    tcell.StyleDefault,
+					hasFocus: true,
+				},
+			},
+			ev:          tcell.NewEventKey(tcell.KeyRune, 'k', tcell.ModAlt),
+			wantHandled: true,
+			wantContent: "foo",
+		},
+		{
+			name: "handles delete key event",
+			fields: fields{
+				model: &searchModel{
+					content:  "hello",
+					runes:    line.NewPaddedLine("hello"),
+					x:        1,
+					style:    tcell.StyleDefault,
+					hasFocus: true,
+				},
+			},
+			ev:          tcell.NewEventKey(tcell.KeyDelete, 0, tcell.ModNone),
+			wantHandled: true,
+			wantContent: "hllo",
+		},
 		{
 			name: "doesn't handle event without focus",
 			fields: fields{
@@ -197,3 +257,53 @@ func TestSearch_HandleEvent(t *testing.T) {
 		})
 	}
 }
+
+func TestSearch_Moves(t *testing.T) {
+	s := NewSearch()
+	s.SetContent("foo bar")
+	s.SetFocus(true)
+
+	moves := []struct {
+		ev    *tcell.EventKey
+		wantX int
+	}{
+		{tcell.NewEventKey(tcell.KeyCtrlE, 0, tcell.ModCtrl), 7},
+		{tcell.NewEventKey(tcell.KeyRune, 'b', tcell.ModAlt), 4},
+		{tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone), 3},
+		{tcell.NewEventKey(tcell.KeyCtrlA, 0, tcell.ModCtrl), 0},
+		{tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModNone), 1},
+		{tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModCtrl), 3},
+	}
+	for _, move := range moves {
+		if !s.HandleEvent(move.ev) || s.model.x != move.wantX {
+			t.Errorf("Search.HandleEvent(%v) moved to %d, want %d", move.ev.Name(), s.model.x, move.wantX)
+		}
+	}
+}
+
+func TestSearch_Paste(t *testing.T) {
+	s := NewSearch()
+	s.SetContent("ad")
+	s.SetFocus(true)
+	s.model.x = 1
+
+	changes := 0
+	s.OnChange(func(ev tcell.Event) bool {
+		changes++
+		return true
+	})
+
+	s.HandleEvent(tcell.NewEventPaste(true))
+	for _, r := range "bc" {
+		s.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+	s.HandleEvent(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+	s.HandleEvent(tcell.NewEventPaste(false))
+
+	if got := s.GetContent(); got != "abcd" || changes != 1 {
+		t.Errorf("Search.GetContent() = %q after %d changes, want %q after one", got, changes, "abcd")
+	}
+	if s.model.x != 3 {
+		t.Errorf("cursor = %d, want 3", s.model.x)
+	}
+}
@@ -1,8 +1,6 @@
 package components
 
 import (
-	"sync"
-
 	"github.com/gdamore/tcell/v2"
 	"github.com/gdamore/tcell/v2/views"
 )
@@ -15,8 +13,6 @@ type Container struct {
 	wview  views.ViewPort
 	widget views.Widget
 
-	once sync.Once
-
 	views.WidgetWatchers
 }
 
@@ -45,6 +41,15 @@ func (c *Container) Size() (int, int) {
 	return c.widget.Size()
 }
 
+// Returns the size of the area the content is shown in
+func (c *Container) ViewSize() (int, int) {
+	if c.view == nil {
+		return 0, 0
+	}
+	return c.view.Size()
+}
+
+// Shows w, replacing the content shown before
 func (c *Container) SetContent(w views.Widget) {
 	c.initialize(w)
 	c.PostEventWidgetContent(c)
@@ -65,11 +70,17 @@ func (c *Container) HandleEvent(ev tcell.Event) bool {
 }
 
 func (c *Container) initialize(w views.Widget) {
+	if w == c.widget {
+		return
+	}
+
+	if c.widget != nil {
+		c.widget.Unwatch(c)
+	}
+
 	c.widget = w
-	c.once.Do(func() {
-		c.widget.SetView(&c.wview)
-		c.widget.Watch(c)
-	})
+	c.widget.SetView(&c.wview)
+	c.widget.Watch(c)
 }
 
 func (c *Container) layout() {
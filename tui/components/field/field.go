@@ -3,7 +3,10 @@ package field
 import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/gdamore/tcell/v2/views"
+	"github.com/shikaan/keydex/pkg/secure"
 	"github.com/shikaan/keydex/tui/components"
+	"github.com/shikaan/keydex/tui/keymap"
+	"github.com/shikaan/keydex/tui/theme"
 )
 
 type Field struct {
@@ -19,6 +22,8 @@ type FieldOptions struct {
 	InitialValue string
 	InputType    InputType
 	Disabled     bool
+	// Bindings of the line editing actions. Nil means the default ones.
+	Keys *keymap.Keymap
 }
 
 func (f *Field) HasFocus() bool {
@@ -53,6 +58,10 @@ func (f *Field) GetContent() string {
 	return f.input.GetContent()
 }
 
+func (f *Field) SetSecret(value *secure.Buffer) {
+	f.input.SetSecret(value)
+}
+
 func (f *Field) SetInputType(t InputType) {
 	f.input.SetInputType(t)
 }
@@ -61,18 +70,30 @@ func (f *Field) GetInputType() InputType {
 	return f.input.GetInputType()
 }
 
+func (f *Field) Undo() bool {
+	return f.input.Undo()
+}
+
+func (f *Field) Redo() bool {
+	return f.input.Redo()
+}
+
+func (f *Field) ClearHistory() {
+	f.input.ClearHistory()
+}
+
 func NewField(options *FieldOptions) *Field {
 	// TODO: we can maybe add some padding by directly accessing the model and tampering wiht GetBounds
 	field := &Field{}
 	field.SetOrientation(views.Horizontal)
 
-	opts := &InputOptions{InitialValue: options.InitialValue, Type: options.InputType, Disabled: options.Disabled}
+	opts := &InputOptions{InitialValue: options.InitialValue, Type: options.InputType, Disabled: options.Disabled, Keys: options.Keys}
 	input := NewInput(opts)
 	input.SetContent(options.InitialValue)
 	input.SetInputType(options.InputType)
 
 	label := views.NewSimpleStyledText()
-	label.SetStyle(tcell.StyleDefault.Attributes(tcell.AttrBold))
+	label.SetStyle(theme.Get(theme.STYLE_LABEL))
 	label.SetText(options.Label + ": ")
 
 	field.AddWidget(label, 0)
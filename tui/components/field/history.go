@@ -0,0 +1,122 @@
+package field
+
+import (
+	"github.com/shikaan/keydex/pkg/secure"
+)
+
+// Maximum number of changes that can be undone
+const HISTORY_LIMIT = 100
+
+// Kind of change to the content. Consecutive changes of the same kind are
+// undone at once, like typing a word.
+type editKind int
+
+const (
+	editNone editKind = iota
+	editInsert
+	editDelete
+	editNewline
+	editKill
+	editPaste
+)
+
+// A content of the input, with the cursor position to restore with it
+type revision struct {
+	// Sealed like hidden password fields, as revisions can hold them
+	sealed []byte
+	x      int
+	y      int
+}
+
+type history struct {
+	undo []revision
+	redo []revision
+
+	// Kind of the last change, and where it left the cursor
+	last editKind
+	x    int
+	y    int
+}
+
+// Returns true if a change of kind at x, y continues the last change. Only
+// typing and deleting characters are grouped.
+func (h *history) continues(kind editKind, x, y int) bool {
+	grouped := kind == editInsert || kind == editDelete
+	return grouped && kind == h.last && x == h.x && y == h.y
+}
+
+// Records the state before a change, which clears the changes to redo
+func (h *history) push(r revision) {
+	h.undo = append(h.undo, r)
+	if len(h.undo) > HISTORY_LIMIT {
+		h.undo = h.undo[len(h.undo)-HISTORY_LIMIT:]
+	}
+	h.redo = nil
+}
+
+// Stops grouping changes, such as when the cursor moves
+func (h *history) interrupt() {
+	h.last = editNone
+}
+
+func (h *history) clear() {
+	h.undo, h.redo = nil, nil
+	h.interrupt()
+}
+
+func (m *inputModel) revision(content string) revision {
+	plaintext := []byte(content)
+	defer clear(plaintext)
+
+	return revision{sealed: secure.Seal(plaintext), x: m.x, y: m.y}
+}
+
+// Undoes the last change to the content, returning false if there is none.
+// Hidden password fields cannot be changed, as with typing.
+func (i *Input) Undo() bool {
+	h := &i.model.history
+	if len(h.undo) == 0 || !i.isEditable() {
+		return false
+	}
+
+	previous := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, i.model.revision(i.GetContent()))
+	i.restore(previous)
+	return true
+}
+
+// Redoes the last change undone, returning false if there is none
+func (i *Input) Redo() bool {
+	h := &i.model.history
+	if len(h.redo) == 0 || !i.isEditable() {
+		return false
+	}
+
+	next := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, i.model.revision(i.GetContent()))
+	i.restore(next)
+	return true
+}
+
+// Forgets the changes to undo and redo, such as once they are saved
+func (i *Input) ClearHistory() {
+	i.model.history.clear()
+}
+
+func (i *Input) isEditable() bool {
+	return !i.model.disabled && i.model.inputType != InputTypePassword
+}
+
+func (i *Input) restore(r revision) {
+	content, err := secure.Open(r.sealed)
+	if err != nil {
+		return
+	}
+	defer content.Destroy()
+
+	i.SetContent(string(content.Bytes()))
+	i.model.SetCursor(r.x, r.y)
+	i.model.history.interrupt()
+}
@@ -9,8 +9,11 @@ import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/gdamore/tcell/v2/views"
 	"github.com/mattn/go-runewidth"
+	"github.com/shikaan/keydex/pkg/secure"
 	"github.com/shikaan/keydex/tui/components"
 	"github.com/shikaan/keydex/tui/components/line"
+	"github.com/shikaan/keydex/tui/keymap"
+	"github.com/shikaan/keydex/tui/theme"
 	"golang.org/x/exp/slices"
 )
 
@@ -26,6 +29,8 @@ type InputOptions struct {
 	InitialValue string
 	Type         InputType
 	Disabled     bool
+	// Bindings of the line editing actions. Nil means the default ones.
+	Keys *keymap.Keymap
 }
 
 type InputType int
@@ -56,6 +61,11 @@ type inputModel struct {
 	// For example: "😀" (len 2) is represented as []rune{😀, PAD_BYTE}
 	cells [][]rune
 
+	// Content of a hidden password field, sealed in memory. While it is set,
+	// the cells are overwritten and the content is empty; revealing the
+	// field restores them.
+	sealed []byte
+
 	// Display width of the input being displayed
 	width int
 	// Display height of the input being displayed
@@ -72,6 +82,13 @@ type inputModel struct {
 	disabled bool
 	// Whether the field is a password field or a regular one
 	inputType InputType
+	// Changes that can be undone and redone. Setting the content or the
+	// input type keeps them.
+	history history
+	// Bindings of the line editing actions
+	keys *keymap.Keymap
+	// Text being pasted, inserted at once when the paste ends
+	paste line.Paste
 
 	// Handle keypress events: triggered every time a key is pressed
 	// Returns true if handled, false if needs cascading
@@ -85,20 +102,43 @@ type inputModel struct {
 }
 
 func (m *inputModel) GetCell(x, y int) (rune, tcell.Style, []rune, int) {
+	style := m.cellStyle()
+
 	if m.isOutOfBounds(x, y) {
-		return line.EMPTY_CELL, m.style, nil, 1
+		return line.EMPTY_CELL, style, nil, 1
 	}
 
 	if m.inputType == InputTypePassword {
-		return '*', m.style, nil, 1
+		return '*', style, nil, 1
 	}
 
 	char := m.cells[y][x]
 	if unicode.IsPrint(char) {
-		return char, m.style, nil, runewidth.RuneWidth(char)
+		return char, style, nil, runewidth.RuneWidth(char)
+	}
+
+	return line.EMPTY_CELL, style, nil, 1
+}
+
+// Returns the style of the cells, which depends on the state of the input
+func (m *inputModel) cellStyle() tcell.Style {
+	if m.hasFocus {
+		return theme.Merge(m.style, theme.Get(theme.STYLE_FOCUSED))
 	}
 
-	return line.EMPTY_CELL, m.style, nil, 1
+	if m.inputType == InputTypePassword {
+		return theme.Merge(m.style, theme.Get(theme.STYLE_PROTECTED))
+	}
+
+	return m.style
+}
+
+// Returns the keymap in use
+func (m *inputModel) keymap() *keymap.Keymap {
+	if m.keys == nil {
+		m.keys = keymap.Default()
+	}
+	return m.keys
 }
 
 func (m *inputModel) GetBounds() (int, int) {
@@ -152,6 +192,34 @@ func (m *inputModel) isOutOfBounds(x, y int) bool {
 	return y < 0 || x < 0 || y >= len(m.cells) || x >= len(m.cells[y])
 }
 
+// Seals the content, and overwrites the cells. The content string cannot be
+// overwritten, so it is only dropped.
+func (m *inputModel) seal(plaintext []byte) {
+	m.sealed = secure.Seal(plaintext)
+	m.wipe()
+	m.content = ""
+	m.width = PASSWORD_FIELD_LENGTH
+	m.height = 1
+}
+
+// Returns the plaintext of the sealed content
+func (m *inputModel) open() string {
+	buffer, err := secure.Open(m.sealed)
+	if err != nil {
+		return ""
+	}
+	defer buffer.Destroy()
+
+	return string(buffer.Bytes())
+}
+
+func (m *inputModel) wipe() {
+	for _, row := range m.cells {
+		clear(row)
+	}
+	m.cells = [][]rune{{}}
+}
+
 func (i *Input) HasFocus() bool {
 	return i.model.hasFocus
 }
@@ -168,6 +236,8 @@ func (i *Input) SetFocus(on bool) {
 func (i *Input) SetContent(text string) {
 	i.Init()
 	m := i.model
+	m.wipe()
+	m.sealed = nil
 	m.content = text
 	textLines := getTextLines(text)
 	m.height = len(textLines)
@@ -179,24 +249,47 @@ func (i *Input) SetContent(text string) {
 		m.cells[lineIndex] = line.NewPaddedLine(textLine)
 	}
 
+	if m.inputType == InputTypePassword {
+		plaintext := []byte(text)
+		m.seal(plaintext)
+		clear(plaintext)
+	}
+
 	i.CellView.SetModel(m)
 }
 
+// Sets the content from locked memory. Password fields seal it right away,
+// so that it never becomes a string while hidden.
+func (i *Input) SetSecret(value *secure.Buffer) {
+	i.Init()
+	if i.model.inputType != InputTypePassword {
+		i.SetContent(string(value.Bytes()))
+		return
+	}
+
+	i.model.seal(value.Bytes())
+	i.CellView.SetModel(i.model)
+}
+
+// Returns the content. Hidden password fields open it for the occasion.
 func (i *Input) GetContent() string {
+	if i.model.sealed != nil {
+		return i.model.open()
+	}
+
 	return i.model.content
 }
 
+// Revealing a password field opens its content, and hiding it seals the
+// content again
 func (i *Input) SetInputType(t InputType) {
+	i.Init()
+	content := i.GetContent()
+
 	i.model.inputType = t
 	i.model.x = 0
 	i.model.y = 0
-	if i.model.inputType == InputTypePassword {
-		i.model.width = PASSWORD_FIELD_LENGTH
-		i.model.height = 1
-	} else {
-		i.SetContent(i.model.content)
-	}
-	i.Init()
+	i.SetContent(content)
 }
 
 func (i *Input) GetInputType() InputType {
@@ -208,6 +301,13 @@ func (i *Input) HandleEvent(ev tcell.Event) bool {
 		return false
 	}
 
+	if consumed, ended := i.model.paste.Feed(ev); consumed {
+		if ended {
+			return i.insertText(ev, i.model.paste.Take())
+		}
+		return true
+	}
+
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
 		handled := false
@@ -225,6 +325,19 @@ func (i *Input) HandleEvent(ev tcell.Event) bool {
 			return false
 		}
 
+		edit := line.EditOf(i.model.keymap(), ev)
+
+		switch ev.Key() {
+		case tcell.KeyLeft, tcell.KeyRight, tcell.KeyDown, tcell.KeyUp:
+			// Typing elsewhere is a change of its own
+			i.model.history.interrupt()
+		}
+
+		if i.handleMotion(edit) {
+			i.model.history.interrupt()
+			return true
+		}
+
 		switch ev.Key() {
 		case tcell.KeyLeft:
 			_, p := i.model.GetRuneAtPosition(i.model.x-1, i.model.y)
@@ -255,10 +368,15 @@ func (i *Input) HandleEvent(ev tcell.Event) bool {
 			return false
 		}
 
+		if edit != line.EDIT_NONE {
+			return i.handleCellsUpdate(ev, editKill, func() (int, int) { return i.model.deleteFor(edit) })
+		}
+
 		switch ev.Key() {
 		case tcell.KeyEnter:
 			return i.handleCellsUpdate(
 				ev,
+				editNewline,
 				func() (int, int) {
 					c, x, y := i.model.cells, i.model.x, i.model.y
 					line := c[y]
@@ -278,6 +396,7 @@ func (i *Input) HandleEvent(ev tcell.Event) bool {
 		case tcell.KeyRune:
 			return i.handleCellsUpdate(
 				ev,
+				editInsert,
 				func() (int, int) {
 					c, x, y := i.model.cells, i.model.x, i.model.y
 					char := ev.Rune()
@@ -290,6 +409,7 @@ func (i *Input) HandleEvent(ev tcell.Event) bool {
 		case tcell.KeyBackspace:
 			return i.handleCellsUpdate(
 				ev,
+				editDelete,
 				func() (int, int) {
 					c, x, y := i.model.cells, i.model.x, i.model.y
 
@@ -316,6 +436,7 @@ func (i *Input) HandleEvent(ev tcell.Event) bool {
 		case tcell.KeyDelete:
 			return i.handleCellsUpdate(
 				ev,
+				editDelete,
 				func() (int, int) {
 					c, x, y := i.model.cells, i.model.x, i.model.y
 					currentLineLength := len(c[y])
@@ -343,12 +464,140 @@ func (i *Input) HandleEvent(ev tcell.Event) bool {
 	return false
 }
 
-func (i *Input) handleCellsUpdate(ev tcell.Event, updateCells func() (int, int)) bool {
+// Moves the cursor for the line editing motions, returning false for other
+// edits
+func (i *Input) handleMotion(edit line.Edit) bool {
+	m := i.model
+	c, x, y := m.cells, m.x, m.y
+
+	switch edit {
+	case line.EDIT_LINE_START:
+		m.SetCursor(0, y)
+	case line.EDIT_LINE_END:
+		m.SetCursor(len(c[y]), y)
+	case line.EDIT_WORD_LEFT:
+		// Words continue on the previous line
+		if x == 0 && y > 0 {
+			m.SetCursor(len(c[y-1]), y-1)
+		} else {
+			m.SetCursor(line.PreviousWord(c[y], x), y)
+		}
+	case line.EDIT_WORD_RIGHT:
+		if x >= len(c[y]) && y < len(c)-1 {
+			m.SetCursor(0, y+1)
+		} else {
+			m.SetCursor(line.NextWord(c[y], x), y)
+		}
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Deletes the text covered by edit, returning how the cursor moves. Deleting
+// across the start or the end of a line joins it with the adjacent one.
+func (m *inputModel) deleteFor(edit line.Edit) (int, int) {
+	c, x, y := m.cells, m.x, m.y
+
+	switch edit {
+	case line.EDIT_DELETE_WORD:
+		if x == 0 {
+			return m.joinPreviousLine()
+		}
+
+		start := line.PreviousWord(c[y], x)
+		c[y] = slices.Delete(c[y], start, x)
+		return start - x, 0
+	case line.EDIT_DELETE_NEXT_WORD:
+		if x >= len(c[y]) {
+			return m.joinNextLine()
+		}
+
+		c[y] = slices.Delete(c[y], x, line.NextWord(c[y], x))
+	case line.EDIT_KILL_LINE:
+		if x >= len(c[y]) {
+			return m.joinNextLine()
+		}
+
+		clear(c[y][x:])
+		c[y] = c[y][:x]
+	}
+
+	return 0, 0
+}
+
+func (m *inputModel) joinPreviousLine() (int, int) {
+	c, x, y := m.cells, m.x, m.y
+	if y == 0 {
+		return 0, 0
+	}
+
+	previousLineLength := len(c[y-1])
+	c[y-1] = append(c[y-1], c[y]...)
+	m.cells = slices.Delete(c, y, y+1)
+	return previousLineLength - x, -1
+}
+
+func (m *inputModel) joinNextLine() (int, int) {
+	c, y := m.cells, m.y
+	if y == len(c)-1 {
+		return 0, 0
+	}
+
+	c[y] = append(c[y], c[y+1]...)
+	m.cells = slices.Delete(c, y+1, y+2)
+	return 0, 0
+}
+
+// Inserts text at the cursor as a single change, such as once pasted
+func (i *Input) insertText(ev tcell.Event, text string) bool {
+	if i.model.disabled || i.model.inputType == InputTypePassword || text == "" {
+		return false
+	}
+
+	return i.handleCellsUpdate(ev, editPaste, func() (int, int) {
+		c, x, y := i.model.cells, i.model.x, i.model.y
+
+		lines := [][]rune{}
+		for _, textLine := range getTextLines(text) {
+			lines = append(lines, line.NewPaddedLine(textLine))
+		}
+
+		last := len(lines) - 1
+		lastLength := len(lines[last])
+		after := slices.Clone(c[y][x:])
+		lines[0] = append(slices.Clone(c[y][:x]), lines[0]...)
+		lines[last] = append(lines[last], after...)
+
+		i.model.cells = slices.Replace(c, y, y+1, lines...)
+		if last == 0 {
+			return lastLength, 0
+		}
+		return lastLength - x, last
+	})
+}
+
+func (i *Input) handleCellsUpdate(ev tcell.Event, kind editKind, updateCells func() (int, int)) bool {
+	m := i.model
+
 	// Warning: this is order dependent!
 	deltaX, deltaY := updateCells()
-	i.SetContent(toString(i.model.cells))
+	content := toString(m.cells)
+
+	changed := content != m.content
+	if changed && !m.history.continues(kind, m.x, m.y) {
+		m.history.push(m.revision(m.content))
+	}
+
+	i.SetContent(content)
 	i.model.MoveCursor(deltaX, deltaY)
 
+	if changed {
+		h := &i.model.history
+		h.last, h.x, h.y = kind, i.model.x, i.model.y
+	}
+
 	if i.model.changeHandler != nil {
 		return i.model.changeHandler(ev)
 	}
@@ -396,6 +645,8 @@ func NewInput(options *InputOptions) *Input {
 	i.Init()
 	i.model.inputType = options.Type
 	i.model.disabled = options.Disabled
+	i.model.keys = options.Keys
+	i.model.style = theme.Get(theme.STYLE_VALUE)
 	return i
 }
 
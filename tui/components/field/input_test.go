@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/shikaan/keydex/pkg/secure"
 	"github.com/shikaan/keydex/tui/components/line"
 )
 
@@ -247,7 +248,7 @@ func Test_toString(t *testing.T) {
 func TestInput_handleCellsUpdate(t *testing.T) {
 	t.Run("updates content and moves cursor", func(t *testing.T) {
 		i := &Input{model: &inputModel{}}
-		handled := i.handleCellsUpdate(&tcell.EventKey{}, func() (int, int) {
+		handled := i.handleCellsUpdate(&tcell.EventKey{}, editInsert, func() (int, int) {
 			i.model.cells = [][]rune{{'l'}}
 			return 1, 0
 		})
@@ -276,7 +277,7 @@ func TestInput_handleCellsUpdate(t *testing.T) {
 			triggered = true
 			return true
 		}
-		handled := i.handleCellsUpdate(&tcell.EventKey{}, func() (int, int) { return 0, 0 })
+		handled := i.handleCellsUpdate(&tcell.EventKey{}, editInsert, func() (int, int) { return 0, 0 })
 
 		if !triggered {
 			t.Errorf("Input.handleCellsUpdate() expected change handler to be triggered")
@@ -294,7 +295,7 @@ func TestInput_handleCellsUpdate(t *testing.T) {
 			triggered = true
 			return false
 		}
-		handled := i.handleCellsUpdate(&tcell.EventKey{}, func() (int, int) { return 0, 0 })
+		handled := i.handleCellsUpdate(&tcell.EventKey{}, editInsert, func() (int, int) { return 0, 0 })
 
 		if !triggered {
 			t.Errorf("Input.handleCellsUpdate() expected change handler to be triggered")
@@ -679,3 +680,232 @@ func TestInput_SetInputType(t *testing.T) {
 		})
 	}
 }
+
+func TestInput_SealsHiddenContent(t *testing.T) {
+	i := NewInput(&InputOptions{Type: InputTypePassword})
+	i.SetSecret(secure.FromString("secret"))
+
+	hidden := func() {
+		t.Helper()
+		if i.model.sealed == nil || i.model.content != "" || toString(i.model.cells) != "" {
+			t.Errorf("expected the hidden content to be sealed, got content %q and cells %q", i.model.content, toString(i.model.cells))
+		}
+		if got := i.GetContent(); got != "secret" {
+			t.Errorf("Input.GetContent() = %q, want %q", got, "secret")
+		}
+	}
+
+	hidden()
+
+	i.SetInputType(InputTypeText)
+	if i.model.sealed != nil || toString(i.model.cells) != "secret" {
+		t.Errorf("expected the revealed content in the cells, got %q", toString(i.model.cells))
+	}
+
+	cells := i.model.cells
+	i.SetInputType(InputTypePassword)
+	hidden()
+
+	if string(cells[0]) != string(make([]rune, len(cells[0]))) {
+		t.Errorf("expected the revealed cells to be overwritten, got %q", string(cells[0]))
+	}
+}
+
+func TestInput_Undo(t *testing.T) {
+	typeKeys := func(i *Input, keys ...*tcell.EventKey) {
+		for _, key := range keys {
+			i.HandleEvent(key)
+		}
+	}
+	runes := func(s string) []*tcell.EventKey {
+		keys := []*tcell.EventKey{}
+		for _, r := range s {
+			keys = append(keys, tcell.NewEventKey(tcell.KeyRune, r, 0))
+		}
+		return keys
+	}
+	backspace := tcell.NewEventKey(tcell.KeyBackspace2, 0, 0)
+	left := tcell.NewEventKey(tcell.KeyLeft, 0, 0)
+	right := tcell.NewEventKey(tcell.KeyRight, 0, 0)
+
+	newInput := func() *Input {
+		i := NewInput(&InputOptions{})
+		i.SetContent("abc")
+		i.SetFocus(true)
+		i.model.SetCursor(3, 0)
+		return i
+	}
+
+	t.Run("undoes consecutive typing at once", func(t *testing.T) {
+		i := newInput()
+		typeKeys(i, runes("def")...)
+		typeKeys(i, backspace, backspace)
+
+		wants := []struct {
+			content string
+			x       int
+		}{{"abcdef", 6}, {"abc", 3}}
+		for _, want := range wants {
+			if !i.Undo() {
+				t.Fatalf("Input.Undo() = false, want %q", want.content)
+			}
+			if got := i.GetContent(); got != want.content || i.model.x != want.x {
+				t.Errorf("Input.Undo() content = %q at %d, want %q at %d", got, i.model.x, want.content, want.x)
+			}
+		}
+
+		if i.Undo() {
+			t.Errorf("Input.Undo() = true with nothing to undo")
+		}
+
+		if !i.Redo() || !i.Redo() || i.GetContent() != "abcd" || i.model.x != 4 {
+			t.Errorf("Input.Redo() content = %q at %d, want %q at %d", i.GetContent(), i.model.x, "abcd", 4)
+		}
+		if i.Redo() {
+			t.Errorf("Input.Redo() = true with nothing to redo")
+		}
+	})
+
+	t.Run("moving the cursor starts a new change", func(t *testing.T) {
+		i := newInput()
+		typeKeys(i, runes("d")...)
+		typeKeys(i, left, right)
+		typeKeys(i, runes("e")...)
+
+		if !i.Undo() || i.GetContent() != "abcd" {
+			t.Errorf("Input.Undo() content = %q, want %q", i.GetContent(), "abcd")
+		}
+	})
+
+	t.Run("typing clears the changes to redo", func(t *testing.T) {
+		i := newInput()
+		typeKeys(i, runes("d")...)
+		i.Undo()
+		typeKeys(i, runes("e")...)
+
+		if i.Redo() {
+			t.Errorf("Input.Redo() = true after typing")
+		}
+	})
+
+	t.Run("survives toggling the input type", func(t *testing.T) {
+		i := NewInput(&InputOptions{Type: InputTypePassword})
+		i.SetSecret(secure.FromString("secret"))
+		i.SetFocus(true)
+		i.SetInputType(InputTypeText)
+		i.model.SetCursor(6, 0)
+		typeKeys(i, runes("!")...)
+
+		i.SetInputType(InputTypePassword)
+		if i.Undo() {
+			t.Errorf("Input.Undo() = true on a hidden field")
+		}
+
+		i.SetInputType(InputTypeText)
+		if !i.Undo() || i.GetContent() != "secret" {
+			t.Errorf("Input.Undo() content = %q, want %q", i.GetContent(), "secret")
+		}
+	})
+
+	t.Run("clears the history", func(t *testing.T) {
+		i := newInput()
+		typeKeys(i, runes("d")...)
+		i.ClearHistory()
+
+		if i.Undo() {
+			t.Errorf("Input.Undo() = true after ClearHistory")
+		}
+	})
+}
+
+func TestInput_LineEditing(t *testing.T) {
+	ctrl := func(key tcell.Key) *tcell.EventKey { return tcell.NewEventKey(key, 0, tcell.ModCtrl) }
+	alt := func(r rune) *tcell.EventKey { return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModAlt) }
+
+	tests := []struct {
+		name        string
+		content     string
+		x, y        int
+		ev          *tcell.EventKey
+		wantContent string
+		wantX       int
+		wantY       int
+	}{
+		{"moves to the start", "foo bar", 4, 0, ctrl(tcell.KeyCtrlA), "foo bar", 0, 0},
+		{"moves to the end", "foo bar", 1, 0, tcell.NewEventKey(tcell.KeyEnd, 0, tcell.ModNone), "foo bar", 7, 0},
+		{"moves to the previous word", "foo bar", 7, 0, alt('b'), "foo bar", 4, 0},
+		{"moves to the next word", "foo bar", 0, 0, ctrl(tcell.KeyRight), "foo bar", 3, 0},
+		{"moves words across lines", "foo\nbar", 3, 0, alt('f'), "foo\nbar", 0, 1},
+		{"deletes the previous word", "foo bar", 7, 0, ctrl(tcell.KeyCtrlW), "foo ", 4, 0},
+		{"deletes the next word", "foo bar baz", 3, 0, alt('d'), "foo baz", 3, 0},
+		{"kills to the end of the line", "foo bar\nbaz", 3, 0, alt('k'), "foo\nbaz", 3, 0},
+		{"kills the line break at the end", "foo\nbaz", 3, 0, alt('k'), "foobaz", 3, 0},
+		{"deletes words across lines", "foo\nbaz", 0, 1, ctrl(tcell.KeyCtrlW), "foobaz", 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := NewInput(&InputOptions{})
+			i.SetContent(tt.content)
+			i.SetFocus(true)
+			i.model.SetCursor(tt.x, tt.y)
+
+			if !i.HandleEvent(tt.ev) {
+				t.Fatalf("Input.HandleEvent() = false")
+			}
+			if got := i.GetContent(); got != tt.wantContent {
+				t.Errorf("Input.GetContent() = %q, want %q", got, tt.wantContent)
+			}
+			if i.model.x != tt.wantX || i.model.y != tt.wantY {
+				t.Errorf("cursor = %d, %d, want %d, %d", i.model.x, i.model.y, tt.wantX, tt.wantY)
+			}
+		})
+	}
+
+	t.Run("doesn't change disabled inputs", func(t *testing.T) {
+		i := NewInput(&InputOptions{Disabled: true})
+		i.SetContent("foo bar")
+		i.SetFocus(true)
+		i.model.SetCursor(7, 0)
+
+		i.HandleEvent(ctrl(tcell.KeyCtrlW))
+		if got := i.GetContent(); got != "foo bar" {
+			t.Errorf("Input.GetContent() = %q, want %q", got, "foo bar")
+		}
+	})
+}
+
+func TestInput_Paste(t *testing.T) {
+	i := NewInput(&InputOptions{})
+	i.SetContent("ad")
+	i.SetFocus(true)
+	i.model.SetCursor(1, 0)
+
+	changes := 0
+	i.OnChange(func(ev tcell.Event) bool {
+		changes++
+		return true
+	})
+
+	i.HandleEvent(tcell.NewEventPaste(true))
+	for _, r := range "b\nc" {
+		key := tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone)
+		if r == '\n' {
+			key = tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)
+		}
+		i.HandleEvent(key)
+	}
+	if changes != 0 {
+		t.Fatalf("expected no change before the paste ends, got %d", changes)
+	}
+	i.HandleEvent(tcell.NewEventPaste(false))
+
+	if got := i.GetContent(); got != "ab\ncd" || changes != 1 {
+		t.Errorf("Input.GetContent() = %q after %d changes, want %q after one", got, changes, "ab\ncd")
+	}
+	if i.model.x != 1 || i.model.y != 1 {
+		t.Errorf("cursor = %d, %d, want 1, 1", i.model.x, i.model.y)
+	}
+	if !i.Undo() || i.GetContent() != "ad" {
+		t.Errorf("Input.Undo() content = %q, want %q", i.GetContent(), "ad")
+	}
+}
@@ -0,0 +1,116 @@
+package field
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/views"
+	"github.com/shikaan/keydex/pkg/secure"
+	"github.com/shikaan/keydex/tui/theme"
+)
+
+// Longest content, in bytes, that can be typed into a Secret
+const SECRET_CAPACITY = 1024
+
+// Secret is a single-line field whose content is never shown, such as a
+// passphrase. Unlike password inputs, it can be typed into while hidden.
+type Secret struct {
+	label string
+	// Typed content, in locked memory. Only the first length bytes are used.
+	content  *secure.Buffer
+	length   int
+	hasFocus bool
+
+	views.Text
+}
+
+func (s *Secret) HasFocus() bool {
+	return s.hasFocus
+}
+
+func (s *Secret) SetFocus(on bool) {
+	s.hasFocus = on
+	s.render()
+}
+
+func (s *Secret) HandleEvent(ev tcell.Event) bool {
+	if !s.hasFocus {
+		return false
+	}
+
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		switch ev.Key() {
+		case tcell.KeyRune:
+			s.append(ev.Rune())
+		case tcell.KeyBackspace, tcell.KeyBackspace2:
+			data := s.content.Bytes()[:s.length]
+			_, size := utf8.DecodeLastRune(data)
+			clear(data[s.length-size:])
+			s.length -= size
+		case tcell.KeyCtrlU:
+			s.Clear()
+		default:
+			return false
+		}
+
+		s.render()
+		return true
+	}
+
+	return false
+}
+
+func (s *Secret) append(r rune) {
+	// The buffer is allocated on the first rune, since Clear destroys it
+	if s.content == nil {
+		s.content = secure.NewBuffer(SECRET_CAPACITY)
+	}
+
+	if s.length+utf8.RuneLen(r) > SECRET_CAPACITY {
+		return
+	}
+
+	s.length += utf8.EncodeRune(s.content.Bytes()[s.length:], r)
+}
+
+// Returns a copy of the content. Destroy it once used.
+func (s *Secret) GetSecret() *secure.Buffer {
+	secret := secure.NewBuffer(s.length)
+	copy(secret.Bytes(), s.content.Bytes())
+	return secret
+}
+
+// Overwrites and releases the content, so that it does not linger in memory
+func (s *Secret) Clear() {
+	s.content.Destroy()
+	s.content = nil
+	s.length = 0
+	s.render()
+}
+
+func (s *Secret) render() {
+	label := s.label + ": "
+	// The trailing space shows where typing goes
+	value := strings.Repeat("*", utf8.RuneCount(s.content.Bytes()[:s.length])) + " "
+	s.SetText(label + value)
+
+	valueStyle := theme.Get(theme.STYLE_VALUE)
+	if s.hasFocus {
+		valueStyle = theme.Merge(valueStyle, theme.Get(theme.STYLE_FOCUSED))
+	}
+
+	for i := range len(label) {
+		s.SetStyleAt(i, theme.Get(theme.STYLE_LABEL))
+	}
+	for i := range len(value) {
+		s.SetStyleAt(len(label)+i, valueStyle)
+	}
+}
+
+func NewSecret(label string) *Secret {
+	s := &Secret{label: label}
+	s.render()
+	return s
+}
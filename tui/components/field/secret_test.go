@@ -0,0 +1,37 @@
+package field
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestSecret_HandleEvent(t *testing.T) {
+	s := NewSecret("Passphrase")
+
+	typeRune := func(r rune) bool {
+		return s.HandleEvent(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+	}
+
+	if typeRune('a') {
+		t.Fatalf("Secret.HandleEvent() handled events without focus")
+	}
+
+	s.SetFocus(true)
+	for _, r := range "secret" {
+		typeRune(r)
+	}
+	s.HandleEvent(tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone))
+
+	if got := s.GetSecret(); string(got.Bytes()) != "secre" {
+		t.Errorf("Secret.GetSecret() = %s, want secre", got.Bytes())
+	}
+	if got := s.Text.Text(); got != "Passphrase: ***** " {
+		t.Errorf("Secret.Text() = %q, want masked content", got)
+	}
+
+	s.Clear()
+	if got := s.GetSecret(); got.Len() != 0 {
+		t.Errorf("Secret.GetSecret() = %s after Clear, want empty", got.Bytes())
+	}
+}
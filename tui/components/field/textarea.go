@@ -0,0 +1,319 @@
+package field
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/views"
+	"github.com/shikaan/keydex/pkg/secure"
+	"github.com/shikaan/keydex/tui/components"
+	"github.com/shikaan/keydex/tui/components/line"
+	"github.com/shikaan/keydex/tui/theme"
+)
+
+// Number of rows shown by a text area, unless expanded
+const TEXTAREA_HEIGHT = 6
+
+// Editor is a widget editing the value of a field: Field for values on a
+// single line, TextArea for the others
+type Editor interface {
+	views.Widget
+	components.Focusable
+
+	OnFocus(cb func() bool) func()
+	OnKeyPress(cb func(ev *tcell.EventKey) bool) func()
+	OnChange(cb func(ev tcell.Event) bool) func()
+	GetContent() string
+	SetSecret(value *secure.Buffer)
+	SetInputType(t InputType)
+	GetInputType() InputType
+	Undo() bool
+	Redo() bool
+	ClearHistory()
+}
+
+// TextArea edits values spanning several lines, such as notes. Lines longer
+// than the area are wrapped, and the rows scroll within a bounded height.
+type TextArea struct {
+	input *Input
+	label *views.SimpleStyledText
+	area  *views.CellView
+	model *textAreaModel
+	// True when the rows take more than the default height
+	expanded bool
+
+	components.Focusable
+	views.BoxLayout
+}
+
+// A row of the text area, showing a span of a line of the input
+type row struct {
+	y int
+	line.Span
+}
+
+// Shows the content of the input wrapped and scrolled. Positions are in rows
+// on screen, and are translated to the lines of the input.
+type textAreaModel struct {
+	input *Input
+	rows  []row
+
+	// Width at which lines wrap
+	width int
+	// Maximum number of rows shown
+	height int
+	// Index of the first row shown
+	offset int
+}
+
+// Breaks the lines of the input in rows
+func (m *textAreaModel) wrap() {
+	im := m.input.model
+	m.rows = m.rows[:0]
+
+	if im.inputType == InputTypePassword {
+		m.rows = append(m.rows, row{0, line.Span{Start: 0, End: PASSWORD_FIELD_LENGTH}})
+		return
+	}
+
+	for y, cells := range im.cells {
+		for _, span := range line.Wrap(cells, m.width) {
+			m.rows = append(m.rows, row{y, span})
+		}
+	}
+}
+
+// Returns the index of the row holding the cursor. At the boundary of two
+// rows of the same line, the cursor is at the start of the second one.
+func (m *textAreaModel) cursorRow() int {
+	x, y := m.input.model.x, m.input.model.y
+
+	for i, r := range m.rows {
+		if r.y != y || x < r.Start {
+			continue
+		}
+
+		isLast := i == len(m.rows)-1 || m.rows[i+1].y != y
+		if x < r.End || isLast {
+			return i
+		}
+	}
+
+	return 0
+}
+
+// Scrolls the rows so that the cursor is shown
+func (m *textAreaModel) scroll() {
+	visible := m.visibleRows()
+	r := m.cursorRow()
+
+	if r < m.offset {
+		m.offset = r
+	}
+	if r >= m.offset+visible {
+		m.offset = r - visible + 1
+	}
+	m.offset = clamp(m.offset, 0, max(len(m.rows)-visible, 0))
+}
+
+func (m *textAreaModel) visibleRows() int {
+	return min(len(m.rows), m.height)
+}
+
+func (m *textAreaModel) GetCell(x, y int) (rune, tcell.Style, []rune, int) {
+	index := y + m.offset
+	if index < 0 || index >= len(m.rows) || y >= m.height {
+		return line.EMPTY_CELL, m.input.model.cellStyle(), nil, 1
+	}
+
+	r := m.rows[index]
+	if x < 0 || x >= r.End-r.Start {
+		return line.EMPTY_CELL, m.input.model.cellStyle(), nil, 1
+	}
+
+	return m.input.model.GetCell(r.Start+x, r.y)
+}
+
+func (m *textAreaModel) GetBounds() (int, int) {
+	return m.width, m.visibleRows()
+}
+
+func (m *textAreaModel) GetCursor() (int, int, bool, bool) {
+	index := m.cursorRow()
+	x := m.input.model.x - m.rows[index].Start
+	return x, index - m.offset, true, m.input.model.hasFocus
+}
+
+// Moves the cursor to column x of row y on screen. Columns past the end of
+// the row stop at its last rune, and wide runes are entered at their start.
+func (m *textAreaModel) SetCursor(x, y int) {
+	index := clamp(y+m.offset, 0, len(m.rows)-1)
+	r := m.rows[index]
+
+	last := r.End
+	if index < len(m.rows)-1 && m.rows[index+1].y == r.y {
+		// The end of the row is the start of the next one
+		last = max(r.End-1, r.Start)
+	}
+
+	x = r.Start + clamp(x, 0, last-r.Start)
+	if _, position := m.input.model.GetRuneAtPosition(x, r.y); position >= 0 {
+		x = position
+	}
+
+	m.input.model.SetCursor(x, r.y)
+}
+
+func (m *textAreaModel) MoveCursor(x, y int) {
+	cx, cy, _, _ := m.GetCursor()
+	m.SetCursor(cx+x, cy+y)
+}
+
+func (t *TextArea) HasFocus() bool {
+	return t.input.HasFocus()
+}
+
+func (t *TextArea) SetFocus(on bool) {
+	t.input.SetFocus(on)
+	t.refresh()
+}
+
+func (t *TextArea) OnFocus(cb func() bool) func() {
+	return t.input.OnFocus(cb)
+}
+
+func (t *TextArea) HandleEvent(ev tcell.Event) bool {
+	if !t.HasFocus() {
+		return false
+	}
+
+	if ev, ok := ev.(*tcell.EventKey); ok {
+		if handled, ok := t.handleScroll(ev); ok {
+			return handled
+		}
+	}
+
+	handled := t.input.HandleEvent(ev)
+	t.refresh()
+	return handled
+}
+
+// Moves the cursor across rows. Returns false as second value for the keys
+// it does not handle, and false as first value when the cursor is already on
+// the first or last row, so that focus can move on.
+func (t *TextArea) handleScroll(ev *tcell.EventKey) (handled bool, ok bool) {
+	m := t.model
+	index := m.cursorRow()
+
+	offset := 0
+	switch ev.Key() {
+	case tcell.KeyUp:
+		offset = -1
+	case tcell.KeyDown:
+		offset = 1
+	case tcell.KeyPgUp:
+		offset = -m.visibleRows()
+	case tcell.KeyPgDn:
+		offset = m.visibleRows()
+	default:
+		return false, false
+	}
+
+	if offset < 0 && index == 0 || offset > 0 && index == len(m.rows)-1 {
+		return false, true
+	}
+
+	m.MoveCursor(0, offset)
+	t.input.model.history.interrupt()
+	t.refresh()
+	return true, true
+}
+
+// Wraps the content again, and shows the cursor
+func (t *TextArea) refresh() {
+	t.model.wrap()
+	t.model.scroll()
+	t.area.SetModel(t.model)
+}
+
+// Shows height rows, such as to take the whole screen. Zero goes back to the
+// default height.
+func (t *TextArea) SetExpanded(height int) {
+	t.expanded = height > 0
+	if !t.expanded {
+		height = TEXTAREA_HEIGHT
+	}
+
+	t.model.height = height
+	t.refresh()
+}
+
+func (t *TextArea) IsExpanded() bool {
+	return t.expanded
+}
+
+func (t *TextArea) OnKeyPress(cb func(ev *tcell.EventKey) bool) func() {
+	return t.input.OnKeyPress(cb)
+}
+
+func (t *TextArea) OnChange(cb func(ev tcell.Event) bool) func() {
+	return t.input.OnChange(cb)
+}
+
+func (t *TextArea) GetContent() string {
+	return t.input.GetContent()
+}
+
+func (t *TextArea) SetSecret(value *secure.Buffer) {
+	t.input.SetSecret(value)
+	t.refresh()
+}
+
+func (t *TextArea) SetInputType(it InputType) {
+	t.input.SetInputType(it)
+	t.refresh()
+}
+
+func (t *TextArea) GetInputType() InputType {
+	return t.input.GetInputType()
+}
+
+func (t *TextArea) Undo() bool {
+	defer t.refresh()
+	return t.input.Undo()
+}
+
+func (t *TextArea) Redo() bool {
+	defer t.refresh()
+	return t.input.Redo()
+}
+
+func (t *TextArea) ClearHistory() {
+	t.input.ClearHistory()
+}
+
+func NewTextArea(options *FieldOptions) *TextArea {
+	textArea := &TextArea{}
+	textArea.SetOrientation(views.Vertical)
+
+	opts := &InputOptions{InitialValue: options.InitialValue, Type: options.InputType, Disabled: options.Disabled, Keys: options.Keys}
+	input := NewInput(opts)
+	input.SetContent(options.InitialValue)
+	input.SetInputType(options.InputType)
+
+	label := views.NewSimpleStyledText()
+	label.SetStyle(theme.Get(theme.STYLE_LABEL))
+	label.SetText(options.Label + ":")
+
+	model := &textAreaModel{input: input, width: components.CONTENT_WIDTH, height: TEXTAREA_HEIGHT}
+	area := views.NewCellView()
+
+	textArea.input = input
+	textArea.label = label
+	textArea.area = area
+	textArea.model = model
+	textArea.refresh()
+
+	textArea.AddWidget(label, 0)
+	textArea.AddWidget(area, 1)
+
+	return textArea
+}
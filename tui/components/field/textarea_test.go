@@ -0,0 +1,141 @@
+package field
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func newTextArea(content string, width int) *TextArea {
+	t := NewTextArea(&FieldOptions{Label: "Notes", InitialValue: content})
+	t.model.width = width
+	t.SetFocus(true)
+	return t
+}
+
+func TestTextArea_Wrapping(t *testing.T) {
+	ta := newTextArea("foo bar baz\nqux", 8)
+
+	rows := []string{}
+	for y := 0; y < 3; y++ {
+		b := &strings.Builder{}
+		for x := 0; x < 8; x++ {
+			if char, _, _, _ := ta.model.GetCell(x, y); char != 0 {
+				b.WriteRune(char)
+			}
+		}
+		rows = append(rows, b.String())
+	}
+
+	want := []string{"foo bar ", "baz", "qux"}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestTextArea_HandleEvent(t *testing.T) {
+	up := tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone)
+	down := tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone)
+
+	t.Run("moves across wrapped rows", func(t *testing.T) {
+		ta := newTextArea("foo bar baz", 8)
+		ta.input.model.SetCursor(1, 0)
+
+		if !ta.HandleEvent(down) {
+			t.Fatalf("TextArea.HandleEvent() = false, want true")
+		}
+		if x, y, _, _ := ta.model.GetCursor(); x != 1 || y != 1 || ta.input.model.x != 9 {
+			t.Errorf("cursor = %d, %d at %d, want 1, 1 at 9", x, y, ta.input.model.x)
+		}
+
+		if !ta.HandleEvent(up) || ta.input.model.x != 1 {
+			t.Errorf("cursor at %d, want 1", ta.input.model.x)
+		}
+	})
+
+	t.Run("enters wide runes at their start", func(t *testing.T) {
+		ta := newTextArea("abc\n日本", 8)
+		ta.input.model.SetCursor(3, 0)
+
+		ta.HandleEvent(down)
+		if ta.input.model.x != 2 || ta.input.model.y != 1 {
+			t.Errorf("cursor = %d, %d, want 2, 1", ta.input.model.x, ta.input.model.y)
+		}
+	})
+
+	t.Run("stops at the end of a wrapped row", func(t *testing.T) {
+		// Wraps as "ab ", "cdef", and "gh"
+		ta := newTextArea("ab cdefgh", 4)
+		ta.input.model.SetCursor(6, 0)
+
+		ta.HandleEvent(up)
+		if x, y, _, _ := ta.model.GetCursor(); ta.input.model.x != 2 || x != 2 || y != 0 {
+			t.Errorf("cursor = %d, %d at %d, want 2, 0 at 2", x, y, ta.input.model.x)
+		}
+	})
+
+	t.Run("lets focus move past the first and last rows", func(t *testing.T) {
+		ta := newTextArea("foo\nbar", 8)
+
+		if ta.HandleEvent(up) {
+			t.Errorf("TextArea.HandleEvent() = true on the first row")
+		}
+
+		ta.input.model.SetCursor(0, 1)
+		if ta.HandleEvent(down) {
+			t.Errorf("TextArea.HandleEvent() = true on the last row")
+		}
+	})
+
+	t.Run("wraps typed text", func(t *testing.T) {
+		ta := newTextArea("foo bar", 8)
+		ta.input.model.SetCursor(7, 0)
+		ta.HandleEvent(tcell.NewEventKey(tcell.KeyRune, '!', tcell.ModNone))
+
+		// Wraps as "foo " and "bar!"
+		if x, y, _, _ := ta.model.GetCursor(); x != 4 || y != 1 {
+			t.Errorf("cursor = %d, %d, want 4, 1", x, y)
+		}
+		if ta.GetContent() != "foo bar!" {
+			t.Errorf("TextArea.GetContent() = %q, want %q", ta.GetContent(), "foo bar!")
+		}
+	})
+}
+
+func TestTextArea_Scrolling(t *testing.T) {
+	lines := []string{}
+	for i := 0; i < TEXTAREA_HEIGHT*2; i++ {
+		lines = append(lines, string(rune('a'+i)))
+	}
+	ta := newTextArea(strings.Join(lines, "\n"), 8)
+
+	if _, h := ta.model.GetBounds(); h != TEXTAREA_HEIGHT {
+		t.Fatalf("height = %d, want %d", h, TEXTAREA_HEIGHT)
+	}
+
+	for i := 0; i < TEXTAREA_HEIGHT; i++ {
+		ta.HandleEvent(tcell.NewEventKey(tcell.KeyDown, 0, tcell.ModNone))
+	}
+	if char, _, _, _ := ta.model.GetCell(0, TEXTAREA_HEIGHT-1); char != rune('a'+TEXTAREA_HEIGHT) {
+		t.Errorf("last row shows %q, want %q", char, rune('a'+TEXTAREA_HEIGHT))
+	}
+	if _, y, _, _ := ta.model.GetCursor(); y != TEXTAREA_HEIGHT-1 {
+		t.Errorf("cursor row = %d, want %d", y, TEXTAREA_HEIGHT-1)
+	}
+
+	ta.SetExpanded(TEXTAREA_HEIGHT * 3)
+	if _, h := ta.model.GetBounds(); !ta.IsExpanded() || h != TEXTAREA_HEIGHT*2 {
+		t.Errorf("expanded height = %d, want %d", h, TEXTAREA_HEIGHT*2)
+	}
+	if char, _, _, _ := ta.model.GetCell(0, 0); char != 'a' {
+		t.Errorf("first row shows %q once expanded, want %q", char, 'a')
+	}
+
+	ta.SetExpanded(0)
+	if _, h := ta.model.GetBounds(); ta.IsExpanded() || h != TEXTAREA_HEIGHT {
+		t.Errorf("collapsed height = %d, want %d", h, TEXTAREA_HEIGHT)
+	}
+}
@@ -0,0 +1,99 @@
+package line
+
+import (
+	"unicode"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shikaan/keydex/tui/keymap"
+)
+
+// Line editing command, shared by the components taking text
+type Edit int
+
+const (
+	EDIT_NONE Edit = iota
+	EDIT_LINE_START
+	EDIT_LINE_END
+	EDIT_WORD_LEFT
+	EDIT_WORD_RIGHT
+	EDIT_DELETE_WORD
+	EDIT_DELETE_NEXT_WORD
+	EDIT_KILL_LINE
+)
+
+var editActions = map[keymap.Action]Edit{
+	keymap.ACTION_LINE_START:       EDIT_LINE_START,
+	keymap.ACTION_LINE_END:         EDIT_LINE_END,
+	keymap.ACTION_WORD_LEFT:        EDIT_WORD_LEFT,
+	keymap.ACTION_WORD_RIGHT:       EDIT_WORD_RIGHT,
+	keymap.ACTION_DELETE_WORD:      EDIT_DELETE_WORD,
+	keymap.ACTION_DELETE_NEXT_WORD: EDIT_DELETE_NEXT_WORD,
+	keymap.ACTION_KILL_LINE:        EDIT_KILL_LINE,
+}
+
+// Returns the edit triggered by ev: Home, End, Ctrl+Left and Ctrl+Right, or
+// the keys bound to the editing actions in keys
+func EditOf(keys *keymap.Keymap, ev *tcell.EventKey) Edit {
+	switch {
+	case ev.Key() == tcell.KeyHome:
+		return EDIT_LINE_START
+	case ev.Key() == tcell.KeyEnd:
+		return EDIT_LINE_END
+	case ev.Key() == tcell.KeyLeft && ev.Modifiers()&tcell.ModCtrl != 0:
+		return EDIT_WORD_LEFT
+	case ev.Key() == tcell.KeyRight && ev.Modifiers()&tcell.ModCtrl != 0:
+		return EDIT_WORD_RIGHT
+	}
+
+	for action, edit := range editActions {
+		if keys.Is(ev, action) {
+			return edit
+		}
+	}
+
+	return EDIT_NONE
+}
+
+func isWordRune(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char)
+}
+
+// Returns the position of the start of the word before x, as the cursor
+// moves word-wise to the left
+func PreviousWord(line PaddedLine, x int) int {
+	x = min(x, len(line))
+
+	for inWord := false; x > 0; {
+		char, position := GetRune(line, x-1)
+		if isWordRune(char) {
+			inWord = true
+		} else if inWord {
+			break
+		}
+		x = position
+	}
+
+	return x
+}
+
+// Returns the position after the end of the word after x, as the cursor
+// moves word-wise to the right
+func NextWord(line PaddedLine, x int) int {
+	x = max(x, 0)
+
+	for inWord := false; x < len(line); {
+		if isWordRune(line[x]) {
+			inWord = true
+		} else if inWord {
+			break
+		}
+
+		// Skips the padding of wide runes
+		x++
+		for x < len(line) && line[x] == PAD_BYTE {
+			x++
+		}
+	}
+
+	return x
+}
@@ -0,0 +1,77 @@
+package line
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shikaan/keydex/tui/keymap"
+)
+
+func TestEditOf(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   *tcell.EventKey
+		want Edit
+	}{
+		{"home", tcell.NewEventKey(tcell.KeyHome, 0, tcell.ModNone), EDIT_LINE_START},
+		{"end", tcell.NewEventKey(tcell.KeyEnd, 0, tcell.ModNone), EDIT_LINE_END},
+		{"ctrl+left", tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModCtrl), EDIT_WORD_LEFT},
+		{"ctrl+right", tcell.NewEventKey(tcell.KeyRight, 0, tcell.ModCtrl), EDIT_WORD_RIGHT},
+		{"bound key", tcell.NewEventKey(tcell.KeyCtrlW, 0, tcell.ModCtrl), EDIT_DELETE_WORD},
+		{"bound alt key", tcell.NewEventKey(tcell.KeyRune, 'f', tcell.ModAlt), EDIT_WORD_RIGHT},
+		{"left", tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone), EDIT_NONE},
+		{"rune", tcell.NewEventKey(tcell.KeyRune, 'f', tcell.ModNone), EDIT_NONE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EditOf(keymap.Default(), tt.ev); got != tt.want {
+				t.Errorf("EditOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreviousWord(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		x    int
+		want int
+	}{
+		{"from the end", "foo bar", 7, 4},
+		{"from inside a word", "foo bar", 6, 4},
+		{"across separators", "foo -- bar", 7, 0},
+		{"from the start", "foo", 0, 0},
+		{"wide runes", "✅ 日本", 7, 3},
+		{"out of bounds", "foo bar", 10, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PreviousWord(NewPaddedLine(tt.line), tt.x); got != tt.want {
+				t.Errorf("PreviousWord() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextWord(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		x    int
+		want int
+	}{
+		{"from the start", "foo bar", 0, 3},
+		{"from inside a word", "foo bar", 1, 3},
+		{"across separators", "foo -- bar", 3, 10},
+		{"from the end", "foo", 3, 3},
+		{"wide runes", "日本 ✅", 0, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NextWord(NewPaddedLine(tt.line), tt.x); got != tt.want {
+				t.Errorf("NextWord() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -40,3 +40,51 @@ func GetRune(line PaddedLine, x int) (char rune, position int) {
 
 	return EMPTY_CELL, -1
 }
+
+// Part of a line, from Start included to End excluded
+type Span struct {
+	Start int
+	End   int
+}
+
+// Breaks a line in rows no wider than width, after the last space that fits
+// when there is one. Wide runes are never split across rows, and the last
+// row is always shorter than width to leave room for the cursor.
+func Wrap(line PaddedLine, width int) []Span {
+	spans := []Span{}
+	start := 0
+
+	for width > 0 && len(line)-start >= width {
+		end := start + width
+		// Wide runes go to the next row whole
+		for end > start && end < len(line) && line[end] == PAD_BYTE {
+			end--
+		}
+
+		if space := lastSpace(line[start:end]); space > 0 {
+			end = start + space + 1
+		}
+
+		// Rows narrower than a wide rune still take it
+		if end == start {
+			end++
+			for end < len(line) && line[end] == PAD_BYTE {
+				end++
+			}
+		}
+
+		spans = append(spans, Span{start, end})
+		start = end
+	}
+
+	return append(spans, Span{start, len(line)})
+}
+
+func lastSpace(line PaddedLine) int {
+	for i := len(line) - 1; i >= 0; i-- {
+		if line[i] == ' ' {
+			return i
+		}
+	}
+	return -1
+}
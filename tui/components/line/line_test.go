@@ -51,3 +51,27 @@ func TestGetRune(t *testing.T) {
 		})
 	}
 }
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		width int
+		want  []Span
+	}{
+		{"short", "foo", 10, []Span{{0, 3}}},
+		{"empty", "", 10, []Span{{0, 0}}},
+		{"at spaces", "foo bar baz", 8, []Span{{0, 8}, {8, 11}}},
+		{"within words", "foobarbaz", 4, []Span{{0, 4}, {4, 8}, {8, 9}}},
+		{"leaves room for the cursor", "foobar", 6, []Span{{0, 6}, {6, 6}}},
+		{"keeps wide runes whole", "a日本", 4, []Span{{0, 3}, {3, 5}}},
+		{"narrower than wide runes", "日本", 1, []Span{{0, 2}, {2, 4}, {4, 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Wrap(NewPaddedLine(tt.line), tt.width); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Wrap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
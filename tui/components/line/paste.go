@@ -0,0 +1,56 @@
+package line
+
+import (
+	"github.com/gdamore/tcell/v2"
+)
+
+// Collects the keys of a bracketed paste, so that the pasted text is
+// inserted as a single change rather than one key at a time
+type Paste struct {
+	active bool
+	text   []rune
+}
+
+// Feeds ev to the paste. Returns true if ev is part of it, and therefore
+// should not be handled further. Once the paste ends, Take returns the text.
+func (p *Paste) Feed(ev tcell.Event) (consumed, ended bool) {
+	switch ev := ev.(type) {
+	case *tcell.EventPaste:
+		if ev.Start() {
+			p.active = true
+			p.Clear()
+			return true, false
+		}
+
+		ended = p.active
+		p.active = false
+		return true, ended
+	case *tcell.EventKey:
+		if !p.active {
+			return false, false
+		}
+
+		switch ev.Key() {
+		case tcell.KeyRune:
+			p.text = append(p.text, ev.Rune())
+		case tcell.KeyEnter, tcell.KeyLF:
+			p.text = append(p.text, '\n')
+		}
+		return true, false
+	}
+
+	return false, false
+}
+
+// Returns the pasted text, and forgets it
+func (p *Paste) Take() string {
+	text := string(p.text)
+	p.Clear()
+	return text
+}
+
+// Overwrites the pasted text, which can be a secret
+func (p *Paste) Clear() {
+	clear(p.text)
+	p.text = p.text[:0]
+}
@@ -0,0 +1,38 @@
+package line
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestPaste_Feed(t *testing.T) {
+	p := &Paste{}
+
+	key := tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone)
+	if consumed, _ := p.Feed(key); consumed {
+		t.Fatalf("Paste.Feed() consumed a key outside of a paste")
+	}
+
+	events := []tcell.Event{
+		tcell.NewEventPaste(true),
+		tcell.NewEventKey(tcell.KeyRune, 'a', tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone),
+		tcell.NewEventKey(tcell.KeyRune, 'b', tcell.ModNone),
+	}
+	for _, ev := range events {
+		if consumed, ended := p.Feed(ev); !consumed || ended {
+			t.Fatalf("Paste.Feed() = %v, %v, want true, false", consumed, ended)
+		}
+	}
+
+	if consumed, ended := p.Feed(tcell.NewEventPaste(false)); !consumed || !ended {
+		t.Fatalf("Paste.Feed() = %v, %v at the end, want true, true", consumed, ended)
+	}
+	if got := p.Take(); got != "a\nb" {
+		t.Errorf("Paste.Take() = %q, want %q", got, "a\nb")
+	}
+	if got := p.Take(); got != "" {
+		t.Errorf("Paste.Take() = %q after taking, want nothing", got)
+	}
+}
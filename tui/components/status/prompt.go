@@ -1,14 +1,21 @@
 package status
 
 import (
+	"strings"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/gdamore/tcell/v2/views"
+	"github.com/mattn/go-runewidth"
 	"github.com/shikaan/keydex/tui/components"
+	"github.com/shikaan/keydex/tui/components/field"
+	"github.com/shikaan/keydex/tui/theme"
 )
 
 type Prompt struct {
 	text  *views.Text
 	model promptModel
+	// Takes the answer to a question, if one is asked
+	input *field.Input
 
 	components.Focusable
 	views.BoxLayout
@@ -37,6 +44,34 @@ func (p *Prompt) OnKeyPress(cb func(ev *tcell.EventKey) bool) func() {
 	}
 }
 
+// Shows an input after the text, starting with value
+func (p *Prompt) Ask(value string) {
+	p.StopAsking()
+
+	p.input = field.NewInput(&field.InputOptions{})
+	p.input.SetContent(value)
+	p.input.SetCursor(runewidth.StringWidth(value), 0)
+	p.input.SetFocus(true)
+	p.AddWidget(p.input, 1)
+}
+
+// Returns the content of the input on a single line, as pasted text can
+// break it
+func (p *Prompt) Answer() string {
+	if p.input == nil {
+		return ""
+	}
+	return strings.ReplaceAll(p.input.GetContent(), "\n", " ")
+}
+
+// Removes the input, if any
+func (p *Prompt) StopAsking() {
+	if p.input != nil {
+		p.RemoveWidget(p.input)
+		p.input = nil
+	}
+}
+
 func (p *Prompt) HandleEvent(ev tcell.Event) bool {
 	if !p.model.hasFocus {
 		return false
@@ -44,7 +79,14 @@ func (p *Prompt) HandleEvent(ev tcell.Event) bool {
 
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
-		return p.model.keyPressHandler != nil && p.model.keyPressHandler(ev)
+		if p.model.keyPressHandler != nil && p.model.keyPressHandler(ev) {
+			return true
+		}
+	}
+
+	// Keys left by the handler are typed in the answer
+	if p.input != nil {
+		return p.input.HandleEvent(ev)
 	}
 
 	return false
@@ -53,10 +95,10 @@ func (p *Prompt) HandleEvent(ev tcell.Event) bool {
 func newPrompt() *Prompt {
 	p := &Prompt{}
 	p.SetOrientation(views.Horizontal)
-	p.SetStyle(tcell.StyleDefault.Reverse(true))
+	p.SetStyle(theme.Get(theme.STYLE_PROMPT))
 
 	p.text = views.NewText()
-	p.text.SetStyle(tcell.StyleDefault.Reverse(true))
+	p.text.SetStyle(theme.Get(theme.STYLE_PROMPT))
 
 	p.InsertWidget(0, p.text, 0)
 
@@ -8,6 +8,7 @@ import (
 	"github.com/gdamore/tcell/v2"
 	"github.com/gdamore/tcell/v2/views"
 	"github.com/mattn/go-runewidth"
+	"github.com/shikaan/keydex/tui/theme"
 )
 
 // Notifications will be cleared upon the first interaction
@@ -23,18 +24,29 @@ type Status struct {
 
 	prompt       *Prompt
 	confirmLines [2]views.Widget
+	askLines     [2]views.Widget
 
 	views.BoxLayout
 }
 
+// A key and the label of its command, as shown in the help lines
+type Shortcut struct {
+	Key   string
+	Label string
+}
+
 type statusModel struct {
 	isConfirming bool
 	onAccept     func()
 	onReject     func()
+
+	// True while a question is asked, which onAnswer is called with
+	isAsking bool
+	onAnswer func(answer string)
 }
 
 func (s *Status) Notify(st string) {
-	s.notification.SetCenter(fmt.Sprintf("[ %s ]", st), tcell.StyleDefault)
+	s.notification.SetCenter(fmt.Sprintf("[ %s ]", st), theme.Get(theme.STYLE_NOTIFICATION))
 	go func() {
 		time.Sleep(NOTIFICATION_MIN_DURATION_IN_SECONDS * time.Second)
 		s.notification.SetCenter(EMPTY_NOTIFICATION, tcell.StyleDefault)
@@ -49,13 +61,33 @@ func (s *Status) Confirm(message string, onAccept func(), onReject func()) {
 	s.model.onAccept = onAccept
 	s.model.onReject = onReject
 
+	s.showPrompt(s.confirmLines)
+}
+
+// Asks for a line of text, starting with value. Enter calls onAnswer with it,
+// while Esc calls onReject. Asking counts as confirming.
+func (s *Status) Ask(message, value string, onAnswer func(answer string), onReject func()) {
+	s.model.isConfirming = true
+	s.model.isAsking = true
+
+	s.prompt.SetText(message + " ")
+	s.prompt.Ask(value)
+	s.prompt.SetFocus(true)
+	s.model.onAnswer = onAnswer
+	s.model.onReject = onReject
+
+	s.showPrompt(s.askLines)
+}
+
+// Replaces the notification and the help with the prompt and lines
+func (s *Status) showPrompt(lines [2]views.Widget) {
 	s.RemoveWidget(s.notification)
 	for _, l := range s.helpLines {
 		s.RemoveWidget(l)
 	}
 
 	s.InsertWidget(0, s.prompt, 0)
-	for i, l := range s.confirmLines {
+	for i, l := range lines {
 		s.InsertWidget(i+1, l, 0)
 	}
 
@@ -64,12 +96,17 @@ func (s *Status) Confirm(message string, onAccept func(), onReject func()) {
 
 func (s *Status) reset() {
 	s.model.isConfirming = false
+	s.model.isAsking = false
 	s.prompt.SetFocus(false)
+	s.prompt.StopAsking()
 
 	s.RemoveWidget(s.prompt)
 	for _, l := range s.confirmLines {
 		s.RemoveWidget(l)
 	}
+	for _, l := range s.askLines {
+		s.RemoveWidget(l)
+	}
 
 	s.InsertWidget(0, s.notification, 0)
 	for i, l := range s.helpLines {
@@ -78,11 +115,19 @@ func (s *Status) reset() {
 	s.Resize()
 }
 
+// Closes the pending confirmation, if any, without accepting or rejecting it
+func (s *Status) Dismiss() {
+	if s.model.isConfirming {
+		s.reset()
+	}
+}
+
 func (s *Status) IsConfirming() bool {
 	return s.model.isConfirming
 }
 
-func NewStatus() *Status {
+// Returns the status bar, showing the shortcuts in helpLines
+func NewStatus(helpLines [2][]Shortcut) *Status {
 	status := &Status{}
 	status.SetOrientation(views.Vertical)
 
@@ -90,8 +135,17 @@ func NewStatus() *Status {
 	// Prevents jumps on the first render
 	status.notification.SetCenter(EMPTY_NOTIFICATION, tcell.StyleDefault)
 
-	status.helpLines[0] = newLine("^O Save     ", "^P  Browse  ", "^C Copy     ", "^N New      ", "^K Groups   ")
-	status.helpLines[1] = newLine("^X Exit     ", "ESC Cancel  ", "^R Reveal   ", "^D Delete   ", "^G Help     ")
+	// Aligns the labels of the two lines
+	keyWidth := 0
+	for _, shortcuts := range helpLines {
+		for _, shortcut := range shortcuts {
+			keyWidth = max(keyWidth, runewidth.StringWidth(shortcut.Key))
+		}
+	}
+
+	for i, shortcuts := range helpLines {
+		status.helpLines[i] = newLine(keyWidth, shortcuts...)
+	}
 
 	status.prompt = newPrompt()
 	status.prompt.OnKeyPress(func(ev *tcell.EventKey) bool {
@@ -99,6 +153,10 @@ func NewStatus() *Status {
 			return status.BoxLayout.HandleEvent(ev)
 		}
 
+		if status.model.isAsking {
+			return status.handleAnswer(ev)
+		}
+
 		if ev.Rune() == 'y' || ev.Rune() == 'Y' {
 			if status.model.onAccept != nil {
 				status.model.onAccept()
@@ -117,28 +175,54 @@ func NewStatus() *Status {
 
 		return true
 	})
-	status.confirmLines[0] = newLine("Y Yes")
-	status.confirmLines[1] = newLine("N No")
+	status.confirmLines[0] = newLine(1, Shortcut{"Y", "Yes"})
+	status.confirmLines[1] = newLine(1, Shortcut{"N", "No"})
+	status.askLines[0] = newLine(5, Shortcut{"Enter", "Accept"})
+	status.askLines[1] = newLine(5, Shortcut{"ESC", "Cancel"})
 
 	status.reset()
 
 	return status
 }
 
-func newLine(blocks ...string) views.Widget {
+// Accepts the answer on Enter, and rejects it on Esc. Other keys are typed in
+// the answer.
+func (s *Status) handleAnswer(ev *tcell.EventKey) bool {
+	switch {
+	case ev.Key() == tcell.KeyEnter:
+		answer, onAnswer := s.prompt.Answer(), s.model.onAnswer
+		// Reset first, as answering can ask again
+		s.reset()
+		if onAnswer != nil {
+			onAnswer(answer)
+		}
+		return true
+	case ev.Key() == tcell.KeyESC || ev.Name() == "Ctrl+C":
+		onReject := s.model.onReject
+		s.reset()
+		if onReject != nil {
+			onReject()
+		}
+		return true
+	}
+
+	return false
+}
+
+// Returns a line of shortcuts, padding keys to keyWidth
+func newLine(keyWidth int, shortcuts ...Shortcut) views.Widget {
 	l := views.NewBoxLayout(views.Horizontal)
 
-	for _, block := range blocks {
+	for _, shortcut := range shortcuts {
 		blockElement := views.NewText()
-		blockElement.SetText(block)
-
-		spaceIndex := runewidth.StringWidth(strings.Split(block, " ")[0])
+		padding := strings.Repeat(" ", max(keyWidth-runewidth.StringWidth(shortcut.Key), 0)+1)
+		blockElement.SetText(shortcut.Key + padding + shortcut.Label)
 
-		for i := range spaceIndex {
-			blockElement.SetStyleAt(i, tcell.StyleDefault.Reverse(true))
+		for i := range len([]rune(shortcut.Key)) {
+			blockElement.SetStyleAt(i, theme.Get(theme.STYLE_SHORTCUT))
 		}
 
-		l.AddWidget(blockElement, 1.0/float64(len(blocks)))
+		l.AddWidget(blockElement, 1.0/float64(len(shortcuts)))
 	}
 
 	return l
@@ -3,39 +3,59 @@ package components
 import (
 	"fmt"
 
-	"github.com/gdamore/tcell/v2"
 	"github.com/gdamore/tcell/v2/views"
 	"github.com/shikaan/keydex/pkg/info"
+	"github.com/shikaan/keydex/tui/theme"
 )
 
 type Title struct {
-	content string
-	isDirty bool
+	content    string
+	isDirty    bool
+	isReadOnly bool
 
 	views.TextBar
 }
 
 func (t *Title) SetTitle(title string) {
 	t.content = title
-	t.SetDirty(t.isDirty)
+	t.render()
 }
 
 func (t *Title) SetDirty(dirty bool) {
 	t.isDirty = dirty
+	t.render()
+}
+
+func (t *Title) SetReadOnly(readOnly bool) {
+	t.isReadOnly = readOnly
+	t.render()
+}
+
+func (t *Title) render() {
 	text := t.content
-	if dirty && text != "" {
+	style := theme.Get(theme.STYLE_TITLE)
+
+	if t.isReadOnly && text != "" {
+		text = text + " [READ ONLY]"
+		style = theme.Get(theme.STYLE_READ_ONLY)
+	}
+
+	if t.isDirty && text != "" {
 		text = text + " [MODIFIED]"
+		style = theme.Get(theme.STYLE_DIRTY)
 	}
-	t.SetCenter(text, tcell.StyleDefault.Reverse(true))
+
+	t.SetCenter(text, style)
 }
 
 func NewTitle(database string) *Title {
 	tb := &Title{}
-	tb.TextBar.SetStyle(tcell.StyleDefault.Reverse(true))
+	style := theme.Get(theme.STYLE_TITLE)
+	tb.TextBar.SetStyle(style)
 	left := fmt.Sprintf("  %s %s (%s)", info.NAME, info.VERSION, info.REVISION)
-	tb.TextBar.SetLeft(left, tcell.StyleDefault.Reverse(true))
+	tb.TextBar.SetLeft(left, style)
 	right := fmt.Sprintf("%s  ", database)
-	tb.TextBar.SetRight(right, tcell.StyleDefault.Reverse(true))
+	tb.TextBar.SetRight(right, style)
 
 	return tb
 }
@@ -71,3 +71,32 @@ func TestSetTitleDoesNotOverrideSetDirty(t *testing.T) {
 		t.Fatalf("expected 'UpdatedTitle' to still appear, got: %q", afterClean)
 	}
 }
+
+func TestSetReadOnly(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("failed to init simulation screen: %v", err)
+	}
+	defer screen.Fini()
+
+	title := NewTitle("TestDB")
+	vp := views.NewViewPort(screen, 0, 0, 80, 1)
+	title.SetView(vp)
+
+	title.SetTitle("MyTitle")
+	title.SetReadOnly(true)
+	title.SetDirty(true)
+	title.Draw()
+
+	if got := firstLine(screen); !strings.Contains(got, "MyTitle [READ ONLY] [MODIFIED]") {
+		t.Fatalf("expected read-only and modified badges, got: %q", got)
+	}
+
+	title.SetReadOnly(false)
+	screen.Clear()
+	title.Draw()
+
+	if got := firstLine(screen); strings.Contains(got, "[READ ONLY]") {
+		t.Fatalf("expected no read-only badge after SetReadOnly(false), got: %q", got)
+	}
+}
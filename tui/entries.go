@@ -9,8 +9,12 @@ import (
 	"github.com/shikaan/keydex/pkg/log"
 	"github.com/shikaan/keydex/tui/components"
 	"github.com/shikaan/keydex/tui/components/autocomplete"
+	"github.com/shikaan/keydex/tui/keymap"
 )
 
+// Number of recently accessed entries ranking higher in the finder
+const RECENT_ENTRIES_COUNT = 10
+
 type EntriesView struct {
 	autoComplete *autocomplete.AutoComplete
 	components.Container
@@ -19,7 +23,11 @@ type EntriesView struct {
 func (lv *EntriesView) HandleEvent(ev tcell.Event) bool {
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
-		if ev.Name() == "Ctrl+D" {
+		if App.Keys().Is(ev, keymap.ACTION_TREE) {
+			App.NavigateTo(NewTreeView)
+			return true
+		}
+		if App.Keys().Is(ev, keymap.ACTION_DELETE) {
 			if App.IsReadOnly() {
 				msg := "Cannot delete. Archive in read-only mode."
 				App.Notify(msg)
@@ -32,6 +40,7 @@ func (lv *EntriesView) HandleEvent(ev tcell.Event) bool {
 				msg := "Could not delete. Entry cannot be found."
 				App.Notify(msg)
 				log.Error(msg, nil)
+				return true
 			}
 
 			title := entry.GetTitle()
@@ -47,7 +56,7 @@ func (lv *EntriesView) HandleEvent(ev tcell.Event) bool {
 						return
 					}
 
-					if e := App.State.Database.SaveAndUnlockEntries(); e != nil {
+					if e := App.SaveDatabase(); e != nil {
 						App.LockCurrentDatabase(e)
 						return
 					}
@@ -83,10 +92,23 @@ func NewEntryListView(screen tcell.Screen) views.Widget {
 		TotalCount: count,
 		MaxX:       maxX,
 		MaxY:       maxY,
+		Keys:       App.Keys(),
+		Recent:     App.State.Database.GetRecentEntryPaths(RECENT_ENTRIES_COUNT),
 		OnSelect: func(ref string) bool {
+			// The database might have changed since the list was built
+			entry := App.State.Database.GetFirstEntryByPath(ref)
+			if entry == nil {
+				msg := "Could not open. Entry cannot be found."
+				App.Notify(msg)
+				log.Error(msg, nil)
+				return true
+			}
+
 			App.State.Reference = ref
-			App.State.Entry = App.State.Database.GetFirstEntryByPath(ref)
-			App.State.Group = App.State.Database.GetGroupForEntry(App.State.Entry)
+			App.State.Entry = entry
+			App.State.Group = App.State.Database.GetGroupForEntry(entry)
+			// Persisted with the next save, as KeePass does
+			App.State.Entry.SetLastAccessed()
 			App.NavigateTo(NewEntryView)
 			return true
 		},
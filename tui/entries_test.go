@@ -0,0 +1,36 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shikaan/keydex/pkg/kdbx"
+)
+
+func TestEntriesView_SelectMissingEntry(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	screen.Init()
+	defer screen.Fini()
+
+	db := createTestDatabase()
+	entry := db.NewEntry()
+	entry.SetValue(kdbx.TITLE_KEY, "GitHub")
+	root := db.GetRootGroup()
+	root.Entries = append(root.Entries, *entry.Entry)
+
+	// Left over by other tests, it would keep the finder from opening
+	App.isDirty = false
+	Setup(screen, State{Database: db}, false)
+	App.NavigateTo(NewEntryListView)
+
+	// As when the database is reloaded after the list was built
+	if err := db.RemoveEntry(entry.UUID); err != nil {
+		t.Fatal(err)
+	}
+
+	App.layout.HandleEvent(tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone))
+
+	if App.State.Entry != nil || App.State.Reference != "" {
+		t.Errorf("expected the missing entry not to be opened, got %q", App.State.Reference)
+	}
+}
@@ -1,44 +1,86 @@
 package tui
 
 import (
+	"bytes"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/gdamore/tcell/v2/views"
 
-	"github.com/shikaan/keydex/pkg/clipboard"
 	"github.com/shikaan/keydex/pkg/kdbx"
 	"github.com/shikaan/keydex/pkg/log"
+	"github.com/shikaan/keydex/pkg/opener"
 	"github.com/shikaan/keydex/tui/components"
 	"github.com/shikaan/keydex/tui/components/field"
+	"github.com/shikaan/keydex/tui/keymap"
+	"github.com/shikaan/keydex/tui/theme"
 )
 
 type fieldKey = string
-type fieldMap = map[fieldKey]*field.Field
+type fieldMap = map[fieldKey]field.Editor
 
 type EntryView struct {
 	fieldByKey   fieldMap
 	initialGroup *kdbx.Group
 	form         *components.Form
+	// Copy of the entry, where fields are added, removed, renamed, and
+	// protected until it is saved
+	draft *kdbx.Entry
+	// Fields shown even when empty, such as those just added
+	shown map[fieldKey]bool
+	// Position in the form of the text area expanded to the whole screen
+	expandedIndex int
 	components.Container
 }
 
-func (v *EntryView) updateEntry(entry *kdbx.Entry) {
+// Writes the values being edited to the draft
+func (v *EntryView) syncDraft() {
 	for key, field := range v.fieldByKey {
-		entry.SetValue(key, field.GetContent())
+		v.draft.SetValue(key, field.GetContent())
 	}
+}
+
+func (v *EntryView) updateEntry(entry *kdbx.Entry) {
+	v.syncDraft()
+	entry.SetFields(v.draft)
 
 	entry.SetLastUpdated()
 	App.State.Database.MoveEntryToGroup(entry, App.State.Group)
 	App.State.Entry = entry
 }
 
+// Forgets the changes to undo, once they are saved
+func (v *EntryView) clearHistory() {
+	for _, field := range v.fieldByKey {
+		field.ClearHistory()
+	}
+}
+
+// Returns a copy of the entry, with the values being edited
+func (v *EntryView) pendingEntry() *kdbx.Entry {
+	v.syncDraft()
+	clone := v.draft.Clone()
+	clone.UUID = App.State.Entry.UUID
+
+	return &kdbx.Entry{Entry: &clone}
+}
+
 func (v *EntryView) HandleEvent(ev tcell.Event) bool {
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
-		if ev.Name() == "Ctrl+K" {
+		if v.handleFieldAction(ev) {
+			return true
+		}
+
+		if App.Keys().Is(ev, keymap.ACTION_OPEN_URL) {
+			v.openURL()
+			return true
+		}
+
+		if App.Keys().Is(ev, keymap.ACTION_GROUPS) {
 			if App.IsReadOnly() {
 				msg := "Cannot select group. Archive in read-only mode."
 				App.Notify(msg)
@@ -50,7 +92,7 @@ func (v *EntryView) HandleEvent(ev tcell.Event) bool {
 			return true
 		}
 
-		if ev.Name() == "Ctrl+O" {
+		if App.Keys().Is(ev, keymap.ACTION_SAVE) {
 			if App.IsReadOnly() {
 				msg := "Cannot save. Archive in read-only mode."
 				App.Notify(msg)
@@ -66,7 +108,7 @@ func (v *EntryView) HandleEvent(ev tcell.Event) bool {
 					"Create \""+App.State.Entry.GetTitle()+"\"? This will overwrite the existing file.",
 					func() {
 						v.updateEntry(App.State.Entry)
-						if e := App.State.Database.SaveAndUnlockEntries(); e != nil {
+						if e := App.SaveDatabase(); e != nil {
 							App.LockCurrentDatabase(e)
 							return
 						}
@@ -75,6 +117,7 @@ func (v *EntryView) HandleEvent(ev tcell.Event) bool {
 						App.Notify(msg)
 						log.Info(msg)
 						App.SetDirty(false)
+						v.clearHistory()
 						App.RefreshCurrentView()
 					}, func() {
 						msg := "Operation cancelled. Entry was not created."
@@ -90,7 +133,7 @@ func (v *EntryView) HandleEvent(ev tcell.Event) bool {
 				func() {
 					v.updateEntry(existingEntry)
 
-					if e := App.State.Database.SaveAndUnlockEntries(); e != nil {
+					if e := App.SaveDatabase(); e != nil {
 						App.LockCurrentDatabase(e)
 						return
 					}
@@ -99,6 +142,7 @@ func (v *EntryView) HandleEvent(ev tcell.Event) bool {
 					App.Notify(msg)
 					log.Info(msg)
 					App.SetDirty(false)
+					v.clearHistory()
 					App.RefreshCurrentView()
 				}, func() {
 					msg := "Operation cancelled. Entry was not saved."
@@ -109,7 +153,7 @@ func (v *EntryView) HandleEvent(ev tcell.Event) bool {
 			)
 		}
 
-		if ev.Name() == "Ctrl+D" {
+		if App.Keys().Is(ev, keymap.ACTION_DELETE) {
 			if App.IsReadOnly() {
 				msg := "Cannot delete. Archive in read-only mode."
 				App.Notify(msg)
@@ -140,7 +184,7 @@ func (v *EntryView) HandleEvent(ev tcell.Event) bool {
 						return
 					}
 
-					if e := App.State.Database.SaveAndUnlockEntries(); e != nil {
+					if e := App.SaveDatabase(); e != nil {
 						App.LockCurrentDatabase(e)
 						return
 					}
@@ -164,6 +208,56 @@ func (v *EntryView) HandleEvent(ev tcell.Event) bool {
 	return v.Container.HandleEvent(ev)
 }
 
+// Opens the URL of the entry, as currently edited, and copies the password
+// if the settings say so
+func (v *EntryView) openURL() {
+	entry := v.pendingEntry()
+
+	// Protected values are left out of the URL, which ends up on command lines
+	fields := map[string]string{}
+	for _, f := range entry.Values {
+		if !f.Value.Protected.Bool {
+			fields[f.Key] = f.Value.Content
+		}
+	}
+
+	url, err := opener.Resolve(entry.GetContent(kdbx.URL_KEY), fields)
+	if err != nil {
+		msg := "Cannot open URL. Entry has no URL."
+		App.Notify(msg)
+		log.Info(msg)
+		return
+	}
+
+	if opener.IsCommand(url) && !App.Settings.Opener.AllowCommands {
+		msg := "Cannot open URL. Running cmd:// URLs is not allowed."
+		App.Notify(msg)
+		log.Info(msg)
+		return
+	}
+
+	if err := opener.Open(url, App.Settings.Opener); err != nil {
+		msg := "Could not open URL. Check logs for details."
+		App.Notify(msg)
+		log.Error(msg, err)
+		return
+	}
+
+	msg := "Opened " + url + "."
+	if password := entry.GetPassword(); App.Settings.CopyPasswordOnOpen && password != "" {
+		if err := App.CopyToClipboard(password); err != nil {
+			msg := "Opened URL, but could not copy the password. Check logs for details."
+			App.Notify(msg)
+			log.Error(msg, err)
+			return
+		}
+		msg = "Opened " + url + ". Copied \"" + kdbx.PASSWORD_KEY + "\" to the clipboard."
+	}
+
+	App.Notify(msg)
+	log.Info("Opened URL of " + App.State.Reference)
+}
+
 func NewEntryView(screen tcell.Screen) views.Widget {
 	if App.State.Entry == nil {
 		panic("missing entry")
@@ -173,15 +267,22 @@ func NewEntryView(screen tcell.Screen) views.Widget {
 		panic("missing group")
 	}
 
-	title := App.State.Entry.GetTitle()
-	if App.IsReadOnly() {
-		title += " [READ ONLY]"
-	}
-	App.SetTitle(title)
+	App.SetTitle(App.State.Entry.GetTitle())
 	view := &EntryView{}
 	view.Container = components.Container{}
 
-	form, fieldMap := view.newForm(screen, App.State.Entry, App.State.Group)
+	draft := App.State.Entry.Clone()
+	view.draft = &kdbx.Entry{Entry: &draft}
+	view.shown = map[fieldKey]bool{}
+
+	// New entries show all the fields of their template, to be filled in
+	if App.State.Database.GetGroupForEntry(App.State.Entry) == nil {
+		for _, f := range view.draft.Values {
+			view.shown[f.Key] = true
+		}
+	}
+
+	form, fieldMap := view.newForm(screen, view.draft, App.State.Group)
 	view.fieldByKey = fieldMap
 
 	view.SetContent(form)
@@ -195,7 +296,7 @@ func (view *EntryView) newForm(_ tcell.Screen, entry *kdbx.Entry, group *kdbx.Gr
 	fields := fieldMap{}
 
 	for _, f := range entry.Values {
-		if field := view.newEntryField(f.Key, f.Value.Content, f.Value.Protected.Bool); field != nil {
+		if field := view.newEntryField(f); field != nil {
 			form.AddWidget(field, 0)
 			// Using f.Value as binding key (for example, is we just used props.reference)
 			// would cause the title field to be unmodifiable, because the reference
@@ -227,9 +328,19 @@ func (view *EntryView) newForm(_ tcell.Screen, entry *kdbx.Entry, group *kdbx.Gr
 	return form, fields
 }
 
-func (view *EntryView) newEntryField(label, initialValue string, isProtected bool) *field.Field {
-	// Do not print empty fields, unless they are the title
-	if initialValue == "" && label != kdbx.TITLE_KEY {
+func (view *EntryView) newEntryField(entryField kdbx.EntryField) field.Editor {
+	label, isProtected := entryField.Key, entryField.Value.Protected.Bool
+
+	// Protected values are moved to the field without becoming strings
+	value, err := kdbx.OpenField(entryField)
+	if err != nil {
+		log.Error("Could not open field "+label, err)
+		return nil
+	}
+	defer value.Destroy()
+
+	// Do not print empty fields, unless they are the title or just added
+	if value.Len() == 0 && label != kdbx.TITLE_KEY && !view.shown[label] {
 		return nil
 	}
 
@@ -238,8 +349,14 @@ func (view *EntryView) newEntryField(label, initialValue string, isProtected boo
 		inputType = field.InputTypePassword
 	}
 
-	fieldOptions := &field.FieldOptions{Label: label, InitialValue: initialValue, InputType: inputType, Disabled: App.IsReadOnly()}
-	f := field.NewField(fieldOptions)
+	fieldOptions := &field.FieldOptions{Label: label, InputType: inputType, Disabled: App.IsReadOnly(), Keys: App.Keys()}
+	var f field.Editor
+	if label == kdbx.NOTES_KEY || bytes.ContainsRune(value.Bytes(), '\n') {
+		f = field.NewTextArea(fieldOptions)
+	} else {
+		f = field.NewField(fieldOptions)
+	}
+	f.SetSecret(value)
 
 	f.OnFocus(func() bool {
 		App.LastFocused = f
@@ -252,13 +369,19 @@ func (view *EntryView) newEntryField(label, initialValue string, isProtected boo
 	})
 
 	f.OnKeyPress(func(ev *tcell.EventKey) bool {
-		if ev.Name() == "Ctrl+C" {
-			clipboard.Write(string(f.GetContent()))
+		if App.Keys().Is(ev, keymap.ACTION_COPY) {
+			if err := App.CopyToClipboard(f.GetContent()); err != nil {
+				msg := "Could not copy. Check logs for details."
+				App.Notify(msg)
+				log.Error(msg, err)
+				return true
+			}
+
 			App.Notify(fmt.Sprintf("Copied \"%s\" to the clipboard.", label))
 			return true
 		}
 
-		if ev.Name() == "Ctrl+R" {
+		if App.Keys().Is(ev, keymap.ACTION_REVEAL) {
 			if isProtected {
 				if f.GetInputType() == field.InputTypePassword {
 					f.SetInputType(field.InputTypeText)
@@ -270,9 +393,36 @@ func (view *EntryView) newEntryField(label, initialValue string, isProtected boo
 			return true
 		}
 
+		if App.Keys().Is(ev, keymap.ACTION_UNDO) {
+			if f.Undo() {
+				App.SetDirty(true)
+			} else {
+				App.Notify("Nothing to undo.")
+			}
+			return true
+		}
+
+		if App.Keys().Is(ev, keymap.ACTION_REDO) {
+			if f.Redo() {
+				App.SetDirty(true)
+			} else {
+				App.Notify("Nothing to redo.")
+			}
+			return true
+		}
+
+		if App.Keys().Is(ev, keymap.ACTION_EXPAND) {
+			if textArea, ok := f.(*field.TextArea); ok {
+				view.toggleExpanded(textArea)
+			} else {
+				App.Notify("Only notes and values on several lines expand.")
+			}
+			return true
+		}
+
 		if ev.Key() == tcell.KeyRune {
 			if isProtected && f.GetInputType() == field.InputTypePassword {
-				App.Notify("Reveal (^R) the field to edit.")
+				App.Notify("Reveal (" + App.Keys().Describe(keymap.ACTION_REVEAL) + ") the field to edit.")
 			}
 		}
 
@@ -282,9 +432,188 @@ func (view *EntryView) newEntryField(label, initialValue string, isProtected boo
 	return f
 }
 
+// Handles the actions adding, removing, renaming, and protecting fields.
+// Returns false for other keys.
+func (view *EntryView) handleFieldAction(ev *tcell.EventKey) bool {
+	actions := []keymap.Action{keymap.ACTION_ADD_FIELD, keymap.ACTION_REMOVE_FIELD, keymap.ACTION_RENAME_FIELD, keymap.ACTION_PROTECT_FIELD}
+	index := slices.IndexFunc(actions, func(action keymap.Action) bool { return App.Keys().Is(ev, action) })
+	if index < 0 {
+		return false
+	}
+
+	if App.IsReadOnly() {
+		msg := "Cannot change fields. Archive in read-only mode."
+		App.Notify(msg)
+		log.Info(msg)
+		return true
+	}
+
+	if actions[index] == keymap.ACTION_ADD_FIELD {
+		App.Ask("Name of the new field:", "", view.addField, func() {
+			App.Notify("Operation cancelled. Field was not added.")
+		})
+		return true
+	}
+
+	key := view.focusedKey()
+	if key == "" {
+		App.Notify("No field selected.")
+		return true
+	}
+
+	switch actions[index] {
+	case keymap.ACTION_REMOVE_FIELD:
+		if key == kdbx.TITLE_KEY {
+			App.Notify("The title cannot be removed.")
+			return true
+		}
+
+		App.Confirm(
+			fmt.Sprintf("Remove field \"%s\"? Its value will be lost on save.", key),
+			func() {
+				view.changeFields(func() error {
+					delete(view.shown, key)
+					return view.draft.RemoveField(key)
+				}, "")
+			},
+			func() {
+				App.Notify("Operation cancelled. Field was not removed.")
+			},
+		)
+	case keymap.ACTION_RENAME_FIELD:
+		if key == kdbx.TITLE_KEY {
+			App.Notify("The title cannot be renamed.")
+			return true
+		}
+
+		App.Ask(fmt.Sprintf("Rename \"%s\" to:", key), key, func(name string) {
+			view.renameField(key, strings.TrimSpace(name))
+		}, func() {
+			App.Notify("Operation cancelled. Field was not renamed.")
+		})
+	case keymap.ACTION_PROTECT_FIELD:
+		if key == kdbx.TITLE_KEY {
+			App.Notify("The title cannot be protected.")
+			return true
+		}
+
+		protected := !view.draft.Get(key).Value.Protected.Bool
+		changed := view.changeFields(func() error {
+			return view.draft.SetProtected(key, protected)
+		}, key)
+
+		if changed && protected {
+			App.Notify(fmt.Sprintf("Field \"%s\" is now protected.", key))
+		} else if changed {
+			App.Notify(fmt.Sprintf("Field \"%s\" is no longer protected.", key))
+		}
+	}
+
+	return true
+}
+
+func (view *EntryView) addField(name string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		App.Notify("Operation cancelled. Field was not added.")
+		return
+	}
+
+	if _, ok := view.fieldByKey[name]; ok {
+		App.Notify(fmt.Sprintf("Field \"%s\" already exists.", name))
+		return
+	}
+
+	view.changeFields(func() error {
+		view.shown[name] = true
+		// Empty fields are in the entry, only hidden
+		if view.draft.Get(name) != nil {
+			return nil
+		}
+		return view.draft.AddField(name, false)
+	}, name)
+}
+
+func (view *EntryView) renameField(key, name string) {
+	if name == "" || name == key {
+		App.Notify("Operation cancelled. Field was not renamed.")
+		return
+	}
+
+	if view.draft.Get(name) != nil {
+		App.Notify(fmt.Sprintf("Field \"%s\" already exists.", name))
+		return
+	}
+
+	view.changeFields(func() error {
+		view.shown[name] = view.shown[key]
+		delete(view.shown, key)
+		return view.draft.RenameField(key, name)
+	}, name)
+}
+
+// Returns the key of the field with focus, if any
+func (view *EntryView) focusedKey() fieldKey {
+	for key, f := range view.fieldByKey {
+		if f == App.LastFocused {
+			return key
+		}
+	}
+	return ""
+}
+
+// Applies change to the draft, and lays the form out again with focus on
+// the field called focus. Returns false if the change failed.
+func (view *EntryView) changeFields(change func() error, focus fieldKey) bool {
+	view.syncDraft()
+
+	if err := change(); err != nil {
+		msg := "Could not change fields. Check logs for details."
+		App.Notify(msg)
+		log.Error(msg, err)
+		return false
+	}
+
+	form, fields := view.newForm(App.screen, view.draft, App.State.Group)
+	view.form = form
+	view.fieldByKey = fields
+	view.SetContent(form)
+
+	if f, ok := fields[focus]; ok {
+		for _, other := range form.Focusables() {
+			other.SetFocus(false)
+		}
+		f.SetFocus(true)
+	}
+
+	App.SetDirty(true)
+	return true
+}
+
+// Shows textArea alone on the whole screen, or puts it back in the form
+func (view *EntryView) toggleExpanded(textArea *field.TextArea) {
+	if textArea.IsExpanded() {
+		textArea.SetExpanded(0)
+		view.form.InsertWidget(view.expandedIndex, textArea, 0)
+		view.SetContent(view.form)
+		return
+	}
+
+	view.expandedIndex = slices.Index(view.form.Widgets(), views.Widget(textArea))
+	if view.expandedIndex < 0 {
+		return
+	}
+
+	// The label takes a row
+	_, height := view.ViewSize()
+	textArea.SetExpanded(max(height-1, field.TEXTAREA_HEIGHT))
+	view.form.RemoveWidget(textArea)
+	view.SetContent(textArea)
+}
+
 func (view *EntryView) newMetaField(label, value string) *views.Text {
 	field := views.NewText()
-	field.SetStyle(tcell.StyleDefault.Dim(true))
+	field.SetStyle(theme.Get(theme.STYLE_META))
 	field.SetText(label + ": " + value)
 	return field
 }
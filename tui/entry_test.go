@@ -0,0 +1,122 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/tui/components/field"
+)
+
+func TestEntryView_FieldActions(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	screen.Init()
+	defer screen.Fini()
+
+	db := openLockTestDatabase(t)
+	entry := db.GetFirstEntryByPath("/TestDB/GitHub")
+	// Left over by other tests, it would keep the entry from opening
+	App.isDirty = false
+	Setup(screen, State{Database: db, Entry: entry, Group: db.GetGroupForEntry(entry), Reference: "/TestDB/GitHub"}, false)
+
+	press := func(keys ...*tcell.EventKey) {
+		for _, key := range keys {
+			App.layout.HandleEvent(key)
+		}
+	}
+	typeText := func(text string) {
+		for _, r := range text {
+			press(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+		}
+	}
+	alt := func(r rune) *tcell.EventKey { return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModAlt) }
+	enter := tcell.NewEventKey(tcell.KeyEnter, 0, tcell.ModNone)
+	backspace := tcell.NewEventKey(tcell.KeyBackspace2, 0, tcell.ModNone)
+	view := func() *EntryView { return App.lastWidget.(*EntryView) }
+
+	press(alt('a'))
+	if !App.layout.Status.IsConfirming() {
+		t.Fatalf("expected the name of the field to be asked")
+	}
+	typeText("URL")
+	press(enter)
+
+	f, ok := view().fieldByKey["URL"]
+	if !ok || !f.HasFocus() || !App.IsDirty() {
+		t.Fatalf("expected the added field to be shown with focus")
+	}
+	typeText("example.com")
+
+	press(alt('n'))
+	press(backspace, backspace, backspace)
+	typeText("Website")
+	press(enter)
+
+	f, ok = view().fieldByKey["Website"]
+	if _, old := view().fieldByKey["URL"]; !ok || old || f.GetContent() != "example.com" {
+		t.Fatalf("expected the field to be renamed with its value")
+	}
+
+	press(alt('p'))
+	if f := view().fieldByKey["Website"]; f.GetInputType() != field.InputTypePassword {
+		t.Errorf("expected the field to be protected")
+	}
+
+	press(alt('a'))
+	typeText("PIN")
+	press(enter)
+	press(alt('x'))
+	press(tcell.NewEventKey(tcell.KeyRune, 'y', tcell.ModNone))
+	if _, ok := view().fieldByKey["PIN"]; ok {
+		t.Errorf("expected the field to be removed")
+	}
+
+	press(tcell.NewEventKey(tcell.KeyCtrlO, 0, tcell.ModCtrl))
+	press(tcell.NewEventKey(tcell.KeyRune, 'y', tcell.ModNone))
+
+	saved, err := kdbx.OpenFromPath(db.Path(), lockTestPassphrase, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer saved.Wipe()
+
+	savedEntry := saved.GetFirstEntryByPath("/TestDB/GitHub")
+	website := savedEntry.Get("Website")
+	if website == nil || !website.Value.Protected.Bool || savedEntry.GetContent("Website") != "example.com" {
+		t.Errorf("expected the protected field to be saved")
+	}
+	if savedEntry.Get("URL") != nil || savedEntry.Get("PIN") != nil {
+		t.Errorf("expected the renamed and removed fields to be gone")
+	}
+}
+
+func TestEntryView_UndoAfterSave(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	screen.Init()
+	defer screen.Fini()
+
+	db := openLockTestDatabase(t)
+	entry := db.GetFirstEntryByPath("/TestDB/GitHub")
+	// Left over by other tests, it would keep the entry from opening
+	App.isDirty = false
+	Setup(screen, State{Database: db, Entry: entry, Group: db.GetGroupForEntry(entry), Reference: "/TestDB/GitHub"}, false)
+
+	press := func(keys ...*tcell.EventKey) {
+		for _, key := range keys {
+			App.layout.HandleEvent(key)
+		}
+	}
+	title := func() string { return App.lastWidget.(*EntryView).fieldByKey[kdbx.TITLE_KEY].GetContent() }
+
+	// Edits the title, which is focused
+	press(tcell.NewEventKey(tcell.KeyRune, 'X', tcell.ModNone))
+	press(tcell.NewEventKey(tcell.KeyCtrlO, 0, tcell.ModCtrl), tcell.NewEventKey(tcell.KeyRune, 'y', tcell.ModNone))
+	if App.IsDirty() || title() != "XGitHub" {
+		t.Fatalf("expected the entry to be saved, got %q", title())
+	}
+
+	press(tcell.NewEventKey(tcell.KeyRune, 'u', tcell.ModAlt))
+	if App.IsDirty() || title() != "XGitHub" {
+		t.Errorf("expected nothing to undo after saving, got %q", title())
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/shikaan/keydex/pkg/log"
 	"github.com/shikaan/keydex/tui/components"
 	"github.com/shikaan/keydex/tui/components/autocomplete"
+	"github.com/shikaan/keydex/tui/keymap"
 )
 
 type GroupsView struct {
@@ -19,7 +20,7 @@ type GroupsView struct {
 func (gv *GroupsView) HandleEvent(ev tcell.Event) bool {
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
-		if ev.Name() == "Ctrl+D" {
+		if App.Keys().Is(ev, keymap.ACTION_DELETE) {
 			if App.IsReadOnly() {
 				msg := "Cannot delete. Archive in read-only mode."
 				App.Notify(msg)
@@ -47,7 +48,7 @@ func (gv *GroupsView) HandleEvent(ev tcell.Event) bool {
 						return
 					}
 
-					if e := App.State.Database.SaveAndUnlockEntries(); e != nil {
+					if e := App.SaveDatabase(); e != nil {
 						App.LockCurrentDatabase(e)
 						return
 					}
@@ -83,6 +84,7 @@ func NewGroupListView(screen tcell.Screen) views.Widget {
 		TotalCount: count,
 		MaxX:       maxX,
 		MaxY:       maxY,
+		Keys:       App.Keys(),
 		OnSelect: func(groupRef string) bool {
 			App.State.Group = App.State.Database.GetFirstGroupByPath(groupRef)
 			App.SetDirty(true)
@@ -94,7 +96,7 @@ func NewGroupListView(screen tcell.Screen) views.Widget {
 			root := App.State.Database.GetRootGroup()
 			root.Groups = append(root.Groups, *group)
 
-			if e := App.State.Database.SaveAndUnlockEntries(); e != nil {
+			if e := App.SaveDatabase(); e != nil {
 				App.LockCurrentDatabase(e)
 				return true
 			}
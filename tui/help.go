@@ -1,22 +1,55 @@
 package tui
 
 import (
+	"strings"
+
 	"github.com/gdamore/tcell/v2"
 	"github.com/gdamore/tcell/v2/views"
+	"github.com/mattn/go-runewidth"
 	"github.com/shikaan/keydex/pkg/info"
 	"github.com/shikaan/keydex/tui/components"
+	"github.com/shikaan/keydex/tui/keymap"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
-// Line breaking the border is to accommodate for the space introduced
-// with the usage of the constant. Do not align it!
-const welcomeBanner = `+--------------------------------------------------------------------------+
-|                              Welcome to ` + info.NAME + `!                          |
-|                                                                          |
-|                Press Ctrl+P to start browsing the database.              |
-|                       Use ▴ ▾ to scroll the help text.                   |
-+--------------------------------------------------------------------------+`
+const BANNER_WIDTH = 74
+
+// Returns a box around lines, each of them centered
+func banner(lines ...string) string {
+	border := "+" + strings.Repeat("-", BANNER_WIDTH) + "+"
+	rows := []string{border}
+
+	for _, line := range lines {
+		width := runewidth.StringWidth(line)
+		left := max(BANNER_WIDTH-width, 0) / 2
+		right := max(BANNER_WIDTH-width-left, 0)
+		rows = append(rows, "|"+strings.Repeat(" ", left)+line+strings.Repeat(" ", right)+"|")
+	}
+
+	return strings.Join(append(rows, border), "\n")
+}
+
+// Returns the list of actions with their keys, aligned in two columns
+func helpBindings(keys *keymap.Keymap) string {
+	width := 0
+	for _, definition := range keymap.ACTIONS {
+		width = max(width, runewidth.StringWidth(keys.Describe(definition.Action)))
+	}
+
+	lines := []string{}
+	for _, definition := range keymap.ACTIONS {
+		key := keys.Describe(definition.Action)
+		if key == "" {
+			continue
+		}
+
+		padding := strings.Repeat(" ", width-runewidth.StringWidth(key)+4)
+		lines = append(lines, key+padding+definition.Description)
+	}
+
+	return strings.Join(lines, "\n")
+}
 
 var caser cases.Caser
 var nameTitle string
@@ -58,7 +91,12 @@ func NewHelpView(screen tcell.Screen) views.Widget {
 	var firstAccessLine string
 
 	if App.State.Reference == "" {
-		firstAccessLine = welcomeBanner
+		firstAccessLine = banner(
+			"Welcome to "+info.NAME+"!",
+			"",
+			"Press "+App.Keys().Describe(keymap.ACTION_BROWSE)+" to start browsing the database.",
+			"Use ▴ ▾ to scroll the help text.",
+		)
 	}
 
 	view.text.SetContent(firstAccessLine + `
@@ -75,20 +113,20 @@ bottom are a list of available commands, the third is a notification line
 for informational messages.
 
 Commands - except for navigation - are issued by pressing a combination of
-Ctrl and another letter. We use the caret (^) symbol to indicate Ctrl. For
-example, ^C means Ctrl+C.
+Ctrl and another letter. We use the caret (^) symbol to indicate Ctrl, and
+M- to indicate Alt. For example, ^C means Ctrl+C, and M-w means Alt+W.
+Keys separated by a space are pressed one after the other.
+
+The fuzzy finder can be switched to a tree of the groups of the database,
+showing how many entries each of them holds. The Left and Right arrows
+collapse and expand groups, and Enter opens entries. New entries are
+created in the selected group.
 
 The following functions are available in ` + info.NAME + `:
 
-^X    Close the application.
-^P    Open the fuzzy finder to search entries.
-^O    Save the current state to the open file.
-^N    Create a new entry.
-^D    Delete the selected item (group or entry).
-^K    Change an entry’s group or create a new one.
-^C    Copy the current field’s content to the clipboard.
-^R    Reveal hidden fields (e.g., passwords).
-^G    Open this help.
+` + helpBindings(App.Keys()) + `
+
+Keybindings can be changed in the configuration file. See 'keydex config'.
 
 End of help.
 `)
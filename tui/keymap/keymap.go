@@ -0,0 +1,335 @@
+package keymap
+
+import (
+	"slices"
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+// Identifies a command of the editor, as used in the configuration
+type Action = string
+
+const (
+	ACTION_QUIT     Action = "quit"
+	ACTION_BROWSE   Action = "browse"
+	ACTION_SAVE     Action = "save"
+	ACTION_NEW      Action = "new"
+	ACTION_DELETE   Action = "delete"
+	ACTION_GROUPS   Action = "groups"
+	ACTION_COPY     Action = "copy"
+	ACTION_REVEAL   Action = "reveal"
+	ACTION_HELP     Action = "help"
+	ACTION_CANCEL   Action = "cancel"
+	ACTION_UNDO     Action = "undo"
+	ACTION_REDO     Action = "redo"
+	ACTION_EXPAND   Action = "expand"
+	ACTION_OPEN_URL Action = "open_url"
+
+	ACTION_ADD_FIELD     Action = "add_field"
+	ACTION_REMOVE_FIELD  Action = "remove_field"
+	ACTION_RENAME_FIELD  Action = "rename_field"
+	ACTION_PROTECT_FIELD Action = "protect_field"
+
+	ACTION_TREE         Action = "tree"
+	ACTION_ADD_GROUP    Action = "add_group"
+	ACTION_RENAME_GROUP Action = "rename_group"
+
+	ACTION_LINE_START       Action = "line_start"
+	ACTION_LINE_END         Action = "line_end"
+	ACTION_WORD_LEFT        Action = "word_left"
+	ACTION_WORD_RIGHT       Action = "word_right"
+	ACTION_DELETE_WORD      Action = "delete_word"
+	ACTION_DELETE_NEXT_WORD Action = "delete_next_word"
+	ACTION_KILL_LINE        Action = "kill_line"
+)
+
+type Definition struct {
+	Action Action
+	// Short name, used in the status bar
+	Label string
+	// Used in the help text
+	Description string
+}
+
+// Every action of the editor, in the order they are documented
+var ACTIONS = []Definition{
+	{ACTION_QUIT, "Exit", "Close the application."},
+	{ACTION_BROWSE, "Browse", "Open the fuzzy finder to search entries."},
+	{ACTION_SAVE, "Save", "Save the current state to the open file."},
+	{ACTION_NEW, "New", "Create a new entry from a template."},
+	{ACTION_DELETE, "Delete", "Delete the selected item (group or entry)."},
+	{ACTION_GROUPS, "Groups", "Change an entry’s group or create a new one."},
+	{ACTION_COPY, "Copy", "Copy the current field’s content to the clipboard."},
+	{ACTION_REVEAL, "Reveal", "Reveal hidden fields (e.g., passwords)."},
+	{ACTION_HELP, "Help", "Open this help."},
+	{ACTION_CANCEL, "Cancel", "Discard the changes and go back to the entry."},
+	{ACTION_UNDO, "Undo", "Undo the last change to the current field."},
+	{ACTION_REDO, "Redo", "Redo the last change undone in the current field."},
+	{ACTION_EXPAND, "Expand", "Edit the current notes on the whole screen, or shrink them back."},
+	{ACTION_OPEN_URL, "Open URL", "Open the URL of the entry in the browser."},
+	{ACTION_ADD_FIELD, "Add field", "Add a field to the entry, such as a URL or recovery codes."},
+	{ACTION_REMOVE_FIELD, "Remove field", "Remove the current field from the entry."},
+	{ACTION_RENAME_FIELD, "Rename field", "Rename the current field."},
+	{ACTION_PROTECT_FIELD, "Protect field", "Protect the current field like a password, or stop protecting it."},
+	{ACTION_TREE, "Tree", "Switch between the fuzzy finder and the tree of groups."},
+	{ACTION_ADD_GROUP, "Add group", "Add a group to the selected one in the tree."},
+	{ACTION_RENAME_GROUP, "Rename group", "Rename the selected group in the tree."},
+	{ACTION_LINE_START, "Start", "Move to the start of the line (also Home, except in searches)."},
+	{ACTION_LINE_END, "End", "Move to the end of the line (also End, except in searches)."},
+	{ACTION_WORD_LEFT, "Word left", "Move to the previous word (also Ctrl+Left)."},
+	{ACTION_WORD_RIGHT, "Word right", "Move to the next word (also Ctrl+Right)."},
+	{ACTION_DELETE_WORD, "Delete word", "Delete the word before the cursor."},
+	{ACTION_DELETE_NEXT_WORD, "Delete next word", "Delete the word after the cursor."},
+	{ACTION_KILL_LINE, "Kill", "Delete from the cursor to the end of the line."},
+}
+
+const (
+	PRESET_NANO  = "nano"
+	PRESET_EMACS = "emacs"
+	PRESET_VI    = "vi"
+)
+
+// Bindings of each preset. The nano preset is the default one.
+var PRESETS = map[string]map[Action]string{
+	PRESET_NANO: {
+		ACTION_QUIT:             "Ctrl+X",
+		ACTION_BROWSE:           "Ctrl+P",
+		ACTION_SAVE:             "Ctrl+O",
+		ACTION_NEW:              "Ctrl+N",
+		ACTION_DELETE:           "Ctrl+D",
+		ACTION_GROUPS:           "Ctrl+K",
+		ACTION_COPY:             "Ctrl+C",
+		ACTION_REVEAL:           "Ctrl+R",
+		ACTION_HELP:             "Ctrl+G",
+		ACTION_CANCEL:           "Esc",
+		ACTION_UNDO:             "Alt+u",
+		ACTION_REDO:             "Alt+e",
+		ACTION_EXPAND:           "Alt+z",
+		ACTION_OPEN_URL:         "Alt+l",
+		ACTION_ADD_FIELD:        "Alt+a",
+		ACTION_REMOVE_FIELD:     "Alt+x",
+		ACTION_RENAME_FIELD:     "Alt+n",
+		ACTION_PROTECT_FIELD:    "Alt+p",
+		ACTION_TREE:             "Ctrl+T",
+		ACTION_ADD_GROUP:        "Alt+g",
+		ACTION_RENAME_GROUP:     "Alt+r",
+		ACTION_LINE_START:       "Ctrl+A",
+		ACTION_LINE_END:         "Ctrl+E",
+		ACTION_WORD_LEFT:        "Alt+b",
+		ACTION_WORD_RIGHT:       "Alt+f",
+		ACTION_DELETE_WORD:      "Ctrl+W",
+		ACTION_DELETE_NEXT_WORD: "Alt+d",
+		// Ctrl+K selects groups
+		ACTION_KILL_LINE: "Alt+k",
+	},
+	PRESET_EMACS: {
+		ACTION_QUIT:             "Ctrl+X Ctrl+C",
+		ACTION_BROWSE:           "Ctrl+X Ctrl+F",
+		ACTION_SAVE:             "Ctrl+X Ctrl+S",
+		ACTION_NEW:              "Ctrl+X n",
+		ACTION_DELETE:           "Ctrl+X d",
+		ACTION_GROUPS:           "Ctrl+X g",
+		ACTION_COPY:             "Alt+w",
+		ACTION_REVEAL:           "Ctrl+X r",
+		ACTION_HELP:             "F1",
+		ACTION_CANCEL:           "Ctrl+G",
+		ACTION_UNDO:             "Ctrl+X u",
+		ACTION_REDO:             "Ctrl+X U",
+		ACTION_EXPAND:           "Ctrl+X 1",
+		ACTION_OPEN_URL:         "Ctrl+X o",
+		ACTION_ADD_FIELD:        "Ctrl+X a",
+		ACTION_REMOVE_FIELD:     "Ctrl+X k",
+		ACTION_RENAME_FIELD:     "Ctrl+X m",
+		ACTION_PROTECT_FIELD:    "Ctrl+X p",
+		ACTION_TREE:             "Ctrl+X t",
+		ACTION_ADD_GROUP:        "Ctrl+X G",
+		ACTION_RENAME_GROUP:     "Ctrl+X R",
+		ACTION_LINE_START:       "Ctrl+A",
+		ACTION_LINE_END:         "Ctrl+E",
+		ACTION_WORD_LEFT:        "Alt+b",
+		ACTION_WORD_RIGHT:       "Alt+f",
+		ACTION_DELETE_WORD:      "Ctrl+W",
+		ACTION_DELETE_NEXT_WORD: "Alt+d",
+		ACTION_KILL_LINE:        "Ctrl+K",
+	},
+	// Input is not modal, hence the mnemonics of vi commands are bound to Alt
+	PRESET_VI: {
+		ACTION_QUIT:      "Alt+q",
+		ACTION_BROWSE:    "Alt+/",
+		ACTION_SAVE:      "Alt+w",
+		ACTION_NEW:       "Alt+o",
+		ACTION_DELETE:    "Alt+d",
+		ACTION_GROUPS:    "Alt+g",
+		ACTION_COPY:      "Alt+y",
+		ACTION_REVEAL:    "Alt+r",
+		ACTION_HELP:      "F1",
+		ACTION_CANCEL:    "Esc",
+		ACTION_UNDO:      "Alt+u",
+		ACTION_REDO:      "Ctrl+R",
+		ACTION_EXPAND:    "Alt+z",
+		ACTION_OPEN_URL:  "Alt+l",
+		ACTION_ADD_FIELD: "Alt+a",
+		// Alt+x deletes the next word
+		ACTION_REMOVE_FIELD:  "Alt+D",
+		ACTION_RENAME_FIELD:  "Alt+c",
+		ACTION_PROTECT_FIELD: "Alt+p",
+		ACTION_TREE:          "Alt+t",
+		// Alt+g and Alt+r select groups and reveal fields
+		ACTION_ADD_GROUP:    "Alt+G",
+		ACTION_RENAME_GROUP: "Alt+R",
+		ACTION_LINE_START:   "Ctrl+A",
+		ACTION_LINE_END:     "Ctrl+E",
+		ACTION_WORD_LEFT:    "Alt+b",
+		ACTION_WORD_RIGHT:   "Alt+f",
+		ACTION_DELETE_WORD:  "Ctrl+W",
+		// Alt+d deletes the entry
+		ACTION_DELETE_NEXT_WORD: "Alt+x",
+		ACTION_KILL_LINE:        "Ctrl+K",
+	},
+}
+
+// Keymap resolves key presses to actions. Actions can be bound to
+// sequences of keys, which are matched across events.
+type Keymap struct {
+	bindings map[Action]Sequence
+
+	// Keys of the sequence being typed
+	pending Sequence
+
+	// Last resolved event, so that the action can be read back down the
+	// widget tree
+	last       *tcell.EventKey
+	lastAction Action
+}
+
+// Returns the keymap of preset (the nano one if empty), with bindings
+// overriding some of its actions. An empty binding unbinds the action.
+func New(preset string, bindings map[Action]string) (*Keymap, error) {
+	if preset == "" {
+		preset = PRESET_NANO
+	}
+
+	specs, ok := PRESETS[preset]
+	if !ok {
+		return nil, errors.MakeError(`Unknown keymap "`+preset+`". Use "nano", "emacs", or "vi".`, "keymap")
+	}
+
+	k := &Keymap{bindings: map[Action]Sequence{}}
+	for action, spec := range specs {
+		if err := k.bind(action, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	for action, spec := range bindings {
+		if !slices.ContainsFunc(ACTIONS, func(d Definition) bool { return d.Action == action }) {
+			return nil, errors.MakeError(`Unknown action "`+action+`" in keybindings.`, "keymap")
+		}
+
+		if err := k.bind(action, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := k.validate(); err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Returns the keymap of the nano preset
+func Default() *Keymap {
+	k, _ := New(PRESET_NANO, nil)
+	return k
+}
+
+func (k *Keymap) bind(action Action, spec string) error {
+	sequence, err := ParseSequence(spec)
+	if err != nil {
+		return err
+	}
+
+	if len(sequence) == 0 {
+		delete(k.bindings, action)
+		return nil
+	}
+
+	k.bindings[action] = sequence
+	return nil
+}
+
+// Sequences must not be the prefix of each other, or the longest would
+// never be matched
+func (k *Keymap) validate() error {
+	actions := make([]Action, 0, len(k.bindings))
+	for action := range k.bindings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+
+	for i, a := range actions {
+		for _, b := range actions[i+1:] {
+			if k.bindings[a].hasPrefix(k.bindings[b]) || k.bindings[b].hasPrefix(k.bindings[a]) {
+				return errors.MakeError(`Conflicting keybindings for "`+a+`" ("`+k.bindings[a].String()+`") and "`+b+`" ("`+k.bindings[b].String()+`").`, "keymap")
+			}
+		}
+	}
+
+	return nil
+}
+
+// Feeds ev to the keymap, returning the action whose sequence it completes.
+// Consumed is true when ev is part of a sequence, and therefore should not
+// be handled further, even if no action is returned.
+func (k *Keymap) Resolve(ev *tcell.EventKey) (action Action, consumed bool) {
+	k.last, k.lastAction = ev, ""
+
+	typed := append(slices.Clone(k.pending), KeyOf(ev))
+	hadPending := len(k.pending) > 0
+	k.pending = nil
+
+	for candidate, sequence := range k.bindings {
+		if !sequence.hasPrefix(typed) {
+			continue
+		}
+
+		if len(sequence) == len(typed) {
+			k.lastAction = candidate
+			return candidate, true
+		}
+
+		k.pending = typed
+		return "", true
+	}
+
+	return "", hadPending
+}
+
+// Returns true if ev triggers action. Events not fed through Resolve are
+// matched against single-key bindings only.
+func (k *Keymap) Is(ev *tcell.EventKey, action Action) bool {
+	if ev == k.last {
+		return k.lastAction == action
+	}
+
+	sequence := k.bindings[action]
+	return len(sequence) == 1 && sequence[0] == KeyOf(ev)
+}
+
+// Returns the keys typed so far of an incomplete sequence
+func (k *Keymap) Pending() Sequence {
+	return k.pending
+}
+
+// Returns the sequence bound to action, nil if unbound
+func (k *Keymap) Binding(action Action) Sequence {
+	return k.bindings[action]
+}
+
+// Returns the short form of the sequence bound to action (e.g., ^X)
+func (k *Keymap) Describe(action Action) string {
+	return k.bindings[action].String()
+}
@@ -0,0 +1,180 @@
+package keymap
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func ctrl(r rune) *tcell.EventKey {
+	return tcell.NewEventKey(tcell.KeyRune, r-'A'+1, tcell.ModNone)
+}
+
+func TestParseSequence(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Sequence
+		wantErr bool
+	}{
+		{"Ctrl+X", Sequence{"Ctrl+X"}, false},
+		{"ctrl+x", Sequence{"Ctrl+X"}, false},
+		{"Ctrl+X Ctrl+S", Sequence{"Ctrl+X", "Ctrl+S"}, false},
+		{"alt+w", Sequence{"Alt+w"}, false},
+		{"Ctrl+Alt+d", Sequence{"Alt+Ctrl+D"}, false},
+		{"esc", Sequence{"Esc"}, false},
+		{"F1", Sequence{"F1"}, false},
+		{"Ctrl+X n", Sequence{"Ctrl+X", "n"}, false},
+		{"", Sequence{}, false},
+		{"Hyper+X", nil, true},
+		{"Ctrl+Nope", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseSequence(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSequence() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSequence() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   *tcell.EventKey
+		want Key
+	}{
+		{"control keys", ctrl('X'), "Ctrl+X"},
+		{"runes", tcell.NewEventKey(tcell.KeyRune, 'n', tcell.ModNone), "n"},
+		{"alt runes", tcell.NewEventKey(tcell.KeyRune, 'w', tcell.ModAlt), "Alt+w"},
+		{"named keys", tcell.NewEventKey(tcell.KeyEsc, 0, tcell.ModNone), "Esc"},
+		{"space", tcell.NewEventKey(tcell.KeyRune, ' ', tcell.ModNone), "Space"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := KeyOf(tt.ev); got != tt.want {
+				t.Errorf("KeyOf() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSequence_String(t *testing.T) {
+	tests := []struct {
+		sequence Sequence
+		want     string
+	}{
+		{Sequence{"Ctrl+X"}, "^X"},
+		{Sequence{"Ctrl+X", "Ctrl+S"}, "^X ^S"},
+		{Sequence{"Alt+w"}, "M-w"},
+		{Sequence{"Esc"}, "ESC"},
+		{Sequence{"F1"}, "F1"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.sequence.String(); got != tt.want {
+				t.Errorf("Sequence.String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		preset   string
+		bindings map[Action]string
+		action   Action
+		want     string
+		wantErr  bool
+	}{
+		{"uses nano by default", "", nil, ACTION_SAVE, "^O", false},
+		{"uses presets", PRESET_EMACS, nil, ACTION_SAVE, "^X ^S", false},
+		{"remaps conflicting line editing", PRESET_NANO, nil, ACTION_KILL_LINE, "M-k", false},
+		{"binds line editing", PRESET_VI, nil, ACTION_DELETE_WORD, "^W", false},
+		{"binds field actions", PRESET_VI, nil, ACTION_REMOVE_FIELD, "M-D", false},
+		{"binds field actions in chords", PRESET_EMACS, nil, ACTION_ADD_FIELD, "^X a", false},
+		{"binds opening URLs", PRESET_EMACS, nil, ACTION_OPEN_URL, "^X o", false},
+		{"binds group actions", PRESET_VI, nil, ACTION_RENAME_GROUP, "M-R", false},
+		{"overrides presets", PRESET_NANO, map[Action]string{ACTION_SAVE: "Ctrl+S"}, ACTION_SAVE, "^S", false},
+		{"unbinds actions", PRESET_NANO, map[Action]string{ACTION_HELP: ""}, ACTION_HELP, "", false},
+		{"fails on unknown presets", "ed", nil, "", "", true},
+		{"fails on unknown actions", "", map[Action]string{"launch": "Ctrl+L"}, "", "", true},
+		{"fails on invalid keys", "", map[Action]string{ACTION_SAVE: "Ctrl+Nope"}, "", "", true},
+		{"fails on duplicates", "", map[Action]string{ACTION_SAVE: "Ctrl+X"}, "", "", true},
+		{"fails on prefixes", PRESET_EMACS, map[Action]string{ACTION_HELP: "Ctrl+X"}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.preset, tt.bindings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if describe := got.Describe(tt.action); describe != tt.want {
+				t.Errorf("Keymap.Describe() = %v, want %v", describe, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeymap_Resolve(t *testing.T) {
+	k, _ := New(PRESET_EMACS, nil)
+
+	t.Run("resolves sequences", func(t *testing.T) {
+		if action, consumed := k.Resolve(ctrl('X')); action != "" || !consumed {
+			t.Fatalf("Keymap.Resolve() = %v, %v, want pending", action, consumed)
+		}
+		if pending := k.Pending().String(); pending != "^X" {
+			t.Errorf("Keymap.Pending() = %v, want ^X", pending)
+		}
+
+		ev := ctrl('S')
+		if action, consumed := k.Resolve(ev); action != ACTION_SAVE || !consumed {
+			t.Fatalf("Keymap.Resolve() = %v, %v, want %v", action, consumed, ACTION_SAVE)
+		}
+		if !k.Is(ev, ACTION_SAVE) {
+			t.Errorf("Keymap.Is() = false, want true for resolved event")
+		}
+		if len(k.Pending()) != 0 {
+			t.Errorf("Keymap.Pending() = %v, want empty", k.Pending())
+		}
+	})
+
+	t.Run("consumes keys breaking sequences", func(t *testing.T) {
+		k.Resolve(ctrl('X'))
+
+		ev := tcell.NewEventKey(tcell.KeyRune, 'z', tcell.ModNone)
+		if action, consumed := k.Resolve(ev); action != "" || !consumed {
+			t.Fatalf("Keymap.Resolve() = %v, %v, want consumed", action, consumed)
+		}
+		if action, consumed := k.Resolve(ev); action != "" || consumed {
+			t.Fatalf("Keymap.Resolve() = %v, %v, want unbound", action, consumed)
+		}
+	})
+
+	t.Run("resolves single keys", func(t *testing.T) {
+		ev := tcell.NewEventKey(tcell.KeyRune, 'w', tcell.ModAlt)
+		if action, consumed := k.Resolve(ev); action != ACTION_COPY || !consumed {
+			t.Fatalf("Keymap.Resolve() = %v, %v, want %v", action, consumed, ACTION_COPY)
+		}
+	})
+}
+
+func TestKeymap_Is(t *testing.T) {
+	k := Default()
+
+	if !k.Is(ctrl('O'), ACTION_SAVE) {
+		t.Errorf("Keymap.Is() = false, want true for unresolved single key")
+	}
+	if k.Is(ctrl('O'), ACTION_QUIT) {
+		t.Errorf("Keymap.Is() = true, want false for other actions")
+	}
+}
@@ -0,0 +1,154 @@
+package keymap
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+// A key, with its modifiers, in canonical form (e.g., "Ctrl+X", "Alt+w", "Esc")
+type Key = string
+
+// Keys to be pressed one after the other to trigger an action
+type Sequence []Key
+
+// Modifiers, in the order used by canonical keys
+var modifiers = []string{"Shift", "Alt", "Meta", "Ctrl"}
+
+// Canonical names of the named keys, by lowercase name
+var namedKeys = map[string]string{"space": "Space"}
+
+func init() {
+	for _, name := range tcell.KeyNames {
+		if !strings.HasPrefix(name, "Ctrl-") {
+			namedKeys[strings.ToLower(name)] = name
+		}
+	}
+}
+
+// Returns the canonical name of the key pressed in ev
+func KeyOf(ev *tcell.EventKey) Key {
+	if ev.Key() != tcell.KeyRune {
+		return ev.Name()
+	}
+
+	name := string(ev.Rune())
+	if ev.Rune() == ' ' {
+		name = "Space"
+	}
+
+	prefix := ""
+	for _, m := range []struct {
+		mask tcell.ModMask
+		name string
+	}{{tcell.ModAlt, "Alt"}, {tcell.ModMeta, "Meta"}, {tcell.ModCtrl, "Ctrl"}} {
+		if ev.Modifiers()&m.mask != 0 {
+			prefix += m.name + "+"
+		}
+	}
+
+	return prefix + name
+}
+
+// Parses a space-separated list of keys such as "Ctrl+X Ctrl+S". Modifiers
+// and named keys are case insensitive.
+func ParseSequence(spec string) (Sequence, error) {
+	sequence := Sequence{}
+
+	for _, token := range strings.Fields(spec) {
+		key, err := parseKey(token)
+		if err != nil {
+			return nil, err
+		}
+		sequence = append(sequence, key)
+	}
+
+	return sequence, nil
+}
+
+func parseKey(token string) (Key, error) {
+	parts := strings.Split(token, "+")
+	// Allows "+" as a key, as in "Ctrl++"
+	if strings.HasSuffix(token, "++") || token == "+" {
+		parts = append(strings.Split(strings.TrimSuffix(token, "++"), "+"), "+")
+	}
+
+	name := parts[len(parts)-1]
+	present := map[string]bool{}
+	for _, part := range parts[:len(parts)-1] {
+		modifier := ""
+		for _, m := range modifiers {
+			if strings.EqualFold(part, m) {
+				modifier = m
+			}
+		}
+
+		if modifier == "" {
+			return "", errors.MakeError(`Invalid modifier "`+part+`" in "`+token+`".`, "keymap")
+		}
+		present[modifier] = true
+	}
+
+	if utf8.RuneCountInString(name) == 1 {
+		// Terminals cannot tell Ctrl+x from Ctrl+X
+		if present["Ctrl"] {
+			name = strings.ToUpper(name)
+		}
+	} else if canonical, ok := namedKeys[strings.ToLower(name)]; ok {
+		name = canonical
+	} else {
+		return "", errors.MakeError(`Invalid key "`+name+`" in "`+token+`".`, "keymap")
+	}
+
+	key := ""
+	for _, m := range modifiers {
+		if present[m] {
+			key += m + "+"
+		}
+	}
+
+	return key + name, nil
+}
+
+// Returns the short form of the sequence used in help texts: ^X for
+// Ctrl+X, M-x for Alt+x, and ESC for Esc
+func (s Sequence) String() string {
+	keys := make([]string, len(s))
+
+	for i, key := range s {
+		switch {
+		case strings.HasPrefix(key, "Ctrl+") && isSingleRune(key[5:]):
+			keys[i] = "^" + key[5:]
+		case strings.HasPrefix(key, "Alt+"):
+			keys[i] = "M-" + key[4:]
+		case key == "Esc":
+			keys[i] = "ESC"
+		default:
+			keys[i] = key
+		}
+	}
+
+	return strings.Join(keys, " ")
+}
+
+func (s Sequence) hasPrefix(prefix Sequence) bool {
+	if len(prefix) > len(s) {
+		return false
+	}
+
+	for i := range prefix {
+		if s[i] != prefix[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isSingleRune(s string) bool {
+	r, size := utf8.DecodeRuneInString(s)
+	return size == len(s) && !unicode.IsSpace(r)
+}
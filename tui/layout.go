@@ -5,6 +5,7 @@ import (
 	"github.com/gdamore/tcell/v2/views"
 	"github.com/shikaan/keydex/tui/components"
 	"github.com/shikaan/keydex/tui/components/status"
+	"github.com/shikaan/keydex/tui/keymap"
 )
 
 type Layout struct {
@@ -13,6 +14,9 @@ type Layout struct {
 
 	Screen tcell.Screen
 
+	// True while text is being pasted, which is not taken for shortcuts
+	pasting bool
+
 	views.Panel
 }
 
@@ -25,50 +29,77 @@ func (l *Layout) SetContent(w views.Widget) {
 }
 
 func (v *Layout) HandleEvent(ev tcell.Event) bool {
+	switch ev.(type) {
+	case *tcell.EventKey, *tcell.EventMouse:
+		App.ResetIdleTimer()
+	}
+
+	switch ev := ev.(type) {
+	case *tcell.EventPaste:
+		v.pasting = ev.Start()
+		return v.Panel.HandleEvent(ev)
+	case *tcell.EventKey:
+		if v.pasting {
+			return v.Panel.HandleEvent(ev)
+		}
+	}
+
 	// If there is a pending confirmation, delegate to panel to handle Y/N/Cancel
 	if v.Status.IsConfirming() {
 		return v.Panel.HandleEvent(ev)
 	}
 
+	// Only the lock screen is available until the database is unlocked
+	if App.IsLocked() {
+		if ev, ok := ev.(*tcell.EventKey); ok {
+			if action, _ := App.Keys().Resolve(ev); action == keymap.ACTION_QUIT {
+				App.Quit()
+				return true
+			}
+		}
+		return v.Panel.HandleEvent(ev)
+	}
+
 	switch ev := ev.(type) {
 	case *tcell.EventKey:
-		if ev.Name() == "Ctrl+X" {
+		action, consumed := App.Keys().Resolve(ev)
+		if action == "" && consumed {
+			if pending := App.Keys().Pending(); len(pending) > 0 {
+				App.Notify(pending.String() + " -")
+			}
+			return true
+		}
+
+		if action == keymap.ACTION_QUIT {
 			App.Quit()
 			return true
 		}
-		if ev.Name() == "Ctrl+P" {
+		if action == keymap.ACTION_BROWSE {
 			App.NavigateTo(NewEntryListView)
 			return true
 		}
-		if ev.Name() == "Ctrl+G" {
+		if action == keymap.ACTION_HELP {
 			App.NavigateTo(NewHelpView)
 			return true
 		}
-		if ev.Name() == "Ctrl+N" {
+		if action == keymap.ACTION_NEW {
 			if App.IsReadOnly() {
 				App.Notify("Cannot create. Archive in read-only mode.")
 				return true
 			}
-			err := App.CreateEmptyEntry()
-
-			if err != nil {
-				App.Notify("Could not create. Check logs for details.")
-				return true
-			}
-
-			App.NavigateToWithoutDirtyGuard(NewEntryView)
+			App.NavigateTo(NewTemplateListView)
 			return true
 		}
-		if ev.Name() == "Ctrl+C" {
+		if action == keymap.ACTION_COPY {
 			handled := v.Panel.HandleEvent(ev)
 
 			if !handled {
-				App.Notify("No field selected for copy. Use ^X to close.")
+				App.Notify("No field selected for copy. Use " + App.Keys().Describe(keymap.ACTION_QUIT) + " to close.")
 			}
 
 			return true
 		}
-		if ev.Key() == tcell.KeyEsc {
+		if action == keymap.ACTION_CANCEL {
 			if App.State.Entry == nil {
 				App.Notify("No entry selected yet.")
 				return true
@@ -79,6 +110,11 @@ func (v *Layout) HandleEvent(ev tcell.Event) bool {
 				App.SetDirty(false)
 			}
 
+			// The entry might be a copy holding the changes kept while locked
+			if existing := App.State.Database.GetEntry(App.State.Entry.UUID); existing != nil {
+				App.State.Entry = existing
+			}
+
 			// Group for entry is nil when the entry to be edited has just been created.
 			// In that case, we will use the root group.
 			group := App.State.Database.GetGroupForEntry(App.State.Entry)
@@ -113,12 +149,34 @@ func (v *Layout) HandleEvent(ev tcell.Event) bool {
 	return v.Panel.HandleEvent(ev)
 }
 
+// Actions shown in the two help lines of the status bar
+var statusActions = [2][]keymap.Action{
+	{keymap.ACTION_SAVE, keymap.ACTION_BROWSE, keymap.ACTION_COPY, keymap.ACTION_NEW, keymap.ACTION_GROUPS},
+	{keymap.ACTION_QUIT, keymap.ACTION_CANCEL, keymap.ACTION_REVEAL, keymap.ACTION_DELETE, keymap.ACTION_HELP},
+}
+
+func statusShortcuts(keys *keymap.Keymap) [2][]status.Shortcut {
+	lines := [2][]status.Shortcut{}
+
+	for i, actions := range statusActions {
+		for _, action := range actions {
+			for _, definition := range keymap.ACTIONS {
+				if definition.Action == action {
+					lines[i] = append(lines[i], status.Shortcut{Key: keys.Describe(action), Label: definition.Label})
+				}
+			}
+		}
+	}
+
+	return lines
+}
+
 // Returns a Layout component responsible for the shell of the application
 // and of the routing in between pages
 func NewLayout(screen tcell.Screen) *Layout {
 	l := &Layout{}
 	title := components.NewTitle(App.State.Database.Content.Meta.DatabaseName)
-	s := status.NewStatus()
+	s := status.NewStatus(statusShortcuts(App.Keys()))
 
 	t := views.NewText()
 	t.SetText(" ")
@@ -1,10 +1,12 @@
 package tui
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/tui/keymap"
 	"github.com/tobischo/gokeepasslib/v3"
 )
 
@@ -265,3 +267,34 @@ func TestLayout_HandleEvent_Esc_ModifiedBadgeBehavior(t *testing.T) {
 		})
 	}
 }
+
+func TestStatusShortcuts(t *testing.T) {
+	keys, err := keymap.New(keymap.PRESET_NANO, map[keymap.Action]string{keymap.ACTION_SAVE: "Ctrl+S"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := statusShortcuts(keys)
+
+	if got := lines[0][0]; got.Key != "^S" || got.Label != "Save" {
+		t.Errorf("statusShortcuts() = %v, want ^S Save", got)
+	}
+	if got := lines[1][0]; got.Key != "^X" || got.Label != "Exit" {
+		t.Errorf("statusShortcuts() = %v, want ^X Exit", got)
+	}
+}
+
+func TestHelpBindings(t *testing.T) {
+	keys, err := keymap.New(keymap.PRESET_EMACS, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	help := helpBindings(keys)
+
+	for _, want := range []string{"^X ^S    Save the current state", "M-w      Copy the current field"} {
+		if !strings.Contains(help, want) {
+			t.Errorf("helpBindings() = %q, missing %q", help, want)
+		}
+	}
+}
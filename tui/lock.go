@@ -0,0 +1,271 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/views"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/shikaan/keydex/pkg/secure"
+	"github.com/shikaan/keydex/tui/components"
+	"github.com/shikaan/keydex/tui/components/field"
+	"github.com/shikaan/keydex/tui/keymap"
+)
+
+// Default time of inactivity after which the database is locked
+const DEFAULT_LOCK_AFTER = 5 * time.Minute
+
+// Decides what happens to unsaved changes when the database is locked
+type LockPolicy = string
+
+const (
+	// Unsaved changes are lost
+	LOCK_POLICY_DISCARD LockPolicy = "discard"
+	// Unsaved changes are encrypted with the credentials of the database,
+	// and restored once it is unlocked
+	LOCK_POLICY_KEEP LockPolicy = "keep"
+)
+
+var LOCK_POLICIES = []LockPolicy{LOCK_POLICY_DISCARD, LOCK_POLICY_KEEP}
+
+// Returns the policy called name, the discard one if empty
+func ParseLockPolicy(name string) (LockPolicy, error) {
+	if name == "" {
+		return LOCK_POLICY_DISCARD, nil
+	}
+
+	if !slices.Contains(LOCK_POLICIES, name) {
+		return "", errors.MakeError(`Unknown lock policy "`+name+`". Use one of: `+strings.Join(LOCK_POLICIES, ", ")+".", "tui")
+	}
+
+	return name, nil
+}
+
+// Locks the database after a time of inactivity
+type idleGuard struct {
+	timer        *time.Timer
+	lastActivity time.Time
+}
+
+// What is needed to resume the session once the database is unlocked
+type lockedSession struct {
+	// Why the database was locked
+	message string
+	path    string
+	key     string
+	backups int
+
+	reference string
+	entry     *kdbx.UUID
+	group     *kdbx.UUID
+	view      func(tcell.Screen) views.Widget
+	// The entry being edited, sealed with the credentials of the database
+	edits []byte
+}
+
+// Postpones locking the database. Call it on every interaction.
+func (a *Application) ResetIdleTimer() {
+	g := &a.idle
+	g.lastActivity = time.Now()
+
+	after := a.Settings.LockAfter
+	if after <= 0 {
+		return
+	}
+
+	if g.timer == nil {
+		// Timers fire on another goroutine, while the interface must be
+		// changed from the event loop
+		g.timer = time.AfterFunc(after, func() { a.PostFunc(a.lockIfIdle) })
+		return
+	}
+
+	g.timer.Reset(after)
+}
+
+func (a *Application) lockIfIdle() {
+	idle := time.Since(a.idle.lastActivity)
+	if idle < a.Settings.LockAfter {
+		a.idle.timer.Reset(a.Settings.LockAfter - idle)
+		return
+	}
+
+	a.LockSession()
+}
+
+func (a *Application) IsLocked() bool {
+	return a.locked != nil
+}
+
+// Wipes the database from memory, and shows the lock screen. Unsaved changes
+// are handled according to the LockPolicy.
+func (a *Application) LockSession() {
+	a.lockSession("The database has been locked after a period of inactivity.")
+}
+
+func (a *Application) lockSession(message string) {
+	if a.IsLocked() || a.State.Database == nil {
+		return
+	}
+
+	db := a.State.Database
+	session := &lockedSession{
+		message:   message,
+		path:      db.Path(),
+		key:       db.KeyPath(),
+		backups:   db.Backups,
+		reference: a.State.Reference,
+		view:      a.lastView,
+	}
+
+	if a.State.Entry != nil {
+		uuid := a.State.Entry.UUID
+		session.entry = &uuid
+	}
+	if a.State.Group != nil {
+		uuid := a.State.Group.UUID
+		session.group = &uuid
+	}
+
+	// Only changes to the entry being edited can be kept. Other unsaved
+	// changes are discarded, as with the discard policy.
+	discarded := a.isDirty
+	if a.isDirty && a.Settings.LockPolicy == LOCK_POLICY_KEEP {
+		if view, ok := a.lastWidget.(*EntryView); ok {
+			edits, err := db.SealEntry(view.pendingEntry())
+			if err != nil {
+				log.Error("Could not keep unsaved changes", err)
+			}
+			session.edits = edits
+			discarded = edits == nil
+		}
+	}
+
+	a.layout.Status.Dismiss()
+	a.FlushClipboard()
+	a.LastFocused = nil
+
+	db.Wipe()
+	a.State = State{}
+	a.locked = session
+
+	a.SetDirty(session.edits != nil)
+	a.NavigateToWithoutDirtyGuard(NewLockView)
+	log.Info("Database locked")
+
+	if discarded && a.Settings.LockPolicy == LOCK_POLICY_KEEP {
+		msg := "Unsaved changes were discarded. Only changes to an entry being edited are kept."
+		a.Notify(msg)
+		log.Info(msg)
+	}
+}
+
+// Opens the database again, and resumes the session where it was locked
+func (a *Application) UnlockSession(passphrase *secure.Buffer, key string) error {
+	session := a.locked
+	if session == nil {
+		return nil
+	}
+
+	db, err := kdbx.OpenWithPassphrase(session.path, passphrase, key)
+	if err != nil {
+		return err
+	}
+	db.Backups = session.backups
+
+	state := State{Database: db, Reference: session.reference}
+
+	if session.edits != nil {
+		entry, err := db.UnsealEntry(session.edits)
+		if err != nil {
+			return errors.MakeError("Could not restore unsaved changes: "+err.Error(), "tui")
+		}
+		state.Entry = entry
+	} else if session.entry != nil {
+		state.Entry = db.GetEntry(*session.entry)
+	}
+
+	if state.Entry != nil {
+		// The group chosen for the entry is an unsaved change too
+		group := session.group
+		if session.edits == nil {
+			group = nil
+		}
+		state.Group = groupOf(db, state.Entry, group)
+	}
+
+	a.State = state
+	a.locked = nil
+	a.rememberDatabaseFile()
+	a.SetDirty(session.edits != nil)
+	a.ResetIdleTimer()
+	log.Info("Database unlocked")
+
+	// The entry was deleted in the meantime, or it was new and got discarded
+	if session.entry != nil && state.Entry == nil {
+		a.NavigateToWithoutDirtyGuard(NewEntryListView)
+		a.Notify("Entry is not available anymore.")
+		return nil
+	}
+
+	a.NavigateToWithoutDirtyGuard(session.view)
+	return nil
+}
+
+type LockView struct {
+	passphrase *field.Secret
+	key        *field.Field
+	components.Container
+}
+
+func (v *LockView) HandleEvent(ev tcell.Event) bool {
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		if ev.Key() == tcell.KeyEnter {
+			passphrase := v.passphrase.GetSecret()
+			err := App.UnlockSession(passphrase, v.key.GetContent())
+			passphrase.Destroy()
+			v.passphrase.Clear()
+
+			if err != nil {
+				msg := "Could not unlock. Check passphrase and key file."
+				App.Notify(msg)
+				log.Error(msg, err)
+			}
+			return true
+		}
+	}
+
+	return v.Container.HandleEvent(ev)
+}
+
+func NewLockView(screen tcell.Screen) views.Widget {
+	App.SetTitle("Locked")
+	view := &LockView{}
+	view.Container = components.Container{}
+
+	form := components.NewForm()
+
+	message := views.NewText()
+	message.SetText(banner(
+		App.locked.message,
+		"Enter the passphrase and press Enter to unlock it.",
+		"Press "+App.Keys().Describe(keymap.ACTION_QUIT)+" to quit.",
+	) + "\n\n")
+	form.AddWidget(message, 0)
+
+	view.passphrase = field.NewSecret("Passphrase")
+	form.AddWidget(view.passphrase, 0)
+
+	view.key = field.NewField(&field.FieldOptions{Label: "Key file", InitialValue: App.locked.key, InputType: field.InputTypeText, Keys: App.Keys()})
+	form.AddWidget(view.key, 0)
+
+	view.passphrase.SetFocus(true)
+	view.SetContent(form)
+
+	return view
+}
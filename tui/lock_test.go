@@ -0,0 +1,184 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/secure"
+	"github.com/tobischo/gokeepasslib/v3"
+)
+
+const lockTestPassphrase = "test-password"
+
+// Returns a database saved on disk, with a single "GitHub" entry
+func openLockTestDatabase(t *testing.T) *kdbx.Database {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.kdbx")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, _ := kdbx.NewFromFile(file)
+	db.SetPasswordAndKey(lockTestPassphrase, "")
+
+	group := db.NewGroup("TestDB")
+	entry := gokeepasslib.NewEntry()
+	entry.Values = append(entry.Values, gokeepasslib.ValueData{Key: kdbx.TITLE_KEY, Value: gokeepasslib.V{Content: "GitHub"}})
+	group.Entries = append(group.Entries, entry)
+	db.Content.Root.Groups = []gokeepasslib.Group{*group}
+
+	if err := db.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = kdbx.OpenFromPath(path, lockTestPassphrase, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestApplication_LockSession(t *testing.T) {
+	tests := []struct {
+		policy    LockPolicy
+		wantTitle string
+		wantDirty bool
+	}{
+		{LOCK_POLICY_DISCARD, "GitHub", false},
+		{LOCK_POLICY_KEEP, "XGitHub", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			screen := tcell.NewSimulationScreen("UTF-8")
+			screen.Init()
+			defer screen.Fini()
+
+			db := openLockTestDatabase(t)
+			entry := db.GetFirstEntryByPath("/TestDB/GitHub")
+			App.Settings.LockPolicy = tt.policy
+			Setup(screen, State{Database: db, Entry: entry, Group: db.GetGroupForEntry(entry), Reference: "/TestDB/GitHub"}, false)
+
+			// Edits the title, which is focused
+			App.layout.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'X', tcell.ModNone))
+			if !App.IsDirty() {
+				t.Fatalf("expected the entry to be modified")
+			}
+
+			App.LockSession()
+
+			if !App.IsLocked() || App.State.Database != nil || App.State.Entry != nil {
+				t.Fatalf("expected the database to be wiped, got %+v", App.State)
+			}
+			if _, ok := App.lastWidget.(*LockView); !ok {
+				t.Fatalf("expected the lock screen, got %T", App.lastWidget)
+			}
+
+			if err := App.UnlockSession(secure.FromString("wrong"), ""); err == nil || !App.IsLocked() {
+				t.Fatalf("expected unlocking with a wrong passphrase to fail")
+			}
+
+			if err := App.UnlockSession(secure.FromString(lockTestPassphrase), ""); err != nil {
+				t.Fatalf("Application.UnlockSession() error = %v", err)
+			}
+
+			if App.IsLocked() {
+				t.Errorf("expected the database to be unlocked")
+			}
+			if _, ok := App.lastWidget.(*EntryView); !ok {
+				t.Errorf("expected the entry to be resumed, got %T", App.lastWidget)
+			}
+			if title := App.State.Entry.GetTitle(); title != tt.wantTitle {
+				t.Errorf("expected title %q, got %q", tt.wantTitle, title)
+			}
+			if App.IsDirty() != tt.wantDirty {
+				t.Errorf("expected dirty %v, got %v", tt.wantDirty, App.IsDirty())
+			}
+		})
+	}
+}
+
+func TestApplication_LockSession_GroupMove(t *testing.T) {
+	tests := []struct {
+		policy    LockPolicy
+		wantGroup string
+		wantDirty bool
+	}{
+		{LOCK_POLICY_DISCARD, "TestDB", false},
+		{LOCK_POLICY_KEEP, "Work", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			screen := tcell.NewSimulationScreen("UTF-8")
+			screen.Init()
+			defer screen.Fini()
+
+			db := openLockTestDatabase(t)
+			work := db.NewGroup("Work")
+			root := db.GetRootGroup()
+			root.Groups = append(root.Groups, *work)
+			if err := db.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			entry := db.GetFirstEntryByPath("/TestDB/GitHub")
+			App.Settings.LockPolicy = tt.policy
+			App.isDirty = false
+			Setup(screen, State{Database: db, Entry: entry, Group: db.GetGroupForEntry(entry), Reference: "/TestDB/GitHub"}, false)
+
+			// As when a group is chosen in the group list
+			App.State.Group = db.GetGroup(work.UUID)
+			App.SetDirty(true)
+			App.NavigateToWithoutDirtyGuard(NewEntryView)
+
+			App.LockSession()
+			if err := App.UnlockSession(secure.FromString(lockTestPassphrase), ""); err != nil {
+				t.Fatalf("Application.UnlockSession() error = %v", err)
+			}
+
+			if name := App.State.Group.Name; name != tt.wantGroup {
+				t.Errorf("expected group %q, got %q", tt.wantGroup, name)
+			}
+			if App.IsDirty() != tt.wantDirty {
+				t.Errorf("expected dirty %v, got %v", tt.wantDirty, App.IsDirty())
+			}
+		})
+	}
+}
+
+func TestApplication_LockSession_OutsideEntry(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	screen.Init()
+	defer screen.Fini()
+
+	db := openLockTestDatabase(t)
+	App.Settings.LockPolicy = LOCK_POLICY_KEEP
+	App.isDirty = false
+	Setup(screen, State{Database: db}, false)
+	App.NavigateTo(NewTreeView)
+
+	// Only changes to the entry being edited can be kept
+	App.SetDirty(true)
+	App.LockSession()
+
+	if App.IsDirty() || App.locked.edits != nil {
+		t.Fatalf("expected the unsaved changes to be discarded")
+	}
+
+	if err := App.UnlockSession(secure.FromString(lockTestPassphrase), ""); err != nil {
+		t.Fatalf("Application.UnlockSession() error = %v", err)
+	}
+
+	if _, ok := App.lastWidget.(*TreeView); !ok {
+		t.Errorf("expected the tree to be resumed, got %T", App.lastWidget)
+	}
+	if App.IsDirty() {
+		t.Errorf("expected no unsaved changes after unlocking")
+	}
+}
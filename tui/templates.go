@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/views"
+
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/shikaan/keydex/tui/components"
+	"github.com/shikaan/keydex/tui/components/autocomplete"
+)
+
+type TemplatesView struct {
+	autoComplete *autocomplete.AutoComplete
+	components.Container
+}
+
+func (tv *TemplatesView) HandleEvent(ev tcell.Event) bool {
+	return tv.autoComplete.HandleEvent(ev)
+}
+
+func NewTemplateListView(screen tcell.Screen) views.Widget {
+	App.SetTitle("Select template for the new entry")
+	view := &TemplatesView{}
+	view.Container = components.Container{}
+
+	names := []string{}
+	for _, t := range App.State.Database.GetTemplates() {
+		names = append(names, t.Name)
+	}
+	maxX, maxY := getBoundaries(screen)
+
+	autoCompleteOptions := autocomplete.AutoCompleteOptions{
+		Screen:     screen,
+		Entries:    names,
+		TotalCount: len(names),
+		MaxX:       maxX,
+		MaxY:       maxY,
+		Keys:       App.Keys(),
+		OnSelect: func(name string) bool {
+			if err := App.CreateEntryFromTemplate(name); err != nil {
+				msg := "Could not create. Check logs for details."
+				App.Notify(msg)
+				log.Error(msg, err)
+				return true
+			}
+
+			App.NavigateToWithoutDirtyGuard(NewEntryView)
+			return true
+		},
+	}
+
+	autoComplete := autocomplete.NewAutoComplete(autoCompleteOptions)
+	autoComplete.OnFocus(func() bool {
+		App.LastFocused = autoComplete
+		return true
+	})
+	autoComplete.SetFocus(true)
+	view.SetContent(autoComplete)
+	view.autoComplete = autoComplete
+
+	return view
+}
@@ -0,0 +1,285 @@
+package theme
+
+import (
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shikaan/keydex/pkg/errors"
+)
+
+// Identifies an element of the interface, as used in the configuration
+type Name = string
+
+const (
+	// Title bar
+	STYLE_TITLE Name = "title"
+	// Title bar of databases with unsaved changes
+	STYLE_DIRTY Name = "dirty"
+	// Title bar of databases in read-only mode
+	STYLE_READ_ONLY Name = "read_only"
+	// Field labels
+	STYLE_LABEL Name = "label"
+	// Field values
+	STYLE_VALUE Name = "value"
+	// Value of the field being edited
+	STYLE_FOCUSED Name = "focused"
+	// Hidden values, such as passwords
+	STYLE_PROTECTED Name = "protected"
+	// Read-only details of the entry, such as its group and timestamps
+	STYLE_META Name = "meta"
+	// Messages in the status bar
+	STYLE_NOTIFICATION Name = "notification"
+	// Keys in the status bar
+	STYLE_SHORTCUT Name = "shortcut"
+	// Confirmation questions
+	STYLE_PROMPT Name = "prompt"
+	// Selected option of the finder
+	STYLE_SELECTED Name = "selected"
+	// Matched characters in the finder
+	STYLE_MATCH Name = "match"
+	// Counter of the finder, when nothing matches
+	STYLE_NO_MATCH Name = "no_match"
+)
+
+// Every element that can be styled
+var ELEMENTS = []Name{
+	STYLE_TITLE, STYLE_DIRTY, STYLE_READ_ONLY, STYLE_LABEL, STYLE_VALUE,
+	STYLE_FOCUSED, STYLE_PROTECTED, STYLE_META, STYLE_NOTIFICATION,
+	STYLE_SHORTCUT, STYLE_PROMPT, STYLE_SELECTED, STYLE_MATCH, STYLE_NO_MATCH,
+}
+
+const (
+	THEME_DEFAULT       = "default"
+	THEME_DARK          = "dark"
+	THEME_LIGHT         = "light"
+	THEME_HIGH_CONTRAST = "high-contrast"
+)
+
+// Disables colours when set to any value. See https://no-color.org
+const ENV_NO_COLOR = "NO_COLOR"
+
+// Styles of the built-in themes, in the format accepted by ParseStyle.
+// Unlisted elements use the terminal defaults.
+var THEMES = map[string]map[Name]string{
+	// Terminal colours, with attributes only
+	THEME_DEFAULT: {
+		STYLE_TITLE:     "reverse",
+		STYLE_DIRTY:     "reverse",
+		STYLE_READ_ONLY: "reverse",
+		STYLE_LABEL:     "bold",
+		STYLE_META:      "dim",
+		STYLE_SHORTCUT:  "reverse",
+		STYLE_PROMPT:    "reverse",
+		STYLE_MATCH:     "bold underline",
+		STYLE_NO_MATCH:  "bold",
+	},
+	THEME_DARK: {
+		STYLE_TITLE:        "black on lightskyblue",
+		STYLE_DIRTY:        "black on gold",
+		STYLE_READ_ONLY:    "white on firebrick",
+		STYLE_LABEL:        "bold lightskyblue",
+		STYLE_VALUE:        "white",
+		STYLE_FOCUSED:      "white on darkslategray",
+		STYLE_PROTECTED:    "gray",
+		STYLE_META:         "darkgray",
+		STYLE_NOTIFICATION: "gold",
+		STYLE_SHORTCUT:     "black on lightskyblue",
+		STYLE_PROMPT:       "black on gold",
+		STYLE_SELECTED:     "white on darkslategray",
+		STYLE_MATCH:        "bold gold",
+		STYLE_NO_MATCH:     "bold indianred",
+	},
+	THEME_LIGHT: {
+		STYLE_TITLE:        "white on navy",
+		STYLE_DIRTY:        "black on gold",
+		STYLE_READ_ONLY:    "white on darkred",
+		STYLE_LABEL:        "bold navy",
+		STYLE_VALUE:        "black",
+		STYLE_FOCUSED:      "black on lightcyan",
+		STYLE_PROTECTED:    "dimgray",
+		STYLE_META:         "gray",
+		STYLE_NOTIFICATION: "darkgreen",
+		STYLE_SHORTCUT:     "white on navy",
+		STYLE_PROMPT:       "black on gold",
+		STYLE_SELECTED:     "black on lightcyan",
+		STYLE_MATCH:        "bold darkred",
+		STYLE_NO_MATCH:     "bold darkred",
+	},
+	THEME_HIGH_CONTRAST: {
+		STYLE_TITLE:        "bold black on white",
+		STYLE_DIRTY:        "bold black on yellow",
+		STYLE_READ_ONLY:    "bold white on red",
+		STYLE_LABEL:        "bold white",
+		STYLE_VALUE:        "white",
+		STYLE_FOCUSED:      "bold black on yellow",
+		STYLE_PROTECTED:    "white",
+		STYLE_META:         "white",
+		STYLE_NOTIFICATION: "bold yellow",
+		STYLE_SHORTCUT:     "bold black on white",
+		STYLE_PROMPT:       "bold black on yellow",
+		STYLE_SELECTED:     "bold black on yellow",
+		STYLE_MATCH:        "bold underline yellow",
+		STYLE_NO_MATCH:     "bold red",
+	},
+}
+
+var attributes = map[string]tcell.AttrMask{
+	"bold":          tcell.AttrBold,
+	"dim":           tcell.AttrDim,
+	"italic":        tcell.AttrItalic,
+	"underline":     tcell.AttrUnderline,
+	"reverse":       tcell.AttrReverse,
+	"blink":         tcell.AttrBlink,
+	"strikethrough": tcell.AttrStrikeThrough,
+}
+
+type Theme struct {
+	styles map[Name]tcell.Style
+}
+
+// Returns the built-in theme called name (the default one if empty), with
+// overrides replacing some of its styles. Colours are dropped if noColor is
+// true.
+func New(name string, overrides map[Name]string, noColor bool) (*Theme, error) {
+	if name == "" {
+		name = THEME_DEFAULT
+	}
+
+	specs, ok := THEMES[name]
+	if !ok {
+		names := make([]string, 0, len(THEMES))
+		for n := range THEMES {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, errors.MakeError(`Unknown theme "`+name+`". Use one of: `+strings.Join(names, ", ")+".", "theme")
+	}
+
+	t := &Theme{styles: map[Name]tcell.Style{}}
+	for element, spec := range specs {
+		style, _ := ParseStyle(spec)
+		t.styles[element] = style
+	}
+
+	for element, spec := range overrides {
+		if !slices.Contains(ELEMENTS, element) {
+			return nil, errors.MakeError(`Unknown style "`+element+`" in theme.`, "theme")
+		}
+
+		style, err := ParseStyle(spec)
+		if err != nil {
+			return nil, err
+		}
+		t.styles[element] = style
+	}
+
+	if noColor {
+		for element, style := range t.styles {
+			t.styles[element] = style.Foreground(tcell.ColorDefault).Background(tcell.ColorDefault)
+		}
+	}
+
+	return t, nil
+}
+
+// Returns the style of element
+func (t *Theme) Style(element Name) tcell.Style {
+	if style, ok := t.styles[element]; ok {
+		return style
+	}
+	return tcell.StyleDefault
+}
+
+// Parses styles such as "bold yellow on blue": attributes, a foreground
+// colour, and a background colour after "on". Colours are names (see
+// https://www.w3.org/TR/css-color-3/#svg-color), hex codes like #ff8800, or
+// "default" for the colour of the terminal.
+func ParseStyle(spec string) (tcell.Style, error) {
+	style := tcell.StyleDefault
+	tokens := strings.Fields(strings.ToLower(spec))
+
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+
+		if attribute, ok := attributes[token]; ok {
+			style = style.Attributes(attributeMask(style) | attribute)
+			continue
+		}
+
+		if token == "on" {
+			if i+1 >= len(tokens) {
+				return style, errors.MakeError(`Missing background colour in "`+spec+`".`, "theme")
+			}
+
+			i++
+			color, err := parseColor(tokens[i], spec)
+			if err != nil {
+				return style, err
+			}
+			style = style.Background(color)
+			continue
+		}
+
+		color, err := parseColor(token, spec)
+		if err != nil {
+			return style, err
+		}
+		style = style.Foreground(color)
+	}
+
+	return style, nil
+}
+
+func parseColor(token, spec string) (tcell.Color, error) {
+	if token == "default" {
+		return tcell.ColorDefault, nil
+	}
+
+	color := tcell.GetColor(token)
+	if color == tcell.ColorDefault {
+		return color, errors.MakeError(`Invalid colour "`+token+`" in "`+spec+`".`, "theme")
+	}
+
+	return color, nil
+}
+
+func attributeMask(style tcell.Style) tcell.AttrMask {
+	_, _, mask := style.Decompose()
+	return mask
+}
+
+var current, _ = New(THEME_DEFAULT, nil, false)
+
+// Makes t the theme used by the interface
+func Use(t *Theme) {
+	current = t
+}
+
+// Returns base with the attributes of overlay added, and its colours
+// replacing those of base unless they are the terminal defaults
+func Merge(base, overlay tcell.Style) tcell.Style {
+	fg, bg, attributes := overlay.Decompose()
+	style := base.Attributes(attributeMask(base) | attributes)
+
+	if fg != tcell.ColorDefault {
+		style = style.Foreground(fg)
+	}
+	if bg != tcell.ColorDefault {
+		style = style.Background(bg)
+	}
+
+	return style
+}
+
+// Returns the style of element in the current theme
+func Get(element Name) tcell.Style {
+	return current.Style(element)
+}
+
+// Returns true if colours must be disabled
+func NoColor() bool {
+	return os.Getenv(ENV_NO_COLOR) != ""
+}
@@ -0,0 +1,91 @@
+package theme
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+)
+
+func TestParseStyle(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    tcell.Style
+		wantErr bool
+	}{
+		{"", tcell.StyleDefault, false},
+		{"bold", tcell.StyleDefault.Bold(true), false},
+		{"bold underline", tcell.StyleDefault.Bold(true).Underline(true), false},
+		{"yellow", tcell.StyleDefault.Foreground(tcell.ColorYellow), false},
+		{"Bold Yellow on Blue", tcell.StyleDefault.Bold(true).Foreground(tcell.ColorYellow).Background(tcell.ColorBlue), false},
+		{"on #ff8800", tcell.StyleDefault.Background(tcell.NewHexColor(0xff8800)), false},
+		{"default on default", tcell.StyleDefault, false},
+		{"sparkly", tcell.StyleDefault, true},
+		{"white on", tcell.StyleDefault, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseStyle(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseStyle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseStyle() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThemes(t *testing.T) {
+	for name, styles := range THEMES {
+		for element, spec := range styles {
+			if _, err := ParseStyle(spec); err != nil {
+				t.Errorf("theme %s: invalid style for %s: %v", name, element, err)
+			}
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name      string
+		theme     string
+		overrides map[Name]string
+		noColor   bool
+		element   Name
+		want      tcell.Style
+		wantErr   bool
+	}{
+		{"uses default theme", "", nil, false, STYLE_LABEL, tcell.StyleDefault.Bold(true), false},
+		{"uses built-in themes", THEME_HIGH_CONTRAST, nil, false, STYLE_NO_MATCH, tcell.StyleDefault.Bold(true).Foreground(tcell.ColorRed), false},
+		{"applies overrides", THEME_DEFAULT, map[Name]string{STYLE_LABEL: "green"}, false, STYLE_LABEL, tcell.StyleDefault.Foreground(tcell.ColorGreen), false},
+		{"drops colours", THEME_HIGH_CONTRAST, nil, true, STYLE_NO_MATCH, tcell.StyleDefault.Bold(true), false},
+		{"uses terminal defaults for unlisted elements", THEME_DEFAULT, nil, false, STYLE_VALUE, tcell.StyleDefault, false},
+		{"fails on unknown themes", "neon", nil, false, "", tcell.StyleDefault, true},
+		{"fails on unknown elements", THEME_DEFAULT, map[Name]string{"sidebar": "red"}, false, "", tcell.StyleDefault, true},
+		{"fails on invalid styles", THEME_DEFAULT, map[Name]string{STYLE_LABEL: "sparkly"}, false, "", tcell.StyleDefault, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.theme, tt.overrides, tt.noColor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if style := got.Style(tt.element); style != tt.want {
+				t.Errorf("Theme.Style() = %v, want %v", style, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := tcell.StyleDefault.Background(tcell.ColorBlue).Italic(true)
+	overlay := tcell.StyleDefault.Foreground(tcell.ColorYellow).Bold(true)
+
+	want := tcell.StyleDefault.Background(tcell.ColorBlue).Foreground(tcell.ColorYellow).Italic(true).Bold(true)
+	if got := Merge(base, overlay); got != want {
+		t.Errorf("Merge() = %v, want %v", got, want)
+	}
+}
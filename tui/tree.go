@@ -0,0 +1,402 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/gdamore/tcell/v2/views"
+
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/log"
+	"github.com/shikaan/keydex/tui/components"
+	"github.com/shikaan/keydex/tui/components/autocomplete"
+	"github.com/shikaan/keydex/tui/keymap"
+)
+
+// Groups expanded in the tree, and the item selected when it was left.
+// They survive navigation, so that going back to the tree finds it as it was.
+type treeState struct {
+	expanded map[kdbx.UUID]bool
+	selected kdbx.UUID
+}
+
+// A row of the tree: either a group or an entry
+type treeNode struct {
+	group *kdbx.Group
+	entry *kdbx.Entry
+	depth int
+	// Index of the node of the parent group, -1 for top-level groups
+	parent int
+}
+
+func (n treeNode) uuid() kdbx.UUID {
+	if n.entry != nil {
+		return n.entry.UUID
+	}
+	return n.group.UUID
+}
+
+// Returns the group of the node: the node itself, or the group holding the entry
+func (n treeNode) containingGroup(nodes []treeNode) *kdbx.Group {
+	if n.entry != nil {
+		return nodes[n.parent].group
+	}
+	return n.group
+}
+
+type TreeView struct {
+	list  *autocomplete.List
+	nodes []treeNode
+	// False while prompts are shown, which get the keys instead
+	focused bool
+
+	components.Container
+}
+
+// Lists the groups of the database depth first, with the subgroups and the
+// entries of the expanded ones
+func buildTree(groups []kdbx.Group, expanded map[kdbx.UUID]bool) []treeNode {
+	nodes := []treeNode{}
+
+	var walk func(groups []kdbx.Group, depth, parent int)
+	walk = func(groups []kdbx.Group, depth, parent int) {
+		for i := range groups {
+			group := &groups[i]
+			index := len(nodes)
+			nodes = append(nodes, treeNode{group: group, depth: depth, parent: parent})
+
+			if !expanded[group.UUID] {
+				continue
+			}
+
+			walk(group.Groups, depth+1, index)
+			for j := range group.Entries {
+				nodes = append(nodes, treeNode{entry: &kdbx.Entry{Entry: &group.Entries[j]}, depth: depth + 1, parent: index})
+			}
+		}
+	}
+	walk(groups, 0, -1)
+
+	return nodes
+}
+
+// Returns the number of entries in group and its subgroups
+func countEntries(group *kdbx.Group) int {
+	count := len(group.Entries)
+	for i := range group.Groups {
+		count += countEntries(&group.Groups[i])
+	}
+	return count
+}
+
+// Returns the text of the row of node
+func formatTreeNode(node treeNode, expanded map[kdbx.UUID]bool) string {
+	indent := strings.Repeat("  ", node.depth)
+
+	if node.entry != nil {
+		return indent + "  " + node.entry.GetTitle()
+	}
+
+	marker := "▸ "
+	if expanded[node.group.UUID] {
+		marker = "▾ "
+	}
+	return fmt.Sprintf("%s%s%s (%d)", indent, marker, node.group.Name, countEntries(node.group))
+}
+
+// Returns the selected node, if any
+func (tv *TreeView) selected() (treeNode, bool) {
+	index := tv.list.Index()
+	if index < 0 || index >= len(tv.nodes) {
+		return treeNode{}, false
+	}
+	return tv.nodes[index], true
+}
+
+// Rebuilds the rows of the tree, selecting the node with uuid, if shown
+func (tv *TreeView) refresh(uuid kdbx.UUID) {
+	expanded := App.tree.expanded
+	tv.nodes = buildTree(App.State.Database.Content.Root.Groups, expanded)
+
+	matches := []autocomplete.Match{}
+	index := 0
+	for i, node := range tv.nodes {
+		matches = append(matches, autocomplete.Match{Entry: formatTreeNode(node, expanded)})
+		if node.uuid().Compare(uuid) {
+			index = i
+		}
+	}
+
+	tv.list.SetMatches(matches)
+	tv.list.Select(index)
+}
+
+// Expands or collapses the selected group
+func (tv *TreeView) setExpanded(node treeNode, expanded bool) {
+	if node.group == nil || App.tree.expanded[node.group.UUID] == expanded {
+		return
+	}
+
+	App.tree.expanded[node.group.UUID] = expanded
+	tv.refresh(node.group.UUID)
+}
+
+func (tv *TreeView) SetFocus(on bool) {
+	tv.focused = on
+}
+
+func (tv *TreeView) HasFocus() bool {
+	return tv.focused
+}
+
+func (tv *TreeView) HandleEvent(ev tcell.Event) bool {
+	switch ev := ev.(type) {
+	case *tcell.EventKey:
+		if !tv.focused {
+			return false
+		}
+
+		if App.Keys().Is(ev, keymap.ACTION_TREE) {
+			App.NavigateTo(NewEntryListView)
+			return true
+		}
+		if App.Keys().Is(ev, keymap.ACTION_DELETE) {
+			tv.delete()
+			return true
+		}
+		if App.Keys().Is(ev, keymap.ACTION_ADD_GROUP) {
+			tv.addGroup()
+			return true
+		}
+		if App.Keys().Is(ev, keymap.ACTION_RENAME_GROUP) {
+			tv.renameGroup()
+			return true
+		}
+
+		node, ok := tv.selected()
+		if !ok {
+			break
+		}
+
+		switch ev.Key() {
+		case tcell.KeyRight:
+			if node.group != nil && App.tree.expanded[node.group.UUID] {
+				tv.list.Select(tv.list.Index() + 1)
+				return true
+			}
+			tv.setExpanded(node, true)
+			return true
+		case tcell.KeyLeft:
+			if node.group != nil && App.tree.expanded[node.group.UUID] {
+				tv.setExpanded(node, false)
+				return true
+			}
+			if node.parent >= 0 {
+				tv.list.Select(node.parent)
+			}
+			return true
+		case tcell.KeyEnter:
+			if node.group != nil {
+				tv.setExpanded(node, !App.tree.expanded[node.group.UUID])
+				return true
+			}
+		}
+	}
+
+	return tv.list.HandleEvent(ev)
+}
+
+func (tv *TreeView) openEntry(node treeNode) bool {
+	group := node.containingGroup(tv.nodes)
+	ref, err := App.State.Database.MakeEntryEntityPath(node.entry, group)
+	if err != nil {
+		msg := "Could not open. Check logs for details."
+		App.Notify(msg)
+		log.Error(msg, err)
+		return true
+	}
+
+	App.State.Reference = ref
+	App.State.Entry = node.entry
+	App.State.Group = group
+	// Persisted with the next save, as KeePass does
+	App.State.Entry.SetLastAccessed()
+	App.NavigateTo(NewEntryView)
+	return true
+}
+
+func (tv *TreeView) addGroup() {
+	if App.IsReadOnly() {
+		App.Notify("Cannot create. Archive in read-only mode.")
+		return
+	}
+
+	node, ok := tv.selected()
+	if !ok {
+		App.Notify("Cannot create. No group selected.")
+		return
+	}
+	parent := node.containingGroup(tv.nodes)
+
+	App.Ask("Name of the group in \""+parent.Name+"\":", "", func(name string) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			App.Notify("Cannot create. Group name is empty.")
+			return
+		}
+
+		group := App.State.Database.NewGroup(name)
+		parent.Groups = append(parent.Groups, *group)
+
+		if e := App.SaveDatabase(); e != nil {
+			App.LockCurrentDatabase(e)
+			return
+		}
+
+		App.tree.expanded[parent.UUID] = true
+		tv.refresh(group.UUID)
+
+		msg := fmt.Sprintf("Group \"%s\" created successfully.", name)
+		App.Notify(msg)
+		log.Info(msg)
+	}, func() {
+		App.Notify("Operation cancelled. Group was not created.")
+	})
+}
+
+func (tv *TreeView) renameGroup() {
+	if App.IsReadOnly() {
+		App.Notify("Cannot rename. Archive in read-only mode.")
+		return
+	}
+
+	node, ok := tv.selected()
+	if !ok || node.group == nil {
+		App.Notify("Cannot rename. No group selected.")
+		return
+	}
+
+	group := node.group
+	App.Ask("Rename \""+group.Name+"\" to:", group.Name, func(name string) {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			App.Notify("Cannot rename. Group name is empty.")
+			return
+		}
+
+		old := group.Name
+		group.Name = name
+
+		if e := App.SaveDatabase(); e != nil {
+			App.LockCurrentDatabase(e)
+			return
+		}
+
+		tv.refresh(group.UUID)
+
+		msg := fmt.Sprintf("Group \"%s\" renamed to \"%s\".", old, name)
+		App.Notify(msg)
+		log.Info(msg)
+	}, func() {
+		App.Notify("Operation cancelled. Group was not renamed.")
+	})
+}
+
+func (tv *TreeView) delete() {
+	if App.IsReadOnly() {
+		msg := "Cannot delete. Archive in read-only mode."
+		App.Notify(msg)
+		log.Info(msg)
+		return
+	}
+
+	node, ok := tv.selected()
+	if !ok {
+		App.Notify("Cannot delete. Nothing selected.")
+		return
+	}
+
+	if node.group != nil && node.parent < 0 {
+		App.Notify("Cannot delete. Top-level groups cannot be deleted.")
+		return
+	}
+
+	var name, kind string
+	var remove func() error
+	if node.entry != nil {
+		name, kind = node.entry.GetTitle(), "Entry"
+		remove = func() error { return App.State.Database.RemoveEntry(node.entry.UUID) }
+	} else {
+		name, kind = node.group.Name, "Group"
+		remove = func() error { return App.State.Database.RemoveGroup(node.group.UUID) }
+	}
+	parent := tv.nodes[node.parent].group.UUID
+
+	App.Confirm(
+		"Delete \""+name+"\"? This cannot be undone.",
+		func() {
+			if err := remove(); err != nil {
+				msg := "Could not delete. " + kind + " cannot be found."
+				App.Notify(msg)
+				log.Error(msg, err)
+				return
+			}
+
+			if e := App.SaveDatabase(); e != nil {
+				App.LockCurrentDatabase(e)
+				return
+			}
+
+			tv.refresh(parent)
+
+			msg := fmt.Sprintf("%s \"%s\" deleted successfully.", kind, name)
+			App.Notify(msg)
+			log.Info(msg)
+		}, func() {
+			msg := "Operation cancelled. " + kind + " was not deleted."
+			App.Notify(msg)
+			log.Info(msg)
+		},
+	)
+}
+
+func NewTreeView(screen tcell.Screen) views.Widget {
+	App.SetTitle("Groups")
+
+	if App.tree.expanded == nil {
+		App.tree.expanded = map[kdbx.UUID]bool{}
+		for _, group := range App.State.Database.Content.Root.Groups {
+			App.tree.expanded[group.UUID] = true
+		}
+	}
+
+	view := &TreeView{}
+	view.Container = components.Container{}
+	maxX, maxY := getBoundaries(screen)
+
+	list := autocomplete.NewList(maxX, maxY)
+	list.SetEmpty("--- No Groups ---", nil)
+	list.OnSelect(func(match autocomplete.Match) bool {
+		node, ok := view.selected()
+		return ok && node.entry != nil && view.openEntry(node)
+	})
+	list.OnChange(func(match autocomplete.Match) {
+		node, ok := view.selected()
+		if !ok {
+			return
+		}
+
+		App.tree.selected = node.uuid()
+		// New entries are created in the selected group
+		App.State.Group = node.containingGroup(view.nodes)
+	})
+	view.list = list
+	view.refresh(App.tree.selected)
+
+	view.SetFocus(true)
+	App.LastFocused = view
+
+	view.SetContent(list)
+	return view
+}
@@ -0,0 +1,102 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shikaan/keydex/pkg/kdbx"
+)
+
+func TestTreeView(t *testing.T) {
+	screen := tcell.NewSimulationScreen("UTF-8")
+	screen.Init()
+	defer screen.Fini()
+
+	db := openLockTestDatabase(t)
+	// Left over by other tests, it would keep the views from opening
+	App.isDirty = false
+	Setup(screen, State{Database: db}, false)
+
+	press := func(keys ...*tcell.EventKey) {
+		for _, key := range keys {
+			App.layout.HandleEvent(key)
+		}
+	}
+	typeText := func(text string) {
+		for _, r := range text {
+			press(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+		}
+	}
+	key := func(k tcell.Key) *tcell.EventKey { return tcell.NewEventKey(k, 0, tcell.ModNone) }
+	ctrl := func(k tcell.Key) *tcell.EventKey { return tcell.NewEventKey(k, 0, tcell.ModCtrl) }
+	alt := func(r rune) *tcell.EventKey { return tcell.NewEventKey(tcell.KeyRune, r, tcell.ModAlt) }
+	rows := func() []string {
+		view := App.lastWidget.(*TreeView)
+		result := []string{}
+		for _, node := range view.nodes {
+			result = append(result, formatTreeNode(node, App.tree.expanded))
+		}
+		return result
+	}
+	selected := func() string {
+		view := App.lastWidget.(*TreeView)
+		return formatTreeNode(view.nodes[view.list.Index()], App.tree.expanded)
+	}
+
+	press(ctrl(tcell.KeyCtrlP), ctrl(tcell.KeyCtrlT))
+	if _, ok := App.lastWidget.(*TreeView); !ok {
+		t.Fatalf("expected the tree to be toggled from the finder")
+	}
+	if got := rows(); len(got) != 2 || got[0] != "▾ TestDB (1)" || got[1] != "    GitHub" {
+		t.Fatalf("expected the expanded root group, got %q", got)
+	}
+
+	press(alt('g'))
+	typeText("Work")
+	press(key(tcell.KeyEnter))
+	if got := selected(); got != "  ▸ Work (0)" {
+		t.Fatalf("expected the created group to be selected, got %q", got)
+	}
+
+	press(alt('r'), key(tcell.KeyBackspace2), key(tcell.KeyBackspace2), key(tcell.KeyBackspace2), key(tcell.KeyBackspace2))
+	typeText("Personal")
+	press(key(tcell.KeyEnter))
+	if got := selected(); got != "  ▸ Personal (0)" {
+		t.Fatalf("expected the group to be renamed, got %q", got)
+	}
+
+	press(key(tcell.KeyLeft), key(tcell.KeyLeft))
+	if got := rows(); len(got) != 1 || got[0] != "▸ TestDB (1)" {
+		t.Fatalf("expected the root group to be collapsed, got %q", got)
+	}
+
+	press(ctrl(tcell.KeyCtrlD))
+	if App.layout.Status.IsConfirming() {
+		t.Fatalf("expected top-level groups not to be deleted")
+	}
+
+	press(key(tcell.KeyRight), key(tcell.KeyRight), ctrl(tcell.KeyCtrlD), tcell.NewEventKey(tcell.KeyRune, 'y', tcell.ModNone))
+	if got := rows(); len(got) != 2 {
+		t.Fatalf("expected the group to be deleted, got %q", got)
+	}
+
+	press(key(tcell.KeyDown), key(tcell.KeyEnter))
+	if _, ok := App.lastWidget.(*EntryView); !ok || App.State.Reference != "/TestDB/GitHub" {
+		t.Fatalf("expected the entry to be opened, got %q", App.State.Reference)
+	}
+
+	press(ctrl(tcell.KeyCtrlP), ctrl(tcell.KeyCtrlT))
+	if got := selected(); got != "    GitHub" {
+		t.Errorf("expected the tree to select the last entry, got %q", got)
+	}
+
+	saved, err := kdbx.OpenFromPath(db.Path(), lockTestPassphrase, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer saved.Wipe()
+
+	if groups := saved.GetGroupPaths(); len(groups) != 1 {
+		t.Errorf("expected the group changes to be saved, got %v", groups)
+	}
+}
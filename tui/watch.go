@@ -0,0 +1,226 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/shikaan/keydex/pkg/errors"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/log"
+)
+
+// Interval at which the database is checked for changes, where its file
+// cannot be watched
+const POLL_INTERVAL = 2 * time.Second
+
+// Time given to other programs to finish writing the database
+const RELOAD_DELAY = 250 * time.Millisecond
+
+// Reloads the database when another program changes its file
+type fileWatcher struct {
+	path string
+	// Modification time and size of the file in memory
+	modTime time.Time
+	size    int64
+
+	done chan struct{}
+}
+
+// Watches the file of the database, using polling where the operating
+// system cannot notify changes. Call it once the application is set up.
+func (a *Application) WatchDatabase() {
+	w := &a.watch
+	if w.done != nil || a.State.Database == nil {
+		return
+	}
+
+	// Saving replaces the target of symbolic links
+	path, err := filepath.EvalSymlinks(a.State.Database.Path())
+	if err != nil {
+		log.Error("Could not watch the database", err)
+		return
+	}
+
+	w.path = path
+	w.done = make(chan struct{})
+	a.rememberDatabaseFile()
+
+	// The file is replaced on save, so its directory is watched instead
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		if err = watcher.Add(filepath.Dir(path)); err != nil {
+			watcher.Close()
+		}
+	}
+
+	if err != nil {
+		log.Error("Could not watch the database, checking it every "+POLL_INTERVAL.String(), err)
+		go a.pollDatabase(w.done)
+		return
+	}
+
+	go a.watchDatabase(watcher, path, w.done)
+}
+
+func (a *Application) watchDatabase(watcher *fsnotify.Watcher, path string, done chan struct{}) {
+	defer watcher.Close()
+
+	// Programs write the file in more steps: it is checked once they are done
+	var delay *time.Timer
+	for {
+		select {
+		case <-done:
+			if delay != nil {
+				delay.Stop()
+			}
+			return
+		case event := <-watcher.Events:
+			if filepath.Clean(event.Name) != path {
+				continue
+			}
+
+			if delay == nil {
+				delay = time.AfterFunc(RELOAD_DELAY, func() { a.PostFunc(a.checkDatabaseFile) })
+			} else {
+				delay.Reset(RELOAD_DELAY)
+			}
+		case err := <-watcher.Errors:
+			log.Error("Could not watch the database", err)
+		}
+	}
+}
+
+func (a *Application) pollDatabase(done chan struct{}) {
+	ticker := time.NewTicker(POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			a.PostFunc(a.checkDatabaseFile)
+		}
+	}
+}
+
+func (a *Application) stopWatchingDatabase() {
+	if a.watch.done != nil {
+		close(a.watch.done)
+		a.watch.done = nil
+	}
+}
+
+// Records the file of the database as the version in memory, such as after
+// saving it
+func (a *Application) rememberDatabaseFile() {
+	w := &a.watch
+	if w.path == "" {
+		return
+	}
+
+	if stat, err := os.Stat(w.path); err == nil {
+		w.modTime, w.size = stat.ModTime(), stat.Size()
+	}
+}
+
+// Reloads the database if its file changed since it was read. Unsaved
+// changes are kept, unless the user decides otherwise.
+func (a *Application) checkDatabaseFile() {
+	w := &a.watch
+	// Unlocking reads the file again anyway
+	if a.IsLocked() || a.State.Database == nil || w.path == "" {
+		return
+	}
+
+	// The file is missing while other programs replace it
+	stat, err := os.Stat(w.path)
+	if err != nil || (stat.ModTime().Equal(w.modTime) && stat.Size() == w.size) {
+		return
+	}
+	w.modTime, w.size = stat.ModTime(), stat.Size()
+
+	log.Info("Database changed on disk")
+	if !a.IsDirty() {
+		a.ReloadDatabase()
+		return
+	}
+
+	a.Confirm(
+		"The database changed on disk. Reload it and lose unsaved changes?",
+		func() {
+			a.SetDirty(false)
+			a.ReloadDatabase()
+		},
+		func() {
+			a.Notify("Unsaved changes kept. Saving will overwrite the changes on disk.")
+		},
+	)
+}
+
+// Reads the database from disk again, keeping the current entry and view.
+// If the credentials changed, they are asked for like on the lock screen.
+func (a *Application) ReloadDatabase() {
+	db := a.State.Database
+
+	reloaded, err := db.Reopen()
+	if errors.Is(err, errors.ErrWrongCredentials) {
+		log.Info("Database credentials changed on disk")
+		a.lockSession("The database changed on disk, and its credentials with it.")
+		return
+	}
+	if err != nil {
+		msg := "Could not reload the database changed on disk."
+		a.Notify(msg)
+		log.Error(msg, err)
+		return
+	}
+
+	state := State{Database: reloaded, Reference: a.State.Reference}
+	if a.State.Entry != nil {
+		state.Entry = reloaded.GetEntry(a.State.Entry.UUID)
+	}
+	if state.Entry != nil {
+		var group *kdbx.UUID
+		if a.State.Group != nil {
+			group = &a.State.Group.UUID
+		}
+		state.Group = groupOf(reloaded, state.Entry, group)
+		if path, err := reloaded.MakeEntryEntityPath(state.Entry, state.Group); err == nil {
+			state.Reference = path
+		}
+	}
+
+	hadEntry := a.State.Entry != nil
+	a.State = state
+	db.Wipe()
+	log.Info("Database reloaded")
+
+	// The entry was deleted by the other program, or it was new
+	if hadEntry && state.Entry == nil {
+		a.NavigateToWithoutDirtyGuard(NewEntryListView)
+		a.Notify("Entry is not available anymore.")
+		return
+	}
+
+	a.NavigateToWithoutDirtyGuard(a.lastView)
+}
+
+// Returns the group to show entry in: the one with the given UUID if it is
+// still there, or the one holding it
+func groupOf(db *kdbx.Database, entry *kdbx.Entry, uuid *kdbx.UUID) *kdbx.Group {
+	var group *kdbx.Group
+	if uuid != nil {
+		group = db.GetGroup(*uuid)
+	}
+	if group == nil {
+		group = db.GetGroupForEntry(entry)
+	}
+	if group == nil {
+		group = db.GetRootGroup()
+	}
+
+	return group
+}
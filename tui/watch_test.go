@@ -0,0 +1,126 @@
+package tui
+
+import (
+	"testing"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/shikaan/keydex/pkg/kdbx"
+	"github.com/shikaan/keydex/pkg/secure"
+)
+
+// Changes the title of the "GitHub" entry like another program would
+func changeOnDisk(t *testing.T, path, title string) {
+	t.Helper()
+
+	db, err := kdbx.OpenFromPath(path, lockTestPassphrase, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Wipe()
+
+	db.GetFirstEntryByPath("/TestDB/GitHub").SetValue(kdbx.TITLE_KEY, title)
+	if err := db.SaveAndUnlockEntries(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setupWatched(t *testing.T, screen tcell.Screen) string {
+	t.Helper()
+
+	db := openLockTestDatabase(t)
+	entry := db.GetFirstEntryByPath("/TestDB/GitHub")
+	// Left over by other tests, it would keep the entry from opening
+	App.isDirty = false
+	Setup(screen, State{Database: db, Entry: entry, Group: db.GetGroupForEntry(entry), Reference: "/TestDB/GitHub"}, false)
+
+	App.watch = fileWatcher{path: db.Path()}
+	App.rememberDatabaseFile()
+	return db.Path()
+}
+
+func TestApplication_checkDatabaseFile(t *testing.T) {
+	t.Run("reloads silently without unsaved changes", func(t *testing.T) {
+		screen := tcell.NewSimulationScreen("UTF-8")
+		screen.Init()
+		defer screen.Fini()
+
+		path := setupWatched(t, screen)
+		uuid := App.State.Entry.UUID
+
+		App.checkDatabaseFile()
+		if App.State.Entry.GetTitle() != "GitHub" {
+			t.Fatalf("expected no reload while the file is unchanged")
+		}
+
+		changeOnDisk(t, path, "GitLab")
+		App.checkDatabaseFile()
+
+		if App.layout.Status.IsConfirming() {
+			t.Errorf("expected no confirmation")
+		}
+		if _, ok := App.lastWidget.(*EntryView); !ok {
+			t.Errorf("expected the entry to stay open, got %T", App.lastWidget)
+		}
+		if entry := App.State.Entry; entry.UUID != uuid || entry.GetTitle() != "GitLab" {
+			t.Errorf("expected the changed entry, got %q", entry.GetTitle())
+		}
+		if App.State.Reference != "/TestDB/GitLab" {
+			t.Errorf("expected the reference to follow the entry, got %q", App.State.Reference)
+		}
+	})
+
+	t.Run("asks before dropping unsaved changes", func(t *testing.T) {
+		screen := tcell.NewSimulationScreen("UTF-8")
+		screen.Init()
+		defer screen.Fini()
+
+		path := setupWatched(t, screen)
+		db := App.State.Database
+
+		App.layout.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'X', tcell.ModNone))
+		changeOnDisk(t, path, "GitLab")
+		App.checkDatabaseFile()
+
+		if !App.layout.Status.IsConfirming() || App.State.Database != db {
+			t.Fatalf("expected a confirmation before reloading")
+		}
+
+		App.layout.HandleEvent(tcell.NewEventKey(tcell.KeyRune, 'y', tcell.ModNone))
+
+		if App.IsDirty() || App.State.Entry.GetTitle() != "GitLab" {
+			t.Errorf("expected the changed entry, got %q", App.State.Entry.GetTitle())
+		}
+	})
+
+	t.Run("asks for changed credentials", func(t *testing.T) {
+		screen := tcell.NewSimulationScreen("UTF-8")
+		screen.Init()
+		defer screen.Fini()
+
+		path := setupWatched(t, screen)
+
+		other, err := kdbx.OpenFromPath(path, lockTestPassphrase, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := other.ChangePasswordAndKey(secure.FromString("changed"), ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := other.Save(); err != nil {
+			t.Fatal(err)
+		}
+		other.Wipe()
+
+		App.checkDatabaseFile()
+		if _, ok := App.lastWidget.(*LockView); !ok || !App.IsLocked() {
+			t.Fatalf("expected the lock screen, got %T", App.lastWidget)
+		}
+
+		if err := App.UnlockSession(secure.FromString("changed"), ""); err != nil {
+			t.Fatalf("Application.UnlockSession() error = %v", err)
+		}
+		if _, ok := App.lastWidget.(*EntryView); !ok {
+			t.Errorf("expected the entry to be resumed, got %T", App.lastWidget)
+		}
+	})
+}